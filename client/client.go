@@ -0,0 +1,190 @@
+// Package client provides a library for issuing reads and writes against
+// a cluster of reference EPaxos servers. The client routes requests to
+// the nearest replica, retries preempted proposals with exponential
+// backoff, and tags each write with a session identity so that retried
+// proposals deduplicate on the server instead of executing twice.
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/nvanbenschoten/epaxos/transport"
+	transpb "github.com/nvanbenschoten/epaxos/transport/transportpb"
+)
+
+const (
+	// maxAttempts is the number of times a request is tried before the
+	// client gives up and surfaces the error.
+	maxAttempts = 5
+	// initialBackoff is the delay before the first retry of a preempted
+	// proposal. The delay doubles on each further retry.
+	initialBackoff = 10 * time.Millisecond
+	// maxBackoff caps the retry delay.
+	maxBackoff = 1 * time.Second
+)
+
+// ErrPreempted is returned when a proposal was repeatedly preempted by
+// competing proposals and the client exhausted its retries.
+var ErrPreempted = errors.New("client: proposal preempted")
+
+// ErrNoServers is returned when no server in the cluster is reachable.
+var ErrNoServers = errors.New("client: no servers available")
+
+// replica is a connection to a single server, along with the latency
+// measured while establishing it.
+type replica struct {
+	addr    string
+	client  *transport.ExternalClient
+	latency time.Duration
+}
+
+// Client is a client for a cluster of reference EPaxos servers. It is
+// safe for concurrent use.
+type Client struct {
+	// session identifies this client to the cluster. Writes carry the
+	// session along with a per-write sequence number, giving retried
+	// proposals a stable identity for deduplication.
+	session uint64
+	// seq is the session's write sequence number, incremented atomically.
+	seq uint64
+
+	mu sync.Mutex
+	// replicas holds reachable servers, nearest first.
+	replicas []*replica
+}
+
+// New creates a Client connected to the provided server addresses.
+// Servers that cannot be reached are skipped; an error is returned only
+// if no server is reachable. Connection establishment time is used as a
+// proxy for network proximity when choosing which replica to route
+// requests to.
+func New(addrs []string) (*Client, error) {
+	c := &Client{session: rand.Uint64()}
+	for _, addr := range addrs {
+		start := time.Now()
+		ec, err := transport.NewExternalClient(addr)
+		if err != nil {
+			continue
+		}
+		c.replicas = append(c.replicas, &replica{
+			addr:    addr,
+			client:  ec,
+			latency: time.Since(start),
+		})
+	}
+	if len(c.replicas) == 0 {
+		return nil, ErrNoServers
+	}
+	sort.Slice(c.replicas, func(i, j int) bool {
+		return c.replicas[i].latency < c.replicas[j].latency
+	})
+	return c, nil
+}
+
+// Read reads the value of the provided key.
+func (c *Client) Read(ctx context.Context, key []byte) ([]byte, error) {
+	var value []byte
+	err := c.withRetry(ctx, func(ec *transport.ExternalClient) error {
+		res, err := ec.Read(ctx, &transpb.KVReadRequest{Key: key})
+		if err != nil {
+			return err
+		}
+		value = res.Value
+		return nil
+	})
+	return value, err
+}
+
+// Propose proposes a write of the provided key to the provided value.
+// The write carries the client's session identity, so a retried proposal
+// deduplicates against its earlier attempt instead of executing twice.
+func (c *Client) Propose(ctx context.Context, key, value []byte) error {
+	seq := atomic.AddUint64(&c.seq, 1)
+	return c.withRetry(ctx, func(ec *transport.ExternalClient) error {
+		_, err := ec.Write(ctx, &transpb.KVWriteRequest{
+			Key:        key,
+			Value:      value,
+			SessionID:  c.session,
+			SessionSeq: seq,
+		})
+		return err
+	})
+}
+
+// Close closes all server connections.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.replicas {
+		r.client.Close()
+	}
+	c.replicas = nil
+}
+
+// withRetry runs the request against the nearest replica, retrying
+// preempted proposals with exponential backoff and failing over to the
+// next nearest replica when a server is unreachable.
+func (c *Client) withRetry(ctx context.Context, f func(*transport.ExternalClient) error) error {
+	backoff := initialBackoff
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		r := c.nearest()
+		if r == nil {
+			return ErrNoServers
+		}
+		err := f(r.client)
+		switch {
+		case err == nil:
+			return nil
+		case grpc.Code(err) == codes.Unavailable:
+			// The server is down; drop it and fail over immediately.
+			c.removeReplica(r)
+		case grpc.Code(err) == codes.Aborted:
+			// The proposal was preempted by a competing proposal. Back off
+			// before retrying to let the conflict drain.
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		default:
+			return err
+		}
+	}
+	return ErrPreempted
+}
+
+// nearest returns the reachable replica with the lowest measured latency,
+// or nil if none remain.
+func (c *Client) nearest() *replica {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.replicas) == 0 {
+		return nil
+	}
+	return c.replicas[0]
+}
+
+// removeReplica drops an unreachable replica from the routing set.
+func (c *Client) removeReplica(r *replica) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, cur := range c.replicas {
+		if cur == r {
+			c.replicas = append(c.replicas[:i], c.replicas[i+1:]...)
+			r.client.Close()
+			return
+		}
+	}
+}
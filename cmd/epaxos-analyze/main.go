@@ -0,0 +1,231 @@
+// Command epaxos-analyze inspects an exported instance-space snapshot
+// and reports conditions that stall or corrupt execution: dependency
+// cycles among unexecuted instances, committed instances blocked behind
+// uncommitted dependencies, dependencies on instances missing from the
+// snapshot, and holes in each replica's command space. It turns a raw
+// state dump, written with pb.WriteInstanceSnapshot, into actionable
+// diagnostics when a cluster stops making progress.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	flag "github.com/spf13/pflag"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+const snapshotDesc = "The instance-space snapshot file to analyze."
+
+var snapshotPath = flag.StringP("snapshot", "f", "", snapshotDesc)
+
+func main() {
+	flag.Parse()
+	if *snapshotPath == "" && flag.NArg() > 0 {
+		*snapshotPath = flag.Arg(0)
+	}
+	if *snapshotPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: epaxos-analyze [--snapshot] <file>")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*snapshotPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "opening snapshot: %v\n", err)
+		os.Exit(2)
+	}
+	defer f.Close()
+	insts, err := pb.ReadInstanceSnapshot(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading snapshot: %v\n", err)
+		os.Exit(2)
+	}
+
+	a := newAnalysis(insts)
+	issues := 0
+	issues += a.reportHoles()
+	issues += a.reportOrphanDeps()
+	issues += a.reportBlockedChains()
+	issues += a.reportCycles()
+
+	fmt.Printf("analyzed %d instances: %d issues\n", len(insts), issues)
+	if issues > 0 {
+		os.Exit(1)
+	}
+}
+
+// analysis holds the snapshot indexed for graph traversal.
+type analysis struct {
+	insts    map[pb.InstanceID]*pb.InstanceState
+	replicas []pb.ReplicaID
+	maxNum   map[pb.ReplicaID]pb.InstanceNum
+}
+
+func newAnalysis(insts []pb.InstanceState) *analysis {
+	a := &analysis{
+		insts:  make(map[pb.InstanceID]*pb.InstanceState, len(insts)),
+		maxNum: make(map[pb.ReplicaID]pb.InstanceNum),
+	}
+	for i := range insts {
+		is := &insts[i]
+		a.insts[is.InstanceID] = is
+		if is.InstanceNum > a.maxNum[is.ReplicaID] {
+			a.maxNum[is.ReplicaID] = is.InstanceNum
+		}
+	}
+	for r := range a.maxNum {
+		a.replicas = append(a.replicas, r)
+	}
+	sort.Slice(a.replicas, func(i, j int) bool { return a.replicas[i] < a.replicas[j] })
+	return a
+}
+
+// sortedIDs returns the snapshot's instance IDs in deterministic order.
+func (a *analysis) sortedIDs() []pb.InstanceID {
+	ids := make([]pb.InstanceID, 0, len(a.insts))
+	for id := range a.insts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].ReplicaID != ids[j].ReplicaID {
+			return ids[i].ReplicaID < ids[j].ReplicaID
+		}
+		return ids[i].InstanceNum < ids[j].InstanceNum
+	})
+	return ids
+}
+
+// reportHoles reports gaps in each replica's command space. A hole means
+// the replica never learned about an instance its peers may depend on.
+func (a *analysis) reportHoles() int {
+	found := 0
+	for _, r := range a.replicas {
+		for n := pb.InstanceNum(1); n <= a.maxNum[r]; n++ {
+			if _, ok := a.insts[pb.InstanceID{ReplicaID: r, InstanceNum: n}]; !ok {
+				fmt.Printf("hole: replica %d missing instance %d (max %d)\n", r, n, a.maxNum[r])
+				found++
+			}
+		}
+	}
+	return found
+}
+
+// reportOrphanDeps reports dependencies that reference instances not
+// present in the snapshot at all.
+func (a *analysis) reportOrphanDeps() int {
+	found := 0
+	for _, id := range a.sortedIDs() {
+		for _, dep := range a.insts[id].Deps {
+			if _, ok := a.insts[dep]; !ok {
+				fmt.Printf("orphan dep: %v depends on unknown instance %v\n", id, dep)
+				found++
+			}
+		}
+	}
+	return found
+}
+
+// reportBlockedChains reports committed instances that cannot execute
+// because a dependency has not committed, naming the blocking instance.
+func (a *analysis) reportBlockedChains() int {
+	found := 0
+	for _, id := range a.sortedIDs() {
+		is := a.insts[id]
+		if is.Status != pb.InstanceState_Committed {
+			continue
+		}
+		for _, dep := range is.Deps {
+			depIs, ok := a.insts[dep]
+			if ok && depIs.Status >= pb.InstanceState_Committed {
+				continue
+			}
+			status := "missing"
+			if ok {
+				status = depIs.Status.String()
+			}
+			fmt.Printf("blocked: %v committed but waiting on %v (%s)\n", id, dep, status)
+			found++
+		}
+	}
+	return found
+}
+
+// reportCycles reports strongly connected components of size greater
+// than one among unexecuted instances. Cycles are expected transiently,
+// but a cycle that includes an uncommitted instance pins every committed
+// member of the component.
+func (a *analysis) reportCycles() int {
+	t := &tarjan{a: a, index: make(map[pb.InstanceID]int), low: make(map[pb.InstanceID]int)}
+	for _, id := range a.sortedIDs() {
+		if a.insts[id].Status == pb.InstanceState_Executed {
+			continue
+		}
+		if _, seen := t.index[id]; !seen {
+			t.visit(id)
+		}
+	}
+	for _, scc := range t.sccs {
+		fmt.Printf("cycle: %d unexecuted instances %v\n", len(scc), scc)
+	}
+	return len(t.sccs)
+}
+
+// tarjan computes strongly connected components over the dependency
+// graph of unexecuted instances.
+type tarjan struct {
+	a     *analysis
+	next  int
+	index map[pb.InstanceID]int
+	low   map[pb.InstanceID]int
+	stack []pb.InstanceID
+	sccs  [][]pb.InstanceID
+}
+
+func (t *tarjan) visit(id pb.InstanceID) {
+	t.index[id] = t.next
+	t.low[id] = t.next
+	t.next++
+	t.stack = append(t.stack, id)
+
+	for _, dep := range t.a.insts[id].Deps {
+		depIs, ok := t.a.insts[dep]
+		if !ok || depIs.Status == pb.InstanceState_Executed {
+			continue
+		}
+		if _, seen := t.index[dep]; !seen {
+			t.visit(dep)
+			if t.low[dep] < t.low[id] {
+				t.low[id] = t.low[dep]
+			}
+		} else if t.onStack(dep) && t.index[dep] < t.low[id] {
+			t.low[id] = t.index[dep]
+		}
+	}
+
+	if t.low[id] == t.index[id] {
+		var scc []pb.InstanceID
+		for {
+			n := len(t.stack) - 1
+			member := t.stack[n]
+			t.stack = t.stack[:n]
+			scc = append(scc, member)
+			if member == id {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			t.sccs = append(t.sccs, scc)
+		}
+	}
+}
+
+func (t *tarjan) onStack(id pb.InstanceID) bool {
+	for _, s := range t.stack {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+// Command epaxos-soak runs a small in-process EPaxos cluster under a
+// randomized workload with fault injection, continuously verifying that
+// all replicas execute the same commands in the same order. It is
+// intended for long, nightly runs and release qualification rather than
+// the quick deterministic coverage of the unit tests.
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/nvanbenschoten/epaxos/epaxos"
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+const (
+	durationDesc = "How long to run the soak test for."
+	nodesDesc    = "The number of in-process replicas to run."
+	seedDesc     = "The seed for the workload's random source. 0 picks a random seed."
+	dropDesc     = "The fraction of messages to drop, injecting network faults."
+	keysDesc     = "The number of distinct keys in the workload's keyspace."
+)
+
+var (
+	duration = flag.DurationP("duration", "d", 10*time.Minute, durationDesc)
+	numNodes = flag.IntP("nodes", "n", 3, nodesDesc)
+	seed     = flag.Int64P("seed", "s", 0, seedDesc)
+	dropRate = flag.Float64P("drop-rate", "r", 0.01, dropDesc)
+	numKeys  = flag.IntP("keys", "k", 16, keysDesc)
+)
+
+// checker verifies state-hash agreement across replicas. EPaxos only
+// orders interfering commands, so replicas may legally interleave
+// commands on different keys differently; agreement is therefore checked
+// per key. Each replica reports a rolling hash of its executed command
+// sequence for every key it executes against; replicas that have executed
+// the same number of commands on a key must report identical hashes.
+type checker struct {
+	mu sync.Mutex
+	// perKey maps each key to each replica's rolling hash after every
+	// command executed on that key, indexed by per-key execution position.
+	perKey map[string]map[pb.ReplicaID][]uint64
+	// verified maps each key to the length of its verified common prefix.
+	verified map[string]int
+	// checked is the total number of verified (key, position) pairs.
+	checked int
+}
+
+func newChecker() *checker {
+	return &checker{
+		perKey:   make(map[string]map[pb.ReplicaID][]uint64),
+		verified: make(map[string]int),
+	}
+}
+
+// report records the replica's executed commands and verifies the longest
+// common per-key executed prefix across all replicas. It returns an error
+// on the first observed divergence.
+func (c *checker) report(r pb.ReplicaID, cmds []pb.Command) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cmd := range cmds {
+		key := string(cmd.Span.Key)
+		byReplica := c.perKey[key]
+		if byReplica == nil {
+			byReplica = make(map[pb.ReplicaID][]uint64)
+			c.perKey[key] = byReplica
+		}
+		hashes := byReplica[r]
+		var last uint64
+		if len(hashes) > 0 {
+			last = hashes[len(hashes)-1]
+		}
+		byReplica[r] = append(hashes, chainHash(last, cmd))
+
+		if err := c.verifyKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyKey extends the verified common prefix for the key, comparing all
+// replicas that have executed commands against it.
+func (c *checker) verifyKey(key string) error {
+	byReplica := c.perKey[key]
+	prefix := -1
+	for _, hs := range byReplica {
+		if prefix == -1 || len(hs) < prefix {
+			prefix = len(hs)
+		}
+	}
+	for i := c.verified[key]; i < prefix; i++ {
+		var exp uint64
+		first := true
+		for r, hs := range byReplica {
+			if first {
+				exp, first = hs[i], false
+				continue
+			}
+			if hs[i] != exp {
+				return fmt.Errorf("state hash divergence on key %q at executed "+
+					"index %d (replica %d: %x, expected %x)", key, i, r, hs[i], exp)
+			}
+		}
+		c.checked++
+	}
+	if prefix > c.verified[key] {
+		c.verified[key] = prefix
+	}
+	return nil
+}
+
+// chainHash folds the command into the previous rolling hash.
+func chainHash(last uint64, cmd pb.Command) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for i := uint(0); i < 8; i++ {
+		buf[i] = byte(last >> (8 * i))
+	}
+	h.Write(buf[:])
+	for i := uint(0); i < 8; i++ {
+		buf[i] = byte(cmd.ID >> (8 * i))
+	}
+	h.Write(buf[:])
+	h.Write(cmd.Span.Key)
+	h.Write(cmd.Data)
+	return h.Sum64()
+}
+
+// cluster wires the in-process replicas together, delivering messages
+// between them while injecting faults.
+type cluster struct {
+	nodes map[pb.ReplicaID]epaxos.Node
+	rng   *rand.Rand
+
+	mu      sync.Mutex
+	crashed map[pb.ReplicaID]bool
+}
+
+func (c *cluster) deliver(m pb.Message) {
+	c.mu.Lock()
+	drop := c.crashed[m.To] || c.rng.Float64() < *dropRate
+	c.mu.Unlock()
+	if drop {
+		return
+	}
+	// Nodes only stop after the run is over, so ErrStopped is benign.
+	_ = c.nodes[m.To].Step(context.Background(), m)
+}
+
+// injectFaults periodically "crashes" a random replica by dropping all of
+// its inbound traffic for a short period.
+func (c *cluster) injectFaults(stop <-chan struct{}) {
+	for {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-stop:
+			return
+		}
+		r := pb.ReplicaID(c.rng.Intn(len(c.nodes)))
+		c.mu.Lock()
+		c.crashed[r] = true
+		c.mu.Unlock()
+
+		select {
+		case <-time.After(2 * time.Second):
+		case <-stop:
+			return
+		}
+		c.mu.Lock()
+		c.crashed[r] = false
+		c.mu.Unlock()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	log.Printf("starting soak test: nodes=%d duration=%s seed=%d drop-rate=%f",
+		*numNodes, *duration, *seed, *dropRate)
+
+	replicas := make([]pb.ReplicaID, *numNodes)
+	for i := range replicas {
+		replicas[i] = pb.ReplicaID(i)
+	}
+
+	c := &cluster{
+		nodes:   make(map[pb.ReplicaID]epaxos.Node, *numNodes),
+		rng:     rand.New(rand.NewSource(*seed)),
+		crashed: make(map[pb.ReplicaID]bool),
+	}
+	ck := newChecker()
+	for _, r := range replicas {
+		c.nodes[r] = epaxos.StartNode(&epaxos.Config{
+			ID:       r,
+			Nodes:    replicas,
+			RandSeed: *seed + int64(r) + 1,
+		})
+	}
+
+	stop := make(chan struct{})
+	failc := make(chan error, *numNodes)
+	var wg sync.WaitGroup
+
+	// Per-node event loops: forward outbound messages, tick, and feed the
+	// checker with executed commands.
+	for _, r := range replicas {
+		wg.Add(1)
+		go func(r pb.ReplicaID) {
+			defer wg.Done()
+			n := c.nodes[r]
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case rd := <-n.Ready():
+					for _, m := range rd.Messages {
+						c.deliver(m)
+					}
+					if err := ck.report(r, rd.ExecutedCommands); err != nil {
+						failc <- err
+						return
+					}
+					n.Advance()
+				case <-ticker.C:
+					n.Tick()
+				case <-stop:
+					return
+				}
+			}
+		}(r)
+	}
+
+	// Per-node workload generators.
+	for i, r := range replicas {
+		wg.Add(1)
+		go func(i int, r pb.ReplicaID) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(*seed * int64(i+7)))
+			var count uint64
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(time.Duration(rng.Intn(20)) * time.Millisecond):
+				}
+				count++
+				key := fmt.Sprintf("key-%d", rng.Intn(*numKeys))
+				cmd := pb.Command{
+					ID:      uint64(r)<<32 | count,
+					Span:    pb.Span{Key: pb.Key(key)},
+					Writing: true,
+					Data:    []byte(fmt.Sprintf("%d-%d", r, count)),
+				}
+				_ = c.nodes[r].Propose(context.Background(), cmd)
+			}
+		}(i, r)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.injectFaults(stop)
+	}()
+
+	var err error
+	select {
+	case err = <-failc:
+	case <-time.After(*duration):
+	}
+	close(stop)
+	wg.Wait()
+	for _, n := range c.nodes {
+		n.Stop()
+	}
+
+	if err != nil {
+		log.Printf("FAILED: %v (seed=%d)", err, *seed)
+		os.Exit(1)
+	}
+	log.Printf("PASSED: %d executed commands verified across %d replicas (seed=%d)",
+		ck.checked, *numNodes, *seed)
+}
@@ -0,0 +1,20 @@
+// Command conformance runs the EPaxos conformance harness over
+// stdin/stdout: it reads a trace of steps from stdin and writes the
+// observed protocol behavior to stdout, one event per line. This lets
+// the Go implementation be exercised with the same traces — and checked
+// against the same golden outputs — as any other EPaxos implementation
+// that speaks the trace format.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/nvanbenschoten/epaxos/epaxos"
+)
+
+func main() {
+	if err := epaxos.RunConformance(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
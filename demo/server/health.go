@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nvanbenschoten/epaxos/epaxos"
+)
+
+// healthProbeTimeout bounds how long a health probe may wait on the
+// state machine before reporting the node unhealthy.
+const healthProbeTimeout = time.Second
+
+// serveHealth exposes the node's health predicates over HTTP so that
+// orchestrators can route traffic correctly:
+//
+//	/healthz/live   200 if the state machine is running
+//	/healthz/ready  200 if the node is caught up and executing
+//	/healthz/quorum 200 if the node can reach a quorum
+//	/healthz        200 only if all three predicates hold
+func (s *server) serveHealth(port int) {
+	mux := http.NewServeMux()
+	probe := func(pred func(hs epaxos.HealthStatus) bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+			defer cancel()
+			hs, err := s.node.Health(ctx)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			if !pred(hs) {
+				http.Error(w, fmt.Sprintf("%+v", hs), http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, "%+v\n", hs)
+		}
+	}
+	mux.HandleFunc("/healthz/live", probe(func(hs epaxos.HealthStatus) bool { return hs.Live }))
+	mux.HandleFunc("/healthz/ready", probe(func(hs epaxos.HealthStatus) bool { return hs.Ready }))
+	mux.HandleFunc("/healthz/quorum", probe(func(hs epaxos.HealthStatus) bool { return hs.QuorumConnected }))
+	mux.HandleFunc("/healthz", probe(func(hs epaxos.HealthStatus) bool {
+		return hs.Live && hs.Ready && hs.QuorumConnected
+	}))
+	mux.HandleFunc("/admin/loglevel", s.handleLogLevel)
+
+	addr := fmt.Sprintf(":%d", port)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		s.logger.Warningf("health endpoint failed: %v", err)
+	}
+}
+
+// handleLogLevel adjusts a subsystem's log verbosity at runtime:
+//
+//	/admin/loglevel?subsystem=instance&level=debug
+//
+// so an operator can raise verbosity for one subsystem on a misbehaving
+// replica without drowning in output from the others.
+func (s *server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	sub := epaxos.LogSubsystem(r.URL.Query().Get("subsystem"))
+	switch sub {
+	case epaxos.LogSubsystemInstance, epaxos.LogSubsystemExecutor,
+		epaxos.LogSubsystemTransport, epaxos.LogSubsystemStorage,
+		epaxos.LogSubsystemRecovery:
+	default:
+		http.Error(w, fmt.Sprintf("unknown subsystem %q", sub), http.StatusBadRequest)
+		return
+	}
+	lvl, ok := epaxos.ParseLogLevel(r.URL.Query().Get("level"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown level %q", r.URL.Query().Get("level")), http.StatusBadRequest)
+		return
+	}
+	s.logLevels.Set(sub, lvl)
+	s.logger.Infof("set %s log level to %s", sub, r.URL.Query().Get("level"))
+	fmt.Fprintf(w, "%s=%s\n", sub, r.URL.Query().Get("level"))
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	flag "github.com/spf13/pflag"
@@ -11,6 +13,7 @@ import (
 	"github.com/nvanbenschoten/epaxos/demo/util"
 	"github.com/nvanbenschoten/epaxos/epaxos"
 	"github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+	"github.com/nvanbenschoten/epaxos/transport"
 )
 
 const (
@@ -24,15 +27,23 @@ const (
 	idDesc = "The optional id specifier of this process. Only needed if multiple " +
 		"processes in the hostfile are running on the same host, otherwise it can " +
 		"be deduced from the hostfile. 0-indexed."
-	verboseDesc = "Sets the logging level to verbose."
+	verboseDesc    = "Sets the logging level to verbose."
+	healthPortDesc = "The optional port on which to serve the /healthz health " +
+		"check endpoints over HTTP. Disabled if left as 0."
+	joinDesc = "A comma-separated list of seed addresses to join an existing " +
+		"cluster through, in place of a hostfile. The current membership is " +
+		"discovered from the first reachable seed, which also proposes the " +
+		"membership change admitting this node. Requires the --id flag."
 )
 
 var (
-	help     = flag.Bool("help", false, "")
-	verbose  = flag.BoolP("verbose", "v", false, verboseDesc)
-	hostfile = flag.StringP("hostfile", "h", "hostfile", hostfileDesc)
-	port     = flag.IntP("port", "p", 2346, portDesc)
-	hostID   = flag.IntP("id", "i", -1, idDesc)
+	help       = flag.Bool("help", false, "")
+	verbose    = flag.BoolP("verbose", "v", false, verboseDesc)
+	hostfile   = flag.StringP("hostfile", "h", "hostfile", hostfileDesc)
+	port       = flag.IntP("port", "p", 2346, portDesc)
+	hostID     = flag.IntP("id", "i", -1, idDesc)
+	healthPort = flag.Int("health-port", 0, healthPortDesc)
+	join       = flag.StringP("join", "j", "", joinDesc)
 )
 
 func main() {
@@ -44,7 +55,13 @@ func main() {
 		return
 	}
 
-	ph, err := parseHostfile()
+	var ph parsedHostfile
+	var err error
+	if *join != "" {
+		ph, err = joinThroughSeeds()
+	} else {
+		ph, err = parseHostfile()
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -54,6 +71,10 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *healthPort > 0 {
+		go s.serveHealth(*healthPort)
+	}
+
 	s.Run()
 }
 
@@ -84,6 +105,7 @@ func parseHostfile() (parsedHostfile, error) {
 					"Consider using the --id flag.")
 			}
 			ph.myID = addr.Idx
+			ph.myHost = addr.Host
 			ph.myPort = addr.Port
 		} else {
 			ph.peerAddrs = append(ph.peerAddrs, addr)
@@ -99,13 +121,54 @@ func parseHostfile() (parsedHostfile, error) {
 	return ph, nil
 }
 
+// joinThroughSeeds discovers the cluster through the seed addresses in
+// the --join flag, asking a seed to admit this node, and returns the
+// discovered membership in hostfile form.
+func joinThroughSeeds() (parsedHostfile, error) {
+	var ph parsedHostfile
+	if *hostID < 0 {
+		return ph, errors.New("--id flag required with --join")
+	}
+	myHostname, err := os.Hostname()
+	if err != nil {
+		return ph, err
+	}
+	myAddr := fmt.Sprintf("%s:%d", myHostname, *port)
+	seeds := strings.Split(*join, ",")
+	info, err := transport.JoinCluster(seeds, epaxospb.ReplicaID(*hostID), myAddr)
+	if err != nil {
+		return ph, err
+	}
+	ph.myID = *hostID
+	ph.myHost = myHostname
+	ph.myPort = *port
+	ph.clusterID = info.ClusterID
+	ph.epoch = info.Epoch
+	for i, r := range info.Nodes {
+		if r == epaxospb.ReplicaID(*hostID) {
+			continue
+		}
+		addr, err := util.ParseAddr(int(r), info.Addrs[i], *port)
+		if err != nil {
+			return ph, err
+		}
+		ph.peerAddrs = append(ph.peerAddrs, addr)
+	}
+	return ph, nil
+}
+
 // parsedHostfile represents a hostfile that has been analyzed to determine
 // the network settings for the local process's server and the address of
 // all remove servers.
 type parsedHostfile struct {
 	myID      int
+	myHost    string
 	myPort    int
 	peerAddrs []util.Addr
+	// clusterID and epoch are only set when the membership was discovered
+	// through seed nodes rather than a hostfile.
+	clusterID epaxospb.ClusterID
+	epoch     uint64
 }
 
 func (ph parsedHostfile) toPaxosConfig() *epaxos.Config {
@@ -113,14 +176,20 @@ func (ph parsedHostfile) toPaxosConfig() *epaxos.Config {
 	if *verbose {
 		logger.EnableDebug()
 	}
-	nodes := make([]epaxospb.ReplicaID, len(ph.peerAddrs)+1)
-	for i := range nodes {
-		nodes[i] = epaxospb.ReplicaID(i)
+	nodes := make([]epaxospb.ReplicaID, 0, len(ph.peerAddrs)+1)
+	nodes = append(nodes, epaxospb.ReplicaID(ph.myID))
+	for _, addr := range ph.peerAddrs {
+		nodes = append(nodes, epaxospb.ReplicaID(addr.Idx))
 	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
 	return &epaxos.Config{
-		ID:     epaxospb.ReplicaID(ph.myID),
-		Nodes:  nodes,
-		Logger: logger,
+		ID:            epaxospb.ReplicaID(ph.myID),
+		ClusterID:     ph.clusterID,
+		Nodes:         nodes,
+		Epoch:         ph.epoch,
+		Logger:        logger,
+		LogLevels:     epaxos.NewSubsystemLevels(),
+		RelayMessages: true,
 	}
 }
 
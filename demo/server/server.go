@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,14 +22,18 @@ const (
 )
 
 type server struct {
-	id     epaxospb.ReplicaID
-	node   epaxos.Node
-	logger epaxos.Logger
-	ticker *time.Ticker
+	id        epaxospb.ReplicaID
+	node      epaxos.Node
+	logger    epaxos.Logger
+	logLevels *epaxos.SubsystemLevels
+	// transportLogger gates network-send logging through the transport
+	// subsystem's level.
+	transportLogger epaxos.Logger
+	ticker          *time.Ticker
 
 	server          *transport.EPaxosServer
 	clients         map[epaxospb.ReplicaID]*transport.EPaxosClient
-	unavailClients  map[epaxospb.ReplicaID]struct{}
+	breakers        map[epaxospb.ReplicaID]*transport.Breaker
 	pendingRequests map[uint64]chan<- transpb.KVResult
 
 	kv *store
@@ -40,14 +46,18 @@ func newServer(ph parsedHostfile) (*server, error) {
 		return nil, err
 	}
 
-	// Create EPaxosClients for each other host in the network.
+	// Create EPaxosClients for each other host in the network, each
+	// guarded by a circuit breaker.
 	clients := make(map[epaxospb.ReplicaID]*transport.EPaxosClient, len(ph.peerAddrs))
+	breakers := make(map[epaxospb.ReplicaID]*transport.Breaker, len(ph.peerAddrs))
 	for _, addr := range ph.peerAddrs {
 		pc, err := transport.NewEPaxosClient(addr.AddrStr())
 		if err != nil {
 			return nil, err
 		}
 		clients[epaxospb.ReplicaID(addr.Idx)] = pc
+		breakers[epaxospb.ReplicaID(addr.Idx)] =
+			transport.NewBreaker(transport.DefaultBreakerThreshold, transport.DefaultBreakerCooldown)
 	}
 
 	kv := newStore()
@@ -55,14 +65,21 @@ func newServer(ph parsedHostfile) (*server, error) {
 	config := ph.toPaxosConfig()
 	config.Storage = kv
 
+	// Serve the cluster configuration to joining nodes, so that they can
+	// discover the membership through this node instead of a hostfile.
+	info := buildClusterInfo(ph, config)
+	ps.SetClusterInfoSource(func() transpb.ClusterInfo { return info })
+
 	return &server{
 		id:              config.ID,
 		node:            epaxos.StartNode(config),
 		logger:          config.Logger,
+		logLevels:       config.LogLevels,
+		transportLogger: config.LogLevels.Logger(epaxos.LogSubsystemTransport, config.Logger),
 		ticker:          time.NewTicker(tickInterval),
 		server:          ps,
 		clients:         clients,
-		unavailClients:  make(map[epaxospb.ReplicaID]struct{}, len(ph.peerAddrs)),
+		breakers:        breakers,
 		pendingRequests: make(map[uint64]chan<- transpb.KVResult),
 		kv:              kv,
 	}, nil
@@ -87,15 +104,19 @@ func (s *server) Run() error {
 				s.node.Tick()
 			case m := <-s.server.Msgs():
 				s.node.Step(ctx, *m)
+				epaxospb.PutMessage(m)
 			case req := <-s.server.Requests():
 				s.registerClientRequest(req)
 				s.node.Propose(ctx, req.Command)
+			case jr := <-s.server.Joins():
+				s.handleJoin(ctx, jr)
 			case rd := <-s.node.Ready():
 				if err := s.sendAll(ctx, rd.Messages); err != nil {
-					s.logger.Warning(err)
+					s.transportLogger.Warning(err)
 				}
 
 				s.handleExecutedCmds(rd.ExecutedCommands)
+				s.node.Advance()
 			case <-ctx.Done():
 				return
 			}
@@ -105,6 +126,48 @@ func (s *server) Run() error {
 	return s.server.Serve()
 }
 
+// buildClusterInfo assembles the cluster description served to joining
+// nodes: the membership with each member's address, alongside the
+// cluster's identity and the configuration epoch the node started at.
+func buildClusterInfo(ph parsedHostfile, config *epaxos.Config) transpb.ClusterInfo {
+	info := transpb.ClusterInfo{
+		ClusterID: config.ClusterID,
+		Epoch:     config.Epoch,
+	}
+	addrByID := map[epaxospb.ReplicaID]string{
+		config.ID: fmt.Sprintf("%s:%d", ph.myHost, ph.myPort),
+	}
+	for _, addr := range ph.peerAddrs {
+		addrByID[epaxospb.ReplicaID(addr.Idx)] = addr.AddrStr()
+	}
+	for _, r := range config.Nodes {
+		info.Nodes = append(info.Nodes, r)
+		info.Addrs = append(info.Addrs, addrByID[r])
+	}
+	return info
+}
+
+// handleJoin admits a new replica: it dials the joiner so that protocol
+// traffic can reach it, then proposes the membership change adding it.
+func (s *server) handleJoin(ctx context.Context, jr transpb.JoinRequest) {
+	if _, ok := s.clients[jr.ReplicaID]; !ok {
+		pc, err := transport.NewEPaxosClient(jr.Addr)
+		if err != nil {
+			s.logger.Warningf("could not dial joining replica %d at %s: %v",
+				jr.ReplicaID, jr.Addr, err)
+			return
+		}
+		s.clients[jr.ReplicaID] = pc
+		s.breakers[jr.ReplicaID] =
+			transport.NewBreaker(transport.DefaultBreakerThreshold, transport.DefaultBreakerCooldown)
+	}
+	cc := epaxos.ConfChange{Type: epaxos.ConfChangeAddReplica, Replica: jr.ReplicaID}
+	if err := s.node.Propose(ctx, *epaxos.NewConfChangeCommand(rand.Uint64(), cc)); err != nil {
+		s.logger.Warningf("could not propose conf change for joining replica %d: %v",
+			jr.ReplicaID, err)
+	}
+}
+
 func (s *server) registerClientRequest(req transport.Request) {
 	s.pendingRequests[req.Command.ID] = req.ReturnC
 }
@@ -157,7 +220,16 @@ func (s *server) sendAll(ctx context.Context, msgs []epaxospb.Message) error {
 		outboxes[m.To] = append(outboxes[m.To], m)
 	}
 	for to, toMsgs := range outboxes {
-		if _, unavail := s.unavailClients[to]; unavail {
+		if b, ok := s.breakers[to]; ok && !b.Allow() {
+			// The peer's breaker is open. Relay its messages through a
+			// healthy peer in case only the direct link is broken; if no
+			// relay is available, drop the messages and let the protocol's
+			// retransmission handle recovery once the breaker closes.
+			if via, ok := s.pickRelay(to); ok {
+				if err := s.sendAllVia(ctx, toMsgs, via); err != nil {
+					s.transportLogger.Warning(err)
+				}
+			}
 			continue
 		}
 		if err := s.sendAllTo(ctx, toMsgs, to); err != nil {
@@ -167,20 +239,58 @@ func (s *server) sendAll(ctx context.Context, msgs []epaxospb.Message) error {
 	return nil
 }
 
+// pickRelay selects a peer with a closed breaker to relay messages for
+// the unreachable replica through.
+func (s *server) pickRelay(unreachable epaxospb.ReplicaID) (epaxospb.ReplicaID, bool) {
+	for to, b := range s.breakers {
+		if to != unreachable && b.Allow() {
+			return to, true
+		}
+	}
+	return 0, false
+}
+
+// BreakerStates returns a snapshot of each peer's circuit breaker, for
+// status reporting.
+func (s *server) BreakerStates() map[epaxospb.ReplicaID]transport.BreakerState {
+	states := make(map[epaxospb.ReplicaID]transport.BreakerState, len(s.breakers))
+	for to, b := range s.breakers {
+		states[to] = b.State()
+	}
+	return states
+}
+
 func (s *server) sendAllTo(
 	ctx context.Context, msgs []epaxospb.Message, to epaxospb.ReplicaID,
+) error {
+	for _, m := range msgs {
+		if m.To != to {
+			panic("unexpected destination")
+		}
+	}
+	return s.sendAllVia(ctx, msgs, to)
+}
+
+// sendAllVia delivers the messages over the connection to the provided
+// peer, which need not be their destination: messages for an unreachable
+// replica can be sent to a healthy peer, which relays them onward.
+func (s *server) sendAllVia(
+	ctx context.Context, msgs []epaxospb.Message, via epaxospb.ReplicaID,
 ) (err error) {
-	c, ok := s.clients[to]
+	c, ok := s.clients[via]
 	if !ok {
-		return errors.Errorf("message found with unknown destination: %v", to)
+		return errors.Errorf("message found with unknown destination: %v", via)
 	}
 	defer func() {
-		if grpc.Code(err) == codes.Unavailable {
-			// If the node is down, record that it's unavailable so that we
-			// dont continue to sent to it.
-			s.logger.Warningf("detected node %d unavailable", to)
-			s.unavailClients[to] = struct{}{}
-			c.Close()
+		b := s.breakers[via]
+		if err != nil {
+			if grpc.Code(err) == codes.Unavailable && b.Failure() {
+				// The node has been down for several consecutive sends;
+				// stop attempting until the breaker's cooldown expires.
+				s.transportLogger.Warningf("detected node %d unavailable; tripping breaker", via)
+			}
+		} else {
+			b.Success()
 		}
 	}()
 	stream, err := c.DeliverMessage(ctx)
@@ -188,9 +298,6 @@ func (s *server) sendAllTo(
 		return err
 	}
 	for _, m := range msgs {
-		if m.To != to {
-			panic("unexpected destination")
-		}
 		if err := stream.Send(&m); err != nil {
 			return err
 		}
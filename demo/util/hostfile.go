@@ -53,6 +53,13 @@ func ParseHostfile(filename string, defaultPort int) ([]Addr, error) {
 	return addrs, nil
 }
 
+// ParseAddr parses a "host" or "host:port" string into an Addr with the
+// provided index, applying the default port when none is given.
+func ParseAddr(idx int, s string, defaultPort int) (Addr, error) {
+	host, port, err := parseHost(s, defaultPort)
+	return Addr{Idx: idx, Host: host, Port: port}, err
+}
+
 func parseHost(line string, defaultPort int) (string, int, error) {
 	s := strings.Split(line, ":")
 	switch len(s) {
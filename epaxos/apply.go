@@ -0,0 +1,148 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// ApplyFunc applies an executed command to the application state machine.
+// A non-nil error invokes the configured ApplyErrorPolicy.
+type ApplyFunc func(pb.Command) error
+
+// ApplyErrorPolicy determines how a replica reacts when its ApplyFunc
+// returns an error.
+type ApplyErrorPolicy int
+
+const (
+	// ApplyErrorHalt stops the replica: no further commands are applied
+	// and incoming proposals and messages are ignored. Halting is the
+	// consistent choice when an apply error means the application state
+	// machine can no longer be trusted. It is the default policy.
+	ApplyErrorHalt ApplyErrorPolicy = iota
+	// ApplyErrorRetry retries the failed command with exponential
+	// backoff, holding all later commands in order behind it. Suitable
+	// for transient errors like resource exhaustion.
+	ApplyErrorRetry
+	// ApplyErrorSkip records the failure and continues with the next
+	// command. Only safe when the application tolerates holes in its
+	// applied state.
+	ApplyErrorSkip
+)
+
+// applyRetryBackoff is the initial retry delay, in ticks, under
+// ApplyErrorRetry. The delay doubles on every consecutive failure, up to
+// maxApplyRetryBackoff.
+const applyRetryBackoff = 4
+const maxApplyRetryBackoff = 64
+
+// ApplyErrorEvent is emitted whenever the ApplyFunc returns an error,
+// regardless of policy.
+type ApplyErrorEvent struct {
+	// Command is the command that failed to apply.
+	Command pb.Command
+	// Err is the error returned by the ApplyFunc.
+	Err error
+	// Policy is the policy invoked in response.
+	Policy ApplyErrorPolicy
+}
+
+func (ApplyErrorEvent) event() {}
+
+// applyRetryState holds the command currently being retried under
+// ApplyErrorRetry, along with the commands queued in order behind it.
+type applyRetryState struct {
+	pending *pb.Command
+	backoff int
+	timer   tickingTimer
+	queued  []pb.Command
+}
+
+// applyCommand routes an executed command to the application's
+// ApplyFunc, honoring the halt flag and any in-progress retry.
+func (p *epaxos) applyCommand(cmd pb.Command) {
+	if p.halted {
+		return
+	}
+	if p.applyRetry.pending != nil {
+		// A failed command is being retried; later commands must wait
+		// behind it to preserve execution order.
+		p.applyRetry.queued = append(p.applyRetry.queued, cmd)
+		return
+	}
+	p.tryApply(cmd)
+}
+
+// tryApply applies the command and reacts to failure according to the
+// configured policy.
+func (p *epaxos) tryApply(cmd pb.Command) {
+	res, err := p.applyToStateMachine(cmd)
+	if err == nil {
+		p.foldAppliedCommand(cmd)
+		p.deliverResult(cmd, res, nil)
+		return
+	}
+	p.emitEvent(ApplyErrorEvent{Command: cmd, Err: err, Policy: p.applyErrPolicy})
+	switch p.applyErrPolicy {
+	case ApplyErrorHalt:
+		p.execLogger.Errorf("halting replica: apply failed for command %d: %v", cmd.ID, err)
+		p.halted = true
+	case ApplyErrorRetry:
+		p.execLogger.Warningf("apply failed for command %d, retrying: %v", cmd.ID, err)
+		p.scheduleApplyRetry(cmd)
+	case ApplyErrorSkip:
+		p.execLogger.Warningf("apply failed for command %d, skipping: %v", cmd.ID, err)
+		p.deliverResult(cmd, nil, err)
+	}
+}
+
+// onExecutionCorruption halts the replica when the executor detects that a
+// strongly connected component of committed instances cannot be ordered
+// deterministically. Applying the component in an arbitrary order could
+// diverge from other replicas, so halting is the only safe reaction.
+func (p *epaxos) onExecutionCorruption(reason string) {
+	p.execLogger.Errorf("halting replica: execution corruption detected: %s", reason)
+	p.emitEvent(ExecutionCorruptionEvent{Reason: reason})
+	p.halted = true
+}
+
+// scheduleApplyRetry arranges for the failed command to be retried after
+// a backoff, doubling the delay on each consecutive failure.
+func (p *epaxos) scheduleApplyRetry(cmd pb.Command) {
+	rs := &p.applyRetry
+	if rs.pending == nil {
+		rs.backoff = applyRetryBackoff
+	} else if rs.backoff < maxApplyRetryBackoff {
+		rs.backoff *= 2
+	}
+	rs.pending = &cmd
+	rs.timer = makeTickingTimer(rs.backoff, func() {
+		p.retryApply()
+	})
+	p.registerOneTimeTimer(&rs.timer)
+}
+
+// retryApply retries the pending command. On success, the commands
+// queued behind it are applied in order; any of them failing takes over
+// as the pending command.
+func (p *epaxos) retryApply() {
+	rs := &p.applyRetry
+	cmd := *rs.pending
+	res, err := p.applyToStateMachine(cmd)
+	if err != nil {
+		p.emitEvent(ApplyErrorEvent{Command: cmd, Err: err, Policy: p.applyErrPolicy})
+		p.execLogger.Warningf("apply failed for command %d, retrying: %v", cmd.ID, err)
+		p.scheduleApplyRetry(cmd)
+		return
+	}
+	p.foldAppliedCommand(cmd)
+	p.deliverResult(cmd, res, nil)
+	rs.pending = nil
+	queued := rs.queued
+	rs.queued = nil
+	for i := range queued {
+		if rs.pending != nil || p.halted {
+			rs.queued = append(queued[i:], rs.queued...)
+			return
+		}
+		p.tryApply(queued[i])
+	}
+}
@@ -0,0 +1,111 @@
+package epaxos
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestApplyErrorHalt verifies that an apply error under the default
+// policy halts the replica.
+func TestApplyErrorHalt(t *testing.T) {
+	var events []Event
+	applied := 0
+	p := newEPaxos(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0},
+		Apply: func(cmd pb.Command) error {
+			applied++
+			return errors.New("disk full")
+		},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+
+	p.Request(newTestingCommand("a", "z"))
+	if !p.halted {
+		t.Fatalf("expected replica to halt after apply error")
+	}
+	if e, ok := events[len(events)-1].(ApplyErrorEvent); !ok || e.Policy != ApplyErrorHalt {
+		t.Fatalf("expected ApplyErrorEvent with halt policy, found %+v", events)
+	}
+
+	// A halted replica ignores further proposals.
+	p.Request(newTestingCommand("b", "c"))
+	if applied != 1 {
+		t.Errorf("expected no further commands applied, found %d", applied)
+	}
+}
+
+// TestApplyErrorRetry verifies that a failed command is retried with
+// backoff and that later commands wait behind it in order.
+func TestApplyErrorRetry(t *testing.T) {
+	var applied []uint64
+	fail := true
+	p := newEPaxos(&Config{
+		ID:               0,
+		Nodes:            []pb.ReplicaID{0},
+		ApplyErrorPolicy: ApplyErrorRetry,
+		StateHashEvery:   1,
+		Apply: func(cmd pb.Command) error {
+			if fail {
+				return errors.New("busy")
+			}
+			applied = append(applied, cmd.ID)
+			return nil
+		},
+	})
+
+	cmdA := newTestingCommand("a", "z")
+	cmdA.ID = 1
+	cmdB := newTestingCommand("a", "z")
+	cmdB.ID = 2
+	p.Request(cmdA)
+	p.Request(cmdB)
+	if len(applied) != 0 {
+		t.Fatalf("expected no commands applied while failing, found %v", applied)
+	}
+
+	fail = false
+	for i := 0; i < applyRetryBackoff; i++ {
+		p.Tick()
+	}
+	if len(applied) != 2 || applied[0] != 1 || applied[1] != 2 {
+		t.Fatalf("expected commands applied in order after retry, found %v", applied)
+	}
+	// Commands that succeed on retry must still be folded into the
+	// running state hash, or the replica's checkpoints would diverge
+	// from peers that applied them on the first attempt.
+	if p.appliedCount != 2 {
+		t.Fatalf("expected 2 commands folded into the state hash, found %d", p.appliedCount)
+	}
+}
+
+// TestApplyErrorSkip verifies that failures are skipped and later
+// commands still apply.
+func TestApplyErrorSkip(t *testing.T) {
+	var applied []uint64
+	p := newEPaxos(&Config{
+		ID:               0,
+		Nodes:            []pb.ReplicaID{0},
+		ApplyErrorPolicy: ApplyErrorSkip,
+		Apply: func(cmd pb.Command) error {
+			if cmd.ID == 1 {
+				return errors.New("bad command")
+			}
+			applied = append(applied, cmd.ID)
+			return nil
+		},
+	})
+
+	cmdA := newTestingCommand("a", "z")
+	cmdA.ID = 1
+	cmdB := newTestingCommand("a", "z")
+	cmdB.ID = 2
+	p.Request(cmdA)
+	p.Request(cmdB)
+	if len(applied) != 1 || applied[0] != 2 {
+		t.Fatalf("expected the failed command to be skipped, found %v", applied)
+	}
+}
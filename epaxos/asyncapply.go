@@ -0,0 +1,105 @@
+package epaxos
+
+import (
+	"sync"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// AsyncApplier runs an ApplyFunc on a dedicated goroutine, fed through
+// an ordered handoff queue. Passing its Apply method as Config.Apply
+// moves application state machine execution off the consensus loop, so
+// that a slow application does not delay message handling and timer
+// processing. Commands are applied strictly in the order they were
+// executed.
+//
+// Because Apply only enqueues, it always reports success to the
+// consensus loop; apply errors bypass the configured ApplyErrorPolicy
+// and are instead delivered to the applier's error callback from its
+// goroutine, after which the applier continues with the next command.
+type AsyncApplier struct {
+	apply   ApplyFunc
+	onError func(pb.Command, error)
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []pb.Command
+	applying bool
+	stopped  bool
+	done     chan struct{}
+}
+
+// NewAsyncApplier returns an AsyncApplier wrapping the provided
+// ApplyFunc and starts its goroutine. The onError callback, if non-nil,
+// is invoked with each command whose apply fails.
+func NewAsyncApplier(apply ApplyFunc, onError func(pb.Command, error)) *AsyncApplier {
+	a := &AsyncApplier{
+		apply:   apply,
+		onError: onError,
+		done:    make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+// Apply enqueues the command for application and returns immediately.
+// It is intended to be passed as Config.Apply. After Stop it returns
+// ErrStopped.
+func (a *AsyncApplier) Apply(cmd pb.Command) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return ErrStopped
+	}
+	a.queue = append(a.queue, cmd)
+	a.cond.Broadcast()
+	return nil
+}
+
+// Flush blocks until every command enqueued before the call has been
+// applied.
+func (a *AsyncApplier) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for len(a.queue) > 0 || a.applying {
+		a.cond.Wait()
+	}
+}
+
+// Stop drains the queue, applies the remaining commands, and stops the
+// applier's goroutine.
+func (a *AsyncApplier) Stop() {
+	a.mu.Lock()
+	a.stopped = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+}
+
+func (a *AsyncApplier) run() {
+	defer close(a.done)
+	for {
+		a.mu.Lock()
+		for len(a.queue) == 0 && !a.stopped {
+			a.cond.Wait()
+		}
+		if len(a.queue) == 0 {
+			a.mu.Unlock()
+			return
+		}
+		cmd := a.queue[0]
+		a.queue = a.queue[1:]
+		a.applying = true
+		a.mu.Unlock()
+
+		if err := a.apply(cmd); err != nil && a.onError != nil {
+			a.onError(cmd, err)
+		}
+
+		a.mu.Lock()
+		a.applying = false
+		a.cond.Broadcast()
+		a.mu.Unlock()
+	}
+}
@@ -0,0 +1,75 @@
+package epaxos
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestAsyncApplierOrderedHandoff verifies that Apply returns without
+// waiting for the application and that commands are applied strictly in
+// the order they were handed off, even when the first command is slow.
+func TestAsyncApplierOrderedHandoff(t *testing.T) {
+	block := make(chan struct{})
+	var applied []uint64
+	a := NewAsyncApplier(func(cmd pb.Command) error {
+		if cmd.ID == 1 {
+			<-block
+		}
+		applied = append(applied, cmd.ID)
+		return nil
+	}, nil)
+	defer a.Stop()
+
+	// Both handoffs return immediately, while the first command is still
+	// blocked inside the application.
+	if err := a.Apply(pb.Command{ID: 1}); err != nil {
+		t.Fatalf("unexpected error from Apply: %v", err)
+	}
+	if err := a.Apply(pb.Command{ID: 2}); err != nil {
+		t.Fatalf("unexpected error from Apply: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no command applied while blocked, found %v", applied)
+	}
+
+	close(block)
+	a.Flush()
+	if exp := []uint64{1, 2}; len(applied) != 2 || applied[0] != exp[0] || applied[1] != exp[1] {
+		t.Fatalf("expected commands applied in order %v, found %v", exp, applied)
+	}
+}
+
+// TestAsyncApplierErrorCallback verifies that apply errors are delivered
+// to the error callback and that the applier continues with later
+// commands.
+func TestAsyncApplierErrorCallback(t *testing.T) {
+	var applied, failed []uint64
+	a := NewAsyncApplier(func(cmd pb.Command) error {
+		if cmd.ID == 1 {
+			return errors.New("boom")
+		}
+		applied = append(applied, cmd.ID)
+		return nil
+	}, func(cmd pb.Command, err error) {
+		failed = append(failed, cmd.ID)
+	})
+
+	a.Apply(pb.Command{ID: 1})
+	a.Apply(pb.Command{ID: 2})
+	a.Flush()
+	if len(failed) != 1 || failed[0] != 1 {
+		t.Errorf("expected command 1 to fail, found %v", failed)
+	}
+	if len(applied) != 1 || applied[0] != 2 {
+		t.Errorf("expected command 2 applied after the failure, found %v", applied)
+	}
+
+	// A stopped applier rejects further handoffs.
+	a.Stop()
+	if err := a.Apply(pb.Command{ID: 3}); err != ErrStopped {
+		t.Errorf("expected ErrStopped after Stop, found %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package epaxos
+
+import (
+	"bytes"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Proposal batching.
+//
+// Every proposal normally runs its own round of consensus, so at high
+// load the per-instance overhead — dependency computation, message
+// fan-out, per-instance bookkeeping on every replica — dominates. When
+// proposal batching is enabled, plain commands proposed within a tick
+// are folded into a single envelope command whose span covers the union
+// of theirs, and the envelope runs one round of consensus for the whole
+// group. The envelope's batch executes atomically and in proposal order
+// when the envelope executes; each batched command is delivered to the
+// application individually.
+//
+// Protocol-internal and specially handled commands — membership changes,
+// compactions, conditional and speculative proposals — keep their own
+// instances and bypass batching.
+
+// batchableProposal returns whether the command may be folded into a
+// batching envelope.
+func (p *epaxos) batchableProposal(cmd *pb.Command) bool {
+	return !cmd.ConfChange && !cmd.Compaction && !cmd.Conditional && !cmd.Speculative
+}
+
+// bufferProposal adds the command to the pending proposal batch,
+// flushing the batch early once it reaches the configured size
+// threshold.
+func (p *epaxos) bufferProposal(cmd *pb.Command) {
+	if inst := p.pendingCommand(cmd.ID); inst != nil {
+		p.execLogger.Debugf("ignoring duplicate proposal of command %d", cmd.ID)
+		return
+	}
+	// Persist the proposal on arrival, not at flush time, so that a crash
+	// mid-tick re-proposes the buffered commands on restart.
+	if p.propStorage != nil && cmd.ID != 0 {
+		p.propStorage.PersistPendingProposal(cmd)
+	}
+	p.propBatch = append(p.propBatch, cmd)
+	if len(p.propBatch) >= p.propBatchSize {
+		p.flushProposalBatch()
+	}
+}
+
+// flushProposalBatch proposes the buffered commands. A single buffered
+// command is proposed as-is; more than one are folded into an envelope
+// command carrying the group in its batch.
+func (p *epaxos) flushProposalBatch() {
+	cmds := p.propBatch
+	if len(cmds) == 0 {
+		return
+	}
+	p.propBatch = nil
+	if len(cmds) == 1 {
+		p.onRequest(cmds[0])
+		return
+	}
+
+	batch := make([]pb.Command, len(cmds))
+	var span pb.Span
+	var writing bool
+	for i, cmd := range cmds {
+		batch[i] = *cmd
+		writing = writing || cmd.Writing
+		span = unionSpans(span, cmd.Span)
+	}
+	env := &pb.Command{
+		Span:    span,
+		Writing: writing,
+		Batch:   batch,
+	}
+	inst := p.onRequest(env)
+	if inst == nil {
+		return
+	}
+	// Register the batched commands so that client retries of any of them
+	// dedupe against the envelope's instance.
+	for _, cmd := range cmds {
+		if cmd.ID != 0 {
+			p.pendingCmds[cmd.ID] = inst
+		}
+	}
+}
+
+// unionSpans returns the smallest span enclosing both arguments. A
+// zero-valued first argument seeds the union.
+func unionSpans(a, b pb.Span) pb.Span {
+	bEnd := b.EndKey
+	if len(bEnd) == 0 {
+		bEnd = append(append(pb.Key(nil), b.Key...), 0)
+	}
+	if len(a.Key) == 0 && len(a.EndKey) == 0 {
+		return pb.Span{Key: b.Key, EndKey: bEnd}
+	}
+	u := a
+	if bytes.Compare(b.Key, u.Key) < 0 {
+		u.Key = b.Key
+	}
+	if bytes.Compare(bEnd, u.EndKey) > 0 {
+		u.EndKey = bEnd
+	}
+	return u
+}
@@ -0,0 +1,91 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestProposalBatchingFlushOnTick verifies that buffered proposals are
+// folded into one envelope instance at the next tick and that the
+// envelope's execution delivers every batched command in proposal order.
+func TestProposalBatchingFlushOnTick(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                0,
+		Nodes:             []pb.ReplicaID{0, 1, 2},
+		ProposalBatchSize: 4,
+	})
+
+	cmd1 := newTestingCommand("a", "c")
+	cmd2 := newTestingCommand("m", "z")
+	p.Request(cmd1)
+	p.Request(cmd2)
+	if a := p.maxInstanceNum(p.id); a != 0 {
+		t.Fatalf("expected no instance before the flush, found %d", a)
+	}
+
+	p.Tick()
+	if a := p.maxInstanceNum(p.id); a != 1 {
+		t.Fatalf("expected 1 envelope instance after the flush, found %d", a)
+	}
+	inst := p.getInstance(0, 1)
+	env := inst.is.Command
+	if len(env.Batch) != 2 || env.Batch[0].ID != cmd1.ID || env.Batch[1].ID != cmd2.ID {
+		t.Fatalf("expected envelope batch [%d %d], found %+v", cmd1.ID, cmd2.ID, env.Batch)
+	}
+	if exp := (pb.Span{Key: pb.Key("a"), EndKey: pb.Key("z")}); !env.Span.Equal(exp) {
+		t.Fatalf("expected envelope span %s, found %s", exp, env.Span)
+	}
+
+	// A retry of a batched command dedupes against the envelope's
+	// instance instead of opening a second round of consensus.
+	p.Request(cmd1)
+	p.Tick()
+	if a := p.maxInstanceNum(p.id); a != 1 {
+		t.Fatalf("expected the retry to dedupe, found %d instances", a)
+	}
+
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	inst.assertState(pb.InstanceState_Executed)
+	if l := len(p.executedCmds); l != 2 ||
+		p.executedCmds[0].ID != cmd1.ID || p.executedCmds[1].ID != cmd2.ID {
+		t.Fatalf("expected commands %d and %d delivered in order, found %+v",
+			cmd1.ID, cmd2.ID, p.executedCmds)
+	}
+}
+
+// TestProposalBatchingSizeThreshold verifies that a batch flushes early
+// once it reaches the configured size, without waiting for a tick, and
+// that non-batchable commands bypass the buffer.
+func TestProposalBatchingSizeThreshold(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                0,
+		Nodes:             []pb.ReplicaID{0, 1, 2},
+		ProposalBatchSize: 2,
+	})
+
+	p.Request(newTestingCommand("a", "c"))
+	if a := p.maxInstanceNum(p.id); a != 0 {
+		t.Fatalf("expected no instance below the size threshold, found %d", a)
+	}
+	p.Request(newTestingCommand("m", "z"))
+	if a := p.maxInstanceNum(p.id); a != 1 {
+		t.Fatalf("expected the full batch to flush immediately, found %d instances", a)
+	}
+
+	// A conditional command keeps its own instance.
+	cond := newTestingCommand("a", "z")
+	cond.Conditional = true
+	p.Request(cond)
+	if a := p.maxInstanceNum(p.id); a != 2 {
+		t.Fatalf("expected the conditional command to bypass batching, found %d instances", a)
+	}
+	if inst := p.getInstance(0, 2); len(inst.is.Command.Batch) != 0 || !inst.is.Command.Conditional {
+		t.Fatalf("expected a bare conditional command, found %+v", inst.is.Command)
+	}
+}
@@ -0,0 +1,56 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// recordReplyReplica attributes the reply currently being stepped to its
+// sending replica, building up the set of replicas whose replies may
+// contribute to the instance's commit decision.
+func (inst *instance) recordReplyReplica() {
+	if !inst.p.recordCommitCerts && !inst.p.zoneAware() && inst.p.commitTimeout == 0 {
+		return
+	}
+	r := inst.p.stepFrom
+	for _, existing := range inst.replyReplicas {
+		if existing == r {
+			return
+		}
+	}
+	inst.replyReplicas = append(inst.replyReplicas, r)
+}
+
+// resetReplyReplicas clears reply attribution. Called when the instance
+// moves to the slow path, where only Accept replies contribute to the
+// commit decision.
+func (inst *instance) resetReplyReplicas() {
+	inst.replyReplicas = nil
+}
+
+// sealCommitCert records the instance's commit certificate: the set of
+// replicas, including the command leader itself, whose replies led to the
+// commit decision. The certificate is persisted with the instance state
+// as part of the transition to Committed.
+func (inst *instance) sealCommitCert() {
+	if !inst.p.recordCommitCerts {
+		return
+	}
+	cert := append([]pb.ReplicaID{inst.p.id}, inst.replyReplicas...)
+	sort.Slice(cert, func(i, j int) bool { return cert[i] < cert[j] })
+	inst.is.CommitCert = cert
+}
+
+// CommitCertificate returns the set of replicas whose replies contributed
+// to the instance's commit decision, and whether a certificate was
+// recorded. Certificates are only recorded on the command leader, and
+// only when Config.RecordCommitCertificates is enabled. They are intended
+// for postmortem debugging of suspected consistency bugs.
+func (p *epaxos) CommitCertificate(id pb.InstanceID) ([]pb.ReplicaID, bool) {
+	inst := p.getInstance(id.ReplicaID, id.InstanceNum)
+	if inst == nil || len(inst.is.CommitCert) == 0 {
+		return nil, false
+	}
+	return inst.is.CommitCert, true
+}
@@ -0,0 +1,62 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestCommitCertificateFastPath verifies that when certificate recording is
+// enabled, committing an instance records the set of replicas whose replies
+// contributed to the commit decision.
+func TestCommitCertificateFastPath(t *testing.T) {
+	n := newNetwork(3)
+	for _, p := range n.peers {
+		p.recordCommitCerts = true
+	}
+	leader := n.peers[0]
+
+	inst := leader.onRequest(newTestingCommand("a", "z"))
+	n.deliverAllMessages() // PreAccepts to followers
+	n.deliverAllMessages() // replies back to the leader
+	if !inst.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		t.Fatalf("expected committed instance, found state %v", inst.is.Status)
+	}
+
+	cert, ok := leader.CommitCertificate(inst.is.InstanceID)
+	if !ok {
+		t.Fatalf("expected commit certificate for %+v", inst.is.InstanceID)
+	}
+	if len(cert) < 2 {
+		t.Fatalf("expected certificate with at least a fast quorum, found %v", cert)
+	}
+	seen := make(map[pb.ReplicaID]struct{})
+	for _, r := range cert {
+		if !leader.knownReplica(r) {
+			t.Errorf("certificate contains unknown replica %d", r)
+		}
+		if _, dup := seen[r]; dup {
+			t.Errorf("certificate contains replica %d twice", r)
+		}
+		seen[r] = struct{}{}
+	}
+	if _, ok := seen[leader.id]; !ok {
+		t.Errorf("expected certificate %v to contain the leader", cert)
+	}
+
+	// The certificate is persisted with the instance state.
+	var persisted bool
+	for _, is := range leader.storage.Instances() {
+		if is.InstanceID == inst.is.InstanceID && len(is.CommitCert) > 0 {
+			persisted = true
+		}
+	}
+	if !persisted {
+		t.Errorf("expected commit certificate to be persisted")
+	}
+
+	// No certificate is recorded on non-leader replicas.
+	if _, ok := n.peers[1].CommitCertificate(inst.is.InstanceID); ok {
+		t.Errorf("unexpected commit certificate on non-leader replica")
+	}
+}
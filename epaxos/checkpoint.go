@@ -0,0 +1,51 @@
+package epaxos
+
+import (
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Barrierless checkpoints.
+//
+// A checkpoint captures the state machine at a consistent cut described
+// by the executed watermark vector: for each replica column, the
+// instance number through which every instance has been executed.
+// Because the protocol executes commands sequentially, the vector and
+// the snapshot describe exactly the same state and are captured
+// together, without stopping proposals or agreeing on a barrier command
+// through the log — checkpointing does not interfere with the workload
+// at all.
+
+// Checkpoint is a state-machine snapshot paired with the consistent cut
+// it was taken at.
+type Checkpoint struct {
+	// Data is the application snapshot.
+	Data []byte
+	// Executed is the executed watermark vector the snapshot covers.
+	Executed []pb.InstanceID
+}
+
+// Checkpoint captures a checkpoint of the local state machine at the
+// current executed watermark. It requires a Snapshotter.
+func (p *epaxos) Checkpoint() (Checkpoint, error) {
+	if p.snapshotter == nil {
+		return Checkpoint{}, errors.New("checkpointing requires a Snapshotter")
+	}
+	data, err := p.snapshotter.Snapshot()
+	if err != nil {
+		return Checkpoint{}, errors.Wrap(err, "capturing checkpoint snapshot")
+	}
+	return Checkpoint{
+		Data:     data,
+		Executed: watermarkSlice(p.executedPrefix),
+	}, nil
+}
+
+// RestoreCheckpoint installs a previously captured checkpoint, replacing
+// the state machine's contents and discarding the instance space below
+// the checkpoint's cut. Like an installed snapshot, a checkpoint that
+// would regress the executed watermark is rejected.
+func (p *epaxos) RestoreCheckpoint(c Checkpoint) {
+	p.installSnapshot(p.id, c.Data, c.Executed)
+}
@@ -0,0 +1,119 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestCoalescePreAcceptWithCommit verifies that when a Commit broadcast for
+// instance i and a PreAccept broadcast for instance i+1 are pending for the
+// same peer, the Commit is piggybacked onto the PreAccept, and that peers
+// process the piggybacked Commit before the carrying PreAccept.
+func TestCoalescePreAcceptWithCommit(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	// Drive the first command to commit on the leader; the Commit
+	// broadcast remains in the leader's outbox.
+	inst1 := leader.onRequest(newTestingCommand("a", "z"))
+	n.deliverAllMessages() // PreAccepts to followers
+	n.deliverAllMessages() // replies back to the leader
+	if !inst1.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		t.Fatalf("expected committed instance, found state %v", inst1.is.Status)
+	}
+
+	// Propose a second command before the Commit broadcast is flushed.
+	inst2 := leader.onRequest(newTestingCommand("a", "z"))
+
+	msgs := leader.ReadMessages()
+	if exp := len(n.peers) - 1; len(msgs) != exp {
+		t.Fatalf("expected %d coalesced messages in outbox, found %+v", exp, msgs)
+	}
+	var coalesced int
+	for _, m := range msgs {
+		if _, ok := m.Type.(*pb.Message_PreAccept); !ok {
+			continue
+		}
+		if m.InstanceID != inst2.is.InstanceID {
+			continue
+		}
+		if len(m.Piggyback) != 1 {
+			t.Fatalf("expected 1 piggybacked message on PreAccept, found %+v", m)
+		}
+		pb1 := m.Piggyback[0]
+		if _, ok := pb1.Type.(*pb.Message_Commit); !ok {
+			t.Fatalf("expected piggybacked Commit, found %+v", pb1)
+		}
+		if pb1.InstanceID != inst1.is.InstanceID {
+			t.Errorf("expected piggybacked Commit for %+v, found %+v", inst1.is.InstanceID, pb1.InstanceID)
+		}
+		coalesced++
+
+		// Delivering the coalesced message applies the Commit first and
+		// then the PreAccept.
+		dest := n.peers[m.To]
+		dest.Step(m)
+		destInst1 := dest.getInstance(inst1.is.ReplicaID, inst1.is.InstanceNum)
+		if !destInst1.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+			t.Errorf("expected piggybacked Commit to be applied, state %v", destInst1.is.Status)
+		}
+		destInst2 := dest.getInstance(inst2.is.ReplicaID, inst2.is.InstanceNum)
+		if !destInst2.isStates(pb.InstanceState_PreAccepted) {
+			t.Errorf("expected carrying PreAccept to be applied, state %v", destInst2.is.Status)
+		}
+	}
+	if exp := len(n.peers) - 1; coalesced != exp {
+		t.Errorf("expected %d coalesced PreAccepts, found %d", exp, coalesced)
+	}
+}
+
+// TestNetworkMTUSplitsCoalescedMessages verifies that a simulated
+// transport MTU smaller than a coalesced message forces the network to
+// deliver the piggybacked Commit and the carrying PreAccept as separate
+// datagrams, and that protocol progress is unaffected by the split.
+func TestNetworkMTUSplitsCoalescedMessages(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	// Pipeline two commands so that the Commit broadcast for the first is
+	// coalesced onto the PreAccept broadcast for the second.
+	inst1 := leader.onRequest(newTestingCommand("a", "z"))
+	n.deliverAllMessages() // PreAccepts to followers
+	n.deliverAllMessages() // replies back to the leader
+	if !inst1.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		t.Fatalf("expected committed instance, found state %v", inst1.is.Status)
+	}
+	inst2 := leader.onRequest(newTestingCommand("a", "z"))
+
+	// Size the MTU just below the coalesced messages, so that they must be
+	// split while every individual protocol message still fits.
+	mtu := 0
+	for _, m := range leader.msgs {
+		if len(m.Piggyback) == 0 {
+			t.Fatalf("expected only coalesced messages in outbox, found %+v", m)
+		}
+		if sz := m.Size(); mtu == 0 || sz < mtu {
+			mtu = sz
+		}
+	}
+	n.setMTU(mtu - 1)
+
+	n.deliverAllMessages()
+	for r, p := range n.peers {
+		if p == leader {
+			continue
+		}
+		destInst1 := p.getInstance(inst1.is.ReplicaID, inst1.is.InstanceNum)
+		if !destInst1.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+			t.Errorf("peer %d: expected split Commit to be applied, state %v", r, destInst1.is.Status)
+		}
+		destInst2 := p.getInstance(inst2.is.ReplicaID, inst2.is.InstanceNum)
+		if !destInst2.isStates(pb.InstanceState_PreAccepted) {
+			t.Errorf("peer %d: expected split PreAccept to be applied, state %v", r, destInst2.is.Status)
+		}
+	}
+	if !n.waitExecuteInstance(inst2, false /* quorum */) {
+		t.Fatalf("expected message delivery to succeed")
+	}
+}
@@ -1,8 +1,10 @@
 package epaxos
 
 import (
+	"bytes"
+	"time"
+
 	"github.com/cockroachdb/cockroach/pkg/util/interval"
-	"github.com/google/btree"
 
 	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
 )
@@ -18,7 +20,9 @@ func (p *epaxos) maxInstanceNum(r pb.ReplicaID) pb.InstanceNum {
 	if maxInst := p.maxInstance(r); maxInst != nil {
 		return maxInst.is.InstanceNum
 	}
-	return 0
+	// An empty command space may be the result of truncation, in which
+	// case instance numbers must not restart below the truncation point.
+	return p.maxTruncated[r]
 }
 
 func (p *epaxos) maxSeqNum(r pb.ReplicaID) pb.SeqNum {
@@ -42,6 +46,52 @@ func (p *epaxos) getInstance(r pb.ReplicaID, i pb.InstanceNum) *instance {
 	return nil
 }
 
+// insertInstance installs a newly created instance into its replica's
+// command space, guarding against silent overwrites. The protocol only
+// creates an instance for an unoccupied instance number — local proposals
+// allocate past the space's maximum and remote instances are only created
+// when the number is unknown — so finding the slot held by an instance
+// with a different command or ballot means state has been corrupted.
+// Overwriting the occupant could silently discard agreed state, so it is
+// kept and the conflict is surfaced as an InstanceOverwriteEvent. The
+// return value reports whether the new instance was installed; callers
+// must not proceed with an instance that was not.
+func (p *epaxos) insertInstance(inst *instance) bool {
+	if item := p.commands[inst.is.ReplicaID].Get(inst); item != nil {
+		existing := item.(*instance)
+		if existing == inst {
+			return true
+		}
+		if instanceMismatch(existing, inst) {
+			p.logger.Errorf("refusing to overwrite instance %v: occupant %+v differs from %+v",
+				inst.is.InstanceID, existing.is, inst.is)
+			p.emitEvent(InstanceOverwriteEvent{InstanceID: inst.is.InstanceID})
+		} else {
+			p.logger.Debugf("ignoring duplicate insertion of instance %v", inst.is.InstanceID)
+		}
+		return false
+	}
+	p.commands[inst.is.ReplicaID].ReplaceOrInsert(inst)
+	return true
+}
+
+// instanceMismatch reports whether the two instances disagree on their
+// command or ballot.
+func instanceMismatch(a, b *instance) bool {
+	ac, bc := a.is.Command, b.is.Command
+	if (ac == nil) != (bc == nil) {
+		return true
+	}
+	if ac != nil && !bytes.Equal(ac.Digest(), bc.Digest()) {
+		return true
+	}
+	ab, bb := a.is.Ballot, b.is.Ballot
+	if (ab == nil) != (bb == nil) {
+		return true
+	}
+	return ab != nil && ab.Compare(*bb) != 0
+}
+
 func (p *epaxos) hasAccepted(r pb.ReplicaID, i pb.InstanceNum) bool {
 	if inst := p.getInstance(r, i); inst != nil {
 		return inst.is.Status >= pb.InstanceState_Accepted
@@ -53,7 +103,8 @@ func (p *epaxos) hasExecuted(r pb.ReplicaID, i pb.InstanceNum) bool {
 	if inst := p.getInstance(r, i); inst != nil {
 		return inst.is.Status == pb.InstanceState_Executed
 	}
-	return false
+	// Truncated instances were necessarily executed.
+	return p.hasTruncated(r, i)
 }
 
 // HasExecuted implements the history interface.
@@ -62,67 +113,279 @@ func (p *epaxos) HasExecuted(e executableID) bool {
 	return p.hasExecuted(d.ReplicaID, d.InstanceNum)
 }
 
+// parallelDepLookupMin is the total number of known instances above which
+// dependency lookup fans out across per-replica goroutines. Below it, the
+// cost of spawning goroutines outweighs the scan itself.
+const parallelDepLookupMin = 256
+
 // seqAndDepsForCommand determines the locally known maximum interfering sequence
 // number and dependencies for a given command.
 func (p *epaxos) seqAndDepsForCommand(
 	cmd *pb.Command, ignoredInstance pb.InstanceID,
 ) (pb.SeqNum, map[pb.InstanceID]struct{}) {
+	if cmd.Noop {
+		// No-ops interfere with nothing, so they carry no dependencies.
+		return 0, make(map[pb.InstanceID]struct{})
+	}
+	if p.parallelDepLookup() {
+		return p.seqAndDepsForCommandParallel(cmd, ignoredInstance)
+	}
+
 	var maxSeq pb.SeqNum
 	deps := make(map[pb.InstanceID]struct{})
+	for rID, idx := range p.depIndex {
+		spaceSeq, spaceDeps := scanSpaceForDeps(cmd, ignoredInstance, rID, idx, p.rangeGroup)
+		maxSeq = pb.MaxSeqNum(maxSeq, spaceSeq)
+		for dep := range spaceDeps {
+			deps[dep] = struct{}{}
+		}
+	}
+	return maxSeq, deps
+}
 
-	cmdRage := rangeForCmd(cmd)
-	for rID, cmds := range p.commands {
-		// Adding to the writeRG and readRG allows us to minimize the number of
-		// dependencies we add for this command without building a directed graph
-		// and topological sorting. This relies on the interference relation for
-		// commands ove a given key-range being transitive. It also relies on the
-		// causality of subsequent instances within the same replica instance space.
-		// The logic here is very similar to that in CockroachDB's Command Queue.
-		cmds.Descend(func(i btree.Item) bool {
-			inst := i.(*instance)
-			if inst.is.InstanceID == ignoredInstance {
-				return true
+// parallelDepLookup returns whether dependency lookup should fan out
+// across per-replica goroutines. Each replica's command space can be
+// scanned independently, so large multi-key commands need not serialize
+// the proposer on a sequential scan of every space.
+func (p *epaxos) parallelDepLookup() bool {
+	if len(p.nodes) == 1 {
+		return false
+	}
+	total := 0
+	for _, cmds := range p.commands {
+		total += cmds.Len()
+	}
+	return total >= parallelDepLookupMin
+}
+
+// seqAndDepsForCommandParallel computes the same result as the sequential
+// scan in seqAndDepsForCommand, scanning each replica's command space in
+// its own goroutine. The scans are read-only and the state machine does
+// not advance while they run, so no synchronization beyond the final
+// merge is needed. Each goroutine uses a private RangeGroup; transitive
+// dependency elimination never spans command spaces, so the results are
+// identical to the sequential scan's.
+func (p *epaxos) seqAndDepsForCommandParallel(
+	cmd *pb.Command, ignoredInstance pb.InstanceID,
+) (pb.SeqNum, map[pb.InstanceID]struct{}) {
+	type spaceResult struct {
+		maxSeq pb.SeqNum
+		deps   map[pb.InstanceID]struct{}
+	}
+	results := make(chan spaceResult, len(p.depIndex))
+	for rID, idx := range p.depIndex {
+		go func(rID pb.ReplicaID, idx *depSpaceIndex) {
+			spaceSeq, spaceDeps := scanSpaceForDeps(
+				cmd, ignoredInstance, rID, idx, interval.NewRangeTree())
+			results <- spaceResult{maxSeq: spaceSeq, deps: spaceDeps}
+		}(rID, idx)
+	}
+
+	var maxSeq pb.SeqNum
+	deps := make(map[pb.InstanceID]struct{})
+	for range p.depIndex {
+		res := <-results
+		maxSeq = pb.MaxSeqNum(maxSeq, res.maxSeq)
+		for dep := range res.deps {
+			deps[dep] = struct{}{}
+		}
+	}
+	return maxSeq, deps
+}
+
+// seqAndDepsForCommands computes seqAndDepsForCommand for a batch of
+// commands, sharing a single pass over each replica's command space
+// across the whole batch instead of re-scanning it per command.
+// Interference between batch members is not visible to the shared scan;
+// the batch-propose path resolves it against the instances it creates
+// for earlier members (see onRequestBatch).
+func (p *epaxos) seqAndDepsForCommands(
+	cmds []*pb.Command, ignoredInstance pb.InstanceID,
+) ([]pb.SeqNum, []map[pb.InstanceID]struct{}) {
+	maxSeqs := make([]pb.SeqNum, len(cmds))
+	deps := make([]map[pb.InstanceID]struct{}, len(cmds))
+	for i := range deps {
+		deps[i] = make(map[pb.InstanceID]struct{})
+	}
+	merge := func(spaceSeqs []pb.SeqNum, spaceDeps []map[pb.InstanceID]struct{}) {
+		for i := range cmds {
+			maxSeqs[i] = pb.MaxSeqNum(maxSeqs[i], spaceSeqs[i])
+			for dep := range spaceDeps[i] {
+				deps[i][dep] = struct{}{}
 			}
+		}
+	}
+
+	if p.parallelDepLookup() {
+		type spaceResult struct {
+			maxSeqs []pb.SeqNum
+			deps    []map[pb.InstanceID]struct{}
+		}
+		results := make(chan spaceResult, len(p.depIndex))
+		for rID, idx := range p.depIndex {
+			go func(rID pb.ReplicaID, idx *depSpaceIndex) {
+				spaceSeqs, spaceDeps := scanSpaceForDepsBatch(cmds, ignoredInstance, rID, idx)
+				results <- spaceResult{maxSeqs: spaceSeqs, deps: spaceDeps}
+			}(rID, idx)
+		}
+		for range p.depIndex {
+			res := <-results
+			merge(res.maxSeqs, res.deps)
+		}
+		return maxSeqs, deps
+	}
+
+	for rID, idx := range p.depIndex {
+		merge(scanSpaceForDepsBatch(cmds, ignoredInstance, rID, idx))
+	}
+	return maxSeqs, deps
+}
+
+// scanSpaceForDepsBatch computes scanSpaceForDeps for every command in
+// the batch during a single pass over the replica's interfering
+// instances. Each command keeps its own RangeGroup for transitive
+// dependency elimination, so the per-command results match the
+// single-command scan exactly; the pass stops early once every command's
+// dependencies are fully covered.
+func scanSpaceForDepsBatch(
+	cmds []*pb.Command, ignoredInstance pb.InstanceID, rID pb.ReplicaID, idx *depSpaceIndex,
+) ([]pb.SeqNum, []map[pb.InstanceID]struct{}) {
+	maxSeqs := make([]pb.SeqNum, len(cmds))
+	deps := make([]map[pb.InstanceID]struct{}, len(cmds))
+	rangeGroups := make([]interval.RangeGroup, len(cmds))
+	cmdRanges := make([]interval.Range, len(cmds))
+	done := make([]bool, len(cmds))
+	remaining := len(cmds)
+	for i, cmd := range cmds {
+		deps[i] = make(map[pb.InstanceID]struct{})
+		rangeGroups[i] = interval.NewRangeTree()
+		cmdRanges[i] = rangeForCmd(cmd)
+	}
 
-			addDep := func() {
-				dep := pb.InstanceID{
-					ReplicaID:   rID,
-					InstanceNum: inst.is.InstanceNum,
+	for _, inst := range idx.candidates(cmdRanges...) {
+		if inst.is.InstanceID == ignoredInstance {
+			continue
+		}
+		dep := pb.InstanceID{
+			ReplicaID:   rID,
+			InstanceNum: inst.is.InstanceNum,
+		}
+
+		otherCmd := inst.is.Command
+		var otherCmdRange interval.Range
+		if otherCmd != nil {
+			otherCmdRange = rangeForCmd(otherCmd)
+		}
+		for i, cmd := range cmds {
+			if done[i] {
+				continue
+			}
+			if otherCmd == nil {
+				// As in the single-command scan, an unresolved command is
+				// conservatively depended on.
+				maxSeqs[i] = pb.MaxSeqNum(maxSeqs[i], inst.is.SeqNum)
+				deps[i][dep] = struct{}{}
+				continue
+			}
+			if !otherCmd.Interferes(*cmd) {
+				continue
+			}
+			maxSeqs[i] = pb.MaxSeqNum(maxSeqs[i], inst.is.SeqNum)
+			if otherCmd.Writing {
+				if rangeGroups[i].Add(otherCmdRange) {
+					deps[i][dep] = struct{}{}
+					if rangeGroups[i].Len() == 1 && rangeGroups[i].Encloses(cmdRanges[i]) {
+						done[i] = true
+						remaining--
+					}
+				}
+			} else {
+				if !rangeGroups[i].Overlaps(otherCmdRange) {
+					deps[i][dep] = struct{}{}
 				}
-				deps[dep] = struct{}{}
 			}
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+	return maxSeqs, deps
+}
 
-			if otherCmd := inst.is.Command; otherCmd.Interferes(*cmd) {
-				maxSeq = pb.MaxSeqNum(maxSeq, inst.is.SeqNum)
-
-				otherCmdRange := rangeForCmd(otherCmd)
-				if otherCmd.Writing {
-					// We add the other command's range to the RangeGroup and
-					// observe if it grows the group. If it does, that means
-					// that it is not a full transitive dependency of other
-					// dependencies of ours. If it is, that means that we do
-					// not need to depend on it because previous dependencies
-					// necessarily already have it as a dependency themself.
-					if p.rangeGroup.Add(otherCmdRange) {
-						addDep()
-						if p.rangeGroup.Len() == 1 && p.rangeGroup.Encloses(cmdRage) {
-							return false
-						}
-					}
-				} else {
-					// We check if the current RangeGroup overlaps the read
-					// dependency. Reads don't depend on reads, so this will
-					// only happen if a write was inserted that fully covers
-					// the read.
-					if !p.rangeGroup.Overlaps(otherCmdRange) {
-						addDep()
+// scanSpaceForDeps scans a single replica's command space for instances
+// that interfere with the command, returning the maximum interfering
+// sequence number and the set of dependencies. The space's interval index
+// narrows the scan to the instances whose ranges may overlap the
+// command's. The provided RangeGroup is used for transitive dependency
+// elimination and is left cleared.
+func scanSpaceForDeps(
+	cmd *pb.Command,
+	ignoredInstance pb.InstanceID,
+	rID pb.ReplicaID,
+	idx *depSpaceIndex,
+	rangeGroup interval.RangeGroup,
+) (pb.SeqNum, map[pb.InstanceID]struct{}) {
+	var maxSeq pb.SeqNum
+	deps := make(map[pb.InstanceID]struct{})
+
+	cmdRage := rangeForCmd(cmd)
+	// Adding to the writeRG and readRG allows us to minimize the number of
+	// dependencies we add for this command without building a directed graph
+	// and topological sorting. This relies on the interference relation for
+	// commands ove a given key-range being transitive. It also relies on the
+	// causality of subsequent instances within the same replica instance space.
+	// The logic here is very similar to that in CockroachDB's Command Queue.
+	for _, inst := range idx.candidates(cmdRage) {
+		if inst.is.InstanceID == ignoredInstance {
+			continue
+		}
+
+		addDep := func() {
+			dep := pb.InstanceID{
+				ReplicaID:   rID,
+				InstanceNum: inst.is.InstanceNum,
+			}
+			deps[dep] = struct{}{}
+		}
+
+		otherCmd := inst.is.Command
+		if otherCmd == nil {
+			// The instance's command has not been resolved yet (it was
+			// referenced by digest). We cannot evaluate interference, so
+			// conservatively depend on the instance.
+			maxSeq = pb.MaxSeqNum(maxSeq, inst.is.SeqNum)
+			addDep()
+			continue
+		}
+		if otherCmd.Interferes(*cmd) {
+			maxSeq = pb.MaxSeqNum(maxSeq, inst.is.SeqNum)
+
+			otherCmdRange := rangeForCmd(otherCmd)
+			if otherCmd.Writing {
+				// We add the other command's range to the RangeGroup and
+				// observe if it grows the group. If it does, that means
+				// that it is not a full transitive dependency of other
+				// dependencies of ours. If it is, that means that we do
+				// not need to depend on it because previous dependencies
+				// necessarily already have it as a dependency themself.
+				if rangeGroup.Add(otherCmdRange) {
+					addDep()
+					if rangeGroup.Len() == 1 && rangeGroup.Encloses(cmdRage) {
+						break
 					}
 				}
+			} else {
+				// We check if the current RangeGroup overlaps the read
+				// dependency. Reads don't depend on reads, so this will
+				// only happen if a write was inserted that fully covers
+				// the read.
+				if !rangeGroup.Overlaps(otherCmdRange) {
+					addDep()
+				}
 			}
-			return true
-		})
-		p.rangeGroup.Clear()
+		}
 	}
+	rangeGroup.Clear()
 	return maxSeq, deps
 }
 
@@ -139,30 +402,244 @@ func rangeForCmd(cmd *pb.Command) interval.Range {
 }
 
 func (p *epaxos) onRequest(cmd *pb.Command) *instance {
+	return p.onRequestTraced(cmd, "")
+}
+
+func (p *epaxos) onRequestTraced(cmd *pb.Command, traceCtx string) *instance {
+	// If the command is already being proposed by this replica, return the
+	// existing instance instead of starting a second round of consensus.
+	// Client retries commonly re-propose a command before its first
+	// proposal has committed.
+	if inst := p.pendingCommand(cmd.ID); inst != nil {
+		p.execLogger.Debugf("ignoring duplicate proposal of command %d", cmd.ID)
+		return inst
+	}
+
+	// Persist the proposal before it enters the consensus pipeline, so
+	// that a crash before the instance is persisted re-proposes it on
+	// restart rather than silently dropping it.
+	if p.propStorage != nil && cmd.ID != 0 {
+		p.propStorage.PersistPendingProposal(cmd)
+	}
+
+	// During post-restart warm-up, hold the proposal back once the
+	// in-flight cap is reached.
+	if p.warmingUp() && p.warmup.inFlight >= p.warmup.limit {
+		p.deferProposal(cmd)
+		return nil
+	}
+
 	// Determine the smallest unused instance number.
 	i := p.maxInstanceNum(p.id) + 1
 
 	// Add a new instance for the command in the local commands.
+	latency := p.maybeSampleLatency()
+	var depStart time.Time
+	if latency != nil {
+		depStart = time.Now()
+	}
 	maxLocalSeq, localDeps := p.seqAndDepsForCommand(cmd, pb.InstanceID{})
+	if latency != nil {
+		latency.depCompute = time.Since(depStart)
+	}
+	p.recordKeyConflict(cmd, len(localDeps) > 0)
 	newInst := p.newInstance(p.id, i)
+	newInst.latency = latency
+	newInst.traceCtx = traceCtx
+	newInst.is.Ballot = &pb.Ballot{Epoch: p.epoch, ReplicaID: p.id}
 	newInst.is.Command = cmd
-	newInst.is.SeqNum = maxLocalSeq + 1
+	if !p.depsOnly {
+		newInst.is.SeqNum = maxLocalSeq + 1
+	}
 	newInst.is.Deps = depSliceFromMap(localDeps)
-	p.commands[p.id].ReplaceOrInsert(newInst)
+	if !p.insertInstance(newInst) {
+		return nil
+	}
+	p.indexCommand(newInst)
+
+	if cmd.ID != 0 {
+		p.pendingCmds[cmd.ID] = newInst
+	}
+	p.noteProposalInFlight()
 
 	// Transition the new instance into a preAccepted state.
 	newInst.transitionTo(pb.InstanceState_PreAccepted)
+
+	// The instance has been persisted; the proposal is in the pipeline
+	// and its standalone record is no longer needed.
+	if p.propStorage != nil && cmd.ID != 0 {
+		p.propStorage.DiscardPendingProposal(cmd.ID)
+	}
 	return newInst
 }
 
+// onRequestBatch proposes a slice of commands at once, sharing the
+// dependency computation for the whole batch through
+// seqAndDepsForCommands. Interference between batch members, which the
+// shared scan cannot see, is resolved against the instances created for
+// earlier members. The per-command semantics — duplicate suppression,
+// proposal persistence and warm-up deferral — match onRequest; the
+// returned slice holds the instance proposing each command, or nil for
+// commands deferred by warm-up.
+func (p *epaxos) onRequestBatch(cmds []*pb.Command) []*instance {
+	insts := make([]*instance, len(cmds))
+
+	// Weed out duplicates and, during warm-up, commands over the
+	// in-flight cap before computing dependencies for the remainder.
+	fresh := make([]*pb.Command, 0, len(cmds))
+	freshIdx := make([]int, 0, len(cmds))
+	for j, cmd := range cmds {
+		if inst := p.pendingCommand(cmd.ID); inst != nil {
+			p.execLogger.Debugf("ignoring duplicate proposal of command %d", cmd.ID)
+			insts[j] = inst
+			continue
+		}
+		if p.propStorage != nil && cmd.ID != 0 {
+			p.propStorage.PersistPendingProposal(cmd)
+		}
+		if p.warmingUp() && p.warmup.inFlight+len(fresh) >= p.warmup.limit {
+			p.deferProposal(cmd)
+			continue
+		}
+		fresh = append(fresh, cmd)
+		freshIdx = append(freshIdx, j)
+	}
+	if len(fresh) == 0 {
+		return insts
+	}
+
+	maxSeqs, deps := p.seqAndDepsForCommands(fresh, pb.InstanceID{})
+	created := make([]*instance, len(fresh))
+	for bi, cmd := range fresh {
+		maxSeq, localDeps := maxSeqs[bi], deps[bi]
+		// Fold in interference with the batch's earlier proposals.
+		for pi, prev := range created[:bi] {
+			if prev == nil {
+				continue
+			}
+			if fresh[pi].Interferes(*cmd) {
+				localDeps[prev.is.InstanceID] = struct{}{}
+				maxSeq = pb.MaxSeqNum(maxSeq, prev.is.SeqNum)
+			}
+		}
+		p.recordKeyConflict(cmd, len(localDeps) > 0)
+
+		i := p.maxInstanceNum(p.id) + 1
+		newInst := p.newInstance(p.id, i)
+		newInst.is.Ballot = &pb.Ballot{Epoch: p.epoch, ReplicaID: p.id}
+		newInst.is.Command = cmd
+		if !p.depsOnly {
+			newInst.is.SeqNum = maxSeq + 1
+		}
+		newInst.is.Deps = depSliceFromMap(localDeps)
+		if !p.insertInstance(newInst) {
+			continue
+		}
+		p.indexCommand(newInst)
+
+		if cmd.ID != 0 {
+			p.pendingCmds[cmd.ID] = newInst
+		}
+		p.noteProposalInFlight()
+
+		newInst.transitionTo(pb.InstanceState_PreAccepted)
+
+		if p.propStorage != nil && cmd.ID != 0 {
+			p.propStorage.DiscardPendingProposal(cmd.ID)
+		}
+		created[bi] = newInst
+		insts[freshIdx[bi]] = newInst
+	}
+	return insts
+}
+
+// pendingCommand returns the local instance currently proposing the
+// command with the provided ID, if any. Commands without an ID are never
+// considered duplicates.
+func (p *epaxos) pendingCommand(id uint64) *instance {
+	if id == 0 {
+		return nil
+	}
+	if inst := p.pendingCmds[id]; inst != nil && inst.is.ReplicaID == p.id {
+		return inst
+	}
+	return nil
+}
+
+// forgetPendingCommand removes the instance's command from the pending
+// command table once the proposal has executed.
+func (p *epaxos) forgetPendingCommand(inst *instance) {
+	if inst.is.ReplicaID != p.id || inst.is.Command == nil {
+		return
+	}
+	if p.pendingCmds[inst.is.Command.ID] == inst {
+		delete(p.pendingCmds, inst.is.Command.ID)
+	}
+	for _, batched := range inst.is.Command.Batch {
+		if p.pendingCmds[batched.ID] == inst {
+			delete(p.pendingCmds, batched.ID)
+		}
+	}
+}
+
 func (p *epaxos) prepareToExecute(inst *instance) {
 	inst.assertState(pb.InstanceState_Committed)
+	p.recordCommitted(inst.is.ReplicaID, inst.is.InstanceNum)
+	p.metrics.depsPerInstance.record(len(inst.is.Deps))
+	p.maybeInvalidateReadLeases(inst)
+	if p.maybeExecuteReadOnly(inst) {
+		return
+	}
+	if p.execBatchSize > 0 {
+		// Defer executor ingestion to the tick loop, which caps how many
+		// instances are ingested at a time.
+		p.execBacklog = append(p.execBacklog, inst)
+		return
+	}
 	p.executor.addExec(inst)
 	// TODO pull executor into a different goroutine and run asynchronously.
 	p.executor.run()
 	// p.truncateCommands()
 }
 
+// ingestExecBacklog hands up to execBatchSize committed instances from the
+// backlog to the executor and runs it. The remainder carries over to the
+// next tick, smoothing execution CPU usage after a commit burst.
+func (p *epaxos) ingestExecBacklog() {
+	if len(p.execBacklog) == 0 {
+		return
+	}
+	n := p.execBatchSize
+	if n > len(p.execBacklog) {
+		n = len(p.execBacklog)
+	}
+	for _, inst := range p.execBacklog[:n] {
+		p.executor.addExec(inst)
+	}
+	p.execBacklog = p.execBacklog[n:]
+	p.executor.run()
+}
+
+// maybeExecuteReadOnly executes a committed read-only command immediately
+// if all of its dependencies have already executed, bypassing the
+// executor. Read-only commands are never depended on by reads, and writes
+// that depend on them still wait for them to execute, so executing them
+// out-of-band preserves ordering while cutting read latency in mixed
+// workloads.
+func (p *epaxos) maybeExecuteReadOnly(inst *instance) bool {
+	cmd := inst.is.Command
+	if cmd == nil || cmd.Writing {
+		return false
+	}
+	for _, dep := range inst.is.Deps {
+		if !p.hasExecuted(dep.ReplicaID, dep.InstanceNum) {
+			return false
+		}
+	}
+	inst.Execute()
+	return true
+}
+
 // TODO reintroduce instance space truncation.
 // func (p *epaxos) truncateCommands() {
 // 	for r, cmds := range p.commands {
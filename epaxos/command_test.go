@@ -27,16 +27,46 @@ func newTestingReadCommand(start, end string) *pb.Command {
 	return cmd
 }
 
+// TestExecutionBatchSize verifies that committed instances are handed to
+// the executor at most ExecutionBatchSize per tick, with the remainder
+// carrying over to later ticks.
+func TestExecutionBatchSize(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}, ExecutionBatchSize: 1})
+
+	// Single-node proposals commit immediately, landing in the backlog.
+	p.Request(newTestingCommand("a", "m"))
+	p.Request(newTestingCommand("n", "z"))
+	if l := len(p.execBacklog); l != 2 {
+		t.Fatalf("expected 2 backlogged instances, found %d", l)
+	}
+	if l := len(p.executedCmds); l != 0 {
+		t.Fatalf("expected no execution before a tick, found %d commands", l)
+	}
+
+	p.Tick()
+	if l := len(p.executedCmds); l != 1 {
+		t.Errorf("expected 1 executed command after first tick, found %d", l)
+	}
+	p.Tick()
+	if l := len(p.executedCmds); l != 2 {
+		t.Errorf("expected 2 executed commands after second tick, found %d", l)
+	}
+	if l := len(p.execBacklog); l != 0 {
+		t.Errorf("expected drained backlog, found %d instances", l)
+	}
+}
+
 // newTestingEPaxos creates a new epaxos state machine with the following
 // structure:
 //
 // id: 0
 // nodes: [0, 1 ,2]
-// commands: {
-//  0: [1: {{"a","z"}, 1}, 2: {{"a","m"}, 4}]
-//  1: [1: {{"a","z"}, 2}, 2: {{"n","z"}, 5}]
-//  2: [1: {{"a","b"}, 3}]
-// }
+//
+//	commands: {
+//	 0: [1: {{"a","z"}, 1}, 2: {{"a","m"}, 4}]
+//	 1: [1: {{"a","z"}, 2}, 2: {{"n","z"}, 5}]
+//	 2: [1: {{"a","b"}, 3}]
+//	}
 func newTestingEPaxos() *epaxos {
 	c := Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
 	p := newEPaxos(&c)
@@ -93,6 +123,9 @@ func newTestingEPaxos() *epaxos {
 	p.commands[2].ReplaceOrInsert(inst21)
 	p.commands[0].ReplaceOrInsert(inst02)
 	p.commands[1].ReplaceOrInsert(inst12)
+	for _, inst := range []*instance{inst01, inst11, inst21, inst02, inst12} {
+		p.indexCommand(inst)
+	}
 
 	return p
 }
@@ -106,6 +139,29 @@ func (p *epaxos) changeID(t *testing.T, newR pb.ReplicaID) {
 	p.id = newR
 }
 
+// TestSeqAndDepsParallelMatchesSequential verifies that the parallel
+// per-replica dependency scan produces the same sequence number and
+// dependency set as the sequential scan.
+func TestSeqAndDepsParallelMatchesSequential(t *testing.T) {
+	p := newTestingEPaxos()
+
+	for _, cmd := range []*pb.Command{
+		newTestingCommand("a", "z"),
+		newTestingCommand("c", "f"),
+		newTestingReadCommand("a", "z"),
+		newTestingReadCommand("n", "q"),
+	} {
+		seqSeq, seqDeps := p.seqAndDepsForCommand(cmd, pb.InstanceID{})
+		parSeq, parDeps := p.seqAndDepsForCommandParallel(cmd, pb.InstanceID{})
+		if seqSeq != parSeq {
+			t.Errorf("expected seq %v from parallel scan, found %v", seqSeq, parSeq)
+		}
+		if !reflect.DeepEqual(seqDeps, parDeps) {
+			t.Errorf("expected deps %v from parallel scan, found %v", seqDeps, parDeps)
+		}
+	}
+}
+
 func TestOnRequestIncrementInstanceNumber(t *testing.T) {
 	p := newTestingEPaxos()
 
@@ -241,3 +297,130 @@ func TestOnRequestDependencies(t *testing.T) {
 	}
 	assertMaxDeps()
 }
+
+// TestSeqAndDepsForCommandsMatchesSingle verifies that the batched
+// dependency computation returns exactly what per-command computation
+// would for each member of the batch.
+func TestSeqAndDepsForCommandsMatchesSingle(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	p.onRequest(newTestingCommand("a", "f"))
+	p.onRequest(newTestingCommand("d", "k"))
+	p.onRequest(newTestingReadCommand("b", "p"))
+	p.onRequest(newTestingCommand("t", "z"))
+
+	cmds := []*pb.Command{
+		newTestingCommand("a", "c"),
+		newTestingCommand("e", "u"),
+		newTestingReadCommand("j", "m"),
+		newTestingCommand("x", "y"),
+	}
+	batchSeqs, batchDeps := p.seqAndDepsForCommands(cmds, pb.InstanceID{})
+	for i, cmd := range cmds {
+		seq, deps := p.seqAndDepsForCommand(cmd, pb.InstanceID{})
+		if batchSeqs[i] != seq {
+			t.Errorf("command %d: expected max seq %d, found %d", i, seq, batchSeqs[i])
+		}
+		if !reflect.DeepEqual(batchDeps[i], deps) {
+			t.Errorf("command %d: expected deps %v, found %v", i, deps, batchDeps[i])
+		}
+	}
+}
+
+// TestRequestBatch verifies that a batch proposal assigns consecutive
+// instances, resolves interference between batch members, and suppresses
+// duplicates like the single-command path.
+func TestRequestBatch(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	first := p.onRequest(newTestingCommand("a", "z"))
+
+	cmds := []*pb.Command{
+		newTestingCommand("a", "c"),
+		newTestingCommand("b", "d"),
+	}
+	insts := p.onRequestBatch(cmds)
+	if len(insts) != 2 || insts[0] == nil || insts[1] == nil {
+		t.Fatalf("expected 2 proposed instances, found %+v", insts)
+	}
+	for i, inst := range insts {
+		inst.assertState(pb.InstanceState_PreAccepted)
+		if exp := first.is.InstanceNum + pb.InstanceNum(i) + 1; inst.is.InstanceNum != exp {
+			t.Errorf("expected instance number %d, found %d", exp, inst.is.InstanceNum)
+		}
+		if !containsDep(inst.is.Deps, first.is.InstanceID) {
+			t.Errorf("expected batch command %d to depend on %+v, found %v",
+				i, first.is.InstanceID, inst.is.Deps)
+		}
+	}
+
+	// The second batch member interferes with the first, so it must
+	// depend on it and be sequenced after it.
+	if !containsDep(insts[1].is.Deps, insts[0].is.InstanceID) {
+		t.Errorf("expected second batch member to depend on first, found %v", insts[1].is.Deps)
+	}
+	if insts[1].is.SeqNum <= insts[0].is.SeqNum {
+		t.Errorf("expected second batch member sequenced after first, found %d <= %d",
+			insts[1].is.SeqNum, insts[0].is.SeqNum)
+	}
+
+	// Re-proposing the batch returns the existing instances.
+	if dup := p.onRequestBatch(cmds); dup[0] != insts[0] || dup[1] != insts[1] {
+		t.Errorf("expected duplicate batch to return existing instances, found %+v", dup)
+	}
+}
+
+// containsDep returns whether the dependency slice contains the
+// instance.
+func containsDep(deps []pb.InstanceID, id pb.InstanceID) bool {
+	for _, dep := range deps {
+		if dep == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TestInsertInstanceDetectsOverwrite verifies that an insertion into an
+// occupied instance slot keeps the occupant and surfaces the conflict as
+// an InstanceOverwriteEvent, instead of silently replacing agreed state.
+func TestInsertInstanceDetectsOverwrite(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0, 1, 2},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+
+	occupant := p.newInstance(1, 1)
+	occupant.is.Command = newTestingCommand("a", "z")
+	if !p.insertInstance(occupant) {
+		t.Fatalf("expected insertion into an empty slot to succeed")
+	}
+
+	// An instance with a different command must not displace the
+	// occupant.
+	intruder := p.newInstance(1, 1)
+	intruder.is.Command = newTestingCommand("x", "z")
+	if p.insertInstance(intruder) {
+		t.Fatalf("expected conflicting insertion to be refused")
+	}
+	if got := p.getInstance(1, 1); got != occupant {
+		t.Fatalf("expected occupant to be kept, found %+v", got)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected an InstanceOverwriteEvent, found %+v", events)
+	}
+	if e, ok := events[0].(InstanceOverwriteEvent); !ok || e.InstanceID != occupant.is.InstanceID {
+		t.Errorf("expected InstanceOverwriteEvent for %v, found %+v", occupant.is.InstanceID, events[0])
+	}
+
+	// A duplicate carrying the same command and ballot is ignored without
+	// an event.
+	dup := p.newInstance(1, 1)
+	dup.is.Command = occupant.is.Command
+	if p.insertInstance(dup) {
+		t.Fatalf("expected duplicate insertion to be refused")
+	}
+	if len(events) != 1 {
+		t.Errorf("expected no event for a matching duplicate, found %+v", events)
+	}
+}
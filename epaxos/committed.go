@@ -0,0 +1,171 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Per-replica committed instance tracking.
+//
+// Each command space keeps a compact set of disjoint, inclusive ranges
+// of instance numbers known to be committed locally. The set answers
+// hole queries without scanning the instance btrees, so recovery and
+// catch-up can locate uncommitted gaps cheaply, and it encodes into a
+// handful of ranges for exchange during the anti-entropy rounds driven
+// by the gcTimer (see gc.go). An instance a peer advertises as committed
+// but that this replica holds no commitment for is a hole: the commit
+// decision exists, so querying the advertising peer settles it in a
+// single round trip (see statusquery.go).
+
+// instanceInterval is an inclusive range of committed instance numbers.
+type instanceInterval struct {
+	start, end pb.InstanceNum
+}
+
+// instanceIntervals is a sorted set of disjoint, non-adjacent inclusive
+// ranges of instance numbers. The zero value is an empty set.
+type instanceIntervals []instanceInterval
+
+// contains returns whether the set contains the instance number.
+func (s instanceIntervals) contains(n pb.InstanceNum) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i].end >= n })
+	return i < len(s) && s[i].start <= n
+}
+
+// add returns the set with the single instance number added.
+func (s instanceIntervals) add(n pb.InstanceNum) instanceIntervals {
+	return s.addRange(n, n)
+}
+
+// addRange returns the set with the inclusive range [start, end] added,
+// merged with any ranges it touches.
+func (s instanceIntervals) addRange(start, end pb.InstanceNum) instanceIntervals {
+	// Ranges are mergeable if they overlap or are adjacent, so find the
+	// first range ending at or after start-1 and absorb ranges from there
+	// until one starts beyond end+1.
+	i := sort.Search(len(s), func(i int) bool { return s[i].end+1 >= start })
+	j := i
+	for j < len(s) && s[j].start <= end+1 {
+		if s[j].start < start {
+			start = s[j].start
+		}
+		if s[j].end > end {
+			end = s[j].end
+		}
+		j++
+	}
+	merged := append(s[:i:i], instanceInterval{start: start, end: end})
+	return append(merged, s[j:]...)
+}
+
+// holes returns up to limit instance numbers missing from the set within
+// the inclusive range [start, end].
+func (s instanceIntervals) holes(start, end pb.InstanceNum, limit int) []pb.InstanceNum {
+	var out []pb.InstanceNum
+	i := sort.Search(len(s), func(i int) bool { return s[i].end >= start })
+	for n := start; n <= end && len(out) < limit; {
+		if i < len(s) && s[i].start <= n {
+			// n is covered; resume beyond the covering range.
+			n = s[i].end + 1
+			i++
+			continue
+		}
+		gapEnd := end
+		if i < len(s) && s[i].start-1 < gapEnd {
+			gapEnd = s[i].start - 1
+		}
+		for ; n <= gapEnd && len(out) < limit; n++ {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// recordCommitted records that the instance has committed locally.
+func (p *epaxos) recordCommitted(r pb.ReplicaID, i pb.InstanceNum) {
+	p.committed[r] = p.committed[r].add(i)
+}
+
+// committedHoles returns up to limit uncommitted instance numbers in the
+// replica's command space at or below the provided bound, skipping the
+// truncated prefix, which was necessarily committed.
+func (p *epaxos) committedHoles(r pb.ReplicaID, below pb.InstanceNum, limit int) []pb.InstanceNum {
+	start := p.maxTruncated[r] + 1
+	if start > below {
+		return nil
+	}
+	return p.committed[r].holes(start, below, limit)
+}
+
+// maxCatchUpQueriesPerExchange caps how many holes a single
+// committed-range advertisement may query the sender about. A freshly
+// joined replica can be behind by entire command spaces; later
+// anti-entropy rounds pick up where the cap cut the round off.
+const maxCatchUpQueriesPerExchange = 16
+
+// broadcastCommittedRanges advertises the local committed sets to every
+// peer as part of the anti-entropy round.
+func (p *epaxos) broadcastCommittedRanges() {
+	cr := &pb.CommittedRanges{Ranges: p.committedRangeSlice()}
+	if len(cr.Ranges) == 0 {
+		return
+	}
+	for _, node := range p.nodes {
+		if node != p.id {
+			p.msgs = append(p.msgs, pb.Message{
+				To:   node,
+				From: p.id,
+				Type: pb.WrapMessageInner(cr),
+			})
+		}
+	}
+}
+
+// committedRangeSlice encodes the committed sets as a slice of
+// InstanceRanges, in replica then instance order, folding in each
+// truncated prefix.
+func (p *epaxos) committedRangeSlice() []pb.InstanceRange {
+	var ranges []pb.InstanceRange
+	for _, r := range p.nodes {
+		set := p.committed[r]
+		if t := p.maxTruncated[r]; t > 0 {
+			set = set.addRange(1, t)
+		}
+		for _, iv := range set {
+			ranges = append(ranges, pb.InstanceRange{ReplicaID: r, Start: iv.start, End: iv.end})
+		}
+	}
+	return ranges
+}
+
+// onCommittedRanges handles a peer's committed-range advertisement,
+// querying the sender for the committed state of any advertised instance
+// this replica holds no commitment for. The sender's replies are adopted
+// by maybeAdoptQueriedStatus, catching the replica up without touching
+// the instance btrees. An advertisement this replica has already
+// executed through in full also timestamps the sender's data freshness,
+// feeding the stale-read staleness bound (see staleread.go).
+func (p *epaxos) onCommittedRanges(m pb.Message, cr *pb.CommittedRanges) {
+	covered := true
+	queried := 0
+	for _, rng := range cr.Ranges {
+		if !p.knownReplica(rng.ReplicaID) || rng.End < rng.Start {
+			continue
+		}
+		if rng.End > p.executedPrefix[rng.ReplicaID] && rng.End > p.maxTruncated[rng.ReplicaID] {
+			covered = false
+		}
+		start := rng.Start
+		if t := p.maxTruncated[rng.ReplicaID]; t >= start {
+			start = t + 1
+		}
+		for _, n := range p.committed[rng.ReplicaID].holes(start, rng.End, maxCatchUpQueriesPerExchange-queried) {
+			p.QueryInstance(m.From, pb.InstanceID{ReplicaID: rng.ReplicaID, InstanceNum: n})
+			queried++
+		}
+	}
+	if covered {
+		p.dataFreshness[m.From] = p.ticks
+	}
+}
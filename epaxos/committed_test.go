@@ -0,0 +1,121 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestInstanceIntervals verifies the interval set's merging, membership,
+// and hole queries.
+func TestInstanceIntervals(t *testing.T) {
+	var s instanceIntervals
+	for _, n := range []pb.InstanceNum{5, 1, 3, 2} {
+		s = s.add(n)
+	}
+	exp := instanceIntervals{{1, 3}, {5, 5}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatalf("expected intervals %+v, found %+v", exp, s)
+	}
+
+	for n, expContains := range map[pb.InstanceNum]bool{
+		1: true, 3: true, 4: false, 5: true, 6: false,
+	} {
+		if s.contains(n) != expContains {
+			t.Errorf("expected contains(%d) = %t", n, expContains)
+		}
+	}
+
+	// Adding a range bridges the gap and absorbs both neighbors.
+	s = s.addRange(3, 7)
+	exp = instanceIntervals{{1, 7}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatalf("expected intervals %+v, found %+v", exp, s)
+	}
+
+	s = s.add(10)
+	holes := s.holes(1, 12, 16)
+	expHoles := []pb.InstanceNum{8, 9, 11, 12}
+	if !reflect.DeepEqual(holes, expHoles) {
+		t.Fatalf("expected holes %v, found %v", expHoles, holes)
+	}
+	if holes := s.holes(1, 12, 3); !reflect.DeepEqual(holes, expHoles[:3]) {
+		t.Fatalf("expected limited holes %v, found %v", expHoles[:3], holes)
+	}
+	if holes := s.holes(1, 7, 16); len(holes) != 0 {
+		t.Fatalf("expected no holes in a covered range, found %v", holes)
+	}
+}
+
+// TestCommittedTracking verifies that commitment is recorded in the
+// committed sets and that truncated prefixes fold into the encoded
+// advertisement.
+func TestCommittedTracking(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}})
+	p.Request(newTestingCommand("a", "z"))
+	p.Request(newTestingCommand("a", "z"))
+
+	if !p.committed[0].contains(1) || !p.committed[0].contains(2) {
+		t.Fatalf("expected instances 1 and 2 recorded committed, found %+v", p.committed[0])
+	}
+	if holes := p.committedHoles(0, 2, 16); len(holes) != 0 {
+		t.Fatalf("expected no holes, found %v", holes)
+	}
+
+	// A truncated prefix counts as committed even if it predates the set.
+	p.committed[0] = nil
+	p.maxTruncated = map[pb.ReplicaID]pb.InstanceNum{0: 2}
+	if holes := p.committedHoles(0, 2, 16); len(holes) != 0 {
+		t.Fatalf("expected the truncated prefix hole-free, found %v", holes)
+	}
+	exp := []pb.InstanceRange{{ReplicaID: 0, Start: 1, End: 2}}
+	if ranges := p.committedRangeSlice(); !reflect.DeepEqual(ranges, exp) {
+		t.Fatalf("expected ranges %+v, found %+v", exp, ranges)
+	}
+}
+
+// TestCommittedRangesCatchUp verifies that a peer's committed-range
+// advertisement triggers instance queries for exactly the local holes it
+// reveals, capped per advertisement.
+func TestCommittedRangesCatchUp(t *testing.T) {
+	p := newEPaxos(&Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2}})
+	p.recordCommitted(2, 2)
+	p.recordCommitted(2, 4)
+
+	adv := func(start, end pb.InstanceNum) pb.Message {
+		return pb.Message{
+			To:   1,
+			From: 0,
+			Type: pb.WrapMessageInner(&pb.CommittedRanges{
+				Ranges: []pb.InstanceRange{{ReplicaID: 2, Start: start, End: end}},
+			}),
+		}
+	}
+
+	p.Step(adv(1, 5))
+	var queried []pb.InstanceID
+	for _, msg := range p.msgs {
+		if _, ok := msg.Type.(*pb.Message_InstanceQuery); ok {
+			if msg.To != 0 {
+				t.Errorf("expected queries sent to the advertising peer, found %+v", msg)
+			}
+			queried = append(queried, msg.InstanceID)
+		}
+	}
+	exp := []pb.InstanceID{
+		{ReplicaID: 2, InstanceNum: 1},
+		{ReplicaID: 2, InstanceNum: 3},
+		{ReplicaID: 2, InstanceNum: 5},
+	}
+	if !reflect.DeepEqual(queried, exp) {
+		t.Fatalf("expected queries for %+v, found %+v", exp, queried)
+	}
+
+	// A huge advertisement is capped; later rounds pick up the rest.
+	p.clearMsgs()
+	p.Step(adv(1, 100))
+	if l := len(p.msgs); l != maxCatchUpQueriesPerExchange {
+		t.Fatalf("expected %d capped queries, found %d", maxCatchUpQueriesPerExchange, l)
+	}
+}
@@ -0,0 +1,57 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// PreconditionFunc reports whether a conditional command's precondition
+// still holds. It is evaluated on every replica against the applied
+// state, immediately before the command would be applied, and must be
+// deterministic across replicas: given the same applied state, every
+// replica must reach the same verdict.
+type PreconditionFunc func(pb.Command) bool
+
+// PreconditionFailedEvent is emitted on the command leader when a
+// conditional command's precondition failed and the command executed as
+// a no-op.
+type PreconditionFailedEvent struct {
+	// Command is the command whose precondition failed.
+	Command pb.Command
+}
+
+func (PreconditionFailedEvent) event() {}
+
+// maybeDeliverExecuted delivers the instance's executed command, unless
+// the command is conditional and its precondition no longer holds, in
+// which case the command becomes a no-op. Conditional commands enable
+// optimistic concurrency control on top of the log: a proposer reads a
+// value, proposes a change predicated on what it read, and learns
+// through a PreconditionFailedEvent if it lost the race.
+func (p *epaxos) maybeDeliverExecuted(inst *instance) {
+	cmd := *inst.is.Command
+	if cmd.Noop {
+		// No-ops plug recovered instances that never received a command
+		// and are never delivered to the application.
+		return
+	}
+	if cmd.Compaction {
+		// Compaction commands are protocol-internal and are never
+		// delivered to the application.
+		p.onCompactionCommand(cmd)
+		return
+	}
+	if cmd.ConfChange {
+		// Membership changes are likewise protocol-internal; applying
+		// one switches this replica to the new configuration.
+		p.onConfChangeCommand(cmd)
+		return
+	}
+	if cmd.Conditional && p.checkPrecondition != nil && !p.checkPrecondition(cmd) {
+		p.execLogger.Debugf("precondition failed for command %d; executing as no-op", cmd.ID)
+		if inst.is.ReplicaID == p.id {
+			p.emitEvent(PreconditionFailedEvent{Command: cmd})
+		}
+		return
+	}
+	p.deliverExecutedCommand(cmd)
+}
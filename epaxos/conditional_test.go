@@ -0,0 +1,56 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestConditionalCommand verifies that a conditional command whose
+// precondition fails executes as a no-op and surfaces a
+// PreconditionFailedEvent on the command leader, while a passing
+// precondition leaves execution unchanged.
+func TestConditionalCommand(t *testing.T) {
+	var events []Event
+	hold := false
+	p := newEPaxos(&Config{
+		ID:                0,
+		Nodes:             []pb.ReplicaID{0},
+		CheckPrecondition: func(cmd pb.Command) bool { return hold },
+		OnEvent:           func(e Event) { events = append(events, e) },
+	})
+
+	// The precondition fails: the command executes as a no-op.
+	cmd := newTestingCommand("a", "z")
+	cmd.ID = 1
+	cmd.Conditional = true
+	p.Request(cmd)
+	if len(p.executedCmds) != 0 {
+		t.Fatalf("expected no delivered commands, found %+v", p.executedCmds)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, found %+v", events)
+	}
+	if e, ok := events[0].(PreconditionFailedEvent); !ok || e.Command.ID != 1 {
+		t.Fatalf("expected PreconditionFailedEvent for command 1, found %+v", events[0])
+	}
+
+	// The precondition holds: the command is delivered normally.
+	hold = true
+	cmd2 := newTestingCommand("a", "z")
+	cmd2.ID = 2
+	cmd2.Conditional = true
+	p.Request(cmd2)
+	if len(p.executedCmds) != 1 || p.executedCmds[0].ID != 2 {
+		t.Fatalf("expected command 2 delivered, found %+v", p.executedCmds)
+	}
+
+	// Unconditional commands bypass the precondition entirely.
+	hold = false
+	cmd3 := newTestingCommand("a", "z")
+	cmd3.ID = 3
+	p.Request(cmd3)
+	if len(p.executedCmds) != 2 || p.executedCmds[1].ID != 3 {
+		t.Fatalf("expected command 3 delivered, found %+v", p.executedCmds)
+	}
+}
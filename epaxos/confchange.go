@@ -0,0 +1,168 @@
+package epaxos
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/google/btree"
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Dynamic membership changes via replicated configuration commands.
+//
+// A configuration change is a protocol-internal command, agreed via the
+// normal consensus path, whose payload adds or removes a single replica.
+// Because the command interferes with every other command, it is totally
+// ordered against the rest of the log: every replica applies it at the
+// same point in its execution order, so all replicas switch quorum sizes
+// and configuration epoch together.
+//
+// Applying a change increments the configuration epoch. New ballots are
+// stamped with the current epoch, and proposal traffic carrying an older
+// epoch is fenced out (see Step), so that a replica still operating
+// under the old configuration cannot form quorums with stale sizes.
+// Removing a replica keeps its instance space intact; its committed
+// history must still finish executing on the surviving replicas.
+
+// ConfChangeType is the type of a membership change.
+type ConfChangeType uint64
+
+const (
+	// ConfChangeAddReplica adds a replica to the configuration.
+	ConfChangeAddReplica ConfChangeType = iota
+	// ConfChangeRemoveReplica removes a replica from the configuration.
+	ConfChangeRemoveReplica
+)
+
+// ConfChange describes a single-replica membership change.
+type ConfChange struct {
+	Type    ConfChangeType
+	Replica pb.ReplicaID
+}
+
+// NewConfChangeCommand returns a command that, once agreed and executed,
+// applies the provided membership change on every replica.
+func NewConfChangeCommand(id uint64, cc ConfChange) *pb.Command {
+	return &pb.Command{
+		ID:         id,
+		ConfChange: true,
+		Data:       encodeConfChange(cc),
+	}
+}
+
+// encodeConfChange encodes the change as a pair of varints.
+func encodeConfChange(cc ConfChange) []byte {
+	var buf [2 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(cc.Type))
+	n += binary.PutUvarint(buf[n:], uint64(cc.Replica))
+	return buf[:n]
+}
+
+// decodeConfChange decodes a payload written by encodeConfChange.
+func decodeConfChange(data []byte) (ConfChange, error) {
+	t, n := binary.Uvarint(data)
+	if n <= 0 {
+		return ConfChange{}, errors.Errorf("malformed conf change type")
+	}
+	r, m := binary.Uvarint(data[n:])
+	if m <= 0 {
+		return ConfChange{}, errors.Errorf("malformed conf change replica")
+	}
+	return ConfChange{Type: ConfChangeType(t), Replica: pb.ReplicaID(r)}, nil
+}
+
+// ProposeConfChange proposes a membership change through the normal
+// consensus path. The change takes effect on each replica when the
+// command executes there, announced by a ConfChangeAppliedEvent.
+func (p *epaxos) ProposeConfChange(id uint64, cc ConfChange) {
+	if p.halted {
+		p.logger.Debugf("ignoring conf change proposal on halted replica: %+v", cc)
+		return
+	}
+	if p.sheddingLoad() {
+		p.logger.Debugf("rejecting conf change proposal while storage is slow: %+v", cc)
+		return
+	}
+	p.onRequest(NewConfChangeCommand(id, cc))
+}
+
+// ConfChangeAppliedEvent is emitted on every replica when a membership
+// change command executes, switching the replica to the new
+// configuration.
+type ConfChangeAppliedEvent struct {
+	// Change is the membership change that was applied.
+	Change ConfChange
+	// Nodes is the node set after the change.
+	Nodes []pb.ReplicaID
+	// Epoch is the configuration epoch after the change.
+	Epoch uint64
+}
+
+func (ConfChangeAppliedEvent) event() {}
+
+// onConfChangeCommand handles an executed membership change command,
+// adjusting the node set, recomputing quorum sizes and advancing the
+// configuration epoch.
+func (p *epaxos) onConfChangeCommand(cmd pb.Command) {
+	cc, err := decodeConfChange(cmd.Data)
+	if err != nil {
+		p.logger.Warningf("ignoring malformed conf change command %d: %v", cmd.ID, err)
+		return
+	}
+	switch cc.Type {
+	case ConfChangeAddReplica:
+		if p.knownReplica(cc.Replica) {
+			p.logger.Debugf("ignoring conf change adding existing replica %d", cc.Replica)
+			return
+		}
+		p.nodes = append(p.nodes, cc.Replica)
+		sort.Slice(p.nodes, func(i, j int) bool { return p.nodes[i] < p.nodes[j] })
+		p.commands[cc.Replica] = btree.New(32 /* degree */)
+		p.depIndex[cc.Replica] = newDepSpaceIndex()
+	case ConfChangeRemoveReplica:
+		if !p.knownReplica(cc.Replica) {
+			p.logger.Debugf("ignoring conf change removing unknown replica %d", cc.Replica)
+			return
+		}
+		nodes := p.nodes[:0]
+		for _, r := range p.nodes {
+			if r != cc.Replica {
+				nodes = append(nodes, r)
+			}
+		}
+		p.nodes = nodes
+		// The removed replica's instance space stays; its committed
+		// history must still finish executing.
+	default:
+		p.logger.Warningf("ignoring conf change command %d with unknown type %d", cmd.ID, cc.Type)
+		return
+	}
+	p.epoch++
+	p.refreshQuorumSizes()
+
+	// Persist the new configuration so that it survives restarts.
+	if hs, ok := p.storage.HardState(); ok {
+		hs.Nodes = append([]pb.ReplicaID(nil), p.nodes...)
+		hs.Epoch = p.epoch
+		p.storage.PersistHardState(hs)
+		p.readyHardState = &hs
+	}
+
+	p.logger.Infof("applied conf change %+v; nodes %v, epoch %d", cc, p.nodes, p.epoch)
+	p.emitEvent(ConfChangeAppliedEvent{
+		Change: cc,
+		Nodes:  append([]pb.ReplicaID(nil), p.nodes...),
+		Epoch:  p.epoch,
+	})
+}
+
+// refreshQuorumSizes recomputes the fast-quorum size for the current
+// node set.
+func (p *epaxos) refreshQuorumSizes() {
+	p.fastQuorumSize = len(p.nodes) - 1
+	if p.optimizedFastQuorum {
+		p.fastQuorumSize = p.F() + (p.F()+1)/2
+	}
+}
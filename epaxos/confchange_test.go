@@ -0,0 +1,124 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestConfChangeEncodeDecode verifies that conf change payloads round
+// trip through their encoding.
+func TestConfChangeEncodeDecode(t *testing.T) {
+	for _, cc := range []ConfChange{
+		{Type: ConfChangeAddReplica, Replica: 3},
+		{Type: ConfChangeRemoveReplica, Replica: 0},
+	} {
+		dec, err := decodeConfChange(encodeConfChange(cc))
+		if err != nil {
+			t.Fatalf("unexpected error decoding %+v: %v", cc, err)
+		}
+		if dec != cc {
+			t.Errorf("expected %+v to round trip, found %+v", cc, dec)
+		}
+	}
+}
+
+// TestConfChangeAddReplica verifies that an agreed conf change adding a
+// replica takes effect on every replica: the node set grows, quorum
+// sizes are recomputed and the configuration epoch advances.
+func TestConfChangeAddReplica(t *testing.T) {
+	n := newNetwork(3)
+	n.peers[0].ProposeConfChange(7, ConfChange{Type: ConfChangeAddReplica, Replica: 3})
+	if !n.runNetwork(func(p *epaxos) bool { return len(p.nodes) == 4 }, false) {
+		t.Fatalf("conf change failed to apply on all replicas")
+	}
+	for _, p := range n.peers {
+		if !p.knownReplica(3) {
+			t.Errorf("expected replica %d to know replica 3", p.id)
+		}
+		if p.commands[3] == nil {
+			t.Errorf("expected replica %d to track replica 3's instance space", p.id)
+		}
+		if exp := 3; p.fastQuorumSize != exp {
+			t.Errorf("expected fast quorum size %d on replica %d, found %d",
+				exp, p.id, p.fastQuorumSize)
+		}
+		if p.epoch != 1 {
+			t.Errorf("expected epoch 1 on replica %d, found %d", p.id, p.epoch)
+		}
+	}
+}
+
+// TestConfChangeAddReplicaIndexesDeps verifies that an added replica's
+// instance space is wired into the dependency index, so that the first
+// instance created in its space — as a PreAccept from the new replica
+// would — can be indexed.
+func TestConfChangeAddReplicaIndexesDeps(t *testing.T) {
+	n := newNetwork(3)
+	n.peers[0].ProposeConfChange(7, ConfChange{Type: ConfChangeAddReplica, Replica: 3})
+	if !n.runNetwork(func(p *epaxos) bool { return len(p.nodes) == 4 }, false) {
+		t.Fatalf("conf change failed to apply on all replicas")
+	}
+	p := n.peers[0]
+	if p.depIndex[3] == nil {
+		t.Fatalf("expected replica 3's dependency index to be allocated")
+	}
+
+	inst := p.newInstance(3, 1)
+	p.insertInstance(inst)
+	inst.is.Command = newTestingCommand("a", "z")
+	p.indexCommand(inst)
+	_, deps := p.seqAndDepsForCommand(newTestingCommand("a", "z"), pb.InstanceID{})
+	if _, ok := deps[pb.InstanceID{ReplicaID: 3, InstanceNum: 1}]; !ok {
+		t.Fatalf("expected the indexed instance as a dependency, found %v", deps)
+	}
+}
+
+// TestConfChangeRemoveReplica verifies that an agreed conf change
+// removing a replica shrinks the node set and recomputes quorum sizes,
+// while the removed replica's instance space is retained so that its
+// committed history can finish executing.
+func TestConfChangeRemoveReplica(t *testing.T) {
+	n := newNetwork(3)
+	n.peers[0].ProposeConfChange(7, ConfChange{Type: ConfChangeRemoveReplica, Replica: 2})
+	if !n.runNetwork(func(p *epaxos) bool { return len(p.nodes) == 2 }, false) {
+		t.Fatalf("conf change failed to apply on all replicas")
+	}
+	for _, p := range n.peers {
+		if p.knownReplica(2) {
+			t.Errorf("expected replica %d to have removed replica 2", p.id)
+		}
+		if p.commands[2] == nil {
+			t.Errorf("expected replica %d to retain replica 2's instance space", p.id)
+		}
+		if exp := 1; p.fastQuorumSize != exp {
+			t.Errorf("expected fast quorum size %d on replica %d, found %d",
+				exp, p.id, p.fastQuorumSize)
+		}
+		if p.epoch != 1 {
+			t.Errorf("expected epoch 1 on replica %d, found %d", p.id, p.epoch)
+		}
+	}
+}
+
+// TestConfChangeEpochFencing verifies that proposal traffic carrying a
+// ballot epoch below the receiver's configuration epoch is fenced out.
+func TestConfChangeEpochFencing(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	leader := newEPaxos(&Config{ID: 0, Nodes: nodes})
+	follower := newEPaxos(&Config{ID: 1, Nodes: nodes})
+	follower.epoch = 1
+
+	leader.Request(newTestingCommand("a", "z"))
+	for _, m := range leader.ReadMessages() {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+	if l := follower.commands[leader.id].Len(); l != 0 {
+		t.Fatalf("expected stale-epoch PreAccept fenced, found %d instances", l)
+	}
+	if replies := follower.ReadMessages(); len(replies) != 0 {
+		t.Fatalf("expected no replies to fenced message, found %v", replies)
+	}
+}
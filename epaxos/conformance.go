@@ -0,0 +1,174 @@
+package epaxos
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Protocol conformance harness.
+//
+// The harness drives a deterministic in-process cluster from a trace of
+// steps and writes the observed protocol behavior — every message sent
+// and every command executed — as one canonical line per event. Traces
+// and their golden outputs are language-agnostic: any EPaxos
+// implementation that speaks the same step format over stdin/stdout can
+// be checked against the same goldens, so deviations from the protocol's
+// message flow are caught explicitly rather than masked by end-to-end
+// convergence.
+//
+// A trace is a stream of JSON objects, one per line, with blank lines
+// and lines starting with '#' ignored:
+//
+//	{"op": "init", "replicas": 3}
+//	{"op": "propose", "replica": 0, "id": 1, "start": "a", "end": "z", "writing": true}
+//	{"op": "deliver"}
+//	{"op": "tick"}
+//
+// "deliver" performs one delivery round: it collects every pending
+// message in canonical order (sender ascending, then send order),
+// records it, and steps it into its destination. Messages generated
+// during the round wait for the next one. "tick" advances every
+// replica's logical clock once.
+
+// conformanceStep is one decoded trace line.
+type conformanceStep struct {
+	Op       string `json:"op"`
+	Replicas int    `json:"replicas,omitempty"`
+	Replica  uint64 `json:"replica,omitempty"`
+	ID       uint64 `json:"id,omitempty"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	Writing  bool   `json:"writing,omitempty"`
+}
+
+// RunConformance drives a deterministic cluster from the trace on r,
+// writing the observed protocol behavior to w.
+func RunConformance(r io.Reader, w io.Writer) error {
+	var peers []*epaxos
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var step conformanceStep
+		if err := json.Unmarshal([]byte(text), &step); err != nil {
+			return errors.Wrapf(err, "decoding trace line %d", line)
+		}
+		if step.Op != "init" && peers == nil {
+			return errors.Errorf("trace line %d: %q before init", line, step.Op)
+		}
+		switch step.Op {
+		case "init":
+			if peers != nil {
+				return errors.Errorf("trace line %d: repeated init", line)
+			}
+			nodes := make([]pb.ReplicaID, step.Replicas)
+			for i := range nodes {
+				nodes[i] = pb.ReplicaID(i)
+			}
+			peers = make([]*epaxos, step.Replicas)
+			for i := range peers {
+				peers[i] = newEPaxos(&Config{
+					ID:       pb.ReplicaID(i),
+					Nodes:    nodes,
+					RandSeed: int64(i) + 1,
+				})
+			}
+		case "propose":
+			if int(step.Replica) >= len(peers) {
+				return errors.Errorf("trace line %d: unknown replica %d", line, step.Replica)
+			}
+			cmd := &pb.Command{
+				ID:      step.ID,
+				Span:    pb.Span{Key: pb.Key(step.Start)},
+				Writing: step.Writing,
+			}
+			if step.End != "" {
+				cmd.Span.EndKey = pb.Key(step.End)
+			}
+			peers[step.Replica].Request(cmd)
+		case "deliver":
+			var msgs []pb.Message
+			for _, p := range peers {
+				msgs = append(msgs, p.msgs...)
+				p.clearMsgs()
+			}
+			for _, m := range msgs {
+				fmt.Fprintf(w, "msg %d->%d %s\n", m.From, m.To, describeMessage(m))
+				if int(m.To) < len(peers) {
+					peers[m.To].Step(m)
+				}
+			}
+		case "tick":
+			for _, p := range peers {
+				p.Tick()
+			}
+		default:
+			return errors.Errorf("trace line %d: unknown op %q", line, step.Op)
+		}
+		for _, p := range peers {
+			for _, cmd := range p.executedCmds {
+				fmt.Fprintf(w, "exec %d %d\n", p.id, cmd.ID)
+			}
+			p.clearExecutedCommands()
+		}
+	}
+	return scanner.Err()
+}
+
+// describeMessage renders a message in the canonical form used by
+// conformance goldens: the message type, its instance and ballot, and
+// the proposed attributes for attribute-bearing types.
+func describeMessage(m pb.Message) string {
+	inst := fmt.Sprintf("inst=%d.%d ballot=%d.%d.%d",
+		m.InstanceID.ReplicaID, m.InstanceID.InstanceNum,
+		m.Ballot.Epoch, m.Ballot.Number, m.Ballot.ReplicaID)
+	switch t := m.Type.(type) {
+	case *pb.Message_PreAccept:
+		return fmt.Sprintf("PreAccept %s seq=%d deps=%s", inst, t.PreAccept.SeqNum, depsString(t.PreAccept.Deps))
+	case *pb.Message_PreAcceptOk:
+		return fmt.Sprintf("PreAcceptOK %s", inst)
+	case *pb.Message_PreAcceptReply:
+		return fmt.Sprintf("PreAcceptReply %s seq=%d deps=%s", inst,
+			t.PreAcceptReply.UpdatedSeqNum, depsString(t.PreAcceptReply.UpdatedDeps))
+	case *pb.Message_Accept:
+		return fmt.Sprintf("Accept %s seq=%d deps=%s", inst, t.Accept.SeqNum, depsString(t.Accept.Deps))
+	case *pb.Message_AcceptOk:
+		return fmt.Sprintf("AcceptOK %s", inst)
+	case *pb.Message_Commit:
+		return fmt.Sprintf("Commit %s seq=%d deps=%s", inst, t.Commit.SeqNum, depsString(t.Commit.Deps))
+	case *pb.Message_TryPreAccept:
+		return fmt.Sprintf("TryPreAccept %s seq=%d deps=%s", inst, t.TryPreAccept.SeqNum, depsString(t.TryPreAccept.Deps))
+	case *pb.Message_TryPreAcceptReply:
+		return fmt.Sprintf("TryPreAcceptReply %s conflict=%t", inst, t.TryPreAcceptReply.Conflict)
+	case *pb.Message_Nack:
+		return fmt.Sprintf("Nack %s", inst)
+	default:
+		return fmt.Sprintf("%T %s", m.Type, inst)
+	}
+}
+
+// depsString renders a dependency set in canonical sorted order.
+func depsString(deps []pb.InstanceID) string {
+	sorted := append([]pb.InstanceID(nil), deps...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ReplicaID != sorted[j].ReplicaID {
+			return sorted[i].ReplicaID < sorted[j].ReplicaID
+		}
+		return sorted[i].InstanceNum < sorted[j].InstanceNum
+	})
+	parts := make([]string, len(sorted))
+	for i, d := range sorted {
+		parts[i] = fmt.Sprintf("%d.%d", d.ReplicaID, d.InstanceNum)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
@@ -0,0 +1,64 @@
+package epaxos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConformanceGoldenTraces runs the implementation against every
+// golden trace in testdata, failing on any deviation from the recorded
+// protocol behavior.
+func TestConformanceGoldenTraces(t *testing.T) {
+	traces, err := filepath.Glob(filepath.Join("testdata", "*.trace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) == 0 {
+		t.Fatal("no conformance traces found in testdata")
+	}
+	for _, tracePath := range traces {
+		name := strings.TrimSuffix(filepath.Base(tracePath), ".trace")
+		t.Run(name, func(t *testing.T) {
+			in, err := os.Open(tracePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer in.Close()
+			var out bytes.Buffer
+			if err := RunConformance(in, &out); err != nil {
+				t.Fatalf("unexpected conformance error: %v", err)
+			}
+			golden, err := ioutil.ReadFile(filepath.Join("testdata", name+".golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), golden) {
+				t.Errorf("trace output deviates from golden\nexpected:\n%s\nfound:\n%s",
+					golden, out.Bytes())
+			}
+		})
+	}
+}
+
+// TestConformanceRejectsMalformedTrace verifies that trace errors are
+// reported rather than silently skipped.
+func TestConformanceRejectsMalformedTrace(t *testing.T) {
+	testCases := []struct {
+		trace  string
+		expErr string
+	}{
+		{`{"op": "propose", "replica": 0}`, "before init"},
+		{`{"op": "init", "replicas": 3}` + "\n" + `{"op": "frob"}`, "unknown op"},
+		{`not json`, "decoding trace line 1"},
+	}
+	for _, c := range testCases {
+		err := RunConformance(strings.NewReader(c.trace), ioutil.Discard)
+		if err == nil || !strings.Contains(err.Error(), c.expErr) {
+			t.Errorf("expected error containing %q, found %v", c.expErr, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package epaxos
+
+import (
+	"github.com/google/btree"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// peerLivenessTimeout is the number of ticks after which a peer that has
+// not been heard from is considered unhealthy. Peers start out healthy,
+// so the timeout doubles as a startup grace period.
+const peerLivenessTimeout = 30
+
+// conservativeRetransmitTimeout is the number of ticks between
+// retransmissions of in-flight proposals while in conservative mode.
+const conservativeRetransmitTimeout = 5
+
+// ConservativeModeEnteredEvent is emitted when the set of healthy
+// replicas drops to a bare quorum and the node switches to conservative
+// mode.
+type ConservativeModeEnteredEvent struct {
+	// Healthy is the set of replicas still considered healthy.
+	Healthy []pb.ReplicaID
+}
+
+func (ConservativeModeEnteredEvent) event() {}
+
+// ConservativeModeExitedEvent is emitted when enough replicas become
+// healthy again for the node to leave conservative mode.
+type ConservativeModeExitedEvent struct{}
+
+func (ConservativeModeExitedEvent) event() {}
+
+// recordHeardFrom notes that the provided replica was heard from, for
+// peer liveness tracking. Liveness feeds both conservative mode and the
+// QuorumConnected health predicate.
+func (p *epaxos) recordHeardFrom(r pb.ReplicaID) {
+	if p.knownReplica(r) {
+		p.lastHeard[r] = p.ticks
+	}
+}
+
+// healthyReplicas returns the set of replicas considered healthy: the
+// local replica, plus every peer heard from within peerLivenessTimeout
+// ticks.
+func (p *epaxos) healthyReplicas() []pb.ReplicaID {
+	healthy := make([]pb.ReplicaID, 0, len(p.nodes))
+	for _, r := range p.nodes {
+		if r == p.id || p.ticks-p.lastHeard[r] <= peerLivenessTimeout {
+			healthy = append(healthy, r)
+		}
+	}
+	return healthy
+}
+
+// maybeToggleConservative switches the node into or out of conservative
+// mode based on peer liveness. When the healthy replica count drops to a
+// bare quorum, one more failure makes the cluster unavailable, so the
+// node stops gambling on the fast path: slow-path commits are taken as
+// soon as a quorum replies, and in-flight proposals are retransmitted
+// aggressively in case earlier broadcasts were lost.
+func (p *epaxos) maybeToggleConservative() {
+	if !p.conservativeEnabled || p.singleNode() {
+		return
+	}
+	healthy := p.healthyReplicas()
+	bareQuorum := len(healthy) <= len(p.nodes)/2+1
+	switch {
+	case bareQuorum && !p.conservative:
+		p.conservative = true
+		p.logger.Warningf("entering conservative mode with healthy replicas %v", healthy)
+		p.retransmitTimer = makeTickingTimer(conservativeRetransmitTimeout, func() {
+			p.retransmitInFlight()
+		})
+		p.registerInfiniteTimer(&p.retransmitTimer)
+		p.emitEvent(ConservativeModeEnteredEvent{Healthy: healthy})
+	case !bareQuorum && p.conservative:
+		p.conservative = false
+		p.logger.Infof("exiting conservative mode with healthy replicas %v", healthy)
+		p.unregisterTimer(&p.retransmitTimer)
+		p.emitEvent(ConservativeModeExitedEvent{})
+	}
+}
+
+// retransmitInFlight rebroadcasts every local proposal still awaiting
+// replies. Instances in this replica's own command space are led by this
+// replica, so retransmission never usurps another leader.
+func (p *epaxos) retransmitInFlight() {
+	p.commands[p.id].Ascend(func(i btree.Item) bool {
+		inst := i.(*instance)
+		switch inst.is.Status {
+		case pb.InstanceState_PreAccepted:
+			inst.broadcastPreAcceptByDigest()
+		case pb.InstanceState_Accepted:
+			inst.broadcastAccept()
+		}
+		return true
+	})
+}
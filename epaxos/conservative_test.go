@@ -0,0 +1,57 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestConservativeModeOnBareQuorum verifies that the node enters
+// conservative mode when healthy membership drops to a bare quorum,
+// retransmits in-flight proposals while degraded, and exits the mode
+// when membership recovers.
+func TestConservativeModeOnBareQuorum(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:                       0,
+		Nodes:                    []pb.ReplicaID{0, 1, 2},
+		ConservativeOnBareQuorum: true,
+		OnEvent:                  func(e Event) { events = append(events, e) },
+	})
+
+	// Replica 1 stays healthy; replica 2 goes silent.
+	for i := 0; i < peerLivenessTimeout+2; i++ {
+		p.recordHeardFrom(1)
+		p.Tick()
+	}
+	if !p.conservative {
+		t.Fatalf("expected conservative mode after replica 2 went silent")
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, found %+v", events)
+	}
+	if e, ok := events[0].(ConservativeModeEnteredEvent); !ok || len(e.Healthy) != 2 {
+		t.Fatalf("expected ConservativeModeEnteredEvent with 2 healthy replicas, found %+v", events[0])
+	}
+
+	// An in-flight proposal is retransmitted while degraded.
+	p.onRequest(newTestingCommand("a", "z"))
+	p.clearMsgs()
+	for i := 0; i < conservativeRetransmitTimeout; i++ {
+		p.recordHeardFrom(1)
+		p.Tick()
+	}
+	if len(p.msgs) == 0 {
+		t.Errorf("expected in-flight proposal to be retransmitted")
+	}
+
+	// Hearing from replica 2 again restores normal operation.
+	p.recordHeardFrom(2)
+	p.Tick()
+	if p.conservative {
+		t.Fatalf("expected conservative mode to be exited")
+	}
+	if e, ok := events[len(events)-1].(ConservativeModeExitedEvent); !ok {
+		t.Fatalf("expected ConservativeModeExitedEvent, found %+v", e)
+	}
+}
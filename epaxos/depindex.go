@@ -0,0 +1,130 @@
+package epaxos
+
+import (
+	"container/list"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// IndexBackend is a backing store for the dependency index. Implementations
+// can be backed by persistent storage (e.g. an LSM tree) so that the
+// interference-tracking index does not need to be held fully in memory for
+// long-lived clusters with large keyspaces.
+type IndexBackend interface {
+	// Get returns the entry stored for the provided key, if one exists.
+	Get(key pb.Key) (pb.InstanceID, bool)
+	// Put stores the entry for the provided key, overwriting any
+	// previous entry.
+	Put(key pb.Key, id pb.InstanceID)
+}
+
+// memoryIndexBackend is an in-memory implementation of IndexBackend.
+type memoryIndexBackend struct {
+	entries map[string]pb.InstanceID
+}
+
+// NewMemoryIndexBackend returns a new in-memory implementation of
+// IndexBackend.
+func NewMemoryIndexBackend() IndexBackend {
+	return &memoryIndexBackend{entries: make(map[string]pb.InstanceID)}
+}
+
+// Get implements the IndexBackend interface.
+func (b *memoryIndexBackend) Get(key pb.Key) (pb.InstanceID, bool) {
+	id, ok := b.entries[string(key)]
+	return id, ok
+}
+
+// Put implements the IndexBackend interface.
+func (b *memoryIndexBackend) Put(key pb.Key, id pb.InstanceID) {
+	b.entries[string(key)] = id
+}
+
+// defaultHotCacheSize is the default number of keys held in the dependency
+// index's in-memory hot cache.
+const defaultHotCacheSize = 1024
+
+// keyIndex tracks the latest known instance to touch each key. Lookups and
+// updates go through a bounded in-memory hot cache backed by an
+// IndexBackend, so that memory use does not scale with the size of the
+// keyspace.
+type keyIndex struct {
+	backend IndexBackend
+
+	// hot cache state. entries maps keys to their element in the eviction
+	// list, which is kept in least-recently-used order.
+	maxEntries int
+	entries    map[string]*list.Element
+	eviction   *list.List
+}
+
+type keyIndexEntry struct {
+	key string
+	id  pb.InstanceID
+}
+
+func makeKeyIndex(backend IndexBackend, maxEntries int) keyIndex {
+	return keyIndex{
+		backend:    backend,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		eviction:   list.New(),
+	}
+}
+
+// update records the provided instance as the latest instance to touch the
+// provided key.
+func (ki *keyIndex) update(key pb.Key, id pb.InstanceID) {
+	ki.backend.Put(key, id)
+	ki.addToCache(key, id)
+}
+
+// latest returns the latest known instance to touch the provided key, if
+// one exists.
+func (ki *keyIndex) latest(key pb.Key) (pb.InstanceID, bool) {
+	if e, ok := ki.entries[string(key)]; ok {
+		ki.eviction.MoveToFront(e)
+		return e.Value.(*keyIndexEntry).id, true
+	}
+	id, ok := ki.backend.Get(key)
+	if ok {
+		ki.addToCache(key, id)
+	}
+	return id, ok
+}
+
+func (ki *keyIndex) addToCache(key pb.Key, id pb.InstanceID) {
+	if e, ok := ki.entries[string(key)]; ok {
+		e.Value.(*keyIndexEntry).id = id
+		ki.eviction.MoveToFront(e)
+		return
+	}
+	e := ki.eviction.PushFront(&keyIndexEntry{key: string(key), id: id})
+	ki.entries[string(key)] = e
+	if ki.eviction.Len() > ki.maxEntries {
+		last := ki.eviction.Back()
+		ki.eviction.Remove(last)
+		delete(ki.entries, last.Value.(*keyIndexEntry).key)
+	}
+}
+
+// indexCommand records the instance in the dependency index as the latest
+// instance to touch its command's start key, and records the command's
+// digest so that later messages can reference the command by digest. It
+// also (re)indexes the instance in its command space's interval index,
+// which tolerates an unresolved command, so it must be called whenever an
+// instance enters a command space or its command changes.
+func (p *epaxos) indexCommand(inst *instance) {
+	p.depIndex[inst.is.ReplicaID].add(inst)
+	if cmd := inst.is.Command; cmd != nil {
+		p.keyIndex.update(cmd.Span.Key, inst.is.InstanceID)
+		p.cmdDigests[string(inst.commandDigest())] = cmd
+		p.recordHeat(cmd)
+	}
+}
+
+// commandForDigest resolves a command digest to a known command.
+func (p *epaxos) commandForDigest(digest []byte) (*pb.Command, bool) {
+	cmd, ok := p.cmdDigests[string(digest)]
+	return cmd, ok
+}
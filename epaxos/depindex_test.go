@@ -0,0 +1,55 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestKeyIndexEviction verifies that the dependency index's hot cache is
+// bounded and that evicted entries remain retrievable from the backend.
+func TestKeyIndexEviction(t *testing.T) {
+	backend := NewMemoryIndexBackend()
+	ki := makeKeyIndex(backend, 2 /* maxEntries */)
+
+	idA := pb.InstanceID{ReplicaID: 1, InstanceNum: 1}
+	idB := pb.InstanceID{ReplicaID: 1, InstanceNum: 2}
+	idC := pb.InstanceID{ReplicaID: 2, InstanceNum: 1}
+	ki.update(pb.Key("a"), idA)
+	ki.update(pb.Key("b"), idB)
+	ki.update(pb.Key("c"), idC)
+
+	if l := ki.eviction.Len(); l != 2 {
+		t.Errorf("expected hot cache size 2, found %d", l)
+	}
+	if _, ok := ki.entries["a"]; ok {
+		t.Errorf("expected key \"a\" to be evicted from the hot cache")
+	}
+
+	// The evicted key is still served from the backend and re-cached.
+	if id, ok := ki.latest(pb.Key("a")); !ok || id != idA {
+		t.Errorf("expected instance %+v for key \"a\", found %+v (ok=%t)", idA, id, ok)
+	}
+	if _, ok := ki.entries["a"]; !ok {
+		t.Errorf("expected key \"a\" to be re-cached after backend lookup")
+	}
+}
+
+// TestKeyIndexUpdatedOnRequest verifies that proposing a command records it
+// in the dependency index.
+func TestKeyIndexUpdatedOnRequest(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0, 1, 2},
+	})
+
+	cmd := newTestingCommand("a", "")
+	inst := p.onRequest(cmd)
+
+	if id, ok := p.keyIndex.latest(pb.Key("a")); !ok || id != inst.is.InstanceID {
+		t.Errorf("expected instance %+v for key \"a\", found %+v (ok=%t)", inst.is.InstanceID, id, ok)
+	}
+	if _, ok := p.keyIndex.latest(pb.Key("z")); ok {
+		t.Errorf("expected no index entry for key \"z\"")
+	}
+}
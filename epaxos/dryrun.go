@@ -0,0 +1,130 @@
+package epaxos
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Apply-side dry-run validation.
+//
+// Upgrading state machine code is risky: a behavioral change that looks
+// safe in testing can diverge under live traffic, and by the time the
+// divergence is noticed the upgraded replica's state is already wrong.
+// The dry-run mode lets the new code shadow production first. A replica
+// started with Config.DryRun participates in the cluster as a learner —
+// it never leads proposals — while executing every committed command
+// through its configured apply function, which runs the upgrade
+// candidate. Both it and the production replicas fold their applied
+// commands into a running hash and, every Config.StateHashEvery applied
+// commands, mix in the application's own state digest and publish the
+// checkpoint through a StateHashEvent. The embedder relays production
+// checkpoints to the dry-run replica's VerifyStateHash, which compares
+// them against the shadow's checkpoints at the same applied count: a
+// mismatch means the new code disagrees with production on live traffic
+// and is reported through a DryRunDivergenceEvent before any promotion.
+
+// maxHashCheckpoints caps how many state hash checkpoints are retained
+// for comparison, bounding memory when one side runs ahead.
+const maxHashCheckpoints = 16
+
+// StateHashEvent is emitted every StateHashEvery applied commands,
+// carrying a checkpoint of the replica's applied state for cross-replica
+// comparison.
+type StateHashEvent struct {
+	// Applied is the number of commands applied up to the checkpoint.
+	Applied uint64
+	// Hash is the checkpoint: the running hash of the applied command
+	// digests, folded with the application's state digest if one is
+	// configured.
+	Hash []byte
+}
+
+func (StateHashEvent) event() {}
+
+// DryRunDivergenceEvent is emitted when VerifyStateHash finds that the
+// local checkpoint differs from a production replica's at the same
+// applied count. The shadowed state machine code disagrees with
+// production and must not be promoted.
+type DryRunDivergenceEvent struct {
+	// Applied is the applied count at which the checkpoints differ.
+	Applied uint64
+	// LocalHash is the local (shadow) checkpoint.
+	LocalHash []byte
+	// RemoteHash is the production checkpoint.
+	RemoteHash []byte
+}
+
+func (DryRunDivergenceEvent) event() {}
+
+// StateHashCheck is the outcome of comparing a production checkpoint
+// against the local one.
+type StateHashCheck int
+
+const (
+	// StateHashPending means the local replica has not yet reached the
+	// checkpoint's applied count; check again later.
+	StateHashPending StateHashCheck = iota
+	// StateHashMatch means the checkpoints agree.
+	StateHashMatch
+	// StateHashMismatch means the checkpoints differ.
+	StateHashMismatch
+)
+
+// foldAppliedCommand folds an applied command into the running state
+// hash, recording a checkpoint every stateHashEvery applied commands.
+func (p *epaxos) foldAppliedCommand(cmd pb.Command) {
+	if p.stateHashEvery == 0 {
+		return
+	}
+	p.appliedCount++
+	sum := sha256.Sum256(append(append([]byte(nil), p.runningHash...), cmd.Digest()...))
+	p.runningHash = sum[:]
+	if p.appliedCount%uint64(p.stateHashEvery) == 0 {
+		p.checkpointStateHash()
+	}
+}
+
+// checkpointStateHash records the current running hash, mixed with the
+// application's state digest if one is configured, and publishes it
+// through a StateHashEvent.
+func (p *epaxos) checkpointStateHash() {
+	cp := p.runningHash
+	if p.stateHashFn != nil {
+		sum := sha256.Sum256(append(append([]byte(nil), p.runningHash...), p.stateHashFn()...))
+		cp = sum[:]
+	}
+	p.hashCheckpoints[p.appliedCount] = cp
+	if len(p.hashCheckpoints) > maxHashCheckpoints {
+		oldest := p.appliedCount
+		for applied := range p.hashCheckpoints {
+			if applied < oldest {
+				oldest = applied
+			}
+		}
+		delete(p.hashCheckpoints, oldest)
+	}
+	p.emitEvent(StateHashEvent{Applied: p.appliedCount, Hash: cp})
+}
+
+// VerifyStateHash compares a production replica's published checkpoint
+// against the local checkpoint at the same applied count, reporting a
+// mismatch through a DryRunDivergenceEvent.
+func (p *epaxos) VerifyStateHash(applied uint64, hash []byte) StateHashCheck {
+	cp, ok := p.hashCheckpoints[applied]
+	if !ok {
+		return StateHashPending
+	}
+	if bytes.Equal(cp, hash) {
+		return StateHashMatch
+	}
+	p.logger.Errorf("state hash at %d applied commands diverges from production: local %x, remote %x",
+		applied, cp, hash)
+	p.emitEvent(DryRunDivergenceEvent{
+		Applied:    applied,
+		LocalHash:  cp,
+		RemoteHash: hash,
+	})
+	return StateHashMismatch
+}
@@ -16,8 +16,18 @@ import (
 type Config struct {
 	// ID is the identity of the local epaxos.
 	ID pb.ReplicaID
+	// ClusterID is the identity of the cluster that the local epaxos is a
+	// member of. It is persisted to storage and checked on restart so that
+	// a node cannot accidentally be started against the instance space of
+	// a different cluster.
+	ClusterID pb.ClusterID
 	// Nodes is the set of all nodes in the epaxos network.
 	Nodes []pb.ReplicaID
+	// Epoch is the configuration epoch the node starts at. It is needed
+	// only by nodes joining an existing cluster whose membership has
+	// changed, so that their ballots are not fenced as stale; established
+	// nodes restore their epoch from storage.
+	Epoch uint64
 	// Storage is the persistent storage for epaxos. epaxos reads out
 	// the previous instance state and configuration from storage when
 	// restarting.
@@ -25,9 +35,237 @@ type Config struct {
 	// Logger is the logger that the epaxos state machine will use
 	// to log events. If not set, a default logger will be used.
 	Logger Logger
+	// LogLevels, if set, gates each subsystem's log output below its
+	// configured minimum level. The registry is safe for concurrent use,
+	// so an operator can adjust verbosity at runtime (e.g. instance-level
+	// debug on one misbehaving replica) without restarting the node. If
+	// not set, a registry with every subsystem fully verbose is used.
+	LogLevels *SubsystemLevels
+	// IndexBackend is the backing store for the dependency index. If not
+	// set, an in-memory backend will be used.
+	IndexBackend IndexBackend
 	// RandSeed allows the seed used by epaxos's rand.Source to be
 	// injected, to allow for fully deterministic execution.
 	RandSeed int64
+	// Topology maps each replica to the failure domain (zone, region,
+	// rack) that it runs in. If set and the replicas span more than one
+	// domain, fast-path commits additionally require their backing
+	// replies to span at least two domains, so that no commit decision
+	// rests entirely on a single zone. If set, every replica in Nodes
+	// must be labeled, and all replicas must agree on the topology.
+	Topology map[pb.ReplicaID]string
+	// OnExecutedWatermark, if set, is invoked whenever the node's
+	// executed watermark advances.
+	OnExecutedWatermark WatermarkHandler
+	// OnEvent, if set, is invoked whenever the node emits an operator
+	// event, such as a peer being quarantined.
+	OnEvent EventHandler
+	// Apply, if set, is invoked with each executed command in order,
+	// instead of the command being delivered through the Ready struct.
+	Apply ApplyFunc
+	// ApplyWithResult plays the role of Apply but additionally returns
+	// each command's result, which the proposing replica routes to the
+	// handler registered for the command's ID (see
+	// Node.ProposeWithResult). At most one of Apply and ApplyWithResult
+	// may be set.
+	ApplyWithResult ApplyResultFunc
+	// ApplyErrorPolicy determines how the replica reacts when Apply
+	// returns an error. Defaults to ApplyErrorHalt.
+	ApplyErrorPolicy ApplyErrorPolicy
+	// Snapshotter, if set, produces and consumes snapshots of the
+	// application state machine, enabling a lagging or new replica to be
+	// caught up with SendSnapshot instead of replaying the entire
+	// instance log. Without it, incoming snapshots are dropped.
+	Snapshotter Snapshotter
+	// CheckPrecondition, if set, is evaluated for each conditional
+	// command immediately before it would be applied. If it returns
+	// false the command becomes a no-op and the command leader emits a
+	// PreconditionFailedEvent. It must be deterministic across replicas.
+	CheckPrecondition PreconditionFunc
+	// ExecutionOrder, if set, replaces the default SCC tie-breaking order
+	// (sequence number, then ReplicaID) with a custom comparator. It must
+	// be deterministic and identical on all replicas.
+	ExecutionOrder ExecutionOrder
+	// DepsOnlyOrdering, if set, drops sequence-number maintenance
+	// entirely and orders instances purely by dependency graph analysis:
+	// strongly connected components execute in reverse topological order,
+	// with instance IDs breaking the remaining ties within a component.
+	// All replicas must agree on this setting or they will diverge.
+	DepsOnlyOrdering bool
+	// FairExecution, if set, interleaves the execution of each strongly
+	// connected component round-robin across its proposing replicas,
+	// instead of letting one replica's burst of commands execute back to
+	// back and starve the visible effects of the others. The interleaving
+	// is deterministic, but all replicas must agree on this setting or
+	// they will diverge.
+	FairExecution bool
+	// ExecutionHooks, if set, wraps instance execution in a two-phase
+	// prepare/finalize protocol, allowing an external coordinator to
+	// build cross-cluster transactions spanning multiple epaxos groups.
+	ExecutionHooks ExecutionHooks
+	// SlowStorageThreshold, if set, is the storage write latency above
+	// which a SlowStorageEvent is emitted. Zero disables detection.
+	SlowStorageThreshold time.Duration
+	// ShedLoadOnSlowStorage, if set, rejects proposals while storage
+	// writes are persistently slower than SlowStorageThreshold, instead
+	// of letting writes back up until protocol timers fire spuriously.
+	ShedLoadOnSlowStorage bool
+	// ConservativeOnBareQuorum, if set, switches the node into a
+	// conservative mode whenever the set of healthy replicas drops to a
+	// bare quorum: slow-path commits are taken as soon as a quorum
+	// replies and in-flight proposals are retransmitted aggressively.
+	// Mode changes are surfaced through OnEvent.
+	ConservativeOnBareQuorum bool
+	// Thrifty, if set, sends PreAccept and Accept messages only to enough
+	// peers to form the quorum each phase requires, instead of to every
+	// peer, cutting message load by roughly N/2 per instance. If a
+	// phase's quorum does not materialize within a few ticks, its message
+	// is sent to the remaining peers as well. Equivalent to setting
+	// ThriftyPreAccept and ThriftyAccept.
+	Thrifty bool
+	// ThriftyPreAccept, ThriftyAccept and ThriftyCommit toggle thrifty
+	// sending per message type, since the types trade differently:
+	// PreAccept and Accept breadth affects only quorum latency, while
+	// Commit breadth affects how quickly followers learn of commits. A
+	// thrifty Commit reaches only a quorum of peers; the remaining peers
+	// learn of the commit through instance status queries or recovery.
+	ThriftyPreAccept bool
+	ThriftyAccept    bool
+	ThriftyCommit    bool
+	// OptimizedFastQuorum, if set, uses the optimized EPaxos fast quorum
+	// size of F+⌊(F+1)/2⌋ replies instead of the classic N-1, lowering
+	// the number of identical PreAccept replies needed for a fast-path
+	// commit. All replicas must agree on this setting or their commit
+	// decisions will be inconsistent.
+	OptimizedFastQuorum bool
+	// RelayMessages, if set, lets the node forward messages addressed to
+	// other replicas on toward their destinations. Combined with a
+	// transport that reroutes traffic for unreachable peers through a
+	// healthy one, this preserves availability under partial partitions
+	// where two replicas can both reach a third but not each other.
+	RelayMessages bool
+	// SlowPathTimeoutTicks, if non-zero, is the number of ticks a locally
+	// led proposal that has reached a plain quorum waits for a fast
+	// quorum before falling back to the slow path. Zero selects the
+	// default of 2 ticks; negative values are invalid. Individual
+	// proposals can override it through RequestWithSlowPathTimeout.
+	SlowPathTimeoutTicks int
+	// CommitTimeoutTicks, if non-zero, is the number of ticks a locally
+	// led proposal may remain uncommitted before the node emits a
+	// CommitStalledEvent naming the peers whose replies are outstanding.
+	CommitTimeoutTicks int
+	// StatusQueryTicks, if non-zero, is the number of ticks a replica
+	// that has pre-accepted or accepted an instance led elsewhere waits
+	// for its Commit before querying the command leader for the
+	// instance's status. A reply showing a committed instance is adopted
+	// directly, shortening stalls after transient leader hiccups without
+	// resorting to full recovery. Zero disables the queries.
+	StatusQueryTicks int
+	// MaxBallotAttempts, if non-zero, caps how many ballot rounds the
+	// node attempts for a single instance. An instance whose ballot is
+	// driven past the cap by repeated preemption is parked in a
+	// quarantined state and surfaced through an InstanceAbandonedEvent
+	// instead of being retried forever; contention that deep indicates a
+	// persistent partition or a bug. Zero disables the cap.
+	MaxBallotAttempts int
+	// WatermarkExchangeTicks, if non-zero, is the number of ticks between
+	// advertisements of the node's executed watermark to its peers.
+	// Instances below the cluster-wide minimum of the advertised
+	// watermarks have been executed everywhere and are garbage collected
+	// automatically, bounding instance space growth without explicit
+	// compaction commands. Zero disables the exchange.
+	WatermarkExchangeTicks int
+	// GCRetentionMargin is the number of instances per replica column to
+	// retain below the cluster-wide minimum watermark, keeping a window of
+	// recently executed instances available for debugging and straggler
+	// queries. Only meaningful when WatermarkExchangeTicks is set.
+	GCRetentionMargin int
+	// ExecutionBatchSize, if non-zero, caps how many committed instances
+	// are handed to the executor per tick. Instances committed beyond the
+	// cap carry over to later ticks, smoothing CPU usage so that a commit
+	// burst does not starve concurrent proposal handling on the same
+	// loop. Zero executes instances immediately as they commit.
+	ExecutionBatchSize int
+	// RecordCommitCertificates, if set, persists a commit certificate with
+	// each instance this node leads to commitment: the set of replicas
+	// whose replies contributed to the commit decision. Certificates are
+	// retrievable with CommitCertificate and are intended for audit trails
+	// and postmortems.
+	RecordCommitCertificates bool
+	// RepairScanTicks, if non-zero, runs a background consistency repair
+	// job every RepairScanTicks ticks. Each scan samples a few executed
+	// instances and cross-checks them against a random peer: a peer found
+	// missing a commit is re-sent it, while a peer whose committed state
+	// differs from the locally executed state is reported through a
+	// RepairDivergenceEvent for operator attention. Zero disables the
+	// job.
+	RepairScanTicks int
+	// RepairSampleSize caps how many executed instances each repair scan
+	// samples. Zero uses a small default.
+	RepairSampleSize int
+	// ProposalBatchSize, if non-zero, enables proposal batching: plain
+	// commands proposed within a tick are folded into a single envelope
+	// instance, cutting per-command consensus overhead at high load. The
+	// batch flushes at the next tick, or immediately once it holds
+	// ProposalBatchSize commands. Membership changes, compactions, and
+	// conditional or speculative proposals bypass batching.
+	ProposalBatchSize int
+	// ScheduleLogSize, if non-zero, retains the last ScheduleLogSize
+	// executed instances in an execution schedule log, recording for
+	// each its position in the total execution order, its strongly
+	// connected component, and the tie-break rule that positioned it.
+	// Ranges of the log are exportable with ExportSchedule for
+	// compliance audits (see schedule.go). Zero disables the log.
+	ScheduleLogSize int
+	// ExecutedLogSize, if non-zero, retains the last ExecutedLogSize
+	// executed commands in a linearized log, assigning each a
+	// contiguous, monotonically increasing index in execution order.
+	// Suffixes of the log are exportable with ExportEntries for tooling
+	// built around linear logs (see export.go). Zero disables the log.
+	ExecutedLogSize int
+	// ScheduleSigningKey, if set, signs each schedule export with
+	// HMAC-SHA256 so that it can be authenticated offline with
+	// VerifyScheduleExport.
+	ScheduleSigningKey []byte
+	// StateHashEvery, if non-zero, folds each applied command into a
+	// running hash and records a checkpoint every StateHashEvery applied
+	// commands, published through a StateHashEvent. Checkpoints let a
+	// dry-run replica validate shadowed state machine code against
+	// production (see dryrun.go). Zero disables checkpointing.
+	StateHashEvery int
+	// StateHash, if set, digests the application state machine and is
+	// mixed into each state hash checkpoint, extending the comparison
+	// from command ordering to applied state.
+	StateHash func() []byte
+	// DryRun runs the replica as a learner for upgrade validation: it
+	// applies committed commands through the configured apply function
+	// but never leads proposals. Production checkpoints fed to
+	// VerifyStateHash are compared against the shadow's.
+	DryRun bool
+	// PanicFree routes protocol violations — internal invariant failures
+	// that would otherwise panic through a subsystem logger — into a
+	// failed-replica state instead of crashing the process: the replica
+	// halts, emits a ReplicaFailedEvent, and Node.Step returns
+	// ErrReplicaFailed. Intended for applications that colocate epaxos
+	// with other services in one process (see failure.go). Panics that
+	// guard construction-time misuse or storage corruption are
+	// unaffected.
+	PanicFree bool
+	// ReadLeaseTicks, if non-zero, enables quorum read leases: a replica
+	// may request a lease on a key range with RequestReadLease and, once
+	// a quorum of the cluster has granted it, serve reads of the range
+	// from local state without running consensus. Lease reads are
+	// bounded-stale, not linearizable (see leases.go). Leases lapse
+	// after ReadLeaseTicks ticks and are invalidated early by
+	// interfering writes. Zero disables leasing.
+	ReadLeaseTicks int
+	// LatencySampleEvery, if non-zero, samples every Nth local proposal
+	// for latency attribution. A sampled proposal records how long each
+	// phase of its lifetime took and emits the breakdown as a
+	// ProposalLatencyEvent when the proposal executes, attributing tail
+	// latency to dependency computation, quorum waits, the slow path, or
+	// execution ordering. Zero disables sampling.
+	LatencySampleEvery int
 }
 
 func (c *Config) validate() error {
@@ -40,16 +278,82 @@ func (c *Config) validate() error {
 		if hs.ReplicaID != c.ID {
 			return errors.Errorf("ID different than in HardState")
 		}
+		if hs.ClusterID != c.ClusterID {
+			return errors.Errorf("ClusterID different than in HardState")
+		}
 		if !reflect.DeepEqual(hs.Nodes, c.Nodes) {
-			return errors.Errorf("Node set different than in HardState")
+			// The membership changed while the node was running (see
+			// ProposeConfChange); the persisted node set is authoritative.
+			c.Nodes = hs.Nodes
+		}
+	}
+	if len(c.Topology) > 0 {
+		for _, n := range c.Nodes {
+			if _, ok := c.Topology[n]; !ok {
+				return errors.Errorf("replica %d missing from Topology", n)
+			}
 		}
 	}
 	if c.Logger == nil {
 		c.Logger = NewDefaultLogger()
 	}
+	if c.LogLevels == nil {
+		c.LogLevels = NewSubsystemLevels()
+	}
+	if c.IndexBackend == nil {
+		c.IndexBackend = NewMemoryIndexBackend()
+	}
 	if c.RandSeed == 0 {
 		c.RandSeed = time.Now().UnixNano()
 	}
+	if c.SlowPathTimeoutTicks < 0 {
+		return errors.Errorf("SlowPathTimeoutTicks must not be negative")
+	}
+	if c.SlowPathTimeoutTicks == 0 {
+		c.SlowPathTimeoutTicks = slowPathTimout
+	}
+	if c.StatusQueryTicks < 0 {
+		return errors.Errorf("StatusQueryTicks must not be negative")
+	}
+	if c.Apply != nil && c.ApplyWithResult != nil {
+		return errors.Errorf("at most one of Apply and ApplyWithResult may be set")
+	}
+	if c.LatencySampleEvery < 0 {
+		return errors.Errorf("LatencySampleEvery must not be negative")
+	}
+	if c.ProposalBatchSize < 0 {
+		return errors.Errorf("ProposalBatchSize must not be negative")
+	}
+	if c.ScheduleLogSize < 0 {
+		return errors.Errorf("ScheduleLogSize must not be negative")
+	}
+	if c.ExecutedLogSize < 0 {
+		return errors.Errorf("ExecutedLogSize must not be negative")
+	}
+	if c.StateHashEvery < 0 {
+		return errors.Errorf("StateHashEvery must not be negative")
+	}
+	if c.ReadLeaseTicks < 0 {
+		return errors.Errorf("ReadLeaseTicks must not be negative")
+	}
+	if c.RepairScanTicks < 0 {
+		return errors.Errorf("RepairScanTicks must not be negative")
+	}
+	if c.RepairSampleSize < 0 {
+		return errors.Errorf("RepairSampleSize must not be negative")
+	}
+	if c.RepairSampleSize == 0 {
+		c.RepairSampleSize = defaultRepairSampleSize
+	}
+	if c.MaxBallotAttempts < 0 {
+		return errors.Errorf("MaxBallotAttempts must not be negative")
+	}
+	if c.WatermarkExchangeTicks < 0 {
+		return errors.Errorf("WatermarkExchangeTicks must not be negative")
+	}
+	if c.GCRetentionMargin < 0 {
+		return errors.Errorf("GCRetentionMargin must not be negative")
+	}
 	return nil
 }
 
@@ -61,28 +365,290 @@ type epaxos struct {
 	id pb.ReplicaID
 	// nodes is the set of all nodes in the EPaxos network.
 	nodes []pb.ReplicaID
+	// fastQuorumSize is the number of identical PreAccept replies,
+	// including the leader's own, required for a fast-path commit.
+	fastQuorumSize int
+	// optimizedFastQuorum mirrors Config.OptimizedFastQuorum so that the
+	// fast-quorum size can be recomputed when the membership changes.
+	optimizedFastQuorum bool
+	// epoch counts the membership changes that have been applied. Ballots
+	// carry the epoch they were proposed under, so that messages from
+	// older configurations can be fenced out.
+	epoch uint64
+	// topology maps each replica to its failure domain, if configured.
+	topology map[pb.ReplicaID]string
 	// storage is a handle to the node's persistent storage.
 	storage Storage
+	// propStorage, if the node's storage supports it, persists proposals
+	// that have not yet entered the consensus pipeline.
+	propStorage ProposalStorage
+	// counterStorage, if the node's storage supports it, persists the
+	// node's cumulative protocol counters across restarts.
+	counterStorage CounterStorage
+	// counters holds the node's cumulative protocol counters.
+	counters Counters
 
 	// commands is a map from replica to an ordered tree of instance, indexed by
 	// sequence number. BTree contains *instance elements.
 	commands map[pb.ReplicaID]*btree.BTree
-	// TODO reintroduce instance space truncation.
-	// maxTruncatedInstanceNum is a mapping from replica to the maximum instance
-	// number that has been truncated up to in its command space.
-	// maxTruncatedInstanceNum map[pb.ReplicaID]pb.InstanceNum
-	// maxTruncatedSeqNum is the maximum sequence number that has been truncated.
-	// maxTruncatedSeqNum pb.SeqNum
+	// depIndex mirrors each command space in an interval tree over
+	// command key ranges, so that dependency lookup only visits the
+	// instances whose ranges overlap a proposal.
+	depIndex map[pb.ReplicaID]*depSpaceIndex
+	// maxTruncated is a mapping from replica to the maximum instance
+	// number that has been truncated from its command space by an agreed
+	// compaction command.
+	maxTruncated map[pb.ReplicaID]pb.InstanceNum
+	// agreedCompaction holds cluster-agreed truncation points that the
+	// local replica has not yet fully applied, awaiting local execution.
+	agreedCompaction map[pb.ReplicaID]pb.InstanceNum
 	// rangeGroup is used to minimize dependency lists by tracking transitive
 	// dependencies.
 	rangeGroup interval.RangeGroup
+	// keyIndex tracks the latest known instance to touch each key, backed
+	// by a pluggable IndexBackend with an in-memory hot cache.
+	keyIndex keyIndex
+	// cmdDigests maps command digests to known commands, allowing
+	// messages that reference a command by digest to be resolved.
+	cmdDigests map[string]*pb.Command
+	// heat accumulates per-span interference heat, feeding RebalanceHints.
+	heat map[string]*keyHeat
+	// executedPrefix tracks, for each replica, the largest instance
+	// number through which all instances have been executed locally.
+	executedPrefix map[pb.ReplicaID]pb.InstanceNum
+	// committed tracks, for each replica, the set of instance numbers
+	// known to be committed locally, as compact inclusive ranges. See
+	// committed.go.
+	committed map[pb.ReplicaID]instanceIntervals
+	// onExecutedWatermark, if set, is invoked whenever executedPrefix
+	// advances.
+	onExecutedWatermark WatermarkHandler
+	// compactionHold, if set, clamps how far compaction may proceed,
+	// regardless of the executed watermark.
+	compactionHold map[pb.ReplicaID]pb.InstanceNum
+	// watermarkExchangeTicks is the number of ticks between executed
+	// watermark advertisements to peers. Zero disables the exchange and
+	// the garbage collection that it feeds.
+	watermarkExchangeTicks int
+	// gcRetentionMargin is the number of instances per replica column
+	// retained below the cluster-wide minimum watermark.
+	gcRetentionMargin pb.InstanceNum
+	// peerWatermarks holds the most recent executed watermark advertised
+	// by each peer.
+	peerWatermarks map[pb.ReplicaID]map[pb.ReplicaID]pb.InstanceNum
+	// snapshotter, if set, produces and consumes application state
+	// machine snapshots.
+	snapshotter Snapshotter
+	// snapIncoming holds the partially received snapshot from each peer.
+	snapIncoming map[pb.ReplicaID]*incomingSnapshot
+	// repairScanTicks runs the consistency repair job every that many
+	// ticks. Zero disables the job.
+	repairScanTicks int
+	// repairSampleSize caps how many executed instances each repair scan
+	// samples.
+	repairSampleSize int
+	// repairTimer drives the periodic consistency repair scans.
+	repairTimer tickingTimer
+	// repairProbes holds the repair scan's outstanding cross-checks: for
+	// each probed instance, the peer whose view was requested.
+	repairProbes map[pb.InstanceID]pb.ReplicaID
+	// scheduleLogSize caps the retained execution schedule log. Zero
+	// disables the log.
+	scheduleLogSize int
+	// scheduleKey, if set, signs schedule exports.
+	scheduleKey []byte
+	// scheduleLog holds the most recent executed instances in execution
+	// order.
+	scheduleLog []scheduleEntry
+	// schedulePos counts executed instances, positioning schedule
+	// entries in the total execution order.
+	schedulePos uint64
+	// scheduleSCC counts executed strongly connected components,
+	// grouping schedule entries.
+	scheduleSCC uint64
+	// stateHashEvery is the number of applied commands between state
+	// hash checkpoints. Zero disables checkpointing.
+	stateHashEvery int
+	// stateHashFn, if set, digests the application state machine for
+	// inclusion in state hash checkpoints.
+	stateHashFn func() []byte
+	// dryRun marks the replica as an upgrade-validation learner that
+	// never leads proposals.
+	dryRun bool
+	// appliedCount counts the commands applied through the apply
+	// function, positioning state hash checkpoints.
+	appliedCount uint64
+	// runningHash is the rolling hash of applied command digests.
+	runningHash []byte
+	// hashCheckpoints holds the retained state hash checkpoints by
+	// applied count.
+	hashCheckpoints map[uint64][]byte
+	// readLeaseTicks is the lifetime of quorum read leases, in ticks.
+	// Zero disables leasing.
+	readLeaseTicks int
+	// heldLeases holds the read leases this replica has requested,
+	// active or still collecting grants.
+	heldLeases []*heldLease
+	// grantedLeases records the read leases this replica has granted to
+	// peers and must invalidate when interfering writes commit.
+	grantedLeases []*grantedLease
+	// gcTimer periodically advertises the executed watermark and garbage
+	// collects instances below the cluster-wide minimum.
+	gcTimer tickingTimer
+	// pendingCmds maps command IDs to the local instance currently
+	// proposing them, so that duplicate proposals of the same command can
+	// be detected.
+	pendingCmds map[uint64]*instance
+	// onEvent, if set, is invoked whenever the node emits an Event.
+	onEvent EventHandler
+	// apply, if set, receives each executed command in order.
+	apply ApplyFunc
+	// applyResult, if set, receives each executed command in order and
+	// returns its result. Mutually exclusive with apply.
+	applyResult ApplyResultFunc
+	// resultHandlers maps command IDs to the handlers awaiting their
+	// results, registered at proposal time.
+	resultHandlers map[uint64]ResultFunc
+	// applyErrPolicy determines the reaction to an apply error.
+	applyErrPolicy ApplyErrorPolicy
+	// applyRetry holds retry state under ApplyErrorRetry.
+	applyRetry applyRetryState
+	// checkPrecondition, if set, gates conditional commands at
+	// execution time.
+	checkPrecondition PreconditionFunc
+	// halted is set when the replica has stopped after an apply error.
+	halted bool
+	// panicFree routes protocol violations into the failed state instead
+	// of panicking (see Config.PanicFree).
+	panicFree bool
+	// failed is set when a protocol violation has marked the replica as
+	// failed under panicFree. A failed replica is also halted.
+	failed bool
+	// slowStorage tracks storage write latency against the configured
+	// threshold.
+	slowStorage slowStorageState
+	// execOrder, if set, replaces the default SCC tie-breaking order.
+	execOrder ExecutionOrder
+	// depsOnly drops sequence-number maintenance and orders instances
+	// purely by the dependency graph.
+	depsOnly bool
+	// execHooks, if set, wraps execution in a two-phase prepare/finalize
+	// protocol for cross-cluster coordination.
+	execHooks ExecutionHooks
+	// execHold holds the command currently parked by the execution hooks'
+	// prepare phase, along with the commands queued behind it.
+	execHold executionHoldState
+	// execBatchSize, if non-zero, caps how many committed instances the
+	// executor ingests per tick.
+	execBatchSize int
+	// execBacklog holds committed instances awaiting executor ingestion
+	// under the execBatchSize cap.
+	execBacklog []*instance
+	// recordCommitCerts enables commit-certificate recording.
+	recordCommitCerts bool
+	// propBatchSize enables proposal batching and caps how many commands
+	// a batch may hold before it flushes early. Zero disables batching.
+	propBatchSize int
+	// propBatch holds the commands buffered for the next batching
+	// envelope.
+	propBatch []*pb.Command
+	// latencySampleEvery samples every Nth local proposal for latency
+	// attribution. Zero disables sampling.
+	latencySampleEvery int
+	// latencySampleCount counts local proposals considered for latency
+	// sampling.
+	latencySampleCount uint64
+	// relayMessages enables forwarding of messages addressed to other
+	// replicas, for routing around partial partitions.
+	relayMessages bool
+	// commitTimeout is the number of ticks a locally led proposal may
+	// remain uncommitted before a CommitStalledEvent is emitted. Zero
+	// disables the timeout.
+	commitTimeout int
+	// statusQueryTicks is the number of ticks a promise for an instance
+	// led elsewhere may remain uncommitted before the leader is queried
+	// for its status. Zero disables the queries.
+	statusQueryTicks int
+	// slowPathTimeout is the number of ticks a proposal that has reached
+	// a plain quorum waits for a fast quorum before falling back to the
+	// slow path.
+	slowPathTimeout int
+	// proposalSlowPathTicks, if non-zero, overrides slowPathTimeout for
+	// the proposal currently being requested.
+	proposalSlowPathTicks int
+	// maxBallotAttempts caps how many ballot rounds are attempted for a
+	// single instance before it is abandoned. Zero disables the cap.
+	maxBallotAttempts int
+	// stepFrom is the sender of the message currently being stepped,
+	// allowing message handlers to attribute replies to their senders.
+	stepFrom pb.ReplicaID
+	// stepBallot is the ballot of the message currently being stepped,
+	// allowing message handlers to reject stale ballots.
+	stepBallot pb.Ballot
+	// conservativeEnabled enables conservative mode on bare quorums.
+	conservativeEnabled bool
+	// thriftyPreAccept, thriftyAccept and thriftyCommit send the
+	// corresponding phase message only to quorum-sized peer subsets.
+	thriftyPreAccept bool
+	thriftyAccept    bool
+	thriftyCommit    bool
+	// conservative is whether the node is currently in conservative mode.
+	conservative bool
+	// ticks counts calls to Tick, for peer liveness tracking.
+	ticks uint64
+	// lastHeard maps each peer to the tick at which it was last heard
+	// from.
+	lastHeard map[pb.ReplicaID]uint64
+	// dataFreshness maps each peer to the last tick at which this
+	// replica's executed state covered everything the peer had
+	// advertised as committed, for stale-read staleness accounting (see
+	// staleread.go).
+	dataFreshness map[pb.ReplicaID]uint64
+	// retransmitTimer periodically retransmits in-flight proposals while
+	// in conservative mode.
+	retransmitTimer tickingTimer
+	// violations counts protocol violations observed from each peer.
+	violations map[pb.ReplicaID]int
+	// quarantined tracks peers whose messages are currently being rejected
+	// because of repeated protocol violations, along with the timers that
+	// will lift their quarantines.
+	quarantined map[pb.ReplicaID]*tickingTimer
 
 	// executor holds execution state and handles the execution of committed
 	// instances.
 	executor executor
-	// timers holds all current timers, which are each incremented on every call
-	// to Tick.
+	// noopProbes holds the outstanding no-op probes of instances that
+	// hold no command locally: for each probed instance, the replicas
+	// that reported no command for it, counting this replica (see
+	// startNoopProbe).
+	noopProbes map[pb.InstanceID]map[pb.ReplicaID]struct{}
+	// recovery throttles instance recoveries so that a replica failure
+	// does not trigger a storm of concurrent Prepare traffic.
+	recovery recoveryScheduler
+	// warmup throttles proposals after a restart while the replica
+	// catches up on instances it missed.
+	warmup warmupState
+	// replay holds the state of an in-progress bulk replay.
+	replay bulkReplayState
+	// metrics holds distributions describing the shape of the execution
+	// graph.
+	metrics metrics
+	// keyConflicts tracks, per proposed key span, how often local
+	// proposals started out with interference against them, feeding the
+	// hot-spot splitting guidance (see hotspot.go). Allocated lazily on
+	// the first local proposal.
+	keyConflicts map[spanKey]*spanStats
+	// statusVersion counts Status snapshots taken from the node, stamping
+	// each one so that snapshot pairs can be diffed in order.
+	statusVersion uint64
+	// timers holds all currently registered timers. Scheduling lives in
+	// timerWheel; this set answers membership, so that stale wheel
+	// entries for unregistered timers can be recognized and skipped.
 	timers map[*tickingTimer]struct{}
+	// timerWheel buckets registered timers by the tick at which they
+	// expire, so that a tick visits only the timers expiring on it
+	// instead of every registered timer.
+	timerWheel map[uint64][]*tickingTimer
 
 	// msgs is the outbox for the paxos node, containing all messages that need
 	// to be delivered.
@@ -94,9 +660,30 @@ type epaxos struct {
 	// executedCmds is the outbox for commands that are ready to be executed,
 	// in-order.
 	executedCmds []pb.Command
+	// readyHardState mirrors the most recent HardState written to storage,
+	// staged for the next Ready so that embedders can observe durable state
+	// changes without wrapping the Storage interface. Nil if the hard state
+	// has not changed since the last Ready.
+	readyHardState *pb.HardState
+	// executedLog retains the last executedLogSize executed instances in
+	// a linearized, index-ordered log, used to export EPaxos output to
+	// linear-log tooling. Empty when the log is disabled.
+	executedLog []LogEntry
+	// executedLogSize caps the retained executed log. Zero disables it.
+	executedLogSize int
+	// executedLogIndex is the index assigned to the most recent executed
+	// log entry; it keeps advancing as trimmed entries are dropped.
+	executedLogIndex uint64
 
 	// logger is used by paxos to log event.
 	logger Logger
+	// Per-subsystem loggers gate their output through the configured
+	// SubsystemLevels, so each subsystem's verbosity can be adjusted at
+	// runtime independently.
+	instLogger     Logger
+	execLogger     Logger
+	storageLogger  Logger
+	recoveryLogger Logger
 	// rand holds the paxos instance's local Rand object. This allows us to avoid
 	// using the synchronized global Rand object.
 	rand *rand.Rand
@@ -107,17 +694,90 @@ func newEPaxos(c *Config) *epaxos {
 		panic(err.Error())
 	}
 	p := &epaxos{
-		id:         c.ID,
-		nodes:      c.Nodes,
-		logger:     c.Logger,
-		commands:   make(map[pb.ReplicaID]*btree.BTree, len(c.Nodes)),
-		rangeGroup: interval.NewRangeTree(),
-		timers:     make(map[*tickingTimer]struct{}),
-		rand:       rand.New(rand.NewSource(c.RandSeed)),
+		id:                     c.ID,
+		nodes:                  c.Nodes,
+		optimizedFastQuorum:    c.OptimizedFastQuorum,
+		topology:               c.Topology,
+		logger:                 c.Logger,
+		instLogger:             c.LogLevels.Logger(LogSubsystemInstance, c.Logger),
+		execLogger:             c.LogLevels.Logger(LogSubsystemExecutor, c.Logger),
+		storageLogger:          c.LogLevels.Logger(LogSubsystemStorage, c.Logger),
+		recoveryLogger:         c.LogLevels.Logger(LogSubsystemRecovery, c.Logger),
+		commands:               make(map[pb.ReplicaID]*btree.BTree, len(c.Nodes)),
+		depIndex:               make(map[pb.ReplicaID]*depSpaceIndex, len(c.Nodes)),
+		rangeGroup:             interval.NewRangeTree(),
+		keyIndex:               makeKeyIndex(c.IndexBackend, defaultHotCacheSize),
+		cmdDigests:             make(map[string]*pb.Command),
+		heat:                   make(map[string]*keyHeat),
+		executedPrefix:         make(map[pb.ReplicaID]pb.InstanceNum, len(c.Nodes)),
+		committed:              make(map[pb.ReplicaID]instanceIntervals, len(c.Nodes)),
+		onExecutedWatermark:    c.OnExecutedWatermark,
+		pendingCmds:            make(map[uint64]*instance),
+		onEvent:                c.OnEvent,
+		apply:                  c.Apply,
+		applyResult:            c.ApplyWithResult,
+		applyErrPolicy:         c.ApplyErrorPolicy,
+		snapshotter:            c.Snapshotter,
+		checkPrecondition:      c.CheckPrecondition,
+		execOrder:              c.ExecutionOrder,
+		depsOnly:               c.DepsOnlyOrdering,
+		execHooks:              c.ExecutionHooks,
+		execBatchSize:          c.ExecutionBatchSize,
+		recordCommitCerts:      c.RecordCommitCertificates,
+		relayMessages:          c.RelayMessages,
+		commitTimeout:          c.CommitTimeoutTicks,
+		statusQueryTicks:       c.StatusQueryTicks,
+		slowPathTimeout:        c.SlowPathTimeoutTicks,
+		maxBallotAttempts:      c.MaxBallotAttempts,
+		watermarkExchangeTicks: c.WatermarkExchangeTicks,
+		gcRetentionMargin:      pb.InstanceNum(c.GCRetentionMargin),
+		conservativeEnabled:    c.ConservativeOnBareQuorum,
+		thriftyPreAccept:       c.Thrifty || c.ThriftyPreAccept,
+		thriftyAccept:          c.Thrifty || c.ThriftyAccept,
+		thriftyCommit:          c.ThriftyCommit,
+		latencySampleEvery:     c.LatencySampleEvery,
+		propBatchSize:          c.ProposalBatchSize,
+		repairScanTicks:        c.RepairScanTicks,
+		repairSampleSize:       c.RepairSampleSize,
+		repairProbes:           make(map[pb.InstanceID]pb.ReplicaID),
+		noopProbes:             make(map[pb.InstanceID]map[pb.ReplicaID]struct{}),
+		readLeaseTicks:         c.ReadLeaseTicks,
+		scheduleLogSize:        c.ScheduleLogSize,
+		executedLogSize:        c.ExecutedLogSize,
+		scheduleKey:            c.ScheduleSigningKey,
+		stateHashEvery:         c.StateHashEvery,
+		stateHashFn:            c.StateHash,
+		dryRun:                 c.DryRun,
+		panicFree:              c.PanicFree,
+		hashCheckpoints:        make(map[uint64][]byte),
+		slowStorage: slowStorageState{
+			threshold:   c.SlowStorageThreshold,
+			shedEnabled: c.ShedLoadOnSlowStorage,
+		},
+		lastHeard:     make(map[pb.ReplicaID]uint64, len(c.Nodes)),
+		dataFreshness: make(map[pb.ReplicaID]uint64, len(c.Nodes)),
+		violations:    make(map[pb.ReplicaID]int),
+		quarantined:   make(map[pb.ReplicaID]*tickingTimer),
+		timers:        make(map[*tickingTimer]struct{}),
+		timerWheel:    make(map[uint64][]*tickingTimer),
+		rand:          rand.New(rand.NewSource(c.RandSeed)),
 	}
+	p.refreshQuorumSizes()
 	p.executor = makeExecutor(p)
+	p.recovery = makeRecoveryScheduler(p)
+	p.executor.onExecutedSCC = p.metrics.sccSize.record
+	p.executor.onSCCFairness = func(origins, dominant int) {
+		p.metrics.originsPerSCC.record(origins)
+		p.metrics.dominantOriginPerSCC.record(dominant)
+	}
+	p.executor.fairInterleave = c.FairExecution
+	p.executor.onCorruption = p.onExecutionCorruption
+	if p.scheduleLogSize > 0 {
+		p.executor.onSchedule = p.recordExecutionSchedule
+	}
 	for _, rep := range c.Nodes {
 		p.commands[rep] = btree.New(32 /* degree */)
+		p.depIndex[rep] = newDepSpaceIndex()
 	}
 	p.initStorage(c)
 	p.initTimers()
@@ -127,23 +787,98 @@ func newEPaxos(c *Config) *epaxos {
 func (p *epaxos) initStorage(c *Config) {
 	s := c.Storage
 	p.storage = s
+	p.propStorage, _ = s.(ProposalStorage)
+	p.counterStorage, _ = s.(CounterStorage)
+	if p.counterStorage != nil {
+		if c, ok := p.counterStorage.Counters(); ok {
+			p.counters = c
+		}
+	}
 
 	// Set up the node's HardState.
-	if _, ok := s.HardState(); !ok {
-		s.PersistHardState(pb.HardState{
+	hs, restarted := s.HardState()
+	if !restarted {
+		hs = pb.HardState{
 			ReplicaID: c.ID,
+			ClusterID: c.ClusterID,
 			Nodes:     c.Nodes,
-		})
+			Epoch:     c.Epoch,
+		}
+		s.PersistHardState(hs)
+	}
+
+	// Restore the configuration epoch so that new ballots continue above
+	// any membership change applied before the restart.
+	p.epoch = hs.Epoch
+
+	// Restore truncation points before loading instances, so that stale
+	// records below a truncation point are not resurrected.
+	for _, t := range hs.Truncated {
+		if p.maxTruncated == nil {
+			p.maxTruncated = make(map[pb.ReplicaID]pb.InstanceNum)
+		}
+		p.maxTruncated[t.ReplicaID] = t.InstanceNum
 	}
 
 	// Load all persisted instances.
 	insts := s.Instances()
 	for _, is := range insts {
+		if p.hasTruncated(is.ReplicaID, is.InstanceNum) {
+			// The record outlived its truncation.
+			continue
+		}
 		inst := p.newInstanceFromState(is)
-		p.commands[is.ReplicaID].ReplaceOrInsert(inst)
+		if !p.insertInstance(inst) {
+			continue
+		}
+		p.indexCommand(inst)
+		if is.Status >= pb.InstanceState_Committed {
+			p.recordCommitted(is.ReplicaID, is.InstanceNum)
+		}
 		cmdLeader := is.ReplicaID == p.id
 		if cmdLeader && !inst.isStates(pb.InstanceState_Executed) {
+			if cmd := is.Command; cmd != nil && cmd.ID != 0 {
+				p.pendingCmds[cmd.ID] = inst
+			}
+			if !inst.isStates(pb.InstanceState_Committed) {
+				p.noteProposalInFlight()
+			}
 			inst.restartTransition()
+		} else if !cmdLeader && inst.isStates(pb.InstanceState_Committed) {
+			// The instance committed but had not executed before the
+			// crash; resume its execution.
+			inst.prepareToExecute()
+		}
+	}
+
+	// Throttle proposals while the restarted replica catches up on
+	// instances it missed while down.
+	if restarted {
+		p.startWarmup()
+	}
+
+	// Recompute the executed watermark from the loaded instances.
+	for _, rep := range c.Nodes {
+		next := p.executedPrefix[rep]
+		for p.hasExecuted(rep, next+1) {
+			next++
+		}
+		p.executedPrefix[rep] = next
+	}
+
+	// Re-propose commands that were accepted before the crash but never
+	// made it into the consensus pipeline.
+	if p.propStorage != nil {
+		for _, cmd := range p.propStorage.PendingProposals() {
+			if p.pendingCommand(cmd.ID) != nil {
+				// The command's instance was persisted; the proposal
+				// record just outlived its discard.
+				p.propStorage.DiscardPendingProposal(cmd.ID)
+				continue
+			}
+			cmd := cmd
+			p.logger.Infof("re-proposing command %d recovered from proposal storage", cmd.ID)
+			p.onRequest(&cmd)
 		}
 	}
 }
@@ -158,19 +893,108 @@ func (p *epaxos) initTimers() {
 	// 	p.executor.run()
 	// })
 	// p.registerInfiniteTimer(&executorTimer)
+
+	// The gcTimer periodically advertises the executed watermark to peers
+	// and garbage collects instances executed cluster-wide.
+	if p.watermarkExchangeTicks > 0 {
+		p.gcTimer = makeTickingTimer(p.watermarkExchangeTicks, func() {
+			p.broadcastExecutedWatermark()
+			p.broadcastCommittedRanges()
+			p.maybeGC()
+		})
+		p.registerInfiniteTimer(&p.gcTimer)
+	}
+
+	// The repairTimer periodically samples executed instances and
+	// cross-checks them against peers (see repair.go).
+	if p.repairScanTicks > 0 {
+		p.repairTimer = makeTickingTimer(p.repairScanTicks, func() {
+			p.runRepairScan()
+		})
+		p.registerInfiniteTimer(&p.repairTimer)
+	}
 }
 
 func (p *epaxos) Tick() {
-	for t := range p.timers {
-		t.tick()
+	p.ticks++
+	p.flushProposalBatch()
+	p.expireTimers()
+	p.ingestExecBacklog()
+	p.maybeToggleConservative()
+}
+
+// expireTimers fires the timers due on the current tick. Only the
+// expiring bucket of the timer wheel is visited, so a tick costs
+// O(timers expiring now) rather than O(timers registered).
+func (p *epaxos) expireTimers() {
+	bucket, ok := p.timerWheel[p.ticks]
+	if !ok {
+		return
+	}
+	delete(p.timerWheel, p.ticks)
+	for _, t := range bucket {
+		if _, ok := p.timers[t]; !ok {
+			// Unregistered since it was scheduled.
+			continue
+		}
+		if t.paused || t.deadline != p.ticks {
+			// Stopped, already fired, or rescheduled into a later bucket.
+			continue
+		}
+		t.expire()
 	}
 }
 
+// scheduleTimer places the timer in the wheel bucket for its deadline.
+// Rescheduling leaves any previous bucket entry in place; the entry is
+// recognized as stale by its deadline and skipped when its bucket
+// expires.
+func (p *epaxos) scheduleTimer(t *tickingTimer) {
+	rem := t.remaining()
+	if rem < 1 {
+		// A timer armed with no ticks remaining fires on the next tick,
+		// exactly as the per-tick countdown did.
+		rem = 1
+	}
+	t.deadline = p.ticks + uint64(rem)
+	p.timerWheel[t.deadline] = append(p.timerWheel[t.deadline], t)
+}
+
+// TickN advances the logical clock by n ticks, as if Tick had been
+// called n times. Non-positive counts — which coarse timers and external
+// schedulers can produce on clock regressions — are ignored, and jumps
+// larger than maxTickCatchUp are clamped with the excess dropped (see
+// noteTimeJump), so irregular tick delivery cannot corrupt timer
+// bookkeeping or fire a burst of timeouts that only appear to have
+// expired.
+func (p *epaxos) TickN(n int) {
+	if n <= 0 {
+		return
+	}
+	run, skipped := clampTicks(n)
+	if skipped > 0 {
+		p.noteTimeJump(skipped)
+	}
+	for i := 0; i < run; i++ {
+		p.Tick()
+	}
+}
+
+// noteTimeJump records that the clock jumped: missed ticks were dropped
+// instead of being replayed through the timers.
+func (p *epaxos) noteTimeJump(skipped int) {
+	p.logger.Warningf("time jumped; dropping %d missed ticks", skipped)
+	p.emitEvent(TimeJumpedEvent{SkippedTicks: skipped})
+}
+
 func (p *epaxos) registerInfiniteTimer(t *tickingTimer) {
 	p.timers[t] = struct{}{}
 	t.instrument(func() {
 		t.reset()
+		p.scheduleTimer(t)
 	})
+	t.reset()
+	p.scheduleTimer(t)
 }
 
 func (p *epaxos) registerOneTimeTimer(t *tickingTimer) {
@@ -179,6 +1003,7 @@ func (p *epaxos) registerOneTimeTimer(t *tickingTimer) {
 		p.unregisterTimer(t)
 	})
 	t.reset()
+	p.scheduleTimer(t)
 }
 
 func (p *epaxos) unregisterTimer(t *tickingTimer) {
@@ -187,12 +1012,181 @@ func (p *epaxos) unregisterTimer(t *tickingTimer) {
 }
 
 func (p *epaxos) Request(cmd *pb.Command) {
+	if p.halted {
+		p.logger.Debugf("ignoring proposal on halted replica: %+v", cmd)
+		return
+	}
+	if p.sheddingLoad() {
+		p.logger.Debugf("rejecting proposal while storage is slow: %+v", cmd)
+		return
+	}
+	if p.dryRun {
+		p.logger.Debugf("rejecting proposal on dry-run replica: %+v", cmd)
+		return
+	}
+	if p.propBatchSize > 0 && p.batchableProposal(cmd) {
+		p.bufferProposal(cmd)
+		return
+	}
 	p.onRequest(cmd)
 }
 
+// RequestWithHandle proposes the command like Request and returns the
+// InstanceID of the instance created to order it. Proposals Request
+// would silently drop or hold back are reported as typed errors
+// instead: ErrProposalDropped when the proposal is rejected outright
+// and ErrProposalDeferred when it is queued — buffered into a proposal
+// batch or deferred behind the warm-up cap — before an instance exists
+// to identify it.
+func (p *epaxos) RequestWithHandle(cmd *pb.Command) (pb.InstanceID, error) {
+	if p.halted {
+		p.logger.Debugf("ignoring proposal on halted replica: %+v", cmd)
+		return pb.InstanceID{}, ErrProposalDropped
+	}
+	if p.sheddingLoad() {
+		p.logger.Debugf("rejecting proposal while storage is slow: %+v", cmd)
+		return pb.InstanceID{}, ErrProposalDropped
+	}
+	if p.dryRun {
+		p.logger.Debugf("rejecting proposal on dry-run replica: %+v", cmd)
+		return pb.InstanceID{}, ErrProposalDropped
+	}
+	if p.propBatchSize > 0 && p.batchableProposal(cmd) {
+		p.bufferProposal(cmd)
+		return pb.InstanceID{}, ErrProposalDeferred
+	}
+	inst := p.onRequest(cmd)
+	if inst == nil {
+		// Deferred behind the warm-up cap.
+		return pb.InstanceID{}, ErrProposalDeferred
+	}
+	return inst.is.InstanceID, nil
+}
+
+// RequestBatch proposes a slice of commands at once. Compared to calling
+// Request per command, the dependency computation for the whole batch
+// shares a single pass over each replica's command space, cutting
+// per-command overhead for bulk proposers.
+func (p *epaxos) RequestBatch(cmds []*pb.Command) {
+	if p.halted {
+		p.logger.Debugf("ignoring proposals on halted replica: %+v", cmds)
+		return
+	}
+	if p.sheddingLoad() {
+		p.logger.Debugf("rejecting proposals while storage is slow: %+v", cmds)
+		return
+	}
+	if p.dryRun {
+		p.logger.Debugf("rejecting proposals on dry-run replica: %+v", cmds)
+		return
+	}
+	p.onRequestBatch(cmds)
+}
+
+// RequestWithSlowPathTimeout proposes the command like Request, but with
+// a proposal-specific slow-path timeout of the provided number of ticks
+// in place of the configured SlowPathTimeoutTicks, letting individual
+// proposals tune how long the fast path is waited for. Non-positive
+// values select the configured timeout.
+func (p *epaxos) RequestWithSlowPathTimeout(cmd *pb.Command, ticks int) {
+	if p.halted {
+		p.logger.Debugf("ignoring proposal on halted replica: %+v", cmd)
+		return
+	}
+	if p.sheddingLoad() {
+		p.logger.Debugf("rejecting proposal while storage is slow: %+v", cmd)
+		return
+	}
+	if p.dryRun {
+		p.logger.Debugf("rejecting proposal on dry-run replica: %+v", cmd)
+		return
+	}
+	p.proposalSlowPathTicks = ticks
+	p.onRequest(cmd)
+	p.proposalSlowPathTicks = 0
+}
+
 func (p *epaxos) Step(m pb.Message) {
+	if p.halted {
+		p.logger.Debugf("ignoring message on halted replica: %+v", m)
+		return
+	}
+
+	// Messages addressed to another replica may have been sent here for
+	// relaying around a broken link; forward them toward their
+	// destination, piggybacked messages and all.
+	if p.maybeRelayMessage(m) {
+		return
+	}
+
+	// Process any piggybacked messages first; they are older than the
+	// carrying message.
+	for _, pm := range m.Piggyback {
+		p.Step(pm)
+	}
+	m.Piggyback = nil
+
 	if ok := p.validateMessage(m); !ok {
 		p.logger.Warningf("found invalid Message: %+v", m)
+		p.recordViolation(m)
+		return
+	}
+	if !pb.IsReply(m.Type) && p.isQuarantined(m.InstanceID.ReplicaID) {
+		p.logger.Debugf("ignoring message from quarantined replica %d: %+v",
+			m.InstanceID.ReplicaID, m)
+		return
+	}
+
+	// Proposal traffic carrying a ballot epoch below ours was sent under
+	// an older configuration; fence it out so that the old membership
+	// cannot form quorums. Commits and replies still flow, letting a
+	// lagging sender learn outcomes and catch up to the new epoch.
+	if m.Ballot.Epoch < p.epoch {
+		switch m.Type.(type) {
+		case *pb.Message_PreAccept, *pb.Message_Accept, *pb.Message_TryPreAccept:
+			p.logger.Debugf("fencing message from configuration epoch %d: %+v",
+				m.Ballot.Epoch, m)
+			return
+		}
+	}
+
+	p.stepFrom = m.From
+	p.stepBallot = m.Ballot
+	p.recordHeardFrom(m.From)
+
+	if m.Type == nil {
+		// The message's type was not recognized when it was decoded; it
+		// likely came from a peer running a newer version.
+		p.onUnknownMessage(m)
+		return
+	}
+
+	// Instance queries and watermark advertisements concern no single
+	// instance, so handle them before the instance lookup.
+	switch t := m.Type.(type) {
+	case *pb.Message_InstanceQuery:
+		p.onInstanceQuery(m)
+		return
+	case *pb.Message_InstanceQueryReply:
+		p.onInstanceQueryReply(m, t.InstanceQueryReply)
+		return
+	case *pb.Message_ExecutedWatermark:
+		p.onPeerWatermark(m, t.ExecutedWatermark)
+		return
+	case *pb.Message_InstallSnapshot:
+		p.onInstallSnapshot(m, t.InstallSnapshot)
+		return
+	case *pb.Message_LeaseRequest:
+		p.onLeaseRequest(m, t.LeaseRequest)
+		return
+	case *pb.Message_LeaseGrant:
+		p.onLeaseGrant(m, t.LeaseGrant)
+		return
+	case *pb.Message_LeaseInvalidate:
+		p.onLeaseInvalidate(m, t.LeaseInvalidate)
+		return
+	case *pb.Message_CommittedRanges:
+		p.onCommittedRanges(m, t.CommittedRanges)
 		return
 	}
 
@@ -200,12 +1194,12 @@ func (p *epaxos) Step(m pb.Message) {
 	i := m.InstanceID.InstanceNum
 	inst := p.getInstance(r, i)
 	if inst == nil {
-		// if p.hasTruncated(r, i) {
-		// 	// We've already truncated this instance, which means that it was
-		// 	// already committed. Ignore the messsage.
-		// 	p.logger.Debugf("ignoring message to truncated instance: %+v", m)
-		// 	return
-		// }
+		if p.hasTruncated(r, i) {
+			// We've already truncated this instance, which means that it was
+			// already committed. Ignore the messsage.
+			p.logger.Debugf("ignoring message to truncated instance: %+v", m)
+			return
+		}
 		if r == p.id {
 			// We should always know about our own instances.
 			p.logger.Warningf("unknown local instance number: %+v", m)
@@ -214,8 +1208,16 @@ func (p *epaxos) Step(m pb.Message) {
 
 		// Create a new instance if one does not already exist.
 		inst = p.newInstance(r, i)
-		p.commands[r].ReplaceOrInsert(inst)
+		if !p.insertInstance(inst) {
+			return
+		}
+		p.indexCommand(inst)
 	}
+	if inst.abandoned {
+		p.logger.Debugf("ignoring message to abandoned instance: %+v", m)
+		return
+	}
+	inst.adoptTraceContext(m)
 
 	switch t := m.Type.(type) {
 	case *pb.Message_PreAccept:
@@ -230,17 +1232,56 @@ func (p *epaxos) Step(m pb.Message) {
 		inst.onAcceptOK(t.AcceptOk)
 	case *pb.Message_Commit:
 		inst.onCommit(t.Commit)
+	case *pb.Message_NeedCommand:
+		inst.onNeedCommand(t.NeedCommand)
+	case *pb.Message_TryPreAccept:
+		inst.onTryPreAccept(t.TryPreAccept)
+	case *pb.Message_TryPreAcceptReply:
+		inst.onTryPreAcceptReply(t.TryPreAcceptReply)
+	case *pb.Message_Nack:
+		inst.onNack(t.Nack)
 	default:
-		p.logger.Panicf("unexpected Message type: %T", t)
+		p.logger.Warningf("unexpected Message type: %T", t)
+		p.onUnknownMessage(m)
 	}
 }
 
+// onUnknownMessage surfaces a message whose type this node does not
+// understand through an UnknownMessageEvent, so that rolling upgrades
+// which add new message types degrade gracefully instead of erroring.
+func (p *epaxos) onUnknownMessage(m pb.Message) {
+	p.logger.Debugf("ignoring message with unknown type: %+v", m)
+	raw, err := m.Marshal()
+	if err != nil {
+		raw = nil
+	}
+	p.emitEvent(UnknownMessageEvent{
+		From:       m.From,
+		InstanceID: m.InstanceID,
+		Raw:        raw,
+	})
+}
+
 func (p *epaxos) validateMessage(m pb.Message) bool {
 	// The message should have us as its destination.
 	if m.To != p.id {
 		return false
 	}
 
+	switch m.Type.(type) {
+	case *pb.Message_InstanceQuery, *pb.Message_InstanceQueryReply:
+		// Queries and their replies may concern any instance, including
+		// the receiving replica's own.
+		return p.knownReplica(m.InstanceID.ReplicaID)
+	case *pb.Message_ExecutedWatermark, *pb.Message_InstallSnapshot, *pb.Message_CommittedRanges:
+		// Watermark and committed-range advertisements and snapshot
+		// chunks concern no instance at all.
+		return p.knownReplica(m.From)
+	case *pb.Message_LeaseRequest, *pb.Message_LeaseGrant, *pb.Message_LeaseInvalidate:
+		// Lease traffic concerns key ranges rather than instances.
+		return p.knownReplica(m.From)
+	}
+
 	if pb.IsReply(m.Type) {
 		// The instance's replica should be us.
 		if m.InstanceID.ReplicaID != p.id {
@@ -269,13 +1310,56 @@ func (p *epaxos) validateMessage(m pb.Message) bool {
 // }
 
 func (p *epaxos) deliverExecutedCommand(cmd pb.Command) {
-	p.executedCmds = append(p.executedCmds, cmd)
+	if p.holdExecution(cmd) {
+		// The command is parked by the execution hooks until the external
+		// coordinator finalizes it.
+		return
+	}
+	p.deliverPreparedCommand(cmd)
+}
+
+// deliverPreparedCommand delivers a command that has passed through the
+// execution hooks' prepare phase, if any.
+func (p *epaxos) deliverPreparedCommand(cmd pb.Command) {
+	if len(cmd.Batch) > 0 {
+		// A batching envelope carries no payload of its own; deliver its
+		// commands individually, in proposal order.
+		for _, batched := range cmd.Batch {
+			p.deliverPreparedCommand(batched)
+		}
+		return
+	}
+	if p.apply != nil || p.applyResult != nil {
+		if p.replaying() {
+			// Batch application until the bulk replay finishes.
+			p.replay.pendingApply = append(p.replay.pendingApply, cmd)
+			return
+		}
+		p.applyCommand(cmd)
+	} else {
+		p.executedCmds = append(p.executedCmds, cmd)
+	}
+	if p.execHooks != nil {
+		p.execHooks.FinalizeExecute(cmd)
+	}
 }
 
 func (p *epaxos) clearExecutedCommands() {
 	p.executedCmds = nil
 }
 
+func (p *epaxos) clearReadyHardState() {
+	p.readyHardState = nil
+}
+
+// singleNode returns whether this node is the only member of the EPaxos
+// network. In a single-node configuration quorum logic short-circuits:
+// instances commit immediately without waiting for replies, which allows
+// epaxos-backed services to run locally without a cluster.
+func (p *epaxos) singleNode() bool {
+	return len(p.nodes) == 1
+}
+
 func (p *epaxos) knownReplica(r pb.ReplicaID) bool {
 	return inReplicaSlice(r, p.nodes)
 }
@@ -299,8 +1383,9 @@ func (p *epaxos) quorum(val int) bool {
 	return val > len(p.nodes)/2
 }
 
+// fastQuorum returns whether val identical PreAccept replies, including
+// the leader's own, suffice for a fast-path commit. The size is N-1 by
+// default, or F+⌊(F+1)/2⌋ when OptimizedFastQuorum is set.
 func (p *epaxos) fastQuorum(val int) bool {
-	return val >= len(p.nodes)-1
-	// // (x+y-1)/y == ceil(x/y)
-	// return val > (3*len(p.nodes)+(4-1))/4
+	return val >= p.fastQuorumSize
 }
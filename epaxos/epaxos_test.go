@@ -1,6 +1,7 @@
 package epaxos
 
 import (
+	"fmt"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -30,6 +31,30 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+// TestConfigIdentityMismatch verifies that a Config that disagrees with the
+// identity recorded in storage is rejected.
+func TestConfigIdentityMismatch(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	orig := &Config{ID: 0, ClusterID: 7, Nodes: nodes}
+	p := newEPaxos(orig)
+
+	// Restarting with the same identity succeeds.
+	same := &Config{ID: 0, ClusterID: 7, Nodes: nodes, Storage: p.storage}
+	if err := same.validate(); err != nil {
+		t.Errorf("expected valid config, found error %v", err)
+	}
+
+	// Restarting with a different ReplicaID or ClusterID is rejected.
+	badID := &Config{ID: 1, ClusterID: 7, Nodes: nodes, Storage: p.storage}
+	if err := badID.validate(); err == nil {
+		t.Errorf("expected error for mismatched ReplicaID")
+	}
+	badCluster := &Config{ID: 0, ClusterID: 8, Nodes: nodes, Storage: p.storage}
+	if err := badCluster.validate(); err == nil {
+		t.Errorf("expected error for mismatched ClusterID")
+	}
+}
+
 func (p *epaxos) ReadMessages() []pb.Message {
 	msgs := p.msgs
 	p.clearMsgs()
@@ -51,6 +76,9 @@ type network struct {
 	failures    map[*epaxos]struct{}
 	dropm       map[conn]float64
 	interceptor func(pb.ReplicaID, pb.Message)
+	// mtu, if non-zero, caps the marshaled size of a delivered message,
+	// simulating a transport with a maximum datagram size.
+	mtu int
 }
 
 func newNetwork(nodeCount int) network {
@@ -85,6 +113,32 @@ func (n *network) setInterceptor(f func(from pb.ReplicaID, msg pb.Message)) {
 	n.interceptor = f
 }
 
+func (n *network) setMTU(bytes int) {
+	n.mtu = bytes
+}
+
+// enforceMTU returns the datagrams that the network delivers for the
+// provided message. Messages within the MTU pass through unchanged.
+// Oversized messages are split by detaching their piggybacked messages
+// into separate datagrams, delivered before the stripped carrier so that
+// the processing order of the coalesced form is preserved. An oversized
+// message with no piggybacks panics: the protocol has no way to fragment
+// a single message, so a test hitting this has an undersized MTU.
+func (n *network) enforceMTU(msg pb.Message) []pb.Message {
+	if n.mtu <= 0 || msg.Size() <= n.mtu {
+		return []pb.Message{msg}
+	}
+	if len(msg.Piggyback) == 0 {
+		panic(fmt.Sprintf("message exceeds network MTU %d: %+v", n.mtu, msg))
+	}
+	var split []pb.Message
+	for _, pm := range msg.Piggyback {
+		split = append(split, n.enforceMTU(pm)...)
+	}
+	msg.Piggyback = nil
+	return append(split, n.enforceMTU(msg)...)
+}
+
 func (n *network) restart(id pb.ReplicaID) {
 	p := n.peers[id]
 	n.peers[id] = newEPaxos(&Config{
@@ -168,12 +222,17 @@ func (n *network) deliverAllMessages() {
 						continue
 					}
 				}
-				msgs = append(msgs, msg)
+				msgs = append(msgs, n.enforceMTU(msg)...)
 			}
 		}
 	}
 	for _, msg := range msgs {
-		dest := n.peers[msg.To]
+		dest, ok := n.peers[msg.To]
+		if !ok {
+			// The network does not host the destination; this can happen
+			// when a conf change admits a replica that was never started.
+			continue
+		}
 		if n.alive(dest) {
 			dest.Step(msg)
 		}
@@ -245,6 +304,26 @@ func (n *network) waitExecuteInstance(inst *instance, quorum bool) bool {
 	}, quorum)
 }
 
+// TestExecuteCommandsSingleNode verifies that a single-node configuration
+// short-circuits quorum logic and executes commands immediately, without
+// any messages being exchanged.
+func TestExecuteCommandsSingleNode(t *testing.T) {
+	n := newNetwork(1)
+	peer := n.peers[0]
+
+	for i := 0; i < 3; i++ {
+		cmd := newTestingCommand("a", "z")
+		inst := peer.onRequest(cmd)
+
+		if !peer.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum) {
+			t.Fatalf("expected instance %+v to execute immediately", inst)
+		}
+	}
+	if msgs := peer.ReadMessages(); len(msgs) != 0 {
+		t.Errorf("expected no messages in single-node mode, found %+v", msgs)
+	}
+}
+
 // TestExecuteCommandsNoFailures verifies that each replica can propose a
 // command and that the command will be executed, in the case where there
 // are no failures.
@@ -433,3 +512,188 @@ func TestExecuteCommandsCrashAfterAccept(t *testing.T) {
 		t.Fatalf("command execution failed, instance %+v never installed", instAfterRestart)
 	}
 }
+
+func TestOnRequestDuplicateCommand(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	cmd := newTestingCommand("a", "z")
+	inst := leader.onRequest(cmd)
+
+	// Re-proposing the same command while the first proposal is in flight
+	// returns the existing instance instead of creating a second one.
+	if dup := leader.onRequest(cmd); dup != inst {
+		t.Fatalf("expected duplicate proposal to return instance %+v, found %+v", inst, dup)
+	}
+	if num := leader.maxInstanceNum(0); num != 1 {
+		t.Fatalf("expected a single local instance, found max instance number %d", num)
+	}
+
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	// Once the command has executed, a re-proposal is no longer considered
+	// a duplicate.
+	if dup := leader.onRequest(cmd); dup == inst {
+		t.Fatalf("expected executed command to be re-proposable")
+	}
+}
+
+// TestExecuteReadOnlyOutOfBand verifies that a committed read-only command
+// whose dependencies have all executed runs immediately, without entering
+// the executor's dependency graph.
+func TestExecuteReadOnlyOutOfBand(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	inst := leader.onRequest(newTestingReadCommand("a", "z"))
+	n.deliverAllMessages() // PreAccepts to followers
+	n.deliverAllMessages() // replies back to the leader
+	if !inst.isStates(pb.InstanceState_Executed) {
+		t.Fatalf("expected executed instance, found state %v", inst.is.Status)
+	}
+	if l := len(leader.executor.vertices); l != 0 {
+		t.Fatalf("expected read-only command to bypass the executor, found %d vertices", l)
+	}
+}
+
+// TestStepUnknownMessageType verifies that a message whose type was not
+// recognized at decode time is dropped and surfaced through an
+// UnknownMessageEvent instead of being treated as an error.
+func TestStepUnknownMessageType(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0, 1, 2},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
+	})
+
+	if inst := p.getInstance(1, 1); inst != nil {
+		t.Fatalf("expected unknown message not to create an instance, found %+v", inst)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, found %+v", events)
+	}
+	e, ok := events[0].(UnknownMessageEvent)
+	if !ok {
+		t.Fatalf("expected UnknownMessageEvent, found %+v", events[0])
+	}
+	if e.From != 1 || e.InstanceID.ReplicaID != 1 {
+		t.Errorf("unexpected event contents: %+v", e)
+	}
+	if len(e.Raw) == 0 {
+		t.Errorf("expected event to carry the raw message bytes")
+	}
+}
+
+// TestOptimizedFastQuorumSize verifies the fast quorum sizes used with
+// and without OptimizedFastQuorum, across a range of cluster sizes.
+func TestOptimizedFastQuorumSize(t *testing.T) {
+	testCases := []struct {
+		nodes     int
+		classic   int
+		optimized int
+	}{
+		{nodes: 3, classic: 2, optimized: 2},
+		{nodes: 5, classic: 4, optimized: 3},
+		{nodes: 7, classic: 6, optimized: 5},
+		{nodes: 9, classic: 8, optimized: 6},
+	}
+	for _, tC := range testCases {
+		nodes := make([]pb.ReplicaID, tC.nodes)
+		for i := range nodes {
+			nodes[i] = pb.ReplicaID(i)
+		}
+		for _, optimized := range []bool{false, true} {
+			p := newEPaxos(&Config{
+				ID:                  0,
+				Nodes:               nodes,
+				OptimizedFastQuorum: optimized,
+			})
+			e := tC.classic
+			if optimized {
+				e = tC.optimized
+			}
+			if a := p.fastQuorumSize; a != e {
+				t.Errorf("n=%d optimized=%t: expected fast quorum size %d, found %d",
+					tC.nodes, optimized, e, a)
+			}
+		}
+	}
+}
+
+// TestOptimizedFastPathCommit verifies that with OptimizedFastQuorum set,
+// a 5-node leader commits on the fast path after two identical replies,
+// one short of the classic fast quorum.
+func TestOptimizedFastPathCommit(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                  0,
+		Nodes:               []pb.ReplicaID{0, 1, 2, 3, 4},
+		OptimizedFastQuorum: true,
+	})
+
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	for _, from := range []pb.ReplicaID{1, 2} {
+		p.Step(pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+		})
+	}
+	newInst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+}
+
+// TestSlowPathTimeoutConfigurable verifies that SlowPathTimeoutTicks
+// replaces the default fast-path wait window, and that an individual
+// proposal can override it through RequestWithSlowPathTimeout.
+func TestSlowPathTimeoutConfigurable(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                   0,
+		Nodes:                []pb.ReplicaID{0, 1, 2, 3, 4},
+		SlowPathTimeoutTicks: 4,
+	})
+
+	quorumReplies := func(inst *instance) {
+		for _, from := range []pb.ReplicaID{1, 2} {
+			p.Step(pb.Message{
+				To:         0,
+				From:       from,
+				InstanceID: inst.is.InstanceID,
+				Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+			})
+		}
+	}
+
+	// A plain quorum arms the slow-path timer; the configured window
+	// holds the fast path open for four ticks.
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	quorumReplies(newInst)
+	for i := 0; i < 3; i++ {
+		p.Tick()
+	}
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	p.Tick()
+	newInst.assertState(pb.InstanceState_Accepted)
+
+	// A proposal-level override shortens the window to a single tick.
+	p.RequestWithSlowPathTimeout(newTestingCommand("aa", "bb"), 1)
+	overrideInst := p.maxInstance(0)
+	quorumReplies(overrideInst)
+	overrideInst.assertState(pb.InstanceState_PreAccepted)
+	p.Tick()
+	overrideInst.assertState(pb.InstanceState_Accepted)
+
+	// Later plain proposals are unaffected by the override.
+	laterInst := p.onRequest(newTestingCommand("x", "y"))
+	if a, e := laterInst.slowPathTimer.timeout, 4; a != e {
+		t.Fatalf("expected configured slow-path timeout %d, found %d", e, a)
+	}
+}
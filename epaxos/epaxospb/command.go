@@ -2,6 +2,7 @@ package epaxospb
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 )
 
@@ -49,8 +50,37 @@ func (s Span) String() string {
 	return fmt.Sprintf("[%s-%s)", s.Key, s.EndKey)
 }
 
-// Interferes returns whether the two Commands interfere.
+// digestLen is the length that Command digests are truncated to.
+const digestLen = 16
+
+// Digest returns a short, canonical digest of the Command, suitable for
+// referencing the command in messages without carrying its full payload.
+func (c *Command) Digest() []byte {
+	data, err := c.Marshal()
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return sum[:digestLen]
+}
+
+// NewNoopCommand returns the designated no-op command, committed by
+// recovery to plug an instance that never received a command.
+func NewNoopCommand() *Command {
+	return &Command{Noop: true}
+}
+
+// Interferes returns whether the two Commands interfere. Membership
+// changes interfere with every command, so that the configuration they
+// install is totally ordered against the rest of the log. No-ops carry
+// no payload and interfere with nothing.
 func (c Command) Interferes(o Command) bool {
+	if c.Noop || o.Noop {
+		return false
+	}
+	if c.ConfChange || o.ConfChange {
+		return true
+	}
 	return (c.Writing || o.Writing) && c.Span.Overlaps(o.Span)
 }
 
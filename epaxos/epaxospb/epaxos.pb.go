@@ -3,26 +3,28 @@
 // DO NOT EDIT!
 
 /*
-	Package epaxospb is a generated protocol buffer package.
-
-	It is generated from these files:
-		epaxos.proto
-
-	It has these top-level messages:
-		Span
-		Command
-		InstanceID
-		InstanceData
-		PreAccept
-		PreAcceptOK
-		PreAcceptReply
-		Accept
-		AcceptOK
-		Commit
-		Ballot
-		Message
-		InstanceState
-		HardState
+Package epaxospb is a generated protocol buffer package.
+
+It is generated from these files:
+
+	epaxos.proto
+
+It has these top-level messages:
+
+	Span
+	Command
+	InstanceID
+	InstanceData
+	PreAccept
+	PreAcceptOK
+	PreAcceptReply
+	Accept
+	AcceptOK
+	Commit
+	Ballot
+	Message
+	InstanceState
+	HardState
 */
 package epaxospb
 
@@ -111,6 +113,28 @@ type Command struct {
 	Span    Span   `protobuf:"bytes,2,opt,name=span" json:"span"`
 	Writing bool   `protobuf:"varint,3,opt,name=writing,proto3" json:"writing,omitempty"`
 	Data    []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// conditional marks a command whose precondition must hold at
+	// execution time; if it does not, the command executes as a no-op.
+	Conditional bool `protobuf:"varint,5,opt,name=conditional,proto3" json:"conditional,omitempty"`
+	// compaction marks a protocol-internal command that declares an
+	// executed prefix safe to truncate cluster-wide; its payload is an
+	// encoded set of per-replica instance numbers.
+	Compaction bool `protobuf:"varint,6,opt,name=compaction,proto3" json:"compaction,omitempty"`
+	// speculative marks a command the application expects to be
+	// conflict-free, opting it in to a speculative reply at
+	// PreAccept-quorum time followed by a confirm/abort resolution.
+	Speculative bool `protobuf:"varint,7,opt,name=speculative,proto3" json:"speculative,omitempty"`
+	// conf_change marks a protocol-internal command that changes the
+	// cluster membership; its payload is an encoded ConfChange.
+	ConfChange bool `protobuf:"varint,8,opt,name=conf_change,json=confChange,proto3" json:"conf_change,omitempty"`
+	// batch holds the client commands folded into this command by
+	// proposal batching. A batching envelope's span covers the union of
+	// its batch's spans and its own payload fields are unused.
+	Batch []Command `protobuf:"bytes,9,rep,name=batch" json:"batch"`
+	// noop marks the designated no-op command committed by recovery to
+	// plug an instance that never received a command. No-ops interfere
+	// with nothing and are never delivered to the application.
+	Noop bool `protobuf:"varint,10,opt,name=noop,proto3" json:"noop,omitempty"`
 }
 
 func (m *Command) Reset()                    { *m = Command{} }
@@ -145,6 +169,48 @@ func (m *Command) GetData() []byte {
 	return nil
 }
 
+func (m *Command) GetConditional() bool {
+	if m != nil {
+		return m.Conditional
+	}
+	return false
+}
+
+func (m *Command) GetCompaction() bool {
+	if m != nil {
+		return m.Compaction
+	}
+	return false
+}
+
+func (m *Command) GetSpeculative() bool {
+	if m != nil {
+		return m.Speculative
+	}
+	return false
+}
+
+func (m *Command) GetConfChange() bool {
+	if m != nil {
+		return m.ConfChange
+	}
+	return false
+}
+
+func (m *Command) GetBatch() []Command {
+	if m != nil {
+		return m.Batch
+	}
+	return nil
+}
+
+func (m *Command) GetNoop() bool {
+	if m != nil {
+		return m.Noop
+	}
+	return false
+}
+
 type InstanceID struct {
 	ReplicaID   ReplicaID   `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3,casttype=ReplicaID" json:"replica_id,omitempty"`
 	InstanceNum InstanceNum `protobuf:"varint,2,opt,name=instance_num,json=instanceNum,proto3,casttype=InstanceNum" json:"instance_num,omitempty"`
@@ -173,6 +239,9 @@ type InstanceData struct {
 	Command *Command     `protobuf:"bytes,1,opt,name=command" json:"command,omitempty"`
 	SeqNum  SeqNum       `protobuf:"varint,2,opt,name=seq_num,json=seqNum,proto3,casttype=SeqNum" json:"seq_num,omitempty"`
 	Deps    []InstanceID `protobuf:"bytes,3,rep,name=deps" json:"deps"`
+	// command_digest optionally references the command by its digest
+	// instead of carrying the full command payload.
+	CommandDigest []byte `protobuf:"bytes,4,opt,name=command_digest,json=commandDigest,proto3" json:"command_digest,omitempty"`
 }
 
 func (m *InstanceData) Reset()                    { *m = InstanceData{} }
@@ -273,6 +342,313 @@ func (m *Commit) String() string            { return proto.CompactTextString(m)
 func (*Commit) ProtoMessage()               {}
 func (*Commit) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{9} }
 
+// NeedCommand is used to respond to a message that referenced a command
+// by digest which the receiving replica does not have. The sender should
+// respond by resending the message with the full command payload.
+type NeedCommand struct {
+	Digest []byte `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"`
+}
+
+func (m *NeedCommand) Reset()         { *m = NeedCommand{} }
+func (m *NeedCommand) String() string { return proto.CompactTextString(m) }
+func (*NeedCommand) ProtoMessage()    {}
+
+func (m *NeedCommand) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+// InstanceQuery asks the receiving replica for its view of the instance
+// identified by the carrying message, for debugging divergence and for
+// instance recovery.
+type InstanceQuery struct {
+}
+
+func (m *InstanceQuery) Reset()         { *m = InstanceQuery{} }
+func (m *InstanceQuery) String() string { return proto.CompactTextString(m) }
+func (*InstanceQuery) ProtoMessage()    {}
+
+// InstanceQueryReply carries the replying replica's view of the queried
+// instance. An absent state means the replica has no record of the
+// instance.
+type InstanceQueryReply struct {
+	State *InstanceState `protobuf:"bytes,1,opt,name=state" json:"state,omitempty"`
+}
+
+func (m *InstanceQueryReply) Reset()         { *m = InstanceQueryReply{} }
+func (m *InstanceQueryReply) String() string { return proto.CompactTextString(m) }
+func (*InstanceQueryReply) ProtoMessage()    {}
+
+func (m *InstanceQueryReply) GetState() *InstanceState {
+	if m != nil {
+		return m.State
+	}
+	return nil
+}
+
+// TryPreAccept is used during recovery to probe whether a command with
+// the provided attributes could have been committed on the fast path by
+// a failed leader. Unlike PreAccept, the receiving replica does not
+// update the attributes; it either accepts them exactly as proposed or
+// reports a conflicting instance.
+type TryPreAccept struct {
+	InstanceData `protobuf:"bytes,1,opt,name=data,embedded=data" json:"data"`
+}
+
+func (m *TryPreAccept) Reset()         { *m = TryPreAccept{} }
+func (m *TryPreAccept) String() string { return proto.CompactTextString(m) }
+func (*TryPreAccept) ProtoMessage()    {}
+
+// TryPreAcceptReply is used to respond to a TryPreAccept message. If the
+// receiving replica found no instance conflicting with the proposed
+// attributes it accepted them as-is; otherwise the reply identifies a
+// conflicting instance and its status so that the recovering replica can
+// decide how to proceed.
+type TryPreAcceptReply struct {
+	Conflict         bool                 `protobuf:"varint,1,opt,name=conflict,proto3" json:"conflict,omitempty"`
+	ConflictInstance InstanceID           `protobuf:"bytes,2,opt,name=conflict_instance,json=conflictInstance" json:"conflict_instance"`
+	ConflictStatus   InstanceState_Status `protobuf:"varint,3,opt,name=conflict_status,json=conflictStatus,proto3,enum=epaxospb.InstanceState_Status" json:"conflict_status,omitempty"`
+}
+
+func (m *TryPreAcceptReply) Reset()         { *m = TryPreAcceptReply{} }
+func (m *TryPreAcceptReply) String() string { return proto.CompactTextString(m) }
+func (*TryPreAcceptReply) ProtoMessage()    {}
+
+func (m *TryPreAcceptReply) GetConflict() bool {
+	if m != nil {
+		return m.Conflict
+	}
+	return false
+}
+
+func (m *TryPreAcceptReply) GetConflictInstance() InstanceID {
+	if m != nil {
+		return m.ConflictInstance
+	}
+	return InstanceID{}
+}
+
+func (m *TryPreAcceptReply) GetConflictStatus() InstanceState_Status {
+	if m != nil {
+		return m.ConflictStatus
+	}
+	return 0
+}
+
+// Nack is used to reject a message that carried a ballot older than the
+// highest ballot the replying replica has seen for the instance. The
+// carrying message's ballot holds that higher ballot, so that the sender
+// can re-propose above it.
+type Nack struct {
+}
+
+func (m *Nack) Reset()         { *m = Nack{} }
+func (m *Nack) String() string { return proto.CompactTextString(m) }
+func (*Nack) ProtoMessage()    {}
+
+// ExecutedWatermark advertises the sending replica's executed watermark:
+// for each replica column, the largest instance number through which all
+// instances have been executed by the sender. Replicas exchange these
+// periodically and garbage collect instances that every replica has
+// executed.
+type ExecutedWatermark struct {
+	Executed []InstanceID `protobuf:"bytes,1,rep,name=executed" json:"executed"`
+}
+
+func (m *ExecutedWatermark) Reset()         { *m = ExecutedWatermark{} }
+func (m *ExecutedWatermark) String() string { return proto.CompactTextString(m) }
+func (*ExecutedWatermark) ProtoMessage()    {}
+
+func (m *ExecutedWatermark) GetExecuted() []InstanceID {
+	if m != nil {
+		return m.Executed
+	}
+	return nil
+}
+
+// InstallSnapshot carries one chunk of a state-machine snapshot to a
+// lagging or new replica, so that it can catch up without replaying the
+// entire instance log. The final chunk carries the executed watermark
+// the snapshot covers.
+type InstallSnapshot struct {
+	// ID identifies the snapshot; chunks of different snapshots are never
+	// combined.
+	ID uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Chunk is the zero-based index of this chunk within the snapshot.
+	Chunk uint64 `protobuf:"varint,2,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	// Last marks the snapshot's final chunk.
+	Last bool `protobuf:"varint,3,opt,name=last,proto3" json:"last,omitempty"`
+	// Data is the chunk's portion of the snapshot payload.
+	Data []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// Executed is the executed watermark through which the snapshot
+	// captures the state machine. Only set on the final chunk.
+	Executed []InstanceID `protobuf:"bytes,5,rep,name=executed" json:"executed"`
+}
+
+func (m *InstallSnapshot) Reset()         { *m = InstallSnapshot{} }
+func (m *InstallSnapshot) String() string { return proto.CompactTextString(m) }
+func (*InstallSnapshot) ProtoMessage()    {}
+
+func (m *InstallSnapshot) GetID() uint64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *InstallSnapshot) GetChunk() uint64 {
+	if m != nil {
+		return m.Chunk
+	}
+	return 0
+}
+
+func (m *InstallSnapshot) GetLast() bool {
+	if m != nil {
+		return m.Last
+	}
+	return false
+}
+
+func (m *InstallSnapshot) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *InstallSnapshot) GetExecuted() []InstanceID {
+	if m != nil {
+		return m.Executed
+	}
+	return nil
+}
+
+// LeaseRequest asks the recipient to grant the sender a quorum read
+// lease over a span for the given number of ticks, measured on the
+// recipient's clock. A request over a span the recipient has already
+// granted the sender renews the grant.
+type LeaseRequest struct {
+	Span  Span   `protobuf:"bytes,1,opt,name=span" json:"span"`
+	Ticks uint64 `protobuf:"varint,2,opt,name=ticks,proto3" json:"ticks,omitempty"`
+}
+
+func (m *LeaseRequest) Reset()         { *m = LeaseRequest{} }
+func (m *LeaseRequest) String() string { return proto.CompactTextString(m) }
+func (*LeaseRequest) ProtoMessage()    {}
+
+func (m *LeaseRequest) GetSpan() Span {
+	if m != nil {
+		return m.Span
+	}
+	return Span{}
+}
+
+func (m *LeaseRequest) GetTicks() uint64 {
+	if m != nil {
+		return m.Ticks
+	}
+	return 0
+}
+
+// LeaseGrant grants the recipient a quorum read lease over the span for
+// the given number of ticks. Until the grant expires on the granter's
+// clock, the granter circulates every committed interfering write to
+// the holder with a LeaseInvalidate.
+type LeaseGrant struct {
+	Span  Span   `protobuf:"bytes,1,opt,name=span" json:"span"`
+	Ticks uint64 `protobuf:"varint,2,opt,name=ticks,proto3" json:"ticks,omitempty"`
+}
+
+func (m *LeaseGrant) Reset()         { *m = LeaseGrant{} }
+func (m *LeaseGrant) String() string { return proto.CompactTextString(m) }
+func (*LeaseGrant) ProtoMessage()    {}
+
+func (m *LeaseGrant) GetSpan() Span {
+	if m != nil {
+		return m.Span
+	}
+	return Span{}
+}
+
+func (m *LeaseGrant) GetTicks() uint64 {
+	if m != nil {
+		return m.Ticks
+	}
+	return 0
+}
+
+// LeaseInvalidate notifies a lease holder that a write interfering with
+// the span has committed, invalidating any read lease covering it.
+type LeaseInvalidate struct {
+	Span Span `protobuf:"bytes,1,opt,name=span" json:"span"`
+}
+
+func (m *LeaseInvalidate) Reset()         { *m = LeaseInvalidate{} }
+func (m *LeaseInvalidate) String() string { return proto.CompactTextString(m) }
+func (*LeaseInvalidate) ProtoMessage()    {}
+
+func (m *LeaseInvalidate) GetSpan() Span {
+	if m != nil {
+		return m.Span
+	}
+	return Span{}
+}
+
+// InstanceRange is an inclusive range of instance numbers in a single
+// replica's command space.
+type InstanceRange struct {
+	ReplicaID ReplicaID   `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3,casttype=ReplicaID" json:"replica_id,omitempty"`
+	Start     InstanceNum `protobuf:"varint,2,opt,name=start,proto3,casttype=InstanceNum" json:"start,omitempty"`
+	End       InstanceNum `protobuf:"varint,3,opt,name=end,proto3,casttype=InstanceNum" json:"end,omitempty"`
+}
+
+func (m *InstanceRange) Reset()         { *m = InstanceRange{} }
+func (m *InstanceRange) String() string { return proto.CompactTextString(m) }
+func (*InstanceRange) ProtoMessage()    {}
+
+func (m *InstanceRange) GetReplicaID() ReplicaID {
+	if m != nil {
+		return m.ReplicaID
+	}
+	return 0
+}
+
+func (m *InstanceRange) GetStart() InstanceNum {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *InstanceRange) GetEnd() InstanceNum {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
+// CommittedRanges advertises the instance numbers the sending replica
+// knows to be committed, as a compact set of inclusive ranges per
+// replica column. Peers compare the advertisement against their own
+// committed sets to find holes worth recovering.
+type CommittedRanges struct {
+	Ranges []InstanceRange `protobuf:"bytes,1,rep,name=ranges" json:"ranges"`
+}
+
+func (m *CommittedRanges) Reset()         { *m = CommittedRanges{} }
+func (m *CommittedRanges) String() string { return proto.CompactTextString(m) }
+func (*CommittedRanges) ProtoMessage()    {}
+
+func (m *CommittedRanges) GetRanges() []InstanceRange {
+	if m != nil {
+		return m.Ranges
+	}
+	return nil
+}
+
 // Ballot is a ballot number that ensures message freshness.
 type Ballot struct {
 	Epoch     uint64    `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
@@ -323,6 +699,19 @@ type Message struct {
 	//	*Message_AcceptOk
 	//	*Message_Commit
 	Type isMessage_Type `protobuf_oneof:"type"`
+	// Piggyback holds older messages coalesced onto this message so that
+	// both can be delivered in a single wire message. Piggybacked
+	// messages must be processed before the carrying message.
+	Piggyback []Message `protobuf:"bytes,11,rep,name=piggyback" json:"piggyback"`
+	// from is the replica that sent the message.
+	From ReplicaID `protobuf:"varint,12,opt,name=from,proto3,casttype=ReplicaID" json:"from,omitempty"`
+	// trace_context carries the W3C traceparent of the instance's
+	// proposal, so that spans for message handling on other replicas
+	// can link to the proposal's trace.
+	TraceContext string `protobuf:"bytes,15,opt,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty"`
+	// hops counts how many times the message has been relayed through
+	// intermediate peers, bounding relay loops under partial partitions.
+	Hops uint64 `protobuf:"varint,16,opt,name=hops,proto3" json:"hops,omitempty"`
 }
 
 func (m *Message) Reset()                    { *m = Message{} }
@@ -354,13 +743,61 @@ type Message_AcceptOk struct {
 type Message_Commit struct {
 	Commit *Commit `protobuf:"bytes,9,opt,name=commit,oneof"`
 }
+type Message_NeedCommand struct {
+	NeedCommand *NeedCommand `protobuf:"bytes,10,opt,name=need_command,json=needCommand,oneof"`
+}
+type Message_InstanceQuery struct {
+	InstanceQuery *InstanceQuery `protobuf:"bytes,13,opt,name=instance_query,json=instanceQuery,oneof"`
+}
+type Message_InstanceQueryReply struct {
+	InstanceQueryReply *InstanceQueryReply `protobuf:"bytes,14,opt,name=instance_query_reply,json=instanceQueryReply,oneof"`
+}
+type Message_TryPreAccept struct {
+	TryPreAccept *TryPreAccept `protobuf:"bytes,17,opt,name=try_pre_accept,json=tryPreAccept,oneof"`
+}
+type Message_TryPreAcceptReply struct {
+	TryPreAcceptReply *TryPreAcceptReply `protobuf:"bytes,18,opt,name=try_pre_accept_reply,json=tryPreAcceptReply,oneof"`
+}
+type Message_Nack struct {
+	Nack *Nack `protobuf:"bytes,19,opt,name=nack,oneof"`
+}
+type Message_ExecutedWatermark struct {
+	ExecutedWatermark *ExecutedWatermark `protobuf:"bytes,20,opt,name=executed_watermark,json=executedWatermark,oneof"`
+}
+type Message_InstallSnapshot struct {
+	InstallSnapshot *InstallSnapshot `protobuf:"bytes,21,opt,name=install_snapshot,json=installSnapshot,oneof"`
+}
+type Message_LeaseRequest struct {
+	LeaseRequest *LeaseRequest `protobuf:"bytes,22,opt,name=lease_request,json=leaseRequest,oneof"`
+}
+type Message_LeaseGrant struct {
+	LeaseGrant *LeaseGrant `protobuf:"bytes,23,opt,name=lease_grant,json=leaseGrant,oneof"`
+}
+type Message_LeaseInvalidate struct {
+	LeaseInvalidate *LeaseInvalidate `protobuf:"bytes,24,opt,name=lease_invalidate,json=leaseInvalidate,oneof"`
+}
+type Message_CommittedRanges struct {
+	CommittedRanges *CommittedRanges `protobuf:"bytes,25,opt,name=committed_ranges,json=committedRanges,oneof"`
+}
 
-func (*Message_PreAccept) isMessage_Type()      {}
-func (*Message_PreAcceptOk) isMessage_Type()    {}
-func (*Message_PreAcceptReply) isMessage_Type() {}
-func (*Message_Accept) isMessage_Type()         {}
-func (*Message_AcceptOk) isMessage_Type()       {}
-func (*Message_Commit) isMessage_Type()         {}
+func (*Message_PreAccept) isMessage_Type()          {}
+func (*Message_PreAcceptOk) isMessage_Type()        {}
+func (*Message_PreAcceptReply) isMessage_Type()     {}
+func (*Message_Accept) isMessage_Type()             {}
+func (*Message_AcceptOk) isMessage_Type()           {}
+func (*Message_Commit) isMessage_Type()             {}
+func (*Message_NeedCommand) isMessage_Type()        {}
+func (*Message_InstanceQuery) isMessage_Type()      {}
+func (*Message_InstanceQueryReply) isMessage_Type() {}
+func (*Message_TryPreAccept) isMessage_Type()       {}
+func (*Message_TryPreAcceptReply) isMessage_Type()  {}
+func (*Message_Nack) isMessage_Type()               {}
+func (*Message_ExecutedWatermark) isMessage_Type()  {}
+func (*Message_InstallSnapshot) isMessage_Type()    {}
+func (*Message_LeaseRequest) isMessage_Type()       {}
+func (*Message_LeaseGrant) isMessage_Type()         {}
+func (*Message_LeaseInvalidate) isMessage_Type()    {}
+func (*Message_CommittedRanges) isMessage_Type()    {}
 
 func (m *Message) GetType() isMessage_Type {
 	if m != nil {
@@ -432,6 +869,90 @@ func (m *Message) GetCommit() *Commit {
 	return nil
 }
 
+func (m *Message) GetNeedCommand() *NeedCommand {
+	if x, ok := m.GetType().(*Message_NeedCommand); ok {
+		return x.NeedCommand
+	}
+	return nil
+}
+
+func (m *Message) GetInstanceQuery() *InstanceQuery {
+	if x, ok := m.GetType().(*Message_InstanceQuery); ok {
+		return x.InstanceQuery
+	}
+	return nil
+}
+
+func (m *Message) GetInstanceQueryReply() *InstanceQueryReply {
+	if x, ok := m.GetType().(*Message_InstanceQueryReply); ok {
+		return x.InstanceQueryReply
+	}
+	return nil
+}
+
+func (m *Message) GetTryPreAccept() *TryPreAccept {
+	if x, ok := m.GetType().(*Message_TryPreAccept); ok {
+		return x.TryPreAccept
+	}
+	return nil
+}
+
+func (m *Message) GetTryPreAcceptReply() *TryPreAcceptReply {
+	if x, ok := m.GetType().(*Message_TryPreAcceptReply); ok {
+		return x.TryPreAcceptReply
+	}
+	return nil
+}
+
+func (m *Message) GetNack() *Nack {
+	if x, ok := m.GetType().(*Message_Nack); ok {
+		return x.Nack
+	}
+	return nil
+}
+
+func (m *Message) GetExecutedWatermark() *ExecutedWatermark {
+	if x, ok := m.GetType().(*Message_ExecutedWatermark); ok {
+		return x.ExecutedWatermark
+	}
+	return nil
+}
+
+func (m *Message) GetInstallSnapshot() *InstallSnapshot {
+	if x, ok := m.GetType().(*Message_InstallSnapshot); ok {
+		return x.InstallSnapshot
+	}
+	return nil
+}
+
+func (m *Message) GetLeaseRequest() *LeaseRequest {
+	if x, ok := m.GetType().(*Message_LeaseRequest); ok {
+		return x.LeaseRequest
+	}
+	return nil
+}
+
+func (m *Message) GetLeaseGrant() *LeaseGrant {
+	if x, ok := m.GetType().(*Message_LeaseGrant); ok {
+		return x.LeaseGrant
+	}
+	return nil
+}
+
+func (m *Message) GetLeaseInvalidate() *LeaseInvalidate {
+	if x, ok := m.GetType().(*Message_LeaseInvalidate); ok {
+		return x.LeaseInvalidate
+	}
+	return nil
+}
+
+func (m *Message) GetCommittedRanges() *CommittedRanges {
+	if x, ok := m.GetType().(*Message_CommittedRanges); ok {
+		return x.CommittedRanges
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Message) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Message_OneofMarshaler, _Message_OneofUnmarshaler, _Message_OneofSizer, []interface{}{
@@ -441,6 +962,18 @@ func (*Message) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error
 		(*Message_Accept)(nil),
 		(*Message_AcceptOk)(nil),
 		(*Message_Commit)(nil),
+		(*Message_NeedCommand)(nil),
+		(*Message_InstanceQuery)(nil),
+		(*Message_InstanceQueryReply)(nil),
+		(*Message_TryPreAccept)(nil),
+		(*Message_TryPreAcceptReply)(nil),
+		(*Message_Nack)(nil),
+		(*Message_ExecutedWatermark)(nil),
+		(*Message_InstallSnapshot)(nil),
+		(*Message_LeaseRequest)(nil),
+		(*Message_LeaseGrant)(nil),
+		(*Message_LeaseInvalidate)(nil),
+		(*Message_CommittedRanges)(nil),
 	}
 }
 
@@ -478,29 +1011,89 @@ func _Message_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Commit); err != nil {
 			return err
 		}
-	case nil:
-	default:
-		return fmt.Errorf("Message.Type has unexpected type %T", x)
-	}
-	return nil
-}
-
-func _Message_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
-	m := msg.(*Message)
-	switch tag {
-	case 4: // type.pre_accept
-		if wire != proto.WireBytes {
-			return true, proto.ErrInternalBadWireType
+	case *Message_NeedCommand:
+		_ = b.EncodeVarint(10<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.NeedCommand); err != nil {
+			return err
 		}
-		msg := new(PreAccept)
-		err := b.DecodeMessage(msg)
-		m.Type = &Message_PreAccept{msg}
-		return true, err
-	case 5: // type.pre_accept_ok
-		if wire != proto.WireBytes {
-			return true, proto.ErrInternalBadWireType
+	case *Message_InstanceQuery:
+		_ = b.EncodeVarint(13<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.InstanceQuery); err != nil {
+			return err
 		}
-		msg := new(PreAcceptOK)
+	case *Message_InstanceQueryReply:
+		_ = b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.InstanceQueryReply); err != nil {
+			return err
+		}
+	case *Message_TryPreAccept:
+		_ = b.EncodeVarint(17<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.TryPreAccept); err != nil {
+			return err
+		}
+	case *Message_TryPreAcceptReply:
+		_ = b.EncodeVarint(18<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.TryPreAcceptReply); err != nil {
+			return err
+		}
+	case *Message_Nack:
+		_ = b.EncodeVarint(19<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Nack); err != nil {
+			return err
+		}
+	case *Message_ExecutedWatermark:
+		_ = b.EncodeVarint(20<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ExecutedWatermark); err != nil {
+			return err
+		}
+	case *Message_InstallSnapshot:
+		_ = b.EncodeVarint(21<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.InstallSnapshot); err != nil {
+			return err
+		}
+	case *Message_LeaseRequest:
+		_ = b.EncodeVarint(22<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.LeaseRequest); err != nil {
+			return err
+		}
+	case *Message_LeaseGrant:
+		_ = b.EncodeVarint(23<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.LeaseGrant); err != nil {
+			return err
+		}
+	case *Message_LeaseInvalidate:
+		_ = b.EncodeVarint(24<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.LeaseInvalidate); err != nil {
+			return err
+		}
+	case *Message_CommittedRanges:
+		_ = b.EncodeVarint(25<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.CommittedRanges); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("Message.Type has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _Message_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*Message)
+	switch tag {
+	case 4: // type.pre_accept
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PreAccept)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_PreAccept{msg}
+		return true, err
+	case 5: // type.pre_accept_ok
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PreAcceptOK)
 		err := b.DecodeMessage(msg)
 		m.Type = &Message_PreAcceptOk{msg}
 		return true, err
@@ -536,6 +1129,102 @@ func _Message_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer
 		err := b.DecodeMessage(msg)
 		m.Type = &Message_Commit{msg}
 		return true, err
+	case 10: // type.need_command
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(NeedCommand)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_NeedCommand{msg}
+		return true, err
+	case 13: // type.instance_query
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(InstanceQuery)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_InstanceQuery{msg}
+		return true, err
+	case 14: // type.instance_query_reply
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(InstanceQueryReply)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_InstanceQueryReply{msg}
+		return true, err
+	case 17: // type.try_pre_accept
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(TryPreAccept)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_TryPreAccept{msg}
+		return true, err
+	case 18: // type.try_pre_accept_reply
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(TryPreAcceptReply)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_TryPreAcceptReply{msg}
+		return true, err
+	case 19: // type.nack
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Nack)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_Nack{msg}
+		return true, err
+	case 20: // type.executed_watermark
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ExecutedWatermark)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_ExecutedWatermark{msg}
+		return true, err
+	case 21: // type.install_snapshot
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(InstallSnapshot)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_InstallSnapshot{msg}
+		return true, err
+	case 22: // type.lease_request
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(LeaseRequest)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_LeaseRequest{msg}
+		return true, err
+	case 23: // type.lease_grant
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(LeaseGrant)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_LeaseGrant{msg}
+		return true, err
+	case 24: // type.lease_invalidate
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(LeaseInvalidate)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_LeaseInvalidate{msg}
+		return true, err
+	case 25: // type.committed_ranges
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(CommittedRanges)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_CommittedRanges{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -575,6 +1264,66 @@ func _Message_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(9<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Message_NeedCommand:
+		s := proto.Size(x.NeedCommand)
+		n += proto.SizeVarint(10<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_InstanceQuery:
+		s := proto.Size(x.InstanceQuery)
+		n += proto.SizeVarint(13<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_InstanceQueryReply:
+		s := proto.Size(x.InstanceQueryReply)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_TryPreAccept:
+		s := proto.Size(x.TryPreAccept)
+		n += proto.SizeVarint(17<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_TryPreAcceptReply:
+		s := proto.Size(x.TryPreAcceptReply)
+		n += proto.SizeVarint(18<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_Nack:
+		s := proto.Size(x.Nack)
+		n += proto.SizeVarint(19<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_ExecutedWatermark:
+		s := proto.Size(x.ExecutedWatermark)
+		n += proto.SizeVarint(20<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_InstallSnapshot:
+		s := proto.Size(x.InstallSnapshot)
+		n += proto.SizeVarint(21<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_LeaseRequest:
+		s := proto.Size(x.LeaseRequest)
+		n += proto.SizeVarint(22<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_LeaseGrant:
+		s := proto.Size(x.LeaseGrant)
+		n += proto.SizeVarint(23<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_LeaseInvalidate:
+		s := proto.Size(x.LeaseInvalidate)
+		n += proto.SizeVarint(24<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_CommittedRanges:
+		s := proto.Size(x.CommittedRanges)
+		n += proto.SizeVarint(25<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -587,6 +1336,9 @@ type InstanceState struct {
 	Status       InstanceState_Status `protobuf:"varint,2,opt,name=status,proto3,enum=epaxospb.InstanceState_Status" json:"status,omitempty"`
 	InstanceData `protobuf:"bytes,3,opt,name=data,embedded=data" json:"data"`
 	Ballot       *Ballot `protobuf:"bytes,4,opt,name=ballot" json:"ballot,omitempty"`
+	// commit_cert holds the replicas whose replies contributed to the
+	// instance's commit decision, when certificate recording is enabled.
+	CommitCert []ReplicaID `protobuf:"varint,5,rep,packed,name=commit_cert,json=commitCert,casttype=ReplicaID" json:"commit_cert,omitempty"`
 }
 
 func (m *InstanceState) Reset()                    { *m = InstanceState{} }
@@ -613,6 +1365,15 @@ type HardState struct {
 	ReplicaID ReplicaID `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3,casttype=ReplicaID" json:"replica_id,omitempty"`
 	// nodes is the set of all nodes in the EPaxos network.
 	Nodes []ReplicaID `protobuf:"varint,2,rep,packed,name=nodes,casttype=ReplicaID" json:"nodes,omitempty"`
+	// cluster_id is the unique identifier for the cluster this node is
+	// a member of.
+	ClusterID ClusterID `protobuf:"varint,3,opt,name=cluster_id,json=clusterId,proto3,casttype=ClusterID" json:"cluster_id,omitempty"`
+	// truncated holds, for each replica, the maximum instance number that
+	// has been truncated from its command space by an agreed compaction.
+	Truncated []InstanceID `protobuf:"bytes,4,rep,name=truncated" json:"truncated"`
+	// epoch is the number of membership changes that have been applied,
+	// used to fence messages proposed under older configurations.
+	Epoch uint64 `protobuf:"varint,5,opt,name=epoch,proto3" json:"epoch,omitempty"`
 }
 
 func (m *HardState) Reset()                    { *m = HardState{} }
@@ -634,6 +1395,13 @@ func (m *HardState) GetNodes() []ReplicaID {
 	return nil
 }
 
+func (m *HardState) GetClusterID() ClusterID {
+	if m != nil {
+		return m.ClusterID
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Span)(nil), "epaxospb.Span")
 	proto.RegisterType((*Command)(nil), "epaxospb.Command")
@@ -725,6 +1493,68 @@ func (m *Command) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.Data)))
 		i += copy(dAtA[i:], m.Data)
 	}
+	if m.Conditional {
+		dAtA[i] = 0x28
+		i++
+		if m.Conditional {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Compaction {
+		dAtA[i] = 0x30
+		i++
+		if m.Compaction {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Speculative {
+		dAtA[i] = 0x38
+		i++
+		if m.Speculative {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.ConfChange {
+		dAtA[i] = 0x40
+		i++
+		if m.ConfChange {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Batch) > 0 {
+		for _, msg := range m.Batch {
+			dAtA[i] = 0x4a
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Noop {
+		dAtA[i] = 0x50
+		i++
+		if m.Noop {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -798,6 +1628,12 @@ func (m *InstanceData) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if len(m.CommandDigest) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.CommandDigest)))
+		i += copy(dAtA[i:], m.CommandDigest)
+	}
 	return i, nil
 }
 
@@ -1026,6 +1862,36 @@ func (m *Message) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i += nn8
 	}
+	if len(m.Piggyback) > 0 {
+		for _, msg := range m.Piggyback {
+			dAtA[i] = 0x5a
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.From != 0 {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.From))
+	}
+	if len(m.TraceContext) > 0 {
+		dAtA[i] = 0x7a
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.TraceContext)))
+		i += copy(dAtA[i:], m.TraceContext)
+	}
+	if m.Hops != 0 {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Hops))
+	}
 	return i, nil
 }
 
@@ -1113,58 +1979,195 @@ func (m *Message_Commit) MarshalTo(dAtA []byte) (int, error) {
 	}
 	return i, nil
 }
-func (m *InstanceState) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *Message_NeedCommand) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.NeedCommand != nil {
+		dAtA[i] = 0x52
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.NeedCommand.Size()))
+		n, err := m.NeedCommand.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
 	}
-	return dAtA[:n], nil
+	return i, nil
 }
-
-func (m *InstanceState) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	dAtA[i] = 0xa
-	i++
-	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceID.Size()))
-	n15, err := m.InstanceID.MarshalTo(dAtA[i:])
-	if err != nil {
-		return 0, err
-	}
-	i += n15
-	if m.Status != 0 {
-		dAtA[i] = 0x10
+func (m *Message_InstanceQuery) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.InstanceQuery != nil {
+		dAtA[i] = 0x6a
 		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.Status))
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceQuery.Size()))
+		n, err := m.InstanceQuery.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
 	}
-	dAtA[i] = 0x1a
-	i++
-	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
-	n16, err := m.InstanceData.MarshalTo(dAtA[i:])
-	if err != nil {
-		return 0, err
+	return i, nil
+}
+func (m *Message_InstanceQueryReply) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.InstanceQueryReply != nil {
+		dAtA[i] = 0x72
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceQueryReply.Size()))
+		n, err := m.InstanceQueryReply.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
 	}
-	i += n16
-	if m.Ballot != nil {
-		dAtA[i] = 0x22
+	return i, nil
+}
+func (m *Message_TryPreAccept) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.TryPreAccept != nil {
+		dAtA[i] = 0x8a
 		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
-		n17, err := m.Ballot.MarshalTo(dAtA[i:])
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.TryPreAccept.Size()))
+		n, err := m.TryPreAccept.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n17
+		i += n
 	}
 	return i, nil
 }
-
-func (m *HardState) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
+func (m *Message_TryPreAcceptReply) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.TryPreAcceptReply != nil {
+		dAtA[i] = 0x92
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.TryPreAcceptReply.Size()))
+		n, err := m.TryPreAcceptReply.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_Nack) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Nack != nil {
+		dAtA[i] = 0x9a
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Nack.Size()))
+		n, err := m.Nack.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_ExecutedWatermark) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.ExecutedWatermark != nil {
+		dAtA[i] = 0xa2
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ExecutedWatermark.Size()))
+		n, err := m.ExecutedWatermark.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_InstallSnapshot) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.InstallSnapshot != nil {
+		dAtA[i] = 0xaa
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.InstallSnapshot.Size()))
+		n, err := m.InstallSnapshot.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_LeaseRequest) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.LeaseRequest != nil {
+		dAtA[i] = 0xb2
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.LeaseRequest.Size()))
+		n, err := m.LeaseRequest.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_LeaseGrant) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.LeaseGrant != nil {
+		dAtA[i] = 0xba
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.LeaseGrant.Size()))
+		n, err := m.LeaseGrant.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_LeaseInvalidate) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.LeaseInvalidate != nil {
+		dAtA[i] = 0xc2
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.LeaseInvalidate.Size()))
+		n, err := m.LeaseInvalidate.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *Message_CommittedRanges) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.CommittedRanges != nil {
+		dAtA[i] = 0xca
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.CommittedRanges.Size()))
+		n, err := m.CommittedRanges.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
+	}
+	return i, nil
+}
+func (m *NeedCommand) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
 	if err != nil {
 		return nil, err
@@ -1172,309 +2175,3001 @@ func (m *HardState) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *HardState) MarshalTo(dAtA []byte) (int, error) {
+func (m *NeedCommand) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.ReplicaID != 0 {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
-	}
-	if len(m.Nodes) > 0 {
-		dAtA19 := make([]byte, len(m.Nodes)*10)
-		var j18 int
-		for _, num := range m.Nodes {
-			for num >= 1<<7 {
-				dAtA19[j18] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j18++
-			}
-			dAtA19[j18] = uint8(num)
-			j18++
-		}
-		dAtA[i] = 0x12
+	if len(m.Digest) > 0 {
+		dAtA[i] = 0xa
 		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(j18))
-		i += copy(dAtA[i:], dAtA19[:j18])
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.Digest)))
+		i += copy(dAtA[i:], m.Digest)
 	}
 	return i, nil
 }
 
-func encodeFixed64Epaxos(dAtA []byte, offset int, v uint64) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	dAtA[offset+4] = uint8(v >> 32)
-	dAtA[offset+5] = uint8(v >> 40)
-	dAtA[offset+6] = uint8(v >> 48)
-	dAtA[offset+7] = uint8(v >> 56)
-	return offset + 8
-}
-func encodeFixed32Epaxos(dAtA []byte, offset int, v uint32) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	return offset + 4
-}
-func encodeVarintEpaxos(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *InstanceQuery) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return dAtA[:n], nil
 }
-func (m *Span) Size() (n int) {
+
+func (m *InstanceQuery) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovEpaxos(uint64(l))
-	}
-	l = len(m.EndKey)
-	if l > 0 {
-		n += 1 + l + sovEpaxos(uint64(l))
+	return i, nil
+}
+
+func (m *InstanceQueryReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *Command) Size() (n int) {
+func (m *InstanceQueryReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.ID != 0 {
-		n += 1 + sovEpaxos(uint64(m.ID))
-	}
-	l = m.Span.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	if m.Writing {
-		n += 2
+	if m.State != nil {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.State.Size()))
+		n, err := m.State.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n
 	}
-	l = len(m.Data)
-	if l > 0 {
-		n += 1 + l + sovEpaxos(uint64(l))
+	return i, nil
+}
+
+func (m *TryPreAccept) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *InstanceID) Size() (n int) {
+func (m *TryPreAccept) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.ReplicaID != 0 {
-		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
+	n, err := m.InstanceData.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
 	}
-	if m.InstanceNum != 0 {
-		n += 1 + sovEpaxos(uint64(m.InstanceNum))
+	i += n
+	return i, nil
+}
+
+func (m *TryPreAcceptReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *InstanceData) Size() (n int) {
+func (m *TryPreAcceptReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.Command != nil {
-		l = m.Command.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+	if m.Conflict {
+		dAtA[i] = 0x8
+		i++
+		if m.Conflict {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
 	}
-	if m.SeqNum != 0 {
-		n += 1 + sovEpaxos(uint64(m.SeqNum))
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.ConflictInstance.Size()))
+	n, err := m.ConflictInstance.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
 	}
-	if len(m.Deps) > 0 {
-		for _, e := range m.Deps {
-			l = e.Size()
-			n += 1 + l + sovEpaxos(uint64(l))
-		}
+	i += n
+	if m.ConflictStatus != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ConflictStatus))
 	}
-	return n
+	return i, nil
 }
 
-func (m *PreAccept) Size() (n int) {
-	var l int
-	_ = l
-	l = m.InstanceData.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	return n
+func (m *Nack) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
 }
 
-func (m *PreAcceptOK) Size() (n int) {
+func (m *Nack) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	return n
+	return i, nil
 }
 
-func (m *PreAcceptReply) Size() (n int) {
+func (m *ExecutedWatermark) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ExecutedWatermark) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.UpdatedSeqNum != 0 {
-		n += 1 + sovEpaxos(uint64(m.UpdatedSeqNum))
-	}
-	if len(m.UpdatedDeps) > 0 {
-		for _, e := range m.UpdatedDeps {
-			l = e.Size()
-			n += 1 + l + sovEpaxos(uint64(l))
+	if len(m.Executed) > 0 {
+		for _, msg := range m.Executed {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
 		}
 	}
-	return n
+	return i, nil
 }
 
-func (m *Accept) Size() (n int) {
-	var l int
-	_ = l
-	l = m.InstanceData.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	return n
+func (m *InstallSnapshot) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
 }
 
-func (m *AcceptOK) Size() (n int) {
+func (m *InstallSnapshot) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	return n
+	if m.ID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ID))
+	}
+	if m.Chunk != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Chunk))
+	}
+	if m.Last {
+		dAtA[i] = 0x18
+		i++
+		if m.Last {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Data) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
+	if len(m.Executed) > 0 {
+		for _, msg := range m.Executed {
+			dAtA[i] = 0x2a
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return i, nil
 }
 
-func (m *Commit) Size() (n int) {
-	var l int
-	_ = l
-	l = m.InstanceData.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	return n
+func (m *LeaseRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
 }
 
-func (m *Ballot) Size() (n int) {
+func (m *LeaseRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.Epoch != 0 {
-		n += 1 + sovEpaxos(uint64(m.Epoch))
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Span.Size()))
+	n, err := m.Span.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
 	}
-	if m.Number != 0 {
-		n += 1 + sovEpaxos(uint64(m.Number))
+	i += n
+	if m.Ticks != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Ticks))
 	}
-	if m.ReplicaID != 0 {
-		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	return i, nil
+}
+
+func (m *LeaseGrant) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *Message) Size() (n int) {
+func (m *LeaseGrant) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.To != 0 {
-		n += 1 + sovEpaxos(uint64(m.To))
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Span.Size()))
+	n, err := m.Span.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
 	}
-	l = m.Ballot.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	l = m.InstanceID.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	if m.Type != nil {
-		n += m.Type.Size()
+	i += n
+	if m.Ticks != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Ticks))
 	}
-	return n
+	return i, nil
 }
 
-func (m *Message_PreAccept) Size() (n int) {
-	var l int
-	_ = l
-	if m.PreAccept != nil {
-		l = m.PreAccept.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+func (m *LeaseInvalidate) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
-func (m *Message_PreAcceptOk) Size() (n int) {
+
+func (m *LeaseInvalidate) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.PreAcceptOk != nil {
-		l = m.PreAcceptOk.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Span.Size()))
+	n, err := m.Span.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
 	}
-	return n
+	i += n
+	return i, nil
 }
-func (m *Message_PreAcceptReply) Size() (n int) {
-	var l int
-	_ = l
-	if m.PreAcceptReply != nil {
-		l = m.PreAcceptReply.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+
+func (m *InstanceRange) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
-func (m *Message_Accept) Size() (n int) {
+
+func (m *InstanceRange) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.Accept != nil {
-		l = m.Accept.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+	if m.ReplicaID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
 	}
-	return n
-}
-func (m *Message_AcceptOk) Size() (n int) {
-	var l int
-	_ = l
-	if m.AcceptOk != nil {
-		l = m.AcceptOk.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+	if m.Start != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Start))
 	}
-	return n
+	if m.End != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.End))
+	}
+	return i, nil
 }
-func (m *Message_Commit) Size() (n int) {
-	var l int
-	_ = l
-	if m.Commit != nil {
-		l = m.Commit.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+
+func (m *CommittedRanges) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
-func (m *InstanceState) Size() (n int) {
+
+func (m *CommittedRanges) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	l = m.InstanceID.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	if m.Status != 0 {
-		n += 1 + sovEpaxos(uint64(m.Status))
+	if len(m.Ranges) > 0 {
+		for _, msg := range m.Ranges {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
 	}
-	l = m.InstanceData.Size()
-	n += 1 + l + sovEpaxos(uint64(l))
-	if m.Ballot != nil {
-		l = m.Ballot.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+	return i, nil
+}
+
+func (m *InstanceState) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *HardState) Size() (n int) {
+func (m *InstanceState) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	if m.ReplicaID != 0 {
-		n += 1 + sovEpaxos(uint64(m.ReplicaID))
-	}
-	if len(m.Nodes) > 0 {
-		l = 0
-		for _, e := range m.Nodes {
-			l += sovEpaxos(uint64(e))
-		}
-		n += 1 + sovEpaxos(uint64(l)) + l
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceID.Size()))
+	n15, err := m.InstanceID.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n15
+	if m.Status != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Status))
+	}
+	dAtA[i] = 0x1a
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
+	n16, err := m.InstanceData.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n16
+	if m.Ballot != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
+		n17, err := m.Ballot.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n17
+	}
+	if len(m.CommitCert) > 0 {
+		dAtA21 := make([]byte, len(m.CommitCert)*10)
+		var j20 int
+		for _, num := range m.CommitCert {
+			for num >= 1<<7 {
+				dAtA21[j20] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j20++
+			}
+			dAtA21[j20] = uint8(num)
+			j20++
+		}
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(j20))
+		i += copy(dAtA[i:], dAtA21[:j20])
+	}
+	return i, nil
+}
+
+func (m *HardState) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *HardState) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
+	}
+	if len(m.Nodes) > 0 {
+		dAtA19 := make([]byte, len(m.Nodes)*10)
+		var j18 int
+		for _, num := range m.Nodes {
+			for num >= 1<<7 {
+				dAtA19[j18] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j18++
+			}
+			dAtA19[j18] = uint8(num)
+			j18++
+		}
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(j18))
+		i += copy(dAtA[i:], dAtA19[:j18])
+	}
+	if m.ClusterID != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ClusterID))
+	}
+	if len(m.Truncated) > 0 {
+		for _, msg := range m.Truncated {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Epoch != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Epoch))
+	}
+	return i, nil
+}
+
+func encodeFixed64Epaxos(dAtA []byte, offset int, v uint64) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	dAtA[offset+4] = uint8(v >> 32)
+	dAtA[offset+5] = uint8(v >> 40)
+	dAtA[offset+6] = uint8(v >> 48)
+	dAtA[offset+7] = uint8(v >> 56)
+	return offset + 8
+}
+func encodeFixed32Epaxos(dAtA []byte, offset int, v uint32) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	return offset + 4
+}
+func encodeVarintEpaxos(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+func (m *Span) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	l = len(m.EndKey)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
 	}
 	return n
 }
 
-func sovEpaxos(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
+func (m *Command) Size() (n int) {
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ID))
+	}
+	l = m.Span.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.Writing {
+		n += 2
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if m.Conditional {
+		n += 2
+	}
+	if m.Compaction {
+		n += 2
+	}
+	if m.Speculative {
+		n += 2
+	}
+	if m.ConfChange {
+		n += 2
+	}
+	if len(m.Batch) > 0 {
+		for _, e := range m.Batch {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if m.Noop {
+		n += 2
+	}
+	return n
+}
+
+func (m *InstanceID) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	}
+	if m.InstanceNum != 0 {
+		n += 1 + sovEpaxos(uint64(m.InstanceNum))
+	}
+	return n
+}
+
+func (m *InstanceData) Size() (n int) {
+	var l int
+	_ = l
+	if m.Command != nil {
+		l = m.Command.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if m.SeqNum != 0 {
+		n += 1 + sovEpaxos(uint64(m.SeqNum))
+	}
+	if len(m.Deps) > 0 {
+		for _, e := range m.Deps {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	l = len(m.CommandDigest)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+
+func (m *PreAccept) Size() (n int) {
+	var l int
+	_ = l
+	l = m.InstanceData.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *PreAcceptOK) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *PreAcceptReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.UpdatedSeqNum != 0 {
+		n += 1 + sovEpaxos(uint64(m.UpdatedSeqNum))
+	}
+	if len(m.UpdatedDeps) > 0 {
+		for _, e := range m.UpdatedDeps {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Accept) Size() (n int) {
+	var l int
+	_ = l
+	l = m.InstanceData.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *AcceptOK) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *Commit) Size() (n int) {
+	var l int
+	_ = l
+	l = m.InstanceData.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *Ballot) Size() (n int) {
+	var l int
+	_ = l
+	if m.Epoch != 0 {
+		n += 1 + sovEpaxos(uint64(m.Epoch))
+	}
+	if m.Number != 0 {
+		n += 1 + sovEpaxos(uint64(m.Number))
+	}
+	if m.ReplicaID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	}
+	return n
+}
+
+func (m *Message) Size() (n int) {
+	var l int
+	_ = l
+	if m.To != 0 {
+		n += 1 + sovEpaxos(uint64(m.To))
+	}
+	l = m.Ballot.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	l = m.InstanceID.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.Type != nil {
+		n += m.Type.Size()
+	}
+	if len(m.Piggyback) > 0 {
+		for _, e := range m.Piggyback {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if m.From != 0 {
+		n += 1 + sovEpaxos(uint64(m.From))
+	}
+	l = len(m.TraceContext)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if m.Hops != 0 {
+		n += 2 + sovEpaxos(uint64(m.Hops))
+	}
+	return n
+}
+
+func (m *Message_PreAccept) Size() (n int) {
+	var l int
+	_ = l
+	if m.PreAccept != nil {
+		l = m.PreAccept.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_PreAcceptOk) Size() (n int) {
+	var l int
+	_ = l
+	if m.PreAcceptOk != nil {
+		l = m.PreAcceptOk.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_PreAcceptReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.PreAcceptReply != nil {
+		l = m.PreAcceptReply.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_Accept) Size() (n int) {
+	var l int
+	_ = l
+	if m.Accept != nil {
+		l = m.Accept.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_AcceptOk) Size() (n int) {
+	var l int
+	_ = l
+	if m.AcceptOk != nil {
+		l = m.AcceptOk.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_Commit) Size() (n int) {
+	var l int
+	_ = l
+	if m.Commit != nil {
+		l = m.Commit.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_NeedCommand) Size() (n int) {
+	var l int
+	_ = l
+	if m.NeedCommand != nil {
+		l = m.NeedCommand.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_InstanceQuery) Size() (n int) {
+	var l int
+	_ = l
+	if m.InstanceQuery != nil {
+		l = m.InstanceQuery.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_InstanceQueryReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.InstanceQueryReply != nil {
+		l = m.InstanceQueryReply.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_TryPreAccept) Size() (n int) {
+	var l int
+	_ = l
+	if m.TryPreAccept != nil {
+		l = m.TryPreAccept.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_TryPreAcceptReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.TryPreAcceptReply != nil {
+		l = m.TryPreAcceptReply.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_Nack) Size() (n int) {
+	var l int
+	_ = l
+	if m.Nack != nil {
+		l = m.Nack.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_ExecutedWatermark) Size() (n int) {
+	var l int
+	_ = l
+	if m.ExecutedWatermark != nil {
+		l = m.ExecutedWatermark.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_InstallSnapshot) Size() (n int) {
+	var l int
+	_ = l
+	if m.InstallSnapshot != nil {
+		l = m.InstallSnapshot.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_LeaseRequest) Size() (n int) {
+	var l int
+	_ = l
+	if m.LeaseRequest != nil {
+		l = m.LeaseRequest.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_LeaseGrant) Size() (n int) {
+	var l int
+	_ = l
+	if m.LeaseGrant != nil {
+		l = m.LeaseGrant.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_LeaseInvalidate) Size() (n int) {
+	var l int
+	_ = l
+	if m.LeaseInvalidate != nil {
+		l = m.LeaseInvalidate.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_CommittedRanges) Size() (n int) {
+	var l int
+	_ = l
+	if m.CommittedRanges != nil {
+		l = m.CommittedRanges.Size()
+		n += 2 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *NeedCommand) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Digest)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *InstanceQuery) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *InstanceQueryReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.State != nil {
+		l = m.State.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+
+func (m *TryPreAccept) Size() (n int) {
+	var l int
+	_ = l
+	l = m.InstanceData.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *TryPreAcceptReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Conflict {
+		n += 2
+	}
+	l = m.ConflictInstance.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.ConflictStatus != 0 {
+		n += 1 + sovEpaxos(uint64(m.ConflictStatus))
+	}
+	return n
+}
+
+func (m *Nack) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *ExecutedWatermark) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Executed) > 0 {
+		for _, e := range m.Executed {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *InstallSnapshot) Size() (n int) {
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ID))
+	}
+	if m.Chunk != 0 {
+		n += 1 + sovEpaxos(uint64(m.Chunk))
+	}
+	if m.Last {
+		n += 2
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if len(m.Executed) > 0 {
+		for _, e := range m.Executed {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *LeaseRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Span.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.Ticks != 0 {
+		n += 1 + sovEpaxos(uint64(m.Ticks))
+	}
+	return n
+}
+
+func (m *LeaseGrant) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Span.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.Ticks != 0 {
+		n += 1 + sovEpaxos(uint64(m.Ticks))
+	}
+	return n
+}
+
+func (m *LeaseInvalidate) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Span.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+func (m *InstanceRange) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	}
+	if m.Start != 0 {
+		n += 1 + sovEpaxos(uint64(m.Start))
+	}
+	if m.End != 0 {
+		n += 1 + sovEpaxos(uint64(m.End))
+	}
+	return n
+}
+
+func (m *CommittedRanges) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Ranges) > 0 {
+		for _, e := range m.Ranges {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *InstanceState) Size() (n int) {
+	var l int
+	_ = l
+	l = m.InstanceID.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.Status != 0 {
+		n += 1 + sovEpaxos(uint64(m.Status))
+	}
+	l = m.InstanceData.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.Ballot != nil {
+		l = m.Ballot.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if len(m.CommitCert) > 0 {
+		l = 0
+		for _, e := range m.CommitCert {
+			l += sovEpaxos(uint64(e))
+		}
+		n += 1 + sovEpaxos(uint64(l)) + l
+	}
+	return n
+}
+
+func (m *HardState) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	}
+	if len(m.Nodes) > 0 {
+		l = 0
+		for _, e := range m.Nodes {
+			l += sovEpaxos(uint64(e))
+		}
+		n += 1 + sovEpaxos(uint64(l)) + l
+	}
+	if m.ClusterID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ClusterID))
+	}
+	if len(m.Truncated) > 0 {
+		for _, e := range m.Truncated {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovEpaxos(uint64(m.Epoch))
+	}
+	return n
+}
+
+func sovEpaxos(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozEpaxos(x uint64) (n int) {
+	return sovEpaxos(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Span) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Span: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Span: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EndKey", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EndKey = append(m.EndKey[:0], dAtA[iNdEx:postIndex]...)
+			if m.EndKey == nil {
+				m.EndKey = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Command) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Command: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Command: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Writing", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Writing = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Conditional", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Conditional = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compaction", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compaction = bool(v != 0)
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Speculative", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Speculative = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConfChange", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ConfChange = bool(v != 0)
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Batch", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Batch = append(m.Batch, Command{})
+			if err := m.Batch[len(m.Batch)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Noop", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Noop = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InstanceID) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InstanceID: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InstanceID: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceNum", wireType)
+			}
+			m.InstanceNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.InstanceNum |= (InstanceNum(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InstanceData) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InstanceData: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InstanceData: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Command", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Command == nil {
+				m.Command = &Command{}
+			}
+			if err := m.Command.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeqNum", wireType)
+			}
+			m.SeqNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeqNum |= (SeqNum(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Deps", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Deps = append(m.Deps, InstanceID{})
+			if err := m.Deps[len(m.Deps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommandDigest", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.CommandDigest = append(m.CommandDigest[:0], dAtA[iNdEx:postIndex]...)
+			if m.CommandDigest == nil {
+				m.CommandDigest = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PreAccept) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PreAccept: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PreAccept: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PreAcceptOK) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PreAcceptOK: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PreAcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PreAcceptReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PreAcceptReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedSeqNum", wireType)
+			}
+			m.UpdatedSeqNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UpdatedSeqNum |= (SeqNum(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedDeps", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.UpdatedDeps = append(m.UpdatedDeps, InstanceID{})
+			if err := m.UpdatedDeps[len(m.UpdatedDeps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Accept) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Accept: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Accept: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AcceptOK) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AcceptOK: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Commit) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Commit: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Commit: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *NeedCommand) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: NeedCommand: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: NeedCommand: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Digest", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Digest = append(m.Digest[:0], dAtA[iNdEx:postIndex]...)
+			if m.Digest == nil {
+				m.Digest = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InstanceQuery) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InstanceQuery: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InstanceQuery: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InstanceQueryReply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InstanceQueryReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InstanceQueryReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.State == nil {
+				m.State = &InstanceState{}
+			}
+			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TryPreAccept) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TryPreAccept: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TryPreAccept: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TryPreAcceptReply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TryPreAcceptReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TryPreAcceptReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Conflict", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Conflict = bool(v != 0)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConflictInstance", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ConflictInstance.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConflictStatus", wireType)
+			}
+			m.ConflictStatus = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ConflictStatus |= (InstanceState_Status(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Nack) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Nack: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Nack: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ExecutedWatermark) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExecutedWatermark: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExecutedWatermark: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Executed", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Executed = append(m.Executed, InstanceID{})
+			if err := m.Executed[len(m.Executed)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InstallSnapshot) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InstallSnapshot: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InstallSnapshot: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunk", wireType)
+			}
+			m.Chunk = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Chunk |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Last", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Last = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Executed", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Executed = append(m.Executed, InstanceID{})
+			if err := m.Executed[len(m.Executed)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *LeaseRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: LeaseRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: LeaseRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ticks", wireType)
+			}
+			m.Ticks = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Ticks |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
 		}
 	}
-	return n
-}
-func sozEpaxos(x uint64) (n int) {
-	return sovEpaxos(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
 }
-func (m *Span) Unmarshal(dAtA []byte) error {
+func (m *LeaseGrant) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1497,17 +5192,17 @@ func (m *Span) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Span: wiretype end group for non-group")
+			return fmt.Errorf("proto: LeaseGrant: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Span: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: LeaseGrant: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1517,28 +5212,96 @@ func (m *Span) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
-			if m.Key == nil {
-				m.Key = []byte{}
+			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
 			iNdEx = postIndex
 		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ticks", wireType)
+			}
+			m.Ticks = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Ticks |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *LeaseInvalidate) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: LeaseInvalidate: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: LeaseInvalidate: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field EndKey", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1548,21 +5311,20 @@ func (m *Span) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.EndKey = append(m.EndKey[:0], dAtA[iNdEx:postIndex]...)
-			if m.EndKey == nil {
-				m.EndKey = []byte{}
+			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
 			iNdEx = postIndex
 		default:
@@ -1586,7 +5348,7 @@ func (m *Span) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Command) Unmarshal(dAtA []byte) error {
+func (m *InstanceRange) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1609,17 +5371,17 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Command: wiretype end group for non-group")
+			return fmt.Errorf("proto: InstanceRange: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Command: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: InstanceRange: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
 			}
-			m.ID = 0
+			m.ReplicaID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1629,16 +5391,16 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
 			}
-			var msglen int
+			m.Start = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1648,27 +5410,16 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.Start |= (InstanceNum(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Writing", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field End", wireType)
 			}
-			var v int
+			m.End = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1678,17 +5429,66 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (int(b) & 0x7F) << shift
+				m.End |= (InstanceNum(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Writing = bool(v != 0)
-		case 4:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *CommittedRanges) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CommittedRanges: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CommittedRanges: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ranges", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1698,21 +5498,21 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
-			if m.Data == nil {
-				m.Data = []byte{}
+			m.Ranges = append(m.Ranges, InstanceRange{})
+			if err := m.Ranges[len(m.Ranges)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
 			iNdEx = postIndex
 		default:
@@ -1736,7 +5536,7 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *InstanceID) Unmarshal(dAtA []byte) error {
+func (m *Ballot) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1759,17 +5559,17 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: InstanceID: wiretype end group for non-group")
+			return fmt.Errorf("proto: Ballot: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InstanceID: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Ballot: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
 			}
-			m.ReplicaID = 0
+			m.Epoch = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1779,16 +5579,16 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				m.Epoch |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceNum", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Number", wireType)
 			}
-			m.InstanceNum = 0
+			m.Number = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1798,7 +5598,26 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.InstanceNum |= (InstanceNum(b) & 0x7F) << shift
+				m.Number |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1824,7 +5643,7 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *InstanceData) Unmarshal(dAtA []byte) error {
+func (m *Message) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1847,15 +5666,34 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: InstanceData: wiretype end group for non-group")
+			return fmt.Errorf("proto: Message: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InstanceData: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field To", wireType)
+			}
+			m.To = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.To |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Command", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1879,18 +5717,15 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Command == nil {
-				m.Command = &Command{}
-			}
-			if err := m.Command.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SeqNum", wireType)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceID", wireType)
 			}
-			m.SeqNum = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1900,14 +5735,25 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SeqNum |= (SeqNum(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Deps", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAccept", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1931,64 +5777,47 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Deps = append(m.Deps, InstanceID{})
-			if err := m.Deps[len(m.Deps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			v := &PreAccept{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
+			m.Type = &Message_PreAccept{v}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptOk", wireType)
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PreAccept) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
 			}
-			if iNdEx >= l {
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			v := &PreAcceptOK{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PreAccept: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PreAccept: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Type = &Message_PreAcceptOk{v}
+			iNdEx = postIndex
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptReply", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2012,115 +5841,81 @@ func (m *PreAccept) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			v := &PreAcceptReply{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
+			m.Type = &Message_PreAcceptReply{v}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PreAcceptOK) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
 			}
-			if iNdEx >= l {
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PreAcceptOK: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PreAcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
+			v := &Accept{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
+			m.Type = &Message_Accept{v}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AcceptOk", wireType)
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PreAcceptReply: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PreAcceptReply: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedSeqNum", wireType)
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
 			}
-			m.UpdatedSeqNum = 0
+			v := &AcceptOK{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_AcceptOk{v}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Commit", wireType)
+			}
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2130,14 +5925,27 @@ func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.UpdatedSeqNum |= (SeqNum(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Commit{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_Commit{v}
+			iNdEx = postIndex
+		case 10:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedDeps", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field NeedCommand", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2161,64 +5969,15 @@ func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.UpdatedDeps = append(m.UpdatedDeps, InstanceID{})
-			if err := m.UpdatedDeps[len(m.UpdatedDeps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			v := &NeedCommand{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
+			m.Type = &Message_NeedCommand{v}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Accept) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Accept: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Accept: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 11:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Piggyback", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2242,113 +6001,33 @@ func (m *Accept) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Piggyback = append(m.Piggyback, Message{})
+			if err := m.Piggyback[len(m.Piggyback)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *AcceptOK) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: AcceptOK: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: AcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Commit) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field From", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			m.From = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.From |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Commit: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Commit: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 13:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceQuery", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2372,65 +6051,17 @@ func (m *Commit) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
+			v := &InstanceQuery{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Ballot) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Ballot: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Ballot: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			m.Type = &Message_InstanceQuery{v}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceQueryReply", wireType)
 			}
-			m.Epoch = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2440,16 +6071,29 @@ func (m *Ballot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Epoch |= (uint64(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Number", wireType)
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
 			}
-			m.Number = 0
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &InstanceQueryReply{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_InstanceQueryReply{v}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TraceContext", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2459,16 +6103,26 @@ func (m *Ballot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Number |= (uint64(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TraceContext = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 16:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Hops", wireType)
 			}
-			m.ReplicaID = 0
+			m.Hops = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2478,66 +6132,16 @@ func (m *Ballot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				m.Hops |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Message) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Message: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field To", wireType)
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TryPreAccept", wireType)
 			}
-			m.To = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2547,14 +6151,27 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.To |= (ReplicaID(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &TryPreAccept{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_TryPreAccept{v}
+			iNdEx = postIndex
+		case 18:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TryPreAcceptReply", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2578,13 +6195,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			v := &TryPreAcceptReply{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
+			m.Type = &Message_TryPreAcceptReply{v}
 			iNdEx = postIndex
-		case 3:
+		case 19:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Nack", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2608,13 +6227,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			v := &Nack{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
+			m.Type = &Message_Nack{v}
 			iNdEx = postIndex
-		case 4:
+		case 20:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PreAccept", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ExecutedWatermark", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2638,15 +6259,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &PreAccept{}
+			v := &ExecutedWatermark{}
 			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_PreAccept{v}
+			m.Type = &Message_ExecutedWatermark{v}
 			iNdEx = postIndex
-		case 5:
+		case 21:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptOk", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field InstallSnapshot", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2670,15 +6291,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &PreAcceptOK{}
+			v := &InstallSnapshot{}
 			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_PreAcceptOk{v}
+			m.Type = &Message_InstallSnapshot{v}
 			iNdEx = postIndex
-		case 6:
+		case 22:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptReply", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseRequest", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2702,15 +6323,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &PreAcceptReply{}
+			v := &LeaseRequest{}
 			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_PreAcceptReply{v}
+			m.Type = &Message_LeaseRequest{v}
 			iNdEx = postIndex
-		case 7:
+		case 23:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseGrant", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2734,15 +6355,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &Accept{}
+			v := &LeaseGrant{}
 			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_Accept{v}
+			m.Type = &Message_LeaseGrant{v}
 			iNdEx = postIndex
-		case 8:
+		case 24:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AcceptOk", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaseInvalidate", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2766,15 +6387,15 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &AcceptOK{}
+			v := &LeaseInvalidate{}
 			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_AcceptOk{v}
+			m.Type = &Message_LeaseInvalidate{v}
 			iNdEx = postIndex
-		case 9:
+		case 25:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Commit", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CommittedRanges", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2798,11 +6419,11 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &Commit{}
+			v := &CommittedRanges{}
 			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_Commit{v}
+			m.Type = &Message_CommittedRanges{v}
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -2966,6 +6587,68 @@ func (m *InstanceState) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType == 0 {
+				var v ReplicaID
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (ReplicaID(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.CommitCert = append(m.CommitCert, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthEpaxos
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v ReplicaID
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (ReplicaID(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.CommitCert = append(m.CommitCert, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitCert", wireType)
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
@@ -3097,6 +6780,75 @@ func (m *HardState) Unmarshal(dAtA []byte) error {
 			} else {
 				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClusterID", wireType)
+			}
+			m.ClusterID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ClusterID |= (ClusterID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Truncated", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Truncated = append(m.Truncated, InstanceID{})
+			if err := m.Truncated[len(m.Truncated)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
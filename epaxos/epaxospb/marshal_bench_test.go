@@ -0,0 +1,76 @@
+package epaxospb
+
+import (
+	"testing"
+)
+
+// benchmarkMessage builds a representative PreAccept message carrying a
+// full command payload.
+func benchmarkMessage() Message {
+	cmd := &Command{
+		ID: 42,
+		Span: Span{
+			Key:    Key("a"),
+			EndKey: Key("z"),
+		},
+		Writing: true,
+		Data:    []byte("some representative command payload"),
+	}
+	m := WrapMessage(&PreAccept{
+		InstanceData: InstanceData{
+			Command: cmd,
+			SeqNum:  7,
+			Deps: []InstanceID{
+				{ReplicaID: 1, InstanceNum: 3},
+				{ReplicaID: 2, InstanceNum: 5},
+			},
+		},
+	})
+	m.To = 1
+	m.From = 0
+	m.InstanceID = InstanceID{ReplicaID: 0, InstanceNum: 4}
+	return m
+}
+
+func BenchmarkMessageMarshal(b *testing.B) {
+	m := benchmarkMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.Marshal(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMessageUnmarshal(b *testing.B) {
+	m := benchmarkMessage()
+	data, err := m.Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out Message
+		if err := out.Unmarshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMessageUnmarshalPooled(b *testing.B) {
+	m := benchmarkMessage()
+	data, err := m.Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out := GetMessage()
+		if err := out.Unmarshal(data); err != nil {
+			b.Fatal(err)
+		}
+		PutMessage(out)
+	}
+}
@@ -9,6 +9,10 @@ import (
 // ReplicaID is the id of a replica in an EPaxos deployment.
 type ReplicaID uint64
 
+// ClusterID is the id of an EPaxos deployment. It is used to detect a
+// replica accidentally started against the state of a different cluster.
+type ClusterID uint64
+
 // InstanceNum is the number of an instance slot in a replica's command array.
 type InstanceNum uint64
 
@@ -77,6 +81,30 @@ func WrapMessageInner(msg proto.Message) isMessage_Type {
 		return &Message_AcceptOk{AcceptOk: t}
 	case *Commit:
 		return &Message_Commit{Commit: t}
+	case *NeedCommand:
+		return &Message_NeedCommand{NeedCommand: t}
+	case *InstanceQuery:
+		return &Message_InstanceQuery{InstanceQuery: t}
+	case *InstanceQueryReply:
+		return &Message_InstanceQueryReply{InstanceQueryReply: t}
+	case *TryPreAccept:
+		return &Message_TryPreAccept{TryPreAccept: t}
+	case *TryPreAcceptReply:
+		return &Message_TryPreAcceptReply{TryPreAcceptReply: t}
+	case *Nack:
+		return &Message_Nack{Nack: t}
+	case *ExecutedWatermark:
+		return &Message_ExecutedWatermark{ExecutedWatermark: t}
+	case *InstallSnapshot:
+		return &Message_InstallSnapshot{InstallSnapshot: t}
+	case *LeaseRequest:
+		return &Message_LeaseRequest{LeaseRequest: t}
+	case *LeaseGrant:
+		return &Message_LeaseGrant{LeaseGrant: t}
+	case *LeaseInvalidate:
+		return &Message_LeaseInvalidate{LeaseInvalidate: t}
+	case *CommittedRanges:
+		return &Message_CommittedRanges{CommittedRanges: t}
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in WrapMessageInner", t))
 	}
@@ -94,6 +122,11 @@ func IsReply(t isMessage_Type) bool {
 	case *Message_PreAcceptOk:
 	case *Message_PreAcceptReply:
 	case *Message_AcceptOk:
+	case *Message_NeedCommand:
+	case *Message_InstanceQueryReply:
+	case *Message_TryPreAcceptReply:
+	case *Message_Nack:
+	case *Message_LeaseGrant:
 	default:
 		return false
 	}
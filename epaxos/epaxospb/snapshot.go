@@ -0,0 +1,57 @@
+package epaxospb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// WriteInstanceSnapshot writes the instance states to w as a stream of
+// varint-length-delimited marshaled InstanceState records. The format is
+// consumed by ReadInstanceSnapshot and by offline diagnostic tooling
+// like cmd/epaxos-analyze.
+func WriteInstanceSnapshot(w io.Writer, insts []InstanceState) error {
+	bw := bufio.NewWriter(w)
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i := range insts {
+		data, err := insts[i].Marshal()
+		if err != nil {
+			return err
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+		if _, err := bw.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadInstanceSnapshot reads a stream of varint-length-delimited
+// marshaled InstanceState records, as written by WriteInstanceSnapshot.
+func ReadInstanceSnapshot(r io.Reader) ([]InstanceState, error) {
+	br := bufio.NewReader(r)
+	var insts []InstanceState
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return insts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, errors.Wrap(err, "reading truncated snapshot record")
+		}
+		var is InstanceState
+		if err := is.Unmarshal(data); err != nil {
+			return nil, err
+		}
+		insts = append(insts, is)
+	}
+}
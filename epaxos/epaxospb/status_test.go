@@ -0,0 +1,51 @@
+package epaxospb
+
+import "testing"
+
+// TestInstanceStatusCodesStable pins the numeric code of every instance
+// status. The codes are persisted to stable storage with each instance,
+// so they must never change: reordering or renumbering the constants
+// would silently reinterpret data written by earlier versions. New
+// statuses may only be appended with fresh codes.
+func TestInstanceStatusCodesStable(t *testing.T) {
+	codes := map[InstanceState_Status]int32{
+		InstanceState_None:        0,
+		InstanceState_PreAccepted: 1,
+		InstanceState_Accepted:    2,
+		InstanceState_Committed:   3,
+		InstanceState_Executed:    4,
+	}
+	if len(codes) != len(InstanceState_Status_name) {
+		t.Fatalf("expected %d statuses, found %d; pin any new status here",
+			len(codes), len(InstanceState_Status_name))
+	}
+	for status, code := range codes {
+		if int32(status) != code {
+			t.Fatalf("expected status %s to have persisted code %d, found %d",
+				status, code, int32(status))
+		}
+	}
+}
+
+// TestInstanceStatusSurvivesRoundTrip verifies that each status comes
+// back unchanged from a marshal/unmarshal cycle of its InstanceState.
+func TestInstanceStatusSurvivesRoundTrip(t *testing.T) {
+	for code := range InstanceState_Status_name {
+		status := InstanceState_Status(code)
+		is := InstanceState{
+			InstanceID: InstanceID{ReplicaID: 1, InstanceNum: 2},
+			Status:     status,
+		}
+		data, err := is.Marshal()
+		if err != nil {
+			t.Fatalf("unexpected error marshaling state with status %s: %v", status, err)
+		}
+		var out InstanceState
+		if err := out.Unmarshal(data); err != nil {
+			t.Fatalf("unexpected error unmarshaling state with status %s: %v", status, err)
+		}
+		if out.Status != status {
+			t.Fatalf("expected status %s after round trip, found %s", status, out.Status)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package epaxostest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestClusterExecutionOrder proposes interfering commands from every
+// replica and verifies that they execute in the same order everywhere.
+func TestClusterExecutionOrder(t *testing.T) {
+	c := NewCluster(Config{Replicas: 3})
+	defer c.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const proposals = 9
+	for i := uint64(1); i <= proposals; i++ {
+		r := pb.ReplicaID(i % 3)
+		if err := c.Propose(ctx, r, WriteCommand(i, "a", "z")); err != nil {
+			t.Fatalf("unexpected proposal error: %v", err)
+		}
+	}
+	for i := uint64(1); i <= proposals; i++ {
+		if err := c.WaitExecuted(ctx, i); err != nil {
+			t.Fatalf("command %d not executed everywhere: %v", i, err)
+		}
+	}
+	c.AssertSameOrder(t)
+}
+
+// TestClusterWithLatency verifies that the cluster converges with
+// message delivery delayed, exercising the protocol under reordering.
+func TestClusterWithLatency(t *testing.T) {
+	c := NewCluster(Config{Replicas: 3, Latency: 2 * time.Millisecond})
+	defer c.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := uint64(1); i <= 3; i++ {
+		r := pb.ReplicaID(i % 3)
+		if err := c.Propose(ctx, r, WriteCommand(i, "a", "z")); err != nil {
+			t.Fatalf("unexpected proposal error: %v", err)
+		}
+	}
+	for i := uint64(1); i <= 3; i++ {
+		if err := c.WaitExecuted(ctx, i); err != nil {
+			t.Fatalf("command %d not executed everywhere: %v", i, err)
+		}
+	}
+	c.AssertSameOrder(t)
+}
@@ -0,0 +1,151 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Event is implemented by notable occurrences that the epaxos state
+// machine surfaces to the embedding application, such as operator-facing
+// warnings that do not fit into the normal Ready flow.
+type Event interface {
+	event()
+}
+
+// EventHandler is invoked by the epaxos state machine whenever it emits
+// an Event. Handlers must not block, as they are invoked from within the
+// state machine.
+type EventHandler func(Event)
+
+// PeerQuarantinedEvent is emitted when a peer is quarantined after
+// repeated protocol violations.
+type PeerQuarantinedEvent struct {
+	// Replica is the quarantined peer.
+	Replica pb.ReplicaID
+	// Violations is the number of protocol violations observed from the
+	// peer before it was quarantined.
+	Violations int
+}
+
+func (PeerQuarantinedEvent) event() {}
+
+// PeerUnquarantinedEvent is emitted when a peer's quarantine period
+// expires and its messages are accepted again.
+type PeerUnquarantinedEvent struct {
+	// Replica is the peer whose quarantine expired.
+	Replica pb.ReplicaID
+}
+
+func (PeerUnquarantinedEvent) event() {}
+
+// InstanceAbandonedEvent is emitted when an instance exceeds the
+// configured maximum number of ballot attempts and is parked instead of
+// retried further. It signals a possible persistent partition or bug
+// that the embedder must resolve; the protocol will make no more
+// progress on the instance on its own.
+type InstanceAbandonedEvent struct {
+	// InstanceID identifies the abandoned instance.
+	InstanceID pb.InstanceID
+	// Ballot is the ballot at which the instance was abandoned.
+	Ballot pb.Ballot
+}
+
+func (InstanceAbandonedEvent) event() {}
+
+// UnknownMessageEvent is emitted when a message with an unrecognized type
+// is received, typically from a peer running a newer version during a
+// rolling upgrade. The message is dropped rather than treated as an
+// error.
+type UnknownMessageEvent struct {
+	// From is the replica that sent the message.
+	From pb.ReplicaID
+	// InstanceID identifies the instance the message concerned.
+	InstanceID pb.InstanceID
+	// Raw is the re-encoded message, for diagnostics.
+	Raw []byte
+}
+
+func (UnknownMessageEvent) event() {}
+
+// InstanceQueryResultEvent is emitted when a peer answers an
+// InstanceQuery issued through QueryInstance.
+type InstanceQueryResultEvent struct {
+	// From is the replica that answered the query.
+	From pb.ReplicaID
+	// InstanceID identifies the queried instance.
+	InstanceID pb.InstanceID
+	// State is the peer's view of the instance, or nil if the peer has
+	// no record of it.
+	State *pb.InstanceState
+}
+
+func (InstanceQueryResultEvent) event() {}
+
+// TimeJumpedEvent is emitted when the process missed so many ticks (GC
+// pause, VM steal) that catching up on all of them was abandoned. The
+// dropped ticks are never delivered to the state machine's timers.
+type TimeJumpedEvent struct {
+	// SkippedTicks is the number of missed ticks that were dropped.
+	SkippedTicks int
+}
+
+func (TimeJumpedEvent) event() {}
+
+// ExecutionCorruptionEvent is emitted when a strongly connected component
+// of committed instances fails verification before execution, indicating
+// corrupted or inconsistent committed state. The replica halts rather than
+// executing commands in an order that could diverge from its peers.
+type ExecutionCorruptionEvent struct {
+	// Reason describes the verification failure.
+	Reason string
+}
+
+func (ExecutionCorruptionEvent) event() {}
+
+// InstanceOverwriteEvent is emitted when an instance insertion finds its
+// slot in the command space already held by an instance with a different
+// command or ballot. The occupant is kept, since overwriting it could
+// silently discard agreed state; the event surfaces the corruption that
+// led two instances to claim the same slot.
+type InstanceOverwriteEvent struct {
+	// InstanceID identifies the contested instance slot.
+	InstanceID pb.InstanceID
+}
+
+func (InstanceOverwriteEvent) event() {}
+
+// SpeculativeReplyEvent is emitted when a locally led proposal marked
+// Speculative reaches a PreAccept quorum with its attributes unchanged,
+// before the instance commits. The application may answer the client
+// early on its strength; a SpeculativeResolvedEvent follows at commit
+// time to confirm or abort the speculation.
+type SpeculativeReplyEvent struct {
+	// Command is the speculatively acknowledged command.
+	Command *pb.Command
+}
+
+func (SpeculativeReplyEvent) event() {}
+
+// SpeculativeResolvedEvent is emitted when a speculatively acknowledged
+// proposal commits, resolving the earlier SpeculativeReplyEvent.
+type SpeculativeResolvedEvent struct {
+	// Command is the command whose speculation resolved.
+	Command *pb.Command
+	// Confirmed is true if the command committed with the attributes it
+	// was speculatively acknowledged with, and false if later replies
+	// changed them, in which case the speculative reply must be treated
+	// as aborted.
+	Confirmed bool
+}
+
+func (SpeculativeResolvedEvent) event() {}
+
+// emitEvent delivers the event to the application's event handler, if one
+// is registered.
+func (p *epaxos) emitEvent(e Event) {
+	if p.replaying() && p.replay.opts.SkipEvents {
+		return
+	}
+	if p.onEvent != nil {
+		p.onEvent(e)
+	}
+}
@@ -1,6 +1,7 @@
 package epaxos
 
 import (
+	"fmt"
 	"sort"
 )
 
@@ -27,6 +28,15 @@ type executable interface {
 	Execute()
 }
 
+// originated is optionally implemented by executables that can report
+// which replica proposed them. The origin feeds the executor's fairness
+// metrics and, when fair interleaving is enabled, the round-robin
+// scheduling of large strongly connected components. Executables without
+// an origin are treated as sharing one.
+type originated interface {
+	origin() executableID
+}
+
 // history can answer the question of whether an executable has already been
 // executed or not.
 type history interface {
@@ -41,11 +51,40 @@ type history interface {
 type executor struct {
 	h history
 
+	// onExecutedSCC, if set, is called with the size of each strongly
+	// connected component that the executor successfully executes.
+	onExecutedSCC func(size int)
+
+	// onCorruption, if set, is called when a strongly connected component
+	// fails verification before execution, instead of the component being
+	// executed in an arbitrary (and so potentially divergent) order.
+	onCorruption func(reason string)
+
+	// onSCCFairness, if set, is called for each executed strongly
+	// connected component with the number of distinct origins among its
+	// executables and the size of the largest single-origin contribution.
+	onSCCFairness func(origins, dominant int)
+
+	// fairInterleave interleaves the execution of each strongly connected
+	// component round-robin across the origins of its executables, instead
+	// of running one origin's executables back to back.
+	fairInterleave bool
+
+	// onSchedule, if set, is called with each strongly connected
+	// component in its final execution order, just before it executes.
+	onSchedule func(ordered []executable)
+
 	// values scoped to a single run of the executor's tarjan's strongly
 	// connected components algorithm.
-	vertices   map[executableID]*tarjanNode
+	vertices map[executableID]*tarjanNode
+	// order holds the identifiers of all executables in insertion order.
+	// The graph is always traversed in this order so that, for a given
+	// sequence of addExec calls, the executor's behavior is fully
+	// deterministic and reproducible.
+	order      []executableID
 	index      int
 	stack      []*tarjanNode
+	frames     []tarjanFrame
 	components []scc
 }
 
@@ -77,12 +116,32 @@ func (e *executor) addExecs(execs ...executable) {
 }
 
 func (e *executor) addExec(exec executable) {
-	e.vertices[exec.Identifier()] = &tarjanNode{exec: exec}
+	id := exec.Identifier()
+	if _, ok := e.vertices[id]; !ok {
+		e.order = append(e.order, id)
+	}
+	e.vertices[id] = &tarjanNode{exec: exec}
+}
+
+// removeIf drops all pending executables whose identifiers match the
+// provided predicate, for use when another mechanism (e.g. an installed
+// snapshot) has superseded their execution.
+func (e *executor) removeIf(pred func(executableID) bool) {
+	filtered := e.order[:0]
+	for _, id := range e.order {
+		if pred(id) {
+			delete(e.vertices, id)
+		} else {
+			filtered = append(filtered, id)
+		}
+	}
+	e.order = filtered
 }
 
 func (e *executor) reset() {
 	e.index = 0
 	e.stack = e.stack[:0]
+	e.frames = e.frames[:0]
 	e.components = e.components[:0]
 }
 
@@ -123,7 +182,17 @@ func (comp scc) contains(v *tarjanNode) bool {
 // strongConnect runs the Tarjan's strongly connected components algorithm,
 // returning an ordered slice of strongly connected components.
 func (e *executor) strongConnect() []scc {
-	for _, v := range e.vertices {
+	// Drop identifiers of executables that have since been executed.
+	live := e.order[:0]
+	for _, id := range e.order {
+		if _, ok := e.vertices[id]; ok {
+			live = append(live, id)
+		}
+	}
+	e.order = live
+
+	for _, id := range e.order {
+		v := e.vertices[id]
 		v.reset()
 
 		for _, dep := range v.exec.Dependencies() {
@@ -133,8 +202,8 @@ func (e *executor) strongConnect() []scc {
 		}
 	}
 
-	for _, v := range e.vertices {
-		if !v.visited() {
+	for _, id := range e.order {
+		if v := e.vertices[id]; !v.visited() {
 			e.visit(v)
 		}
 	}
@@ -153,34 +222,71 @@ func (e *executor) pop() *tarjanNode {
 	return n
 }
 
-func (e *executor) visit(v *tarjanNode) {
-	v.index = e.index
-	v.lowlink = e.index
-	e.index++
+// tarjanFrame is one frame of the executor's explicit depth-first search
+// stack. It tracks how many of a node's dependency edges have already been
+// examined, so that the search can resume the node after descending into a
+// dependency.
+type tarjanFrame struct {
+	v    *tarjanNode
+	next int
+}
 
-	v.onStack = true
-	e.push(v)
+// visit performs the depth-first search of Tarjan's algorithm from the
+// provided root. The search is iterative, using an explicit stack of frames
+// instead of recursion, so that arbitrarily long dependency chains cannot
+// overflow the goroutine stack.
+func (e *executor) visit(root *tarjanNode) {
+	e.discover(root)
+	e.frames = append(e.frames, tarjanFrame{v: root})
+
+	for len(e.frames) > 0 {
+		f := &e.frames[len(e.frames)-1]
+		v := f.v
+
+		if f.next < len(v.depNodes) {
+			w := v.depNodes[f.next]
+			f.next++
+			if !w.visited() {
+				e.discover(w)
+				e.frames = append(e.frames, tarjanFrame{v: w})
+			} else if w.onStack {
+				v.lowlink = min(v.lowlink, w.index)
+			}
+			continue
+		}
 
-	for _, w := range v.depNodes {
-		if !w.visited() {
-			e.visit(w)
-			v.lowlink = min(v.lowlink, w.lowlink)
-		} else if w.onStack {
-			v.lowlink = min(v.lowlink, w.index)
+		// All of v's dependency edges have been examined.
+		if v.lowlink == v.index {
+			var component scc
+			for w := (*tarjanNode)(nil); w != v; {
+				w = e.pop()
+				w.onStack = false
+				component = append(component, w)
+			}
+			e.components = append(e.components, component)
 		}
-	}
 
-	if v.lowlink == v.index {
-		var component scc
-		for w := (*tarjanNode)(nil); w != v; {
-			w = e.pop()
-			w.onStack = false
-			component = append(component, w)
+		e.frames = e.frames[:len(e.frames)-1]
+		if len(e.frames) > 0 {
+			// Fold v's lowlink into its parent, exactly as the recursive
+			// formulation does when a recursive call returns.
+			parent := e.frames[len(e.frames)-1].v
+			parent.lowlink = min(parent.lowlink, v.lowlink)
 		}
-		e.components = append(e.components, component)
 	}
 }
 
+// discover assigns the node its depth-first search index and pushes it onto
+// the strongly connected component stack.
+func (e *executor) discover(v *tarjanNode) {
+	v.index = e.index
+	v.lowlink = e.index
+	e.index++
+
+	v.onStack = true
+	e.push(v)
+}
+
 func (e *executor) executeSCC(comp scc) {
 	for _, v := range comp {
 		for _, dep := range v.exec.Dependencies() {
@@ -200,17 +306,120 @@ func (e *executor) executeSCC(comp scc) {
 		}
 	}
 
+	// Verify that the component's internal execution order is well defined
+	// before committing to it. If it is not, executing in whatever order the
+	// sort happens to produce could diverge from other replicas.
+	if reason, ok := verifySCCOrder(comp); !ok {
+		if e.onCorruption != nil {
+			e.onCorruption(reason)
+		}
+		return
+	}
+
+	if e.onExecutedSCC != nil {
+		e.onExecutedSCC(len(comp))
+	}
+
 	// Sort the component based on SCC execution order.
 	sort.Slice(comp, func(i, j int) bool {
 		return comp[i].exec.ExecutesBefore(comp[j].exec)
 	})
 
+	if e.onSCCFairness != nil {
+		e.onSCCFairness(originSpread(comp))
+	}
+	if e.fairInterleave {
+		comp = interleaveByOrigin(comp)
+	}
+
+	if e.onSchedule != nil {
+		ordered := make([]executable, len(comp))
+		for i, v := range comp {
+			ordered[i] = v.exec
+		}
+		e.onSchedule(ordered)
+	}
+
 	// Execute each executable in the SCC, in-order.
 	for _, v := range comp {
 		e.execute(v.exec)
 	}
 }
 
+// execOrigin returns the origin of the executable, or nil if it does not
+// report one.
+func execOrigin(exec executable) executableID {
+	if o, ok := exec.(originated); ok {
+		return o.origin()
+	}
+	return nil
+}
+
+// originSpread returns the number of distinct origins among the
+// component's executables and the size of the largest single-origin
+// contribution.
+func originSpread(comp scc) (origins, dominant int) {
+	counts := make(map[executableID]int)
+	for _, v := range comp {
+		counts[execOrigin(v.exec)]++
+	}
+	for _, c := range counts {
+		if c > dominant {
+			dominant = c
+		}
+	}
+	return len(counts), dominant
+}
+
+// interleaveByOrigin reorders the sorted component round-robin across the
+// origins of its executables, preserving each origin's internal order.
+// Origins take turns in order of their first appearance in the sorted
+// component, so the reordering is a deterministic function of the sorted
+// order and replicas applying it agree on the result.
+func interleaveByOrigin(comp scc) scc {
+	queues := make(map[executableID][]*tarjanNode)
+	var originOrder []executableID
+	for _, v := range comp {
+		o := execOrigin(v.exec)
+		if _, ok := queues[o]; !ok {
+			originOrder = append(originOrder, o)
+		}
+		queues[o] = append(queues[o], v)
+	}
+	if len(originOrder) <= 1 {
+		return comp
+	}
+	interleaved := make(scc, 0, len(comp))
+	for len(interleaved) < len(comp) {
+		for _, o := range originOrder {
+			if q := queues[o]; len(q) > 0 {
+				interleaved = append(interleaved, q[0])
+				queues[o] = q[1:]
+			}
+		}
+	}
+	return interleaved
+}
+
+// verifySCCOrder verifies that ExecutesBefore defines a strict total order
+// over the provided strongly connected component, so that sorting the
+// component produces the same execution order on every replica. If any pair
+// of executables is ordered ambiguously (both before each other, or neither
+// before the other), it returns a description of the violation and false.
+func verifySCCOrder(comp scc) (string, bool) {
+	for i, v := range comp {
+		for _, w := range comp[i+1:] {
+			vw := v.exec.ExecutesBefore(w.exec)
+			wv := w.exec.ExecutesBefore(v.exec)
+			if vw == wv {
+				return fmt.Sprintf("ambiguous execution order between %v and %v",
+					v.exec.Identifier(), w.exec.Identifier()), false
+			}
+		}
+	}
+	return "", true
+}
+
 func (e *executor) execute(exec executable) {
 	delete(e.vertices, exec.Identifier())
 	exec.Execute()
@@ -299,3 +299,151 @@ func TestExecuteStronglyConnectedComponent(t *testing.T) {
 		})
 	}
 }
+
+// ambiguousExecNode is an execNode whose ExecutesBefore never orders it
+// relative to any other executable, producing an ambiguous SCC order.
+type ambiguousExecNode struct {
+	execNode
+}
+
+func (n ambiguousExecNode) ExecutesBefore(executable) bool { return false }
+
+// TestExecuteSCCAmbiguousOrder verifies that an SCC whose executables cannot
+// be totally ordered is not executed and is reported as corruption.
+func TestExecuteSCCAmbiguousOrder(t *testing.T) {
+	e := makeExecutor(&emptyHistory{})
+	var corruption string
+	e.onCorruption = func(reason string) { corruption = reason }
+
+	var executed []int
+	onExecute := func(id int) { executed = append(executed, id) }
+	e.addExecs(
+		ambiguousExecNode{execNode{id: 4, deps: []int{9}, onExecute: onExecute}},
+		ambiguousExecNode{execNode{id: 9, deps: []int{4}, onExecute: onExecute}},
+	)
+
+	comps := e.strongConnect()
+	if l := len(comps); l != 1 {
+		t.Fatalf("expected single SCC, found %d", l)
+	}
+	e.executeSCC(comps[0])
+	if executed != nil {
+		t.Errorf("expected no execution for ambiguous SCC, found %v", executed)
+	}
+	if corruption == "" {
+		t.Errorf("expected corruption to be reported for ambiguous SCC")
+	}
+}
+
+// originExecNode is an execNode that reports an origin, standing in for
+// an instance proposed by a particular replica.
+type originExecNode struct {
+	execNode
+	org int
+}
+
+func (n originExecNode) origin() executableID { return n.org }
+func (n originExecNode) ExecutesBefore(b executable) bool {
+	return n.id < b.(originExecNode).id
+}
+
+// TestExecuteSCCFairInterleave verifies that with fair interleaving
+// enabled, a strongly connected component executes round-robin across
+// its executables' origins, preserving each origin's internal order, and
+// that the executor reports the component's origin spread.
+func TestExecuteSCCFairInterleave(t *testing.T) {
+	for _, fair := range []bool{false, true} {
+		e := makeExecutor(&emptyHistory{})
+		e.fairInterleave = fair
+
+		var origins, dominant int
+		e.onSCCFairness = func(o, d int) { origins, dominant = o, d }
+
+		var executed []int
+		onExecute := func(id int) { executed = append(executed, id) }
+
+		// Origin 1 contributes three executables, origin 2 contributes two.
+		// The dependencies form a single cycle spanning all of them.
+		e.addExecs(
+			originExecNode{execNode{id: 1, deps: []int{2}, onExecute: onExecute}, 1},
+			originExecNode{execNode{id: 2, deps: []int{3}, onExecute: onExecute}, 1},
+			originExecNode{execNode{id: 3, deps: []int{10}, onExecute: onExecute}, 1},
+			originExecNode{execNode{id: 10, deps: []int{11}, onExecute: onExecute}, 2},
+			originExecNode{execNode{id: 11, deps: []int{1}, onExecute: onExecute}, 2},
+		)
+
+		comps := e.strongConnect()
+		if l := len(comps); l != 1 {
+			t.Fatalf("expected single SCC, found %d", l)
+		}
+		e.executeSCC(comps[0])
+
+		exp := []int{1, 2, 3, 10, 11}
+		if fair {
+			exp = []int{1, 10, 2, 11, 3}
+		}
+		if !reflect.DeepEqual(executed, exp) {
+			t.Errorf("fair=%t: expected execution order %v, found %v", fair, exp, executed)
+		}
+		if origins != 2 || dominant != 3 {
+			t.Errorf("fair=%t: expected origin spread (2, 3), found (%d, %d)", fair, origins, dominant)
+		}
+	}
+}
+
+// TestStrongConnectDeterministicOrder verifies that the executor visits its
+// graph in insertion order, so that components with no ordering constraints
+// between them are still returned in a deterministic, reproducible order.
+func TestStrongConnectDeterministicOrder(t *testing.T) {
+	ids := []int{3, 1, 4, 2}
+	for run := 0; run < 10; run++ {
+		e := makeExecutor(&emptyHistory{})
+		for _, id := range ids {
+			e.addExec(execNode{id: id})
+		}
+		comps := e.strongConnect()
+		if len(comps) != len(ids) {
+			t.Fatalf("expected %d components, found %d", len(ids), len(comps))
+		}
+		for i, comp := range comps {
+			if len(comp) != 1 || comp[0].exec.(execNode).id != ids[i] {
+				t.Fatalf("expected components in insertion order %v, found %v at %d",
+					ids, comp[0].exec.(execNode).id, i)
+			}
+		}
+		e.reset()
+	}
+}
+
+// TestStrongConnectDeepChain verifies that the executor handles dependency
+// chains far deeper than any recursion limit, since the depth-first search
+// uses an explicit stack rather than the goroutine stack.
+func TestStrongConnectDeepChain(t *testing.T) {
+	const depth = 500000
+
+	var executed []int
+	hs := make(historySet)
+	onExecute := func(id int) {
+		executed = append(executed, id)
+		hs.SetExecuted(id)
+	}
+
+	e := makeExecutor(hs)
+	for id := 0; id < depth; id++ {
+		n := execNode{id: id, onExecute: onExecute}
+		if id < depth-1 {
+			n.deps = []int{id + 1}
+		}
+		e.addExec(n)
+	}
+	e.run()
+
+	if len(executed) != depth {
+		t.Fatalf("expected %d executed executables, found %d", depth, len(executed))
+	}
+	for i, id := range executed {
+		if exp := depth - 1 - i; id != exp {
+			t.Fatalf("expected executable %d at position %d, found %d", exp, i, id)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package epaxos
+
+import (
+	"sort"
+
+	"github.com/google/btree"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// LogEntry is a single entry in the linearized view of a node's executed
+// command sequence. Entries are assigned contiguous, monotonically
+// increasing indexes in execution order, mirroring the shape of a Raft
+// entry log so that tooling built around linear logs (backup, replay,
+// audit) can consume EPaxos output.
+type LogEntry struct {
+	// Index is the position of the entry in the linearized log, starting
+	// at 1 for the first executed command.
+	Index uint64
+	// InstanceID identifies the instance that the command was ordered in.
+	InstanceID pb.InstanceID
+	// Command is the executed command.
+	Command pb.Command
+}
+
+// recordExecuted appends the instance to the node's linearized executed
+// log, assigning it the next log index and trimming the log to the
+// configured retention bound. A no-op when the log is disabled.
+func (p *epaxos) recordExecuted(inst *instance) {
+	if p.executedLogSize == 0 {
+		return
+	}
+	p.executedLogIndex++
+	p.executedLog = append(p.executedLog, LogEntry{
+		Index:      p.executedLogIndex,
+		InstanceID: inst.is.InstanceID,
+		Command:    *inst.is.Command,
+	})
+	if over := len(p.executedLog) - p.executedLogSize; over > 0 {
+		p.executedLog = append(p.executedLog[:0:0], p.executedLog[over:]...)
+	}
+}
+
+// ExportInstanceSpace returns a copy of the state of every instance the
+// node knows about, across all replicas' command spaces. The snapshot
+// can be written out with pb.WriteInstanceSnapshot for offline analysis.
+func (p *epaxos) ExportInstanceSpace() []pb.InstanceState {
+	var insts []pb.InstanceState
+	for _, r := range p.nodes {
+		p.commands[r].Ascend(func(i btree.Item) bool {
+			insts = append(insts, i.(*instance).is)
+			return true
+		})
+	}
+	return insts
+}
+
+// ExportEntries returns a copy of all retained entries in the
+// linearized executed log with indexes greater than after. Passing 0
+// exports every retained entry; entries trimmed past the
+// Config.ExecutedLogSize retention bound are gone.
+func (p *epaxos) ExportEntries(after uint64) []LogEntry {
+	i := sort.Search(len(p.executedLog), func(i int) bool {
+		return p.executedLog[i].Index > after
+	})
+	if i == len(p.executedLog) {
+		return nil
+	}
+	entries := make([]LogEntry, len(p.executedLog)-i)
+	copy(entries, p.executedLog[i:])
+	return entries
+}
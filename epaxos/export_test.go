@@ -0,0 +1,85 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestExportEntries verifies that executed commands are exported as a
+// contiguous, index-ordered log and that interfering workloads produce
+// identical logs on all replicas.
+func TestExportEntries(t *testing.T) {
+	n := newNetwork(5)
+	for _, peer := range n.peers {
+		peer.executedLogSize = 16
+	}
+
+	var insts []*instance
+	for _, peer := range n.peers {
+		cmd := newTestingCommand("a", "z")
+		inst := peer.onRequest(cmd)
+		insts = append(insts, inst)
+	}
+	for _, inst := range insts {
+		if !n.waitExecuteInstance(inst, false /* all nodes */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+	}
+
+	peer0 := n.peers[0]
+	entries := peer0.ExportEntries(0)
+	if len(entries) != len(insts) {
+		t.Fatalf("expected %d exported entries, found %d", len(insts), len(entries))
+	}
+	for i, entry := range entries {
+		if exp := uint64(i) + 1; entry.Index != exp {
+			t.Errorf("expected entry index %d, found %d", exp, entry.Index)
+		}
+	}
+
+	// All commands interfere, so every replica must export an identical log.
+	for i, peer := range n.peers {
+		if otherEntries := peer.ExportEntries(0); !reflect.DeepEqual(entries, otherEntries) {
+			t.Errorf("peer %d: exported logs differ: %+v vs %+v", i, entries, otherEntries)
+		}
+	}
+
+	// Exporting from a non-zero index returns only the suffix.
+	if suffix := peer0.ExportEntries(entries[0].Index); len(suffix) != len(entries)-1 {
+		t.Errorf("expected %d suffix entries, found %d", len(entries)-1, len(suffix))
+	}
+	if suffix := peer0.ExportEntries(entries[len(entries)-1].Index); suffix != nil {
+		t.Errorf("expected empty suffix, found %+v", suffix)
+	}
+}
+
+// TestExportEntriesRetention verifies that the executed log is off by
+// default and, when enabled, is trimmed to the configured bound while
+// its indexes keep advancing.
+func TestExportEntriesRetention(t *testing.T) {
+	// Without ExecutedLogSize, nothing is retained.
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}})
+	p.Request(newTestingCommand("a", "z"))
+	if entries := p.ExportEntries(0); entries != nil {
+		t.Fatalf("expected no entries with the log disabled, found %+v", entries)
+	}
+
+	p = newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}, ExecutedLogSize: 2})
+	for i := 0; i < 4; i++ {
+		p.Request(newTestingCommand("a", "z"))
+	}
+	entries := p.ExportEntries(0)
+	if len(entries) != 2 {
+		t.Fatalf("expected the log trimmed to 2 entries, found %+v", entries)
+	}
+	for i, entry := range entries {
+		if exp := uint64(3 + i); entry.Index != exp {
+			t.Errorf("expected entry index %d, found %d", exp, entry.Index)
+		}
+	}
+	if suffix := p.ExportEntries(3); len(suffix) != 1 || suffix[0].Index != 4 {
+		t.Errorf("expected only entry 4 after index 3, found %+v", suffix)
+	}
+}
@@ -0,0 +1,53 @@
+package epaxos
+
+import "fmt"
+
+// This file implements the PanicFree mode, which turns protocol
+// violations into a failed-replica state instead of crashing the
+// process. By default an internal invariant failure — an unmapped state
+// transition, an instance observed in a state its caller ruled out, a
+// bulk replay started over another — panics through the owning
+// subsystem's logger, on the theory that a replica whose invariants no
+// longer hold cannot be trusted to keep participating. That theory is
+// sound for a dedicated consensus process, but when epaxos is embedded
+// alongside other services the panic takes the whole host down with it.
+// Under Config.PanicFree the same violations instead mark the replica
+// as failed: it halts, emits a ReplicaFailedEvent, and Node.Step
+// returns ErrReplicaFailed, leaving the colocated services running.
+//
+// Only invariant violations are routed this way. The remaining panics
+// in the package guard conditions that failing cannot make safe or that
+// no mode should paper over: construction-time misuse (newEPaxos config
+// validation, RestartNode without persisted state, DiffStatus argument
+// checks), storage corruption surfaced by encryptedStorage, and
+// impossible marshaling or interval-tree errors. Those keep panicking
+// regardless of the mode.
+
+// ReplicaFailedEvent is emitted when a protocol violation marks the
+// replica as failed under Config.PanicFree.
+type ReplicaFailedEvent struct {
+	// Reason describes the violation, in the words the panic would have
+	// used.
+	Reason string
+}
+
+func (ReplicaFailedEvent) event() {}
+
+// failReplica reports a protocol violation. By default it panics
+// through the provided subsystem logger, preserving the historical
+// behavior. Under PanicFree it instead marks the replica as failed:
+// the replica halts, so proposals and messages are ignored from here
+// on, and a ReplicaFailedEvent carries the violation to the operator.
+// Callers must return immediately after calling failReplica; in
+// PanicFree mode control flow continues past the violation.
+func (p *epaxos) failReplica(l Logger, format string, args ...interface{}) {
+	if !p.panicFree {
+		l.Panicf(format, args...)
+		return
+	}
+	reason := fmt.Sprintf(format, args...)
+	l.Errorf("marking replica as failed: %s", reason)
+	p.emitEvent(ReplicaFailedEvent{Reason: reason})
+	p.failed = true
+	p.halted = true
+}
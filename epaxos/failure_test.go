@@ -0,0 +1,73 @@
+package epaxos
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestProtocolViolationPanicsByDefault verifies that without PanicFree,
+// a protocol violation still panics through the subsystem logger.
+func TestProtocolViolationPanicsByDefault(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	p.Request(newTestingCommand("a", "z"))
+	inst := p.getInstance(0, 1)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic from an unmapped state transition")
+		}
+	}()
+	inst.transitionTo(pb.InstanceState_Executed)
+}
+
+// TestPanicFreeMarksReplicaFailed verifies that under PanicFree, a
+// protocol violation marks the replica as failed instead of panicking:
+// a ReplicaFailedEvent is emitted and subsequent proposals and messages
+// are ignored.
+func TestPanicFreeMarksReplicaFailed(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:        0,
+		Nodes:     []pb.ReplicaID{0, 1, 2},
+		PanicFree: true,
+		OnEvent:   func(e Event) { events = append(events, e) },
+	})
+	p.Request(newTestingCommand("a", "z"))
+	inst := p.getInstance(0, 1)
+	p.clearMsgs()
+
+	// PreAccepted -> Executed is not a mapped transition.
+	inst.transitionTo(pb.InstanceState_Executed)
+	if !p.failed || !p.halted {
+		t.Fatalf("expected the violation to fail and halt the replica; failed=%t halted=%t",
+			p.failed, p.halted)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected a ReplicaFailedEvent, found %v", events)
+	}
+	if e := events[0].(ReplicaFailedEvent); e.Reason == "" {
+		t.Fatalf("expected the event to carry a reason, found %+v", e)
+	}
+
+	// The failed replica ignores further proposals and messages.
+	p.Request(newTestingCommand("a", "z"))
+	if inst := p.getInstance(0, 2); inst != nil {
+		t.Fatalf("expected proposal on failed replica ignored, found %+v", inst.is)
+	}
+	if len(p.msgs) != 0 {
+		t.Fatalf("expected no messages from a failed replica, found %v", p.msgs)
+	}
+}
+
+// TestStepOnFailedReplica verifies that Node.Step reports a failed
+// replica as ErrReplicaFailed instead of silently enqueuing the
+// message.
+func TestStepOnFailedReplica(t *testing.T) {
+	n := makeNode()
+	close(n.failedc)
+	if err := n.Step(context.Background(), pb.Message{}); err != ErrReplicaFailed {
+		t.Fatalf("expected ErrReplicaFailed from a failed node, found %v", err)
+	}
+}
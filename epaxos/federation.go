@@ -0,0 +1,77 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// ExecutionHooks wraps instance execution in a two-phase protocol so that
+// an external coordinator can build cross-cluster transactions spanning
+// multiple epaxos groups, without this library knowing about the other
+// clusters. PrepareExecute is invoked once a command's execution order is
+// fixed but before its effects are delivered; returning false parks the
+// command, and every command ordered after it, until the coordinator
+// calls FinalizeExecution. Hooks must not block, as they are invoked from
+// within the state machine.
+type ExecutionHooks interface {
+	// PrepareExecute is invoked immediately before the command's effects
+	// would be delivered. Returning false parks the command; execution
+	// output is held, in order, until FinalizeExecution is called with
+	// the command's ID.
+	PrepareExecute(cmd pb.Command) bool
+	// FinalizeExecute is invoked immediately after the command's effects
+	// have been delivered.
+	FinalizeExecute(cmd pb.Command)
+}
+
+// executionHoldState holds a command parked by PrepareExecute, along with
+// the commands queued in order behind it.
+type executionHoldState struct {
+	pending *pb.Command
+	queued  []pb.Command
+}
+
+// holdExecution routes the command through the execution hooks, if any.
+// It returns true if the command was parked (or queued behind a parked
+// command) and must not be delivered yet.
+func (p *epaxos) holdExecution(cmd pb.Command) bool {
+	if p.execHooks == nil {
+		return false
+	}
+	if p.execHold.pending != nil {
+		// A parked command holds every later command behind it to
+		// preserve execution order.
+		p.execHold.queued = append(p.execHold.queued, cmd)
+		return true
+	}
+	if !p.execHooks.PrepareExecute(cmd) {
+		p.execHold.pending = &cmd
+		return true
+	}
+	return false
+}
+
+// FinalizeExecution releases the command parked by PrepareExecute,
+// delivering it and then any commands queued in order behind it. Queued
+// commands are themselves offered to PrepareExecute and may park again.
+// It returns false if no command with the provided ID is parked.
+func (p *epaxos) FinalizeExecution(id uint64) bool {
+	hold := &p.execHold
+	if hold.pending == nil || hold.pending.ID != id {
+		return false
+	}
+	cmd := *hold.pending
+	hold.pending = nil
+	p.deliverPreparedCommand(cmd)
+
+	queued := hold.queued
+	hold.queued = nil
+	for i := range queued {
+		if hold.pending != nil {
+			// A queued command parked; the rest wait behind it again.
+			hold.queued = append(queued[i:], hold.queued...)
+			return true
+		}
+		p.deliverExecutedCommand(queued[i])
+	}
+	return true
+}
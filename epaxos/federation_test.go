@@ -0,0 +1,73 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// parkingHooks parks a single command by ID and records each finalized
+// command, in order.
+type parkingHooks struct {
+	park      uint64
+	finalized []uint64
+}
+
+func (h *parkingHooks) PrepareExecute(cmd pb.Command) bool { return cmd.ID != h.park }
+func (h *parkingHooks) FinalizeExecute(cmd pb.Command) {
+	h.finalized = append(h.finalized, cmd.ID)
+}
+
+// TestExecutionHooksParkAndFinalize verifies that a command parked by
+// PrepareExecute holds itself and every later command until the
+// coordinator finalizes it, after which delivery resumes in order.
+func TestExecutionHooksParkAndFinalize(t *testing.T) {
+	hooks := &parkingHooks{}
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}, ExecutionHooks: hooks})
+
+	cmd1 := newTestingCommand("a", "m")
+	cmd2 := newTestingCommand("a", "z")
+	hooks.park = cmd1.ID
+
+	p.Request(cmd1)
+	p.Request(cmd2)
+	if l := len(p.executedCmds); l != 0 {
+		t.Fatalf("expected no delivery while parked, found %d commands", l)
+	}
+	if q := len(p.execHold.queued); q != 1 {
+		t.Fatalf("expected 1 command queued behind the parked command, found %d", q)
+	}
+
+	if p.FinalizeExecution(cmd2.ID) {
+		t.Errorf("expected finalize of unparked command to report false")
+	}
+	if !p.FinalizeExecution(cmd1.ID) {
+		t.Fatalf("expected finalize of parked command to report true")
+	}
+
+	if l := len(p.executedCmds); l != 2 {
+		t.Fatalf("expected both commands delivered after finalize, found %d", l)
+	}
+	if p.executedCmds[0].ID != cmd1.ID || p.executedCmds[1].ID != cmd2.ID {
+		t.Errorf("expected delivery order [%d %d], found %+v", cmd1.ID, cmd2.ID, p.executedCmds)
+	}
+	if len(hooks.finalized) != 2 || hooks.finalized[0] != cmd1.ID || hooks.finalized[1] != cmd2.ID {
+		t.Errorf("expected finalize order [%d %d], found %v", cmd1.ID, cmd2.ID, hooks.finalized)
+	}
+}
+
+// TestExecutionHooksPassThrough verifies that commands the prepare phase
+// accepts are delivered immediately and finalized.
+func TestExecutionHooksPassThrough(t *testing.T) {
+	hooks := &parkingHooks{}
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}, ExecutionHooks: hooks})
+
+	cmd := newTestingCommand("a", "z")
+	p.Request(cmd)
+	if l := len(p.executedCmds); l != 1 {
+		t.Fatalf("expected immediate delivery, found %d commands", l)
+	}
+	if len(hooks.finalized) != 1 || hooks.finalized[0] != cmd.ID {
+		t.Errorf("expected command finalized, found %v", hooks.finalized)
+	}
+}
@@ -0,0 +1,107 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Automatic garbage collection via executed-watermark exchange.
+//
+// Replicated compaction commands (see truncate.go) let the application
+// declare truncation points explicitly. The watermark exchange automates
+// the same reclamation: each replica periodically advertises its executed
+// watermark to its peers, and instances below the cluster-wide minimum of
+// the advertised watermarks have been executed on every replica, so no
+// future message can legitimately concern them and they can be pruned. A
+// configurable retention margin keeps a window of recently executed
+// instances below the minimum, for debugging and straggler queries.
+//
+// Unlike compaction commands, the advertisements are not themselves
+// agreed; each replica prunes independently once its own view of every
+// peer's watermark permits it. A replica that has not been heard from
+// holds GC back entirely, so a down replica pauses reclamation until it
+// recovers or is removed from the cluster.
+
+// broadcastExecutedWatermark advertises the local executed watermark to
+// every peer.
+func (p *epaxos) broadcastExecutedWatermark() {
+	wm := &pb.ExecutedWatermark{Executed: watermarkSlice(p.executedPrefix)}
+	for _, node := range p.nodes {
+		if node != p.id {
+			p.msgs = append(p.msgs, pb.Message{
+				To:   node,
+				From: p.id,
+				Type: pb.WrapMessageInner(wm),
+			})
+		}
+	}
+}
+
+// watermarkSlice encodes a watermark map as a slice of InstanceIDs, in
+// replica order.
+func watermarkSlice(wm map[pb.ReplicaID]pb.InstanceNum) []pb.InstanceID {
+	ids := make([]pb.InstanceID, 0, len(wm))
+	for r, i := range wm {
+		ids = append(ids, pb.InstanceID{ReplicaID: r, InstanceNum: i})
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].ReplicaID < ids[j].ReplicaID })
+	return ids
+}
+
+// onPeerWatermark handles an executed watermark advertised by a peer,
+// recording it and garbage collecting whatever the updated cluster-wide
+// minimum newly permits.
+func (p *epaxos) onPeerWatermark(m pb.Message, wm *pb.ExecutedWatermark) {
+	if p.peerWatermarks == nil {
+		p.peerWatermarks = make(map[pb.ReplicaID]map[pb.ReplicaID]pb.InstanceNum)
+	}
+	adv := make(map[pb.ReplicaID]pb.InstanceNum, len(wm.Executed))
+	for _, id := range wm.Executed {
+		adv[id.ReplicaID] = id.InstanceNum
+	}
+	p.peerWatermarks[m.From] = adv
+	p.maybeGC()
+}
+
+// maybeGC prunes instances below the cluster-wide minimum of the local
+// and advertised executed watermarks, keeping the configured retention
+// margin. The minimum requires a watermark from every peer: until one has
+// been heard from, its executed prefix must be assumed empty and nothing
+// is pruned.
+func (p *epaxos) maybeGC() {
+	if p.watermarkExchangeTicks == 0 {
+		return
+	}
+	for _, r := range p.nodes {
+		if r != p.id {
+			if _, ok := p.peerWatermarks[r]; !ok {
+				return
+			}
+		}
+	}
+	// Start from the local watermark, clamped by any compaction hold, and
+	// lower it to each peer's advertised watermark.
+	safe := p.compactionUpTo()
+	truncated := false
+	for _, r := range p.nodes {
+		limit := safe[r]
+		for _, adv := range p.peerWatermarks {
+			if adv[r] < limit {
+				limit = adv[r]
+			}
+		}
+		if limit <= p.gcRetentionMargin {
+			continue
+		}
+		limit -= p.gcRetentionMargin
+		if limit <= p.maxTruncated[r] {
+			continue
+		}
+		p.truncateSpace(r, limit)
+		truncated = true
+	}
+	if truncated {
+		p.persistTruncation()
+	}
+}
@@ -0,0 +1,101 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestWatermarkExchangeTimer verifies that a node with the watermark
+// exchange enabled periodically advertises its executed watermark to
+// every peer.
+func TestWatermarkExchangeTimer(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                     0,
+		Nodes:                  []pb.ReplicaID{0, 1, 2},
+		WatermarkExchangeTicks: 2,
+	})
+	p.Tick()
+	if len(p.msgs) != 0 {
+		t.Fatalf("expected no advertisements before the timer fires, found %v", p.msgs)
+	}
+	p.Tick()
+	if len(p.msgs) != 2 {
+		t.Fatalf("expected an advertisement per peer, found %v", p.msgs)
+	}
+	for _, m := range p.msgs {
+		if m.GetExecutedWatermark() == nil {
+			t.Errorf("expected an ExecutedWatermark message, found %+v", m)
+		}
+	}
+}
+
+// TestWatermarkExchangeGC verifies that instances executed on every
+// replica are garbage collected once all replicas have advertised their
+// executed watermarks, and not before.
+func TestWatermarkExchangeGC(t *testing.T) {
+	n := newNetwork(3)
+	for _, p := range n.peers {
+		p.watermarkExchangeTicks = 1
+	}
+
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+	n.clearAllMessages()
+
+	// Without a watermark from every peer, nothing may be pruned.
+	n.peers[0].maybeGC()
+	if n.peers[0].getInstance(inst.is.ReplicaID, inst.is.InstanceNum) == nil {
+		t.Fatalf("expected no GC before all peers advertised watermarks")
+	}
+
+	for _, p := range n.peers {
+		p.broadcastExecutedWatermark()
+	}
+	n.deliverAllMessages()
+
+	for i, p := range n.peers {
+		if p.getInstance(inst.is.ReplicaID, inst.is.InstanceNum) != nil {
+			t.Errorf("peer %d: expected instance %+v garbage collected", i, inst.is.InstanceID)
+		}
+		if !p.hasTruncated(inst.is.ReplicaID, inst.is.InstanceNum) {
+			t.Errorf("peer %d: expected hasTruncated for %+v", i, inst.is.InstanceID)
+		}
+	}
+
+	// A later proposal must not reuse a collected instance number.
+	inst2 := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if inst2.is.InstanceNum <= n.peers[0].maxTruncated[0] {
+		t.Errorf("expected new instance above GC point, found %d", inst2.is.InstanceNum)
+	}
+	if !n.waitExecuteInstance(inst2, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst2)
+	}
+}
+
+// TestGCRetentionMargin verifies that the configured retention margin
+// keeps a window of executed instances below the cluster-wide minimum
+// watermark.
+func TestGCRetentionMargin(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                     0,
+		Nodes:                  []pb.ReplicaID{0},
+		WatermarkExchangeTicks: 1,
+		GCRetentionMargin:      1,
+	})
+	p.onRequest(newTestingCommand("a", "z"))
+	p.onRequest(newTestingCommand("a", "z"))
+	if p.executedPrefix[0] != 2 {
+		t.Fatalf("expected both commands executed, found watermark %d", p.executedPrefix[0])
+	}
+
+	p.maybeGC()
+	if p.getInstance(0, 1) != nil {
+		t.Errorf("expected instance 1 garbage collected")
+	}
+	if p.getInstance(0, 2) == nil {
+		t.Errorf("expected instance 2 retained by the margin")
+	}
+}
@@ -0,0 +1,27 @@
+package epaxos
+
+// HealthStatus aggregates the node's programmatic health predicates, so
+// that orchestrators can distinguish a dead process from one that is
+// alive but should not receive traffic yet.
+type HealthStatus struct {
+	// Live is whether the state machine is running. It is false once the
+	// replica halts after an unrecoverable apply error.
+	Live bool `json:"live"`
+	// Ready is whether the node is caught up and executing: live, past
+	// any post-restart warm-up, not rejecting proposals due to slow
+	// storage, and not in the middle of a bulk replay.
+	Ready bool `json:"ready"`
+	// QuorumConnected is whether the node, counting itself, has heard
+	// from a quorum of replicas within the peer liveness timeout.
+	QuorumConnected bool `json:"quorum_connected"`
+}
+
+// Health returns the node's current health predicates.
+func (p *epaxos) Health() HealthStatus {
+	live := !p.halted
+	return HealthStatus{
+		Live:            live,
+		Ready:           live && !p.warmingUp() && !p.sheddingLoad() && !p.replaying(),
+		QuorumConnected: p.singleNode() || p.quorum(len(p.healthyReplicas())),
+	}
+}
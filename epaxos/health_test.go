@@ -0,0 +1,44 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestHealthPredicates verifies the Live, Ready, and QuorumConnected
+// predicates across the states that affect them.
+func TestHealthPredicates(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	// A fresh node is live, ready, and within the startup grace period
+	// for peer liveness.
+	if hs := p.Health(); !hs.Live || !hs.Ready || !hs.QuorumConnected {
+		t.Fatalf("expected fresh node fully healthy, found %+v", hs)
+	}
+
+	// Silence from all peers past the liveness timeout loses quorum.
+	p.ticks = peerLivenessTimeout + 1
+	if hs := p.Health(); hs.QuorumConnected {
+		t.Fatalf("expected quorum lost with silent peers, found %+v", hs)
+	}
+
+	// Hearing from one peer restores a quorum of two out of three.
+	p.recordHeardFrom(1)
+	if hs := p.Health(); !hs.QuorumConnected {
+		t.Fatalf("expected quorum restored after hearing from peer, found %+v", hs)
+	}
+
+	// A bulk replay makes the node unready but leaves it live.
+	p.StartBulkReplay(BulkReplayOptions{})
+	if hs := p.Health(); !hs.Live || hs.Ready {
+		t.Fatalf("expected live but unready node during replay, found %+v", hs)
+	}
+	p.FinishBulkReplay()
+
+	// A halted node is neither live nor ready.
+	p.halted = true
+	if hs := p.Health(); hs.Live || hs.Ready {
+		t.Fatalf("expected halted node not live, found %+v", hs)
+	}
+}
@@ -0,0 +1,166 @@
+package epaxos
+
+import (
+	"bytes"
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Hot-spot splitting guidance.
+//
+// The fast path is only available to proposals that no peer has seen
+// interfering proposals for, so a keyspace hot spot — many writes to the
+// same span — silently degrades the protocol to slow-path commits. The
+// node tracks, per proposed span, how often a local proposal started out
+// with interference already against it, and surfaces the spans whose
+// conflict rate warrants restructuring along with a suggested split
+// point. Applications consume the suggestions to repartition their
+// keyspace; the protocol itself never splits anything.
+
+// spanStats accumulates the conflict history of one proposed span.
+type spanStats struct {
+	proposals uint64
+	conflicts uint64
+}
+
+// spanKey identifies a proposed span in the conflict-tracking map.
+type spanKey struct {
+	start, end string
+}
+
+// recordKeyConflict notes a local proposal of the command's span and
+// whether the proposal already had interfering instances against it.
+// Protocol-internal commands interfere by construction and would only
+// pollute the statistics, so they are skipped.
+func (p *epaxos) recordKeyConflict(cmd *pb.Command, conflicted bool) {
+	if cmd == nil || cmd.Compaction || cmd.ConfChange || len(cmd.Span.Key) == 0 {
+		return
+	}
+	if p.keyConflicts == nil {
+		p.keyConflicts = make(map[spanKey]*spanStats)
+	}
+	k := spanKey{start: string(cmd.Span.Key), end: string(cmd.Span.EndKey)}
+	s := p.keyConflicts[k]
+	if s == nil {
+		s = &spanStats{}
+		p.keyConflicts[k] = s
+	}
+	s.proposals++
+	if conflicted {
+		s.conflicts++
+	}
+}
+
+// KeyConflictStats describes the observed conflict behavior of one
+// proposed key span.
+type KeyConflictStats struct {
+	// Span is the proposed span.
+	Span pb.Span
+	// Proposals counts local proposals of the span.
+	Proposals uint64
+	// Conflicts counts the proposals that had interfering instances
+	// against them when proposed.
+	Conflicts uint64
+}
+
+// ConflictRate returns the fraction of the span's proposals that
+// experienced interference.
+func (s KeyConflictStats) ConflictRate() float64 {
+	if s.Proposals == 0 {
+		return 0
+	}
+	return float64(s.Conflicts) / float64(s.Proposals)
+}
+
+// KeyConflictStats returns the node's per-span conflict statistics,
+// hottest spans first.
+func (p *epaxos) KeyConflictStats() []KeyConflictStats {
+	stats := make([]KeyConflictStats, 0, len(p.keyConflicts))
+	for k, s := range p.keyConflicts {
+		stats = append(stats, KeyConflictStats{
+			Span:      pb.Span{Key: pb.Key(k.start), EndKey: pb.Key(k.end)},
+			Proposals: s.proposals,
+			Conflicts: s.conflicts,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Conflicts != stats[j].Conflicts {
+			return stats[i].Conflicts > stats[j].Conflicts
+		}
+		return bytes.Compare(stats[i].Span.Key, stats[j].Span.Key) < 0
+	})
+	return stats
+}
+
+// SplitSuggestion recommends splitting a conflict-heavy span at SplitAt,
+// so that writes to its two halves stop interfering with each other.
+type SplitSuggestion struct {
+	KeyConflictStats
+	// SplitAt is a key within the span to split it at.
+	SplitAt pb.Key
+}
+
+// SuggestSplits returns split suggestions for the spans whose conflict
+// rate is at least minRate across at least minProposals proposals,
+// hottest spans first. Single-key spans cannot be split and are omitted;
+// their conflicts can only be addressed by restructuring the commands
+// themselves.
+func (p *epaxos) SuggestSplits(minProposals uint64, minRate float64) []SplitSuggestion {
+	var suggestions []SplitSuggestion
+	for _, s := range p.KeyConflictStats() {
+		if s.Proposals < minProposals || s.ConflictRate() < minRate {
+			continue
+		}
+		if len(s.Span.EndKey) == 0 || bytes.Compare(s.Span.EndKey, s.Span.Key) <= 0 {
+			continue
+		}
+		suggestions = append(suggestions, SplitSuggestion{
+			KeyConflictStats: s,
+			SplitAt:          midpointKey(s.Span.Key, s.Span.EndKey),
+		})
+	}
+	return suggestions
+}
+
+// midpointKey returns a key approximately halfway between start and end
+// in lexicographic order, treating the keys as big-endian integers. If
+// the midpoint rounds down to start, a byte is appended so that the
+// returned key is strictly greater than start.
+func midpointKey(start, end pb.Key) pb.Key {
+	n := len(start)
+	if len(end) > n {
+		n = len(end)
+	}
+	a := make([]byte, n)
+	copy(a, start)
+	b := make([]byte, n)
+	copy(b, end)
+
+	// Sum the keys into n+1 big-endian digits, then halve the sum.
+	sum := make([]byte, n+1)
+	carry := 0
+	for i := n - 1; i >= 0; i-- {
+		v := int(a[i]) + int(b[i]) + carry
+		sum[i+1] = byte(v)
+		carry = v >> 8
+	}
+	sum[0] = byte(carry)
+
+	mid := make([]byte, 0, n)
+	rem := 0
+	for i, d := range sum {
+		v := rem<<8 | int(d)
+		rem = v & 1
+		if i == 0 {
+			// The leading digit halves to zero; it only feeds its remainder
+			// into the next digit.
+			continue
+		}
+		mid = append(mid, byte(v>>1))
+	}
+	if bytes.Equal(mid, a) {
+		mid = append(mid, 0x80)
+	}
+	return pb.Key(mid)
+}
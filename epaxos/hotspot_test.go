@@ -0,0 +1,81 @@
+package epaxos
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestKeyConflictStats verifies that local proposals are attributed to
+// their spans and that proposals with pre-existing interference count as
+// conflicts.
+func TestKeyConflictStats(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	p.onRequest(newTestingCommand("a", "m"))
+	p.onRequest(newTestingCommand("a", "m"))
+	p.onRequest(newTestingCommand("x", "z"))
+
+	stats := p.KeyConflictStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 spans, found %+v", stats)
+	}
+	// The hottest span sorts first: the second proposal of [a, m) found
+	// the first already in flight.
+	hot := stats[0]
+	if !bytes.Equal(hot.Span.Key, pb.Key("a")) || hot.Proposals != 2 || hot.Conflicts != 1 {
+		t.Errorf("expected span [a, m) with 2 proposals and 1 conflict, found %+v", hot)
+	}
+	if rate := hot.ConflictRate(); rate != 0.5 {
+		t.Errorf("expected conflict rate 0.5, found %f", rate)
+	}
+	if cold := stats[1]; cold.Conflicts != 0 {
+		t.Errorf("expected no conflicts for span [x, z), found %+v", cold)
+	}
+}
+
+// TestSuggestSplits verifies that split suggestions are produced for
+// spans over the configured thresholds, with a split point inside the
+// span.
+func TestSuggestSplits(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	p.onRequest(newTestingCommand("a", "m"))
+	p.onRequest(newTestingCommand("a", "m"))
+	p.onRequest(newTestingCommand("x", "z"))
+
+	suggestions := p.SuggestSplits(2, 0.5)
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, found %+v", suggestions)
+	}
+	s := suggestions[0]
+	if !bytes.Equal(s.Span.Key, pb.Key("a")) {
+		t.Errorf("expected a suggestion for span [a, m), found %+v", s)
+	}
+	if bytes.Compare(s.SplitAt, s.Span.Key) <= 0 || bytes.Compare(s.SplitAt, s.Span.EndKey) >= 0 {
+		t.Errorf("expected split point inside the span, found %q", s.SplitAt)
+	}
+
+	// Raising the thresholds filters the suggestion out.
+	if sug := p.SuggestSplits(3, 0.5); len(sug) != 0 {
+		t.Errorf("expected no suggestions over 3 proposals, found %+v", sug)
+	}
+}
+
+// TestMidpointKey verifies the lexicographic midpoint computation,
+// including the adjacent-key case where a byte must be appended to land
+// strictly between the bounds.
+func TestMidpointKey(t *testing.T) {
+	testCases := []struct {
+		start, end, exp string
+	}{
+		{"a", "c", "b"},
+		{"a", "b", "a\x80"},
+		{"aa", "ac", "ab"},
+		{"a", "a\x02", "a\x01"},
+	}
+	for _, tc := range testCases {
+		if mid := midpointKey(pb.Key(tc.start), pb.Key(tc.end)); !bytes.Equal(mid, pb.Key(tc.exp)) {
+			t.Errorf("expected midpointKey(%q, %q) = %q, found %q", tc.start, tc.end, tc.exp, mid)
+		}
+	}
+}
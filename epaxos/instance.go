@@ -17,9 +17,58 @@ type instance struct {
 	preAcceptReplies int
 	differentReplies bool
 	slowPathTimer    tickingTimer
-	acceptReplies    int
-}
-
+	// commitTimer surfaces the proposal's per-peer vote bitmap if the
+	// instance remains uncommitted past the configured commit timeout.
+	commitTimer tickingTimer
+	// statusQueryTimer queries the command leader for the instance's
+	// status if the promise this replica holds for it remains uncommitted
+	// past the configured status-query timeout.
+	statusQueryTimer tickingTimer
+	// thriftyTimer sends the current phase's message to the peers a
+	// thrifty send skipped if the phase's quorum does not materialize in
+	// time.
+	thriftyTimer tickingTimer
+	// thriftySent holds the peers the current phase's message was sent to
+	// by a thrifty send.
+	thriftySent   []pb.ReplicaID
+	acceptReplies int
+	// tryPreAcceptReplies counts conflict-free TryPreAcceptReply messages
+	// received while recovering the instance.
+	tryPreAcceptReplies int
+	// tryPreAcceptConflicts counts conflicting TryPreAcceptReply messages
+	// received while recovering the instance.
+	tryPreAcceptConflicts int
+	// preAcceptSentTick is the node's tick count when the instance's
+	// PreAccept was broadcast, for reply latency measurement.
+	preAcceptSentTick uint64
+	// replyReplicas holds the replicas whose replies may contribute to
+	// the instance's commit certificate.
+	replyReplicas []pb.ReplicaID
+	// traceCtx is the W3C traceparent of the instance's proposal, if
+	// one was provided or propagated.
+	traceCtx string
+	// speculated is whether a SpeculativeReplyEvent has been emitted for
+	// the instance and awaits resolution at commit time.
+	speculated bool
+	// abandoned is whether the instance was parked after exceeding the
+	// configured maximum number of ballot attempts. Abandoned instances
+	// ignore all further messages.
+	abandoned bool
+	// latency, if non-nil, accumulates the phase boundaries of a sampled
+	// local proposal for latency attribution.
+	latency *proposalLatency
+	// cmdDigest memoizes the canonical digest of the instance's command.
+	// The digest is computed once (or adopted from a message that carried
+	// it) and reused for dedup and by-digest command references, instead
+	// of re-hashing the payload at every site that needs it.
+	cmdDigest []byte
+}
+
+// slowPathTimout is the default number of ticks a proposal that has
+// reached a plain quorum waits for a fast quorum before falling back to
+// the slow path. It can be overridden cluster-wide through
+// Config.SlowPathTimeoutTicks and per proposal through
+// RequestWithSlowPathTimeout.
 const slowPathTimout = 2
 
 func (p *epaxos) newInstance(r pb.ReplicaID, i pb.InstanceNum) *instance {
@@ -32,18 +81,24 @@ func (p *epaxos) newInstance(r pb.ReplicaID, i pb.InstanceNum) *instance {
 			},
 		},
 	}
-	inst.slowPathTimer = makeTickingTimer(slowPathTimout, func() {
-		inst.transitionTo(pb.InstanceState_Accepted)
-	})
+	inst.initSlowPathTimer()
 	return inst
 }
 
 func (p *epaxos) newInstanceFromState(is *pb.InstanceState) *instance {
 	inst := &instance{p: p, is: *is}
-	inst.slowPathTimer = makeTickingTimer(slowPathTimout, func() {
+	inst.initSlowPathTimer()
+	return inst
+}
+
+func (inst *instance) initSlowPathTimer() {
+	timeout := inst.p.slowPathTimeout
+	if override := inst.p.proposalSlowPathTicks; override > 0 {
+		timeout = override
+	}
+	inst.slowPathTimer = makeTickingTimer(timeout, func() {
 		inst.transitionTo(pb.InstanceState_Accepted)
 	})
-	return inst
 }
 
 //
@@ -65,7 +120,7 @@ func instanceKey(i pb.InstanceNum) btree.Item {
 //
 
 func (inst *instance) persist() {
-	inst.p.storage.PersistInstance(&inst.is)
+	inst.p.persistInstance(&inst.is)
 }
 
 //
@@ -79,6 +134,16 @@ func (inst *instance) Identifier() executableID {
 	}
 }
 
+// origin returns the replica that proposed the instance, implementing
+// the originated interface for the executor's fairness accounting and
+// fair interleaving.
+func (inst *instance) origin() executableID {
+	return inst.is.ReplicaID
+}
+
+// Dependencies returns the instance's dependencies in a stable, sorted
+// order. is.Deps is always kept sorted (see depSliceFromMap), so the
+// executor's graph traversal is deterministic.
 func (inst *instance) Dependencies() []executableID {
 	deps := make([]executableID, 0, len(inst.is.Deps))
 	for _, dep := range inst.is.Deps {
@@ -87,13 +152,27 @@ func (inst *instance) Dependencies() []executableID {
 	return deps
 }
 
-// ExecutesBefore determines which of two instances execute first. The ordering
-// is based on sequence numbers (lamport logical clocks), which break ties in
-// strongly connected components. If the sequence numbers are also the same,
-// then we break ties based on ReplicaID, because commands in the same SCC will
-// always be from different replicas.
+// ExecutesBefore determines which of two instances execute first. By
+// default the ordering is based on sequence numbers (lamport logical
+// clocks), which break ties in strongly connected components. If the
+// sequence numbers are also the same, then we break ties based on
+// ReplicaID, because commands in the same SCC will always be from
+// different replicas. Embedders can replace the ordering with a custom
+// ExecutionOrder.
 func (inst *instance) ExecutesBefore(b executable) bool {
 	instB := b.(*instance)
+	if o := inst.p.execOrder; o != nil {
+		return o.ExecutesBefore(inst.orderedCommand(), instB.orderedCommand())
+	}
+	if inst.p.depsOnly {
+		// Without sequence numbers, the dependency graph alone orders the
+		// components; within a strongly connected component instance IDs
+		// break the remaining ties.
+		if ra, rb := inst.is.ReplicaID, instB.is.ReplicaID; ra != rb {
+			return ra < rb
+		}
+		return inst.is.InstanceNum < instB.is.InstanceNum
+	}
 	if seqA, seqB := inst.is.SeqNum, instB.is.SeqNum; seqA != seqB {
 		return seqA < seqB
 	}
@@ -116,40 +195,92 @@ func (st stateTransition) String() string {
 	return fmt.Sprintf("{%v -> %v}", st.from, st.to)
 }
 
-var stateTransitions = map[stateTransition]func(*instance){
-	stateTransition{pb.InstanceState_None, pb.InstanceState_PreAccepted}: func(inst *instance) {
-		inst.broadcastPreAccept()
-	},
-	stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Accepted}: func(inst *instance) {
-		inst.broadcastAccept()
-	},
-	stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Committed}: func(inst *instance) {
-		inst.broadcastCommit()
-		inst.prepareToExecute()
-	},
-	stateTransition{pb.InstanceState_Accepted, pb.InstanceState_Committed}: func(inst *instance) {
-		inst.broadcastCommit()
-		inst.prepareToExecute()
-	},
-	stateTransition{pb.InstanceState_Committed, pb.InstanceState_Executed}: func(inst *instance) {
-		inst.p.deliverExecutedCommand(*inst.is.Command)
-	},
+var stateTransitions map[stateTransition]func(*instance)
+
+// The transition map is populated in init instead of in its declaration
+// to avoid an initialization cycle through the transition actions.
+func init() {
+	stateTransitions = map[stateTransition]func(*instance){
+		stateTransition{pb.InstanceState_None, pb.InstanceState_PreAccepted}: func(inst *instance) {
+			inst.maybeWatchCommit()
+			inst.broadcastPreAccept()
+		},
+		stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Accepted}: func(inst *instance) {
+			inst.noteLatencyQuorum(true)
+			inst.resetReplyReplicas()
+			inst.broadcastAccept()
+		},
+		stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Committed}: func(inst *instance) {
+			inst.noteLatencyQuorum(false)
+			inst.noteLatencyCommitted()
+			inst.unwatchCommit()
+			inst.disarmThriftyTimer()
+			inst.sealCommitCert()
+			inst.maybeResolveSpeculation()
+			inst.p.noteProposalCommitted(inst)
+			inst.broadcastCommit()
+			inst.prepareToExecute()
+		},
+		stateTransition{pb.InstanceState_Accepted, pb.InstanceState_Committed}: func(inst *instance) {
+			inst.noteLatencyCommitted()
+			inst.unwatchCommit()
+			inst.disarmThriftyTimer()
+			inst.sealCommitCert()
+			inst.maybeResolveSpeculation()
+			inst.p.noteProposalCommitted(inst)
+			inst.broadcastCommit()
+			inst.prepareToExecute()
+		},
+		stateTransition{pb.InstanceState_Committed, pb.InstanceState_Executed}: func(inst *instance) {
+			inst.noteLatencyExecuted()
+			inst.p.recordExecuted(inst)
+			inst.p.maybeDeliverExecuted(inst)
+			inst.p.advanceExecutedWatermark(inst)
+			inst.p.forgetPendingCommand(inst)
+		},
+	}
 }
 
 func (inst *instance) transitionTo(to pb.InstanceState_Status) {
 	st := stateTransition{from: inst.is.Status, to: to}
 	action, ok := stateTransitions[st]
 	if !ok {
-		inst.p.logger.Panicf("unexpected state transition %s", st)
+		inst.p.failReplica(inst.p.instLogger, "unexpected state transition %s", st)
+		return
 	}
 
 	inst.is.Status = to
 	action(inst)
 	inst.persist()
+
+	switch to {
+	case pb.InstanceState_Committed:
+		inst.p.bumpCounter(&inst.p.counters.Committed)
+		if st.from == pb.InstanceState_PreAccepted {
+			inst.p.bumpCounter(&inst.p.counters.FastPathCommitted)
+		} else {
+			inst.p.bumpCounter(&inst.p.counters.SlowPathCommitted)
+		}
+	case pb.InstanceState_Executed:
+		inst.p.bumpCounter(&inst.p.counters.Executed)
+	}
+
+	if to == pb.InstanceState_PreAccepted && inst.p.singleNode() {
+		// With no peers there are no replies to wait for; the instance
+		// trivially has a fast-path quorum.
+		inst.transitionTo(pb.InstanceState_Committed)
+	}
 }
 
 func (inst *instance) restartTransition() {
 	cur := inst.is.Status
+	if cur == pb.InstanceState_PreAccepted {
+		// On restart, peers have likely already seen this instance's
+		// PreAccept. Reference the command by digest to cut bandwidth;
+		// any peer missing the command will reply with NeedCommand.
+		inst.broadcastPreAcceptByDigest()
+		return
+	}
 	st := stateTransition{from: cur - 1, to: cur}
 	action := stateTransitions[st]
 	action(inst)
@@ -167,46 +298,204 @@ func (inst *instance) isStates(states ...pb.InstanceState_Status) bool {
 
 func (inst *instance) assertState(valid ...pb.InstanceState_Status) {
 	if !inst.isStates(valid...) {
-		inst.p.logger.Panicf("unexpected state %v; expected %v", inst.is.Status, valid)
+		inst.p.failReplica(inst.p.instLogger, "unexpected state %v; expected %v", inst.is.Status, valid)
 	}
 }
 
-// broadcastPreAccept broadcasts a PreAccept message to all other nodes.
+// broadcastPreAccept broadcasts a PreAccept message to all other nodes,
+// or in thrifty mode to just enough peers to form a fast quorum. The
+// command's digest is carried alongside the payload so that peers can
+// index the command without re-hashing it.
 func (inst *instance) broadcastPreAccept() {
-	inst.broadcast(&pb.PreAccept{InstanceData: inst.instanceData()})
+	data := inst.instanceData()
+	data.CommandDigest = inst.commandDigest()
+	inst.preAcceptSentTick = inst.p.ticks
+	pa := &pb.PreAccept{InstanceData: data}
+	if inst.p.thriftyPreAccept {
+		// A fast quorum includes the leader's own reply.
+		inst.sendThrifty(pa, inst.p.fastQuorumSize-1)
+		return
+	}
+	inst.broadcast(pa)
+}
+
+// broadcastPreAcceptByDigest broadcasts a PreAccept message to all other
+// nodes that references the instance's command by digest instead of
+// carrying the full command payload. Peers that do not have the command
+// will reply with a NeedCommand message.
+func (inst *instance) broadcastPreAcceptByDigest() {
+	data := inst.instanceDataWithoutCommand()
+	data.CommandDigest = inst.commandDigest()
+	inst.broadcast(&pb.PreAccept{InstanceData: data})
 }
 
-// broadcastAccept broadcasts an Accept message to all other nodes.
+// broadcastAccept broadcasts an Accept message to all other nodes, or in
+// thrifty mode to just enough peers to form a quorum.
 func (inst *instance) broadcastAccept() {
-	inst.broadcast(&pb.Accept{InstanceData: inst.instanceDataWithoutCommand()})
+	a := &pb.Accept{InstanceData: inst.instanceDataWithoutCommand()}
+	if inst.p.thriftyAccept {
+		// A quorum is floor(N/2)+1 replies including the leader's own.
+		inst.sendThrifty(a, len(inst.p.nodes)/2)
+		return
+	}
+	inst.broadcast(a)
 }
 
-// broadcastCommit broadcasts a Commit message to all other nodes.
+// broadcastCommit broadcasts a Commit message to all other nodes, or in
+// thrifty mode to just enough peers that a quorum, including the leader,
+// knows of the commit. Commits expect no replies, so there is no quorum
+// to await and no expansion timer; the skipped peers learn of the commit
+// through instance status queries or recovery.
 func (inst *instance) broadcastCommit() {
-	inst.broadcast(&pb.Commit{InstanceData: inst.instanceData()})
+	c := &pb.Commit{InstanceData: inst.instanceData()}
+	if inst.p.thriftyCommit {
+		for _, node := range inst.p.thriftyPeers(len(inst.p.nodes) / 2) {
+			inst.p.sendTo(c, node, inst)
+		}
+		return
+	}
+	inst.broadcast(c)
+}
+
+// broadcastTryPreAccept broadcasts a TryPreAccept message to all other
+// nodes, probing whether the instance's current attributes could have
+// been committed on the fast path by a failed leader. The instance stays
+// in the PreAccepted state while the probe is in flight; a quorum of
+// conflict-free replies moves it to the existing PreAccepted -> Accepted
+// transition, while any conflict falls back to a fresh PreAccept round.
+func (inst *instance) broadcastTryPreAccept() {
+	inst.assertState(pb.InstanceState_PreAccepted)
+	inst.broadcast(&pb.TryPreAccept{InstanceData: inst.instanceData()})
 }
 
 //
 // Message Handlers
 //
 
+// checkBallot compares the stepped message's ballot against the highest
+// ballot seen for the instance. A stale ballot is rejected with a Nack
+// whose carrying message holds the higher ballot, so that the sender can
+// re-propose above it; a fresh ballot is adopted.
+func (inst *instance) checkBallot() bool {
+	b := inst.p.stepBallot
+	if cur := inst.is.Ballot; cur != nil {
+		switch b.Compare(*cur) {
+		case -1:
+			inst.p.instLogger.Debugf("nacking stale ballot %+v for instance %+v; current ballot %+v",
+				b, inst.is.InstanceID, *cur)
+			inst.reply(&pb.Nack{})
+			return false
+		case 0:
+			return true
+		}
+	}
+	inst.is.Ballot = &b
+	return true
+}
+
+// checkReplyBallot ignores a reply carrying a ballot older than the
+// instance's current ballot; it belongs to a superseded round.
+func (inst *instance) checkReplyBallot() bool {
+	if cur := inst.is.Ballot; cur != nil && inst.p.stepBallot.Compare(*cur) < 0 {
+		inst.p.instLogger.Debugf("ignoring reply with stale ballot %+v for instance %+v",
+			inst.p.stepBallot, inst.is.InstanceID)
+		return false
+	}
+	return true
+}
+
+// onNack handles the rejection of one of this replica's messages for
+// carrying a stale ballot. The nack's carrying message holds the higher
+// ballot; the instance's current phase is re-proposed at a ballot above
+// it.
+func (inst *instance) onNack(n *pb.Nack) {
+	if !inst.isStates(pb.InstanceState_PreAccepted, pb.InstanceState_Accepted, pb.InstanceState_Committed) {
+		inst.p.instLogger.Debugf("ignoring Nack message while in state %v: %v", inst.is.Status, n)
+		return
+	}
+	hb := inst.p.stepBallot
+	if cur := inst.is.Ballot; cur != nil && hb.Compare(*cur) < 0 {
+		inst.p.instLogger.Debugf("ignoring Nack with stale ballot %+v: %v", hb, n)
+		return
+	}
+	inst.is.Ballot = &pb.Ballot{
+		Epoch:     hb.Epoch,
+		Number:    hb.Number + 1,
+		ReplicaID: inst.p.id,
+	}
+	if max := inst.p.maxBallotAttempts; max > 0 && int(inst.is.Ballot.Number) > max {
+		inst.abandon()
+		return
+	}
+	inst.resetAttributeRound()
+	inst.acceptReplies = 0
+	inst.persist()
+	inst.restartTransition()
+}
+
+// abandon parks the instance after its ballot exceeded the configured
+// maximum number of attempts. Contention that deep indicates a
+// persistent partition or a bug, which retrying forever cannot fix, so
+// the instance's timers are disarmed, further messages to it are
+// ignored, and the abandonment is surfaced to the embedder as a hard
+// event.
+func (inst *instance) abandon() {
+	inst.abandoned = true
+	inst.p.unregisterTimer(&inst.slowPathTimer)
+	inst.unwatchCommit()
+	inst.unwatchStatus()
+	inst.disarmThriftyTimer()
+	inst.persist()
+	inst.p.instLogger.Errorf("abandoning instance %+v after %d ballot attempts",
+		inst.is.InstanceID, inst.p.maxBallotAttempts)
+	inst.p.emitEvent(InstanceAbandonedEvent{
+		InstanceID: inst.is.InstanceID,
+		Ballot:     *inst.is.Ballot,
+	})
+}
+
 func (inst *instance) onPreAccept(pa *pb.PreAccept) {
+	if !inst.checkBallot() {
+		return
+	}
+
 	// Only handle if this is a new instance, and set the state to preAccepted.
 	if !inst.isStates(pb.InstanceState_None, pb.InstanceState_PreAccepted) {
-		inst.p.logger.Debugf("ignoring PreAccept message while in state %v: %v", inst.is.Status, pa)
+		inst.p.instLogger.Debugf("ignoring PreAccept message while in state %v: %v", inst.is.Status, pa)
 		return
 	}
+
+	// Resolve the command, which may be referenced by digest instead of
+	// being carried in the message. If we don't have the command, ask the
+	// sender for it.
+	cmd := pa.Command
+	if cmd == nil && len(pa.CommandDigest) > 0 {
+		var ok bool
+		if cmd, ok = inst.p.commandForDigest(pa.CommandDigest); !ok {
+			inst.reply(&pb.NeedCommand{Digest: pa.CommandDigest})
+			return
+		}
+	}
+
 	inst.is.Status = pb.InstanceState_PreAccepted
 
 	// Determine the local sequence number and deps for this command.
-	maxLocalSeq, localDeps := inst.p.seqAndDepsForCommand(pa.Command, inst.is.InstanceID)
+	maxLocalSeq, localDeps := inst.p.seqAndDepsForCommand(cmd, inst.is.InstanceID)
 
-	// Record the command for the instance.
-	inst.is.Command = pa.Command
+	// Record the command for the instance, adopting the digest computed
+	// by the command leader if the message carried one.
+	inst.is.Command = cmd
+	if len(pa.CommandDigest) > 0 {
+		inst.cmdDigest = pa.CommandDigest
+	}
+	inst.p.indexCommand(inst)
 
 	// The updated sequence number is set to the maximum of the local maximum
-	// sequence number and the the PreAccept's sequence number
-	inst.is.SeqNum = pb.MaxSeqNum(pa.SeqNum, maxLocalSeq+1)
+	// sequence number and the the PreAccept's sequence number. In deps-only
+	// ordering mode sequence numbers are not maintained at all.
+	if !inst.p.depsOnly {
+		inst.is.SeqNum = pb.MaxSeqNum(pa.SeqNum, maxLocalSeq+1)
+	}
 
 	// Determine the union of the local dependencies and the PreAccept's dependencies.
 	depsUnion := localDeps
@@ -215,6 +504,11 @@ func (inst *instance) onPreAccept(pa *pb.PreAccept) {
 	}
 	inst.is.Deps = depSliceFromMap(depsUnion)
 
+	// Persist the promise before replying, so that a crashed replica
+	// recovers it on restart instead of silently breaking it.
+	inst.persist()
+	inst.maybeWatchStatus()
+
 	// If the sequence number and the deps turn out to be the same as those in
 	// the PreAccept message, reply with a simple PreAcceptOK message.
 	if inst.is.SeqNum == pa.SeqNum && len(inst.is.Deps) == len(pa.Deps) {
@@ -236,44 +530,63 @@ func (inst *instance) fastPathAvailable() bool {
 }
 
 func (inst *instance) onPreAcceptOK(paOK *pb.PreAcceptOK) {
+	if !inst.checkReplyBallot() {
+		return
+	}
 	if !inst.isStates(pb.InstanceState_PreAccepted) {
-		inst.p.logger.Debugf("ignoring PreAcceptOK message while in state %v: %v", inst.is.Status, paOK)
+		inst.p.instLogger.Debugf("ignoring PreAcceptOK message while in state %v: %v", inst.is.Status, paOK)
 		return
 	}
 
 	inst.preAcceptReplies++
+	inst.recordReplyReplica()
 	inst.onEitherPreAcceptReply()
 }
 
 func (inst *instance) onPreAcceptReply(paReply *pb.PreAcceptReply) {
+	if !inst.checkReplyBallot() {
+		return
+	}
 	if !inst.isStates(pb.InstanceState_PreAccepted) {
-		inst.p.logger.Debugf("ignoring PreAcceptReply message while in state %v: %v", inst.is.Status, paReply)
+		inst.p.instLogger.Debugf("ignoring PreAcceptReply message while in state %v: %v", inst.is.Status, paReply)
 		return
 	}
 
 	// Check whether this PreAccept reply is identical to our preAccept or if
 	// the remote peer returned extra information that we weren't aware of. An
 	// identical fast path quorum allows us to skip the Paxos-Accept phase.
+	updated := false
 	newSeq := paReply.UpdatedSeqNum
-	if newSeq > inst.is.SeqNum {
+	if !inst.p.depsOnly && newSeq > inst.is.SeqNum {
 		inst.is.SeqNum = newSeq
-		inst.differentReplies = true
+		updated = true
 	}
 
 	// Merge remote deps into local deps.
 	oldDepsLen := len(inst.is.Deps)
 	inst.is.Deps = unionDepSlices(inst.is.Deps, paReply.UpdatedDeps)
 	if oldDepsLen != len(inst.is.Deps) {
+		updated = true
+	}
+	if updated {
 		inst.differentReplies = true
+		// Attribute the fast path disqualification to the replying peer, so
+		// that operators can spot a replica that consistently forces the
+		// slow path with stale interference state.
+		inst.p.metrics.recordSlowPathForcedBy(inst.p.stepFrom)
 	}
 
 	inst.preAcceptReplies++
+	inst.recordReplyReplica()
 	inst.onEitherPreAcceptReply()
 }
 
 func (inst *instance) onEitherPreAcceptReply() {
+	inst.p.metrics.preAcceptReplyTicks.record(int(inst.p.ticks - inst.preAcceptSentTick))
 	replies := inst.preAcceptReplies + 1 // +1 for leader
-	takeFastPath := !inst.differentReplies && inst.p.fastQuorum(replies)
+	inst.maybeSpeculateReply(replies)
+	takeFastPath := !inst.differentReplies && inst.p.fastQuorum(replies) &&
+		inst.fastPathSpansZones()
 	takeSlowPath := inst.p.quorum(replies)
 	switch {
 	case takeFastPath:
@@ -282,8 +595,10 @@ func (inst *instance) onEitherPreAcceptReply() {
 	case takeSlowPath:
 		// We have enough replies to take the slow path, however we don't want to
 		// take it immediately in-case it's possible to take the fast path instead.
-		if !inst.fastPathAvailable() {
-			// Since the fast path will never be available, take the slow path.
+		if inst.p.conservative || !inst.fastPathAvailable() {
+			// Take the slow path immediately, either because the fast path
+			// will never be available or because the node is in conservative
+			// mode and should not gamble on further replies arriving.
 			inst.p.unregisterTimer(&inst.slowPathTimer)
 			inst.transitionTo(pb.InstanceState_Accepted)
 		} else if !inst.slowPathTimer.isSet() {
@@ -296,45 +611,238 @@ func (inst *instance) onEitherPreAcceptReply() {
 	}
 }
 
+// maybeSpeculateReply emits a SpeculativeReplyEvent once a proposal
+// marked Speculative reaches a PreAccept quorum with its attributes
+// unchanged. The application may answer the client on its strength
+// before the instance commits; maybeResolveSpeculation confirms or
+// aborts the speculation at commit time.
+func (inst *instance) maybeSpeculateReply(replies int) {
+	cmd := inst.is.Command
+	if cmd == nil || !cmd.Speculative || inst.speculated {
+		return
+	}
+	if inst.differentReplies || !inst.p.quorum(replies) {
+		return
+	}
+	inst.speculated = true
+	inst.p.emitEvent(SpeculativeReplyEvent{Command: cmd})
+}
+
+// maybeResolveSpeculation resolves an outstanding speculative reply at
+// commit time. The speculation is confirmed if the instance committed
+// with the attributes it was speculatively acknowledged with, and
+// aborted if later replies changed them.
+func (inst *instance) maybeResolveSpeculation() {
+	if !inst.speculated {
+		return
+	}
+	inst.speculated = false
+	inst.p.emitEvent(SpeculativeResolvedEvent{
+		Command:   inst.is.Command,
+		Confirmed: !inst.differentReplies,
+	})
+}
+
+func (inst *instance) onNeedCommand(nc *pb.NeedCommand) {
+	if !inst.isStates(pb.InstanceState_PreAccepted) {
+		inst.p.instLogger.Debugf("ignoring NeedCommand message while in state %v: %v", inst.is.Status, nc)
+		return
+	}
+
+	// A peer was referred to our command by digest but does not have it.
+	// Re-broadcast the PreAccept with the full command payload. Messages
+	// do not carry their sender, so the PreAccept is re-broadcast to all
+	// peers; those that already handled it will ignore the duplicate.
+	inst.broadcastPreAccept()
+}
+
 func (inst *instance) onAccept(a *pb.Accept) {
+	if !inst.checkBallot() {
+		return
+	}
 	if !inst.isStates(pb.InstanceState_None, pb.InstanceState_PreAccepted, pb.InstanceState_Accepted) {
-		inst.p.logger.Debugf("ignoring Accept message while in state %v: %v", inst.is.Status, a)
+		inst.p.instLogger.Debugf("ignoring Accept message while in state %v: %v", inst.is.Status, a)
 		return
 	}
 
 	inst.is.Status = pb.InstanceState_Accepted
 	inst.replaceInstanceData(a.SeqNum, a.Deps)
+	// As with PreAccept, the acceptance must be durable before it is
+	// acknowledged.
+	inst.persist()
+	inst.maybeWatchStatus()
 	inst.reply(&pb.AcceptOK{})
 }
 
 func (inst *instance) onAcceptOK(aOK *pb.AcceptOK) {
+	if !inst.checkReplyBallot() {
+		return
+	}
 	if !inst.isStates(pb.InstanceState_Accepted) {
-		inst.p.logger.Debugf("ignoring AcceptOK message while in state %v: %v", inst.is.Status, aOK)
+		inst.p.instLogger.Debugf("ignoring AcceptOK message while in state %v: %v", inst.is.Status, aOK)
 		return
 	}
 
 	inst.acceptReplies++
+	inst.recordReplyReplica()
 	if inst.p.quorum(inst.acceptReplies + 1 /* +1 for leader */) {
 		inst.transitionTo(pb.InstanceState_Committed)
 	}
 }
 
 func (inst *instance) onCommit(c *pb.Commit) {
+	if !inst.checkBallot() {
+		return
+	}
 	if !inst.isStates(pb.InstanceState_None, pb.InstanceState_PreAccepted, pb.InstanceState_Accepted) {
-		inst.p.logger.Debugf("ignoring Commit message while in state %v: %v", inst.is.Status, c)
+		inst.p.instLogger.Debugf("ignoring Commit message while in state %v: %v", inst.is.Status, c)
 		return
 	}
 
+	inst.unwatchStatus()
 	inst.is.Status = pb.InstanceState_Committed
+	inst.p.bumpCounter(&inst.p.counters.Committed)
+	if inst.is.Command != c.Command {
+		// The command (and so its digest memo) may differ from what was
+		// pre-accepted, e.g. after a recovery by a different leader.
+		inst.cmdDigest = nil
+	}
 	inst.is.Command = c.Command
+	inst.p.indexCommand(inst)
 	inst.replaceInstanceData(c.SeqNum, c.Deps)
+	inst.persist()
 	inst.prepareToExecute()
 }
 
+// onTryPreAccept handles a TryPreAccept message sent by a recovering
+// command leader. Unlike onPreAccept, the proposed attributes are not
+// updated: they are either adopted exactly as proposed or rejected with
+// a description of a conflicting instance, so that the recovering leader
+// can determine whether the command could have been committed on the
+// fast path by the failed leader.
+func (inst *instance) onTryPreAccept(tpa *pb.TryPreAccept) {
+	// Another replica is driving this instance's recovery; back off from
+	// any local recovery attempt while it holds the lease.
+	inst.p.recovery.observeRemoteRecovery(inst.is.InstanceID)
+
+	if !inst.checkBallot() {
+		return
+	}
+	if !inst.isStates(pb.InstanceState_None, pb.InstanceState_PreAccepted) {
+		// The instance has progressed past the point where the proposed
+		// attributes could still be adopted; it is its own conflict.
+		inst.reply(&pb.TryPreAcceptReply{
+			Conflict:         true,
+			ConflictInstance: inst.is.InstanceID,
+			ConflictStatus:   inst.is.Status,
+		})
+		return
+	}
+
+	if id, status, ok := inst.findTryPreAcceptConflict(tpa.Command, tpa.Deps); ok {
+		inst.reply(&pb.TryPreAcceptReply{
+			Conflict:         true,
+			ConflictInstance: id,
+			ConflictStatus:   status,
+		})
+		return
+	}
+
+	// No conflict; adopt the proposed attributes exactly as-is.
+	inst.is.Status = pb.InstanceState_PreAccepted
+	inst.is.Command = tpa.Command
+	inst.p.indexCommand(inst)
+	inst.replaceInstanceData(tpa.SeqNum, tpa.Deps)
+	inst.persist()
+	inst.reply(&pb.TryPreAcceptReply{})
+}
+
+// findTryPreAcceptConflict searches the local command spaces for an
+// instance that prevents adopting the proposed attributes as-is. A local
+// interfering instance conflicts if the proposal does not include it as
+// a dependency, because a fast-path commit of the proposed attributes
+// would then have ordered incorrectly with respect to it. The check is
+// conservative: it may report a conflict in cases where a fast-path
+// commit was still possible, which only costs the recovering leader a
+// fallback to a full PreAccept round.
+func (inst *instance) findTryPreAcceptConflict(
+	cmd *pb.Command, deps []pb.InstanceID,
+) (pb.InstanceID, pb.InstanceState_Status, bool) {
+	_, localDeps := inst.p.seqAndDepsForCommand(cmd, inst.is.InstanceID)
+	proposed := make(map[pb.InstanceID]struct{}, len(deps))
+	for _, dep := range deps {
+		proposed[dep] = struct{}{}
+	}
+	for dep := range localDeps {
+		if _, ok := proposed[dep]; ok {
+			continue
+		}
+		status := pb.InstanceState_None
+		if conflictInst := inst.p.getInstance(dep.ReplicaID, dep.InstanceNum); conflictInst != nil {
+			status = conflictInst.is.Status
+		}
+		return dep, status, true
+	}
+	return pb.InstanceID{}, pb.InstanceState_None, false
+}
+
+func (inst *instance) onTryPreAcceptReply(reply *pb.TryPreAcceptReply) {
+	if !inst.checkReplyBallot() {
+		return
+	}
+	if !inst.isStates(pb.InstanceState_PreAccepted) {
+		inst.p.instLogger.Debugf("ignoring TryPreAcceptReply message while in state %v: %v", inst.is.Status, reply)
+		return
+	}
+
+	if reply.Conflict {
+		inst.tryPreAcceptConflicts++
+		// A fast quorum passes over N - fastQuorumSize replicas, so up to
+		// that many conflict reports may all come from replicas that a
+		// successful fast-path commit never consulted. Wait for enough
+		// conflicts to rule the fast path out before abandoning the probed
+		// attributes for a full PreAccept round that recomputes them.
+		if inst.tryPreAcceptConflicts < len(inst.p.nodes)-inst.p.fastQuorumSize {
+			return
+		}
+		inst.p.instLogger.Debugf("TryPreAccept of %+v conflicts with %+v (%v); falling back to PreAccept",
+			inst.is.InstanceID, reply.ConflictInstance, reply.ConflictStatus)
+		inst.resetAttributeRound()
+		inst.broadcastPreAccept()
+		return
+	}
+
+	inst.tryPreAcceptReplies++
+	if inst.p.quorum(inst.tryPreAcceptReplies + 1 /* +1 for leader */) {
+		// A quorum adopted the attributes unchanged, so they are safe to
+		// commit through the slow path.
+		inst.transitionTo(pb.InstanceState_Accepted)
+	}
+}
+
+// resetAttributeRound clears the reply state of the current attribute
+// agreement round so that a new PreAccept round can be started.
+func (inst *instance) resetAttributeRound() {
+	inst.preAcceptReplies = 0
+	inst.tryPreAcceptReplies = 0
+	inst.tryPreAcceptConflicts = 0
+	inst.differentReplies = false
+	inst.resetReplyReplicas()
+}
+
 //
 // Utility Functions
 //
 
+// commandDigest returns the memoized canonical digest of the instance's
+// command, computing it on first use.
+func (inst *instance) commandDigest() []byte {
+	if inst.cmdDigest == nil && inst.is.Command != nil {
+		inst.cmdDigest = inst.is.Command.Digest()
+	}
+	return inst.cmdDigest
+}
+
 func (inst *instance) instanceDataWithoutCommand() pb.InstanceData {
 	return pb.InstanceData{
 		SeqNum: inst.is.SeqNum,
@@ -48,14 +48,40 @@ func TestTransitionToPreAccept(t *testing.T) {
 	// Assert state.
 	newInst.assertState(pb.InstanceState_PreAccepted)
 
-	// Assert outbox.
+	// Assert outbox. The PreAccept carries the command's digest alongside
+	// the payload so that peers can index the command without re-hashing.
+	instData := testingInstanceData
+	instData.CommandDigest = testingCmd.Digest()
 	msg := pb.Message{
 		InstanceID: testingInstanceID,
-		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: testingInstanceData}),
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: instData}),
 	}
 	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
 }
 
+// TestOnPreAcceptAdoptsDigest verifies that a replica handling a
+// PreAccept that carries the command's digest adopts it instead of
+// re-hashing the payload.
+func TestOnPreAcceptAdoptsDigest(t *testing.T) {
+	p := newTestingEPaxos()
+
+	instMeta, instData, _ := preAcceptMsg()
+	instData.CommandDigest = instData.Command.Digest()
+	msg := pb.Message{
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: instData}),
+	}
+	p.Step(msg)
+
+	inst := p.getInstance(instMeta.ReplicaID, instMeta.InstanceNum)
+	if a, e := inst.cmdDigest, instData.CommandDigest; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected adopted digest %x, found %x", e, a)
+	}
+	if _, ok := p.commandForDigest(instData.CommandDigest); !ok {
+		t.Errorf("expected command indexed under its digest")
+	}
+}
+
 func preAcceptMsg() (pb.InstanceID, pb.InstanceData, pb.Message) {
 	instMeta := pb.InstanceID{ReplicaID: 1, InstanceNum: 3}
 	instData := testingInstanceData
@@ -86,6 +112,7 @@ func TestOnPreAcceptWithNoNewInfo(t *testing.T) {
 				Deps:    []pb.InstanceID{},
 			}
 			p.commands[0].ReplaceOrInsert(inst03)
+			p.indexCommand(inst03)
 		}
 
 		instMeta, instData, msg := preAcceptMsg()
@@ -131,6 +158,7 @@ func TestOnPreAcceptWithExtraInterferingCommand(t *testing.T) {
 		Deps:    []pb.InstanceID{},
 	}
 	p.commands[0].ReplaceOrInsert(inst03)
+	p.indexCommand(inst03)
 
 	instMeta, instData, msg := preAcceptMsg()
 	p.Step(msg)
@@ -261,3 +289,325 @@ func TestOnPreAcceptReply(t *testing.T) {
 	}
 	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
 }
+
+// TestOnTryPreAcceptNoConflict verifies that a replica handling a
+// TryPreAccept whose proposed dependencies cover all local interference
+// adopts the attributes exactly as proposed and replies without conflict.
+func TestOnTryPreAcceptNoConflict(t *testing.T) {
+	p := newTestingEPaxos()
+	p.assertOutboxEmpty(t)
+
+	instMeta := pb.InstanceID{ReplicaID: 1, InstanceNum: 3}
+	instData := testingInstanceData
+	p.Step(pb.Message{
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.TryPreAccept{InstanceData: instData}),
+	})
+
+	// Verify internal instance state after receiving message.
+	maxInst := p.maxInstance(1)
+	maxInst.assertState(pb.InstanceState_PreAccepted)
+	if a, e := maxInst.is.SeqNum, instData.SeqNum; a != e {
+		t.Errorf("expected adopted seq num %v, found %v", e, a)
+	}
+	if a, e := maxInst.is.Deps, instData.Deps; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected adopted deps %+v, found %+v", e, a)
+	}
+
+	// Verify outbox after receiving message.
+	reply := pb.Message{
+		To:         1,
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.TryPreAcceptReply{}),
+	}
+	p.assertOutbox(t, reply)
+}
+
+// TestOnTryPreAcceptConflict verifies that a replica handling a
+// TryPreAccept reports a local interfering instance missing from the
+// proposed dependencies instead of adopting the attributes.
+func TestOnTryPreAcceptConflict(t *testing.T) {
+	p := newTestingEPaxos()
+	p.assertOutboxEmpty(t)
+
+	// Add an interfering command unknown to the proposal.
+	inst03 := p.newInstance(0, 3)
+	inst03.is.InstanceData = pb.InstanceData{
+		Command: newTestingCommand("a", "z"),
+		SeqNum:  6,
+		Deps:    []pb.InstanceID{},
+	}
+	p.commands[0].ReplaceOrInsert(inst03)
+	p.indexCommand(inst03)
+
+	instMeta := pb.InstanceID{ReplicaID: 1, InstanceNum: 3}
+	instData := testingInstanceData
+	p.Step(pb.Message{
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.TryPreAccept{InstanceData: instData}),
+	})
+
+	// The attributes must not have been adopted.
+	inst := p.getInstance(instMeta.ReplicaID, instMeta.InstanceNum)
+	inst.assertState(pb.InstanceState_None)
+
+	// Verify outbox after receiving message.
+	reply := pb.Message{
+		To:         1,
+		InstanceID: instMeta,
+		Type: pb.WrapMessageInner(&pb.TryPreAcceptReply{
+			Conflict:         true,
+			ConflictInstance: pb.InstanceID{ReplicaID: 0, InstanceNum: 3},
+			ConflictStatus:   pb.InstanceState_None,
+		}),
+	}
+	p.assertOutbox(t, reply)
+}
+
+// TestOnTryPreAcceptReplyQuorum verifies that a quorum of conflict-free
+// TryPreAcceptReply messages moves the instance to the slow path with
+// its attributes unchanged.
+func TestOnTryPreAcceptReplyQuorum(t *testing.T) {
+	p := newTestingEPaxos()
+
+	newInst := p.onRequest(testingCmd)
+	p.clearMsgs()
+	newInst.assertState(pb.InstanceState_PreAccepted)
+
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: testingInstanceID,
+		Type:       pb.WrapMessageInner(&pb.TryPreAcceptReply{}),
+	})
+
+	// One reply plus the leader is a quorum of three replicas.
+	newInst.assertState(pb.InstanceState_Accepted)
+
+	// Assert outbox.
+	instData := testingInstanceData
+	instData.Command = nil
+	msg := pb.Message{
+		InstanceID: testingInstanceID,
+		Type:       pb.WrapMessageInner(&pb.Accept{InstanceData: instData}),
+	}
+	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
+}
+
+// TestOnTryPreAcceptReplyConflict verifies that a conflicting
+// TryPreAcceptReply falls back to a fresh PreAccept round.
+func TestOnTryPreAcceptReplyConflict(t *testing.T) {
+	p := newTestingEPaxos()
+
+	newInst := p.onRequest(testingCmd)
+	p.clearMsgs()
+	newInst.assertState(pb.InstanceState_PreAccepted)
+
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: testingInstanceID,
+		Type: pb.WrapMessageInner(&pb.TryPreAcceptReply{
+			Conflict:         true,
+			ConflictInstance: pb.InstanceID{ReplicaID: 1, InstanceNum: 2},
+			ConflictStatus:   pb.InstanceState_Committed,
+		}),
+	})
+
+	// The instance remains in Phase 1 with its reply state reset.
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	if a := newInst.preAcceptReplies; a != 0 {
+		t.Errorf("expected reset preAcceptReplies, found %d", a)
+	}
+
+	// Assert outbox.
+	instData := testingInstanceData
+	instData.CommandDigest = testingCmd.Digest()
+	msg := pb.Message{
+		InstanceID: testingInstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: instData}),
+	}
+	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
+}
+
+// TestOnPreAcceptStaleBallot verifies that a message carrying a ballot
+// older than the highest ballot seen for the instance is rejected with a
+// Nack carrying the higher ballot.
+func TestOnPreAcceptStaleBallot(t *testing.T) {
+	p := newTestingEPaxos()
+
+	// Handle a PreAccept at ballot 1.1, which is adopted.
+	instMeta, instData, _ := preAcceptMsg()
+	p.Step(pb.Message{
+		Ballot:     pb.Ballot{Number: 1, ReplicaID: 1},
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: instData}),
+	})
+	inst := p.getInstance(instMeta.ReplicaID, instMeta.InstanceNum)
+	if a, e := *inst.is.Ballot, (pb.Ballot{Number: 1, ReplicaID: 1}); a != e {
+		t.Fatalf("expected adopted ballot %+v, found %+v", e, a)
+	}
+	p.clearMsgs()
+
+	// A PreAccept at the older ballot 0.2 is nacked.
+	p.Step(pb.Message{
+		Ballot:     pb.Ballot{ReplicaID: 2},
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: instData}),
+	})
+	reply := pb.Message{
+		To:         1,
+		Ballot:     pb.Ballot{Number: 1, ReplicaID: 1},
+		InstanceID: instMeta,
+		Type:       pb.WrapMessageInner(&pb.Nack{}),
+	}
+	p.assertOutbox(t, reply)
+}
+
+// TestOnNackRepropose verifies that a nacked leader re-proposes the
+// instance's current phase at a ballot above the one carried by the
+// nack, and that replies from the superseded round are ignored.
+func TestOnNackRepropose(t *testing.T) {
+	p := newTestingEPaxos()
+
+	newInst := p.onRequest(testingCmd)
+	p.clearMsgs()
+	newInst.assertState(pb.InstanceState_PreAccepted)
+
+	p.Step(pb.Message{
+		To:         0,
+		Ballot:     pb.Ballot{Number: 5, ReplicaID: 1},
+		InstanceID: testingInstanceID,
+		Type:       pb.WrapMessageInner(&pb.Nack{}),
+	})
+
+	// The instance re-proposes above the nacking replica's ballot.
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	if a, e := *newInst.is.Ballot, (pb.Ballot{Number: 6}); a != e {
+		t.Fatalf("expected re-proposal ballot %+v, found %+v", e, a)
+	}
+
+	// The re-proposal references the command by digest, as on restart.
+	instData := testingInstanceData
+	instData.Command = nil
+	instData.CommandDigest = testingCmd.Digest()
+	msg := pb.Message{
+		Ballot:     pb.Ballot{Number: 6},
+		InstanceID: testingInstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: instData}),
+	}
+	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
+	p.clearMsgs()
+
+	// A reply from the superseded round carries a stale ballot and is
+	// ignored.
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: testingInstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	if a := newInst.preAcceptReplies; a != 0 {
+		t.Errorf("expected stale reply ignored, found %d replies", a)
+	}
+}
+
+// TestOnTryPreAcceptReplyConflictOptimized verifies that with
+// OptimizedFastQuorum set, a recovering leader does not abandon the
+// probed attributes on the first conflict report, since the conflicting
+// replica may simply not have been part of a successful fast quorum. A
+// second conflict rules the fast path out and triggers the fallback
+// PreAccept round.
+func TestOnTryPreAcceptReplyConflictOptimized(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:                  0,
+		Nodes:               []pb.ReplicaID{0, 1, 2, 3, 4},
+		OptimizedFastQuorum: true,
+	})
+
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	p.clearMsgs()
+
+	conflict := pb.Message{
+		To:         0,
+		InstanceID: newInst.is.InstanceID,
+		Type: pb.WrapMessageInner(&pb.TryPreAcceptReply{
+			Conflict:         true,
+			ConflictInstance: pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
+			ConflictStatus:   pb.InstanceState_PreAccepted,
+		}),
+	}
+
+	// The first conflict is not yet conclusive; the probe stays open.
+	conflict.From = 1
+	p.Step(conflict)
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	p.assertOutboxEmpty(t)
+
+	// A second conflict rules out a fast-path commit of the probed
+	// attributes; a fresh PreAccept round recomputes them.
+	conflict.From = 2
+	p.Step(conflict)
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	if a := newInst.tryPreAcceptConflicts; a != 0 {
+		t.Errorf("expected conflict count reset by fallback, found %d", a)
+	}
+	if l := len(p.msgs); l != 4 {
+		t.Fatalf("expected fallback PreAccept to all 4 peers, found %+v", p.msgs)
+	}
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_PreAccept); !ok {
+			t.Fatalf("expected fallback message to be a PreAccept, found %+v", m)
+		}
+	}
+}
+
+// TestInstanceAbandonedAfterMaxBallotAttempts verifies that a proposal
+// preempted past MaxBallotAttempts is parked and surfaced through an
+// InstanceAbandonedEvent instead of being retried forever, and that the
+// parked instance ignores further messages.
+func TestInstanceAbandonedAfterMaxBallotAttempts(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:                0,
+		Nodes:             []pb.ReplicaID{0, 1, 2},
+		MaxBallotAttempts: 3,
+		OnEvent:           func(e Event) { events = append(events, e) },
+	})
+	inst := p.onRequest(newTestingCommand("a", "z"))
+	p.clearMsgs()
+
+	// Preempt the proposal with ever-higher ballots until the cap trips.
+	for i := 1; !inst.abandoned; i++ {
+		if i > 10 {
+			t.Fatalf("instance never abandoned")
+		}
+		p.Step(pb.Message{
+			To:         0,
+			From:       1,
+			Ballot:     pb.Ballot{Number: uint64(i), ReplicaID: 1},
+			InstanceID: inst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.Nack{}),
+		})
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected an InstanceAbandonedEvent, found %+v", events)
+	}
+	e, ok := events[0].(InstanceAbandonedEvent)
+	if !ok || e.InstanceID != inst.is.InstanceID {
+		t.Fatalf("expected InstanceAbandonedEvent for %+v, found %+v", inst.is.InstanceID, events[0])
+	}
+	if int(e.Ballot.Number) <= 3 {
+		t.Errorf("expected abandonment past the ballot cap, found %+v", e)
+	}
+
+	// The parked instance ignores further messages.
+	p.clearMsgs()
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		Ballot:     pb.Ballot{Number: 100, ReplicaID: 1},
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	inst.assertState(pb.InstanceState_PreAccepted)
+	p.assertOutboxEmpty(t)
+}
@@ -0,0 +1,65 @@
+package epaxos
+
+import (
+	"math"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// InterferenceFunc reports whether two commands interfere. It abstracts
+// over pb.Command.Interferes so that applications experimenting with
+// alternate interference semantics can analyze them before deploying.
+type InterferenceFunc func(a, b pb.Command) bool
+
+// InterferenceReport summarizes how a sample workload behaves under an
+// interference function.
+type InterferenceReport struct {
+	// Commands is the number of commands in the sampled workload.
+	Commands int
+	// ConflictRate is the fraction of distinct command pairs in the
+	// workload that interfere.
+	ConflictRate float64
+	// EstimatedFastPath is the estimated fraction of proposals that
+	// would commit on the fast path at the analyzed concurrency level.
+	EstimatedFastPath float64
+}
+
+// AnalyzeInterference runs the interference function over all distinct
+// pairs of commands in the sample workload and reports the workload's
+// conflict rate along with an estimate of the fast-path commit rate.
+//
+// EPaxos commits on the fast path when no interfering command is proposed
+// concurrently at another replica; with `concurrency` commands in flight
+// cluster-wide, a proposal's fast-path probability is approximated as the
+// chance that none of the other concurrent commands interfere with it:
+//
+//	(1 - ConflictRate)^(concurrency-1)
+//
+// The estimate is intentionally simple — it assumes commands are drawn
+// independently from the sampled workload — but it is a useful first-order
+// guide when designing command interference for EPaxos-friendliness.
+func AnalyzeInterference(
+	fn InterferenceFunc, workload []pb.Command, concurrency int,
+) InterferenceReport {
+	report := InterferenceReport{Commands: len(workload)}
+
+	var pairs, conflicts int
+	for i := range workload {
+		for j := i + 1; j < len(workload); j++ {
+			pairs++
+			if fn(workload[i], workload[j]) {
+				conflicts++
+			}
+		}
+	}
+	if pairs > 0 {
+		report.ConflictRate = float64(conflicts) / float64(pairs)
+	}
+
+	report.EstimatedFastPath = 1
+	if concurrency > 1 {
+		report.EstimatedFastPath =
+			math.Pow(1-report.ConflictRate, float64(concurrency-1))
+	}
+	return report
+}
@@ -0,0 +1,69 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+func TestAnalyzeInterference(t *testing.T) {
+	interferes := func(a, b pb.Command) bool { return a.Interferes(b) }
+
+	read := func(start, end string) pb.Command {
+		cmd := *newTestingCommand(start, end)
+		cmd.Writing = false
+		return cmd
+	}
+	write := func(start, end string) pb.Command {
+		return *newTestingCommand(start, end)
+	}
+
+	testCases := []struct {
+		workload     []pb.Command
+		concurrency  int
+		conflictRate float64
+		fastPath     float64
+	}{
+		// Read-only workloads never conflict.
+		{
+			workload:     []pb.Command{read("a", "z"), read("a", "z"), read("a", "z")},
+			concurrency:  4,
+			conflictRate: 0,
+			fastPath:     1,
+		},
+		// Overlapping writes always conflict.
+		{
+			workload:     []pb.Command{write("a", "z"), write("a", "z")},
+			concurrency:  2,
+			conflictRate: 1,
+			fastPath:     0,
+		},
+		// Disjoint writes never conflict.
+		{
+			workload:     []pb.Command{write("a", "b"), write("c", "d"), write("e", "f")},
+			concurrency:  8,
+			conflictRate: 0,
+			fastPath:     1,
+		},
+		// Half the pairs conflict; a single in-flight command always
+		// takes the fast path regardless.
+		{
+			workload:     []pb.Command{write("a", "b"), write("a", "b"), read("c", "d"), read("c", "d")},
+			concurrency:  1,
+			conflictRate: 1.0 / 6.0,
+			fastPath:     1,
+		},
+	}
+	for i, tc := range testCases {
+		report := AnalyzeInterference(interferes, tc.workload, tc.concurrency)
+		if report.Commands != len(tc.workload) {
+			t.Errorf("%d: expected %d commands, found %d", i, len(tc.workload), report.Commands)
+		}
+		if report.ConflictRate != tc.conflictRate {
+			t.Errorf("%d: expected conflict rate %f, found %f", i, tc.conflictRate, report.ConflictRate)
+		}
+		if report.EstimatedFastPath != tc.fastPath {
+			t.Errorf("%d: expected fast-path estimate %f, found %f", i, tc.fastPath, report.EstimatedFastPath)
+		}
+	}
+}
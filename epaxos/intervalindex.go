@@ -0,0 +1,140 @@
+package epaxos
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/util/interval"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Interval-based conflict detection.
+//
+// Dependency computation needs every prior instance whose command may
+// interfere with a proposal. Descending the full command space finds them,
+// but makes proposal cost proportional to the number of live instances
+// rather than the number of actual conflicts. Each command space therefore
+// also maintains an interval tree over command key ranges, so that a
+// lookup only visits the instances whose ranges overlap the proposal's.
+// Instances whose interference cannot be judged by range — commands still
+// referenced only by digest, and membership changes, which interfere with
+// everything — are held in a side bucket that every lookup considers.
+
+// depTreeEntry is the interval tree element for one range-indexed
+// instance.
+type depTreeEntry struct {
+	inst *instance
+	rng  interval.Range
+}
+
+// Range implements the interval.Interface interface.
+func (e *depTreeEntry) Range() interval.Range { return e.rng }
+
+// ID implements the interval.Interface interface. Instance numbers are
+// unique within a command space, which is the only scope entry IDs are
+// compared in.
+func (e *depTreeEntry) ID() uintptr { return uintptr(e.inst.is.InstanceNum) }
+
+// depSpaceIndex indexes one replica's command space for conflict
+// detection. It mirrors the instances in the space's btree: every instance
+// is either range-indexed in the interval tree or held in the always
+// bucket.
+type depSpaceIndex struct {
+	tree interval.Tree
+	// always holds the instances that every lookup must consider
+	// regardless of its range: instances with unresolved commands,
+	// membership changes, and commands with degenerate ranges.
+	always map[pb.InstanceNum]*instance
+	// entries tracks the tree entry of each range-indexed instance, so
+	// that an instance whose command changes is reindexed under the new
+	// range.
+	entries map[pb.InstanceNum]*depTreeEntry
+}
+
+func newDepSpaceIndex() *depSpaceIndex {
+	return &depSpaceIndex{
+		tree:    interval.Tree{Overlapper: interval.Range.OverlapExclusive},
+		always:  make(map[pb.InstanceNum]*instance),
+		entries: make(map[pb.InstanceNum]*depTreeEntry),
+	}
+}
+
+// add indexes the instance under its command's current range, moving it
+// between the tree and the always bucket as the command resolves or is
+// replaced. It is idempotent and safe to call whenever the instance's
+// command may have changed.
+func (x *depSpaceIndex) add(inst *instance) {
+	num := inst.is.InstanceNum
+	cmd := inst.is.Command
+	if cmd == nil || cmd.ConfChange {
+		x.deleteEntry(num)
+		x.always[num] = inst
+		return
+	}
+	rng := rangeForCmd(cmd)
+	if rng.Start.Compare(rng.End) >= 0 {
+		// An empty or inverted range cannot be stored in the tree.
+		// Consider the instance on every lookup instead; the Interferes
+		// check filters it like any other candidate.
+		x.deleteEntry(num)
+		x.always[num] = inst
+		return
+	}
+	if e, ok := x.entries[num]; ok {
+		if e.rng.Start.Equal(rng.Start) && e.rng.End.Equal(rng.End) {
+			return
+		}
+		x.deleteEntry(num)
+	}
+	delete(x.always, num)
+	e := &depTreeEntry{inst: inst, rng: rng}
+	if err := x.tree.Insert(e, false /* fast */); err != nil {
+		panic(err)
+	}
+	x.entries[num] = e
+}
+
+// remove drops the instance from the index.
+func (x *depSpaceIndex) remove(inst *instance) {
+	x.deleteEntry(inst.is.InstanceNum)
+	delete(x.always, inst.is.InstanceNum)
+}
+
+func (x *depSpaceIndex) deleteEntry(num pb.InstanceNum) {
+	if e, ok := x.entries[num]; ok {
+		if err := x.tree.Delete(e, false /* fast */); err != nil {
+			panic(err)
+		}
+		delete(x.entries, num)
+	}
+}
+
+// candidates returns the instances that may interfere with commands over
+// the provided ranges, newest first. The result is a superset that the
+// caller filters with the Interferes check; visiting it in descending
+// instance order matches the order a descent of the full command space
+// would visit the same instances in, which the transitive dependency
+// elimination in the scan relies on.
+func (x *depSpaceIndex) candidates(rngs ...interval.Range) []*instance {
+	cands := make([]*instance, 0, len(x.always))
+	seen := make(map[pb.InstanceNum]struct{}, len(x.always))
+	add := func(inst *instance) {
+		if _, ok := seen[inst.is.InstanceNum]; !ok {
+			seen[inst.is.InstanceNum] = struct{}{}
+			cands = append(cands, inst)
+		}
+	}
+	for _, inst := range x.always {
+		add(inst)
+	}
+	for _, rng := range rngs {
+		x.tree.DoMatching(func(e interval.Interface) bool {
+			add(e.(*depTreeEntry).inst)
+			return false
+		}, rng)
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		return cands[i].is.InstanceNum > cands[j].is.InstanceNum
+	})
+	return cands
+}
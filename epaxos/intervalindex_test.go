@@ -0,0 +1,88 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestDepSpaceIndexCandidates verifies that lookups only surface the
+// instances whose command ranges overlap the queried range, newest first.
+func TestDepSpaceIndexCandidates(t *testing.T) {
+	p := newTestingEPaxos()
+
+	// Replica 1's command space holds [a, z) at instance 1 and [n, z) at
+	// instance 2.
+	idx := p.depIndex[1]
+	cands := idx.candidates(rangeForCmd(newTestingCommand("b", "c")))
+	if len(cands) != 1 || cands[0].is.InstanceNum != 1 {
+		t.Errorf("expected only instance 1 to overlap [b, c), found %+v", cands)
+	}
+	cands = idx.candidates(rangeForCmd(newTestingCommand("x", "y")))
+	if len(cands) != 2 || cands[0].is.InstanceNum != 2 || cands[1].is.InstanceNum != 1 {
+		t.Errorf("expected instances [2, 1] to overlap [x, y), found %+v", cands)
+	}
+}
+
+// TestDepSpaceIndexUnresolvedCommand verifies that instances whose
+// commands are still unresolved are considered by every lookup, and that
+// resolving the command reindexes the instance under its range.
+func TestDepSpaceIndexUnresolvedCommand(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	inst := p.newInstance(1, 1)
+	p.commands[1].ReplaceOrInsert(inst)
+	p.indexCommand(inst)
+
+	// The proposal does not overlap the unresolved command, but must
+	// conservatively depend on it.
+	_, deps := p.seqAndDepsForCommand(newTestingCommand("a", "b"), pb.InstanceID{})
+	if _, ok := deps[inst.is.InstanceID]; !ok {
+		t.Errorf("expected conservative dependency on unresolved instance, found %+v", deps)
+	}
+
+	// Resolving the command to a non-overlapping range drops the
+	// dependency.
+	inst.is.Command = newTestingCommand("x", "z")
+	p.indexCommand(inst)
+	_, deps = p.seqAndDepsForCommand(newTestingCommand("a", "b"), pb.InstanceID{})
+	if len(deps) != 0 {
+		t.Errorf("expected no dependencies after resolution, found %+v", deps)
+	}
+}
+
+// TestDepSpaceIndexConfChange verifies that membership changes, which
+// interfere with every command, are surfaced for ranges they do not
+// overlap.
+func TestDepSpaceIndexConfChange(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	cmd := newTestingCommand("a", "b")
+	cmd.ConfChange = true
+	inst := p.newInstance(1, 1)
+	inst.is.Command = cmd
+	p.commands[1].ReplaceOrInsert(inst)
+	p.indexCommand(inst)
+
+	_, deps := p.seqAndDepsForCommand(newTestingCommand("x", "z"), pb.InstanceID{})
+	if _, ok := deps[inst.is.InstanceID]; !ok {
+		t.Errorf("expected dependency on membership change, found %+v", deps)
+	}
+}
+
+// TestDepSpaceIndexTruncation verifies that truncated instances stop
+// contributing dependencies.
+func TestDepSpaceIndexTruncation(t *testing.T) {
+	p := newTestingEPaxos()
+
+	_, deps := p.seqAndDepsForCommand(newTestingCommand("a", "z"), pb.InstanceID{})
+	if _, ok := deps[pb.InstanceID{ReplicaID: 2, InstanceNum: 1}]; !ok {
+		t.Fatalf("expected dependency on instance 2.1, found %+v", deps)
+	}
+
+	p.truncateSpace(2, 1)
+	_, deps = p.seqAndDepsForCommand(newTestingCommand("a", "z"), pb.InstanceID{})
+	if _, ok := deps[pb.InstanceID{ReplicaID: 2, InstanceNum: 1}]; ok {
+		t.Errorf("expected no dependency on truncated instance, found %+v", deps)
+	}
+}
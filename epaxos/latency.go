@@ -0,0 +1,116 @@
+package epaxos
+
+import (
+	"time"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Proposal latency attribution.
+//
+// Aggregate histograms show that a tail exists but not where it comes
+// from. When latency sampling is enabled, every Nth local proposal
+// carries a small record through its lifetime, stamping the boundary of
+// each phase: how long dependency computation took, how many ticks
+// passed before a quorum of replies arrived, how many additional ticks
+// the slow path cost, and how long the committed command then waited for
+// its dependencies to execute. The breakdown is emitted as a
+// ProposalLatencyEvent when the proposal executes.
+
+// ProposalLatencyEvent reports the per-phase latency breakdown of a
+// sampled local proposal. Tick-denominated phases are measured in the
+// node's ticks, so their real-time meaning depends on the application's
+// tick interval.
+type ProposalLatencyEvent struct {
+	// InstanceID identifies the sampled proposal's instance.
+	InstanceID pb.InstanceID
+	// CommandID is the ID of the proposed command.
+	CommandID uint64
+	// DepCompute is the wall time spent computing the proposal's
+	// dependencies and sequence number.
+	DepCompute time.Duration
+	// QuorumWaitTicks is the number of ticks between the proposal and
+	// the quorum of replies that decided its commit path.
+	QuorumWaitTicks uint64
+	// SlowPath is whether the proposal fell back to the Accept phase.
+	SlowPath bool
+	// SlowPathTicks is the number of additional ticks the Accept phase
+	// cost. It is zero for fast-path commits.
+	SlowPathTicks uint64
+	// ExecuteWaitTicks is the number of ticks the committed proposal
+	// waited for its dependencies before executing.
+	ExecuteWaitTicks uint64
+	// TotalTicks is the number of ticks from proposal to execution.
+	TotalTicks uint64
+}
+
+func (ProposalLatencyEvent) event() {}
+
+// proposalLatency accumulates the phase boundaries of a sampled
+// proposal. It hangs off the instance on the proposing replica only.
+type proposalLatency struct {
+	proposedTick  uint64
+	depCompute    time.Duration
+	quorumTick    uint64
+	slowPath      bool
+	committedTick uint64
+}
+
+// maybeSampleLatency decides whether the next local proposal is sampled
+// for latency attribution, returning its record if so.
+func (p *epaxos) maybeSampleLatency() *proposalLatency {
+	if p.latencySampleEvery == 0 {
+		return nil
+	}
+	p.latencySampleCount++
+	if p.latencySampleCount%uint64(p.latencySampleEvery) != 0 {
+		return nil
+	}
+	return &proposalLatency{proposedTick: p.ticks}
+}
+
+// noteLatencyQuorum stamps the arrival of the quorum of replies that
+// decided the instance's commit path, along with whether that path is
+// the slow one. The first stamp wins: a fast-path commit after an
+// Accept round does not occur, and repeated transitions are ignored.
+func (inst *instance) noteLatencyQuorum(slowPath bool) {
+	l := inst.latency
+	if l == nil || l.quorumTick != 0 || l.slowPath {
+		return
+	}
+	l.quorumTick = inst.p.ticks
+	l.slowPath = slowPath
+}
+
+// noteLatencyCommitted stamps the instance's commit.
+func (inst *instance) noteLatencyCommitted() {
+	if l := inst.latency; l != nil {
+		l.committedTick = inst.p.ticks
+	}
+}
+
+// noteLatencyExecuted completes the sampled record and emits the
+// breakdown.
+func (inst *instance) noteLatencyExecuted() {
+	l := inst.latency
+	if l == nil {
+		return
+	}
+	inst.latency = nil
+	quorumTick := l.quorumTick
+	if quorumTick == 0 {
+		// Single-node configurations commit without replies; the quorum
+		// was trivially present at proposal time.
+		quorumTick = l.proposedTick
+	}
+	inst.p.emitEvent(ProposalLatencyEvent{
+		InstanceID:       inst.is.InstanceID,
+		CommandID:        inst.is.Command.ID,
+		DepCompute:       l.depCompute,
+		QuorumWaitTicks:  quorumTick - l.proposedTick,
+		SlowPath:         l.slowPath,
+		SlowPathTicks:    l.committedTick - quorumTick,
+		ExecuteWaitTicks: inst.p.ticks - l.committedTick,
+		TotalTicks:       inst.p.ticks - l.proposedTick,
+	})
+}
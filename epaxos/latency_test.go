@@ -0,0 +1,128 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestProposalLatencyBreakdown verifies that a sampled proposal's
+// ProposalLatencyEvent attributes its lifetime to the quorum wait, the
+// slow path, and the execute wait, and that the sampling rate skips
+// unsampled proposals.
+func TestProposalLatencyBreakdown(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:                 0,
+		Nodes:              []pb.ReplicaID{0, 1, 2},
+		LatencySampleEvery: 2,
+		OnEvent:            func(e Event) { events = append(events, e) },
+	})
+
+	// The first proposal is unsampled under LatencySampleEvery: 2; the
+	// second, interfering proposal is sampled and depends on the first.
+	inst1 := p.onRequest(newTestingCommand("a", "z"))
+	inst2 := p.onRequest(newTestingCommand("a", "z"))
+	if inst1.latency != nil {
+		t.Fatalf("expected first proposal to be unsampled")
+	}
+	if inst2.latency == nil {
+		t.Fatalf("expected second proposal to be sampled")
+	}
+
+	// Two ticks pass before a reply with a bumped sequence number forces
+	// the sampled proposal onto the slow path.
+	p.TickN(2)
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst2.is.InstanceID,
+		Type: pb.WrapMessageInner(&pb.PreAcceptReply{
+			UpdatedSeqNum: inst2.is.SeqNum + 1,
+			UpdatedDeps:   inst2.is.Deps,
+		}),
+	})
+	inst2.assertState(pb.InstanceState_Accepted)
+
+	// Three more ticks pass before the Accept round completes. The
+	// committed proposal cannot execute yet: its dependency on the first
+	// proposal is still in flight.
+	p.TickN(3)
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst2.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.AcceptOK{}),
+	})
+	inst2.assertState(pb.InstanceState_Committed)
+
+	// Four more ticks pass before the dependency commits, executes, and
+	// unblocks the sampled proposal.
+	p.TickN(4)
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst1.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	inst2.assertState(pb.InstanceState_Executed)
+
+	var latencies []ProposalLatencyEvent
+	for _, e := range events {
+		if le, ok := e.(ProposalLatencyEvent); ok {
+			latencies = append(latencies, le)
+		}
+	}
+	if len(latencies) != 1 {
+		t.Fatalf("expected 1 ProposalLatencyEvent, found %+v", latencies)
+	}
+	le := latencies[0]
+	if le.InstanceID != inst2.is.InstanceID {
+		t.Errorf("expected event for %v, found %v", inst2.is.InstanceID, le.InstanceID)
+	}
+	if le.QuorumWaitTicks != 2 {
+		t.Errorf("expected 2 quorum wait ticks, found %d", le.QuorumWaitTicks)
+	}
+	if !le.SlowPath || le.SlowPathTicks != 3 {
+		t.Errorf("expected 3 slow-path ticks, found (%t, %d)", le.SlowPath, le.SlowPathTicks)
+	}
+	if le.ExecuteWaitTicks != 4 {
+		t.Errorf("expected 4 execute wait ticks, found %d", le.ExecuteWaitTicks)
+	}
+	if le.TotalTicks != 9 {
+		t.Errorf("expected 9 total ticks, found %d", le.TotalTicks)
+	}
+}
+
+// TestProposalLatencyFastPath verifies the breakdown of a fast-path
+// commit: no slow-path ticks are attributed.
+func TestProposalLatencyFastPath(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:                 0,
+		Nodes:              []pb.ReplicaID{0, 1, 2},
+		LatencySampleEvery: 1,
+		OnEvent:            func(e Event) { events = append(events, e) },
+	})
+
+	inst := p.onRequest(newTestingCommand("a", "z"))
+	p.TickN(4)
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	inst.assertState(pb.InstanceState_Executed)
+
+	for _, e := range events {
+		if le, ok := e.(ProposalLatencyEvent); ok {
+			if le.QuorumWaitTicks != 4 || le.SlowPath || le.SlowPathTicks != 0 ||
+				le.ExecuteWaitTicks != 0 || le.TotalTicks != 4 {
+				t.Errorf("expected fast-path breakdown (4, false, 0, 0, 4), found %+v", le)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected a ProposalLatencyEvent, found %+v", events)
+}
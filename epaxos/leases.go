@@ -0,0 +1,236 @@
+package epaxos
+
+import (
+	"bytes"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Quorum read leases.
+//
+// Committed reads normally run through consensus like any other command,
+// paying a round trip even when the local replica already holds the
+// current state. A quorum read lease removes that round trip for a key
+// range: a replica requests a lease on the range, and each peer that
+// grants one promises to send the holder an invalidation when it
+// observes an interfering write commit. Once a quorum of the cluster has
+// granted, the holder may serve reads of the range from local state
+// without running consensus.
+//
+// Lease reads are bounded-stale, not linearizable. Invalidations are
+// fire-and-forget: nothing delays a write's commit or its
+// acknowledgment until the affected holders have acknowledged their
+// invalidations or their leases have expired, so a holder can serve a
+// read from state that predates a write already acknowledged elsewhere,
+// its invalidation still in flight. The staleness window is bounded by
+// the delivery time of the granters' LeaseInvalidate messages and, if
+// every invalidation is lost, by the lease's own expiry. Removing the
+// window would take an invalidation-acknowledgment round that interfering
+// writes wait on before becoming visible, as in quorum-lease designs;
+// until then, reads that need linearizability must run through
+// consensus.
+//
+// Leases are time-bounded in ticks. The holder's lease expires
+// ReadLeaseTicks after it was requested, while each granter remembers
+// its grant for ReadLeaseTicks after the request arrived; the grant
+// therefore outlives the lease, so a granter never stops invalidating
+// before the holder has stopped reading. A holder renews by simply
+// requesting the lease again, re-collecting grants from scratch. Writes
+// invalidate eagerly: when an interfering writing command commits, a
+// granter sends LeaseInvalidate to the affected holders and a holder
+// drops its own affected leases, whichever it sees first.
+
+// heldLease is a read lease this replica has requested, tracking the
+// grants collected so far and the lease's remaining lifetime.
+type heldLease struct {
+	span   pb.Span
+	grants map[pb.ReplicaID]struct{}
+	active bool
+	timer  tickingTimer
+}
+
+// grantedLease records a read lease granted to a peer, which must be
+// invalidated if an interfering write commits before it expires.
+type grantedLease struct {
+	span   pb.Span
+	holder pb.ReplicaID
+	// expiry is the tick at which the grant lapses on its own.
+	expiry uint64
+}
+
+// RequestReadLease requests a read lease on the key range, broadcasting
+// a LeaseRequest to every peer. The lease activates once a quorum of the
+// cluster, counting this replica, has granted it, and lapses after
+// ReadLeaseTicks ticks; requesting the same range again renews it.
+func (p *epaxos) RequestReadLease(span pb.Span) {
+	if p.readLeaseTicks == 0 {
+		p.logger.Debugf("ignoring read lease request; leasing is disabled")
+		return
+	}
+	l := p.findHeldLease(span)
+	if l == nil {
+		l = &heldLease{span: span}
+		p.heldLeases = append(p.heldLeases, l)
+	}
+	// Grants are re-collected from scratch on renewal, so a renewed
+	// lease is inactive until a quorum answers again.
+	l.grants = make(map[pb.ReplicaID]struct{}, len(p.nodes)-1)
+	l.active = p.quorum(1)
+	l.timer = makeTickingTimer(p.readLeaseTicks, func() {
+		p.dropHeldLease(l)
+	})
+	p.registerOneTimeTimer(&l.timer)
+	req := &pb.LeaseRequest{Span: span, Ticks: uint64(p.readLeaseTicks)}
+	for _, node := range p.nodes {
+		if node != p.id {
+			p.msgs = append(p.msgs, pb.Message{
+				To:   node,
+				From: p.id,
+				Type: pb.WrapMessageInner(req),
+			})
+		}
+	}
+}
+
+// HoldsReadLease returns whether this replica holds an active read lease
+// covering the key range, in which case reads of the range may be served
+// from local state without running consensus. Such reads are
+// bounded-stale, not linearizable; see the file comment.
+func (p *epaxos) HoldsReadLease(span pb.Span) bool {
+	for _, l := range p.heldLeases {
+		if l.active && spanContains(l.span, span) {
+			return true
+		}
+	}
+	return false
+}
+
+// findHeldLease returns the held lease on exactly the given range, if
+// any.
+func (p *epaxos) findHeldLease(span pb.Span) *heldLease {
+	for _, l := range p.heldLeases {
+		if l.span.Equal(span) {
+			return l
+		}
+	}
+	return nil
+}
+
+// dropHeldLease discards a held lease, whether it expired, was
+// invalidated by a granter, or was undercut by a local commit.
+func (p *epaxos) dropHeldLease(l *heldLease) {
+	for i, held := range p.heldLeases {
+		if held == l {
+			p.heldLeases = append(p.heldLeases[:i], p.heldLeases[i+1:]...)
+			break
+		}
+	}
+	l.active = false
+	l.timer.stop()
+	p.unregisterTimer(&l.timer)
+}
+
+// onLeaseRequest grants a peer's read lease request, recording the grant
+// so that interfering writes invalidate it, and answers with a
+// LeaseGrant. A repeated request from the same holder refreshes the
+// existing grant.
+func (p *epaxos) onLeaseRequest(m pb.Message, lr *pb.LeaseRequest) {
+	expiry := p.ticks + lr.Ticks
+	refreshed := false
+	for _, g := range p.grantedLeases {
+		if g.holder == m.From && g.span.Equal(lr.Span) {
+			g.expiry = expiry
+			refreshed = true
+		}
+	}
+	if !refreshed {
+		p.grantedLeases = append(p.grantedLeases, &grantedLease{
+			span:   lr.Span,
+			holder: m.From,
+			expiry: expiry,
+		})
+	}
+	p.msgs = append(p.msgs, pb.Message{
+		To:   m.From,
+		From: p.id,
+		Type: pb.WrapMessageInner(&pb.LeaseGrant{Span: lr.Span, Ticks: lr.Ticks}),
+	})
+}
+
+// onLeaseGrant counts a peer's grant toward the matching held lease,
+// activating the lease once a quorum of the cluster has granted it.
+func (p *epaxos) onLeaseGrant(m pb.Message, lg *pb.LeaseGrant) {
+	l := p.findHeldLease(lg.Span)
+	if l == nil {
+		// The lease expired or was invalidated while the grant was in
+		// flight.
+		return
+	}
+	l.grants[m.From] = struct{}{}
+	if !l.active && p.quorum(len(l.grants)+1) {
+		p.logger.Debugf("read lease on %s active with grants from %v", l.span, l.grants)
+		l.active = true
+	}
+}
+
+// onLeaseInvalidate drops every held lease overlapping the invalidated
+// range. The granter saw an interfering write commit, so reads of the
+// range can no longer be served locally.
+func (p *epaxos) onLeaseInvalidate(m pb.Message, li *pb.LeaseInvalidate) {
+	held := append([]*heldLease(nil), p.heldLeases...)
+	for _, l := range held {
+		if li.Span.Overlaps(l.span) {
+			p.logger.Debugf("read lease on %s invalidated by replica %d", l.span, m.From)
+			p.dropHeldLease(l)
+		}
+	}
+}
+
+// maybeInvalidateReadLeases reacts to the commit of an interfering
+// writing command: grants covering the written range are invalidated at
+// their holders, and this replica's own overlapping leases are dropped.
+func (p *epaxos) maybeInvalidateReadLeases(inst *instance) {
+	if len(p.grantedLeases) == 0 && len(p.heldLeases) == 0 {
+		return
+	}
+	cmd := inst.is.Command
+	if cmd == nil || (!cmd.Writing && !cmd.ConfChange) {
+		return
+	}
+	var live []*grantedLease
+	for _, g := range p.grantedLeases {
+		if p.ticks >= g.expiry {
+			continue
+		}
+		if !cmd.ConfChange && !cmd.Span.Overlaps(g.span) {
+			live = append(live, g)
+			continue
+		}
+		p.msgs = append(p.msgs, pb.Message{
+			To:   g.holder,
+			From: p.id,
+			Type: pb.WrapMessageInner(&pb.LeaseInvalidate{Span: g.span}),
+		})
+	}
+	p.grantedLeases = live
+	held := append([]*heldLease(nil), p.heldLeases...)
+	for _, l := range held {
+		if cmd.ConfChange || cmd.Span.Overlaps(l.span) {
+			p.dropHeldLease(l)
+		}
+	}
+}
+
+// spanContains returns whether the outer span fully contains the inner
+// span, treating point spans as covering just past their key.
+func spanContains(outer, inner pb.Span) bool {
+	oEnd := outer.EndKey
+	if len(oEnd) == 0 {
+		oEnd = append(append(pb.Key(nil), outer.Key...), 0)
+	}
+	iEnd := inner.EndKey
+	if len(iEnd) == 0 {
+		iEnd = append(append(pb.Key(nil), inner.Key...), 0)
+	}
+	return bytes.Compare(outer.Key, inner.Key) <= 0 && bytes.Compare(iEnd, oEnd) <= 0
+}
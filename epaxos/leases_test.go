@@ -0,0 +1,192 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+func span(start, end string) pb.Span {
+	return pb.Span{Key: pb.Key(start), EndKey: pb.Key(end)}
+}
+
+// TestReadLeaseQuorumActivation verifies that a requested lease
+// broadcasts LeaseRequests and activates once a quorum of the cluster
+// has granted it.
+func TestReadLeaseQuorumActivation(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:             0,
+		Nodes:          []pb.ReplicaID{0, 1, 2},
+		ReadLeaseTicks: 10,
+	})
+
+	p.RequestReadLease(span("a", "m"))
+	reqs := 0
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_LeaseRequest); ok {
+			reqs++
+		}
+	}
+	if reqs != 2 {
+		t.Fatalf("expected a LeaseRequest per peer, found %+v", p.msgs)
+	}
+	if p.HoldsReadLease(span("a", "m")) {
+		t.Fatalf("expected no active lease before any grant")
+	}
+
+	// One grant plus the local replica is a quorum of three.
+	p.Step(pb.Message{
+		To:   0,
+		From: 1,
+		Type: pb.WrapMessageInner(&pb.LeaseGrant{Span: span("a", "m"), Ticks: 10}),
+	})
+	if !p.HoldsReadLease(span("a", "m")) {
+		t.Errorf("expected the lease active after a quorum of grants")
+	}
+	if !p.HoldsReadLease(span("c", "f")) {
+		t.Errorf("expected the lease to cover contained ranges")
+	}
+	if p.HoldsReadLease(span("c", "z")) {
+		t.Errorf("expected no coverage of ranges beyond the lease")
+	}
+}
+
+// TestReadLeaseExpiry verifies that a held lease lapses after
+// ReadLeaseTicks ticks.
+func TestReadLeaseExpiry(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:             0,
+		Nodes:          []pb.ReplicaID{0, 1, 2},
+		ReadLeaseTicks: 6,
+	})
+
+	p.RequestReadLease(span("a", "m"))
+	p.Step(pb.Message{
+		To:   0,
+		From: 1,
+		Type: pb.WrapMessageInner(&pb.LeaseGrant{Span: span("a", "m"), Ticks: 6}),
+	})
+	p.TickN(3)
+	if !p.HoldsReadLease(span("a", "m")) {
+		t.Fatalf("expected the lease still active before expiry")
+	}
+	p.TickN(3)
+	if p.HoldsReadLease(span("a", "m")) {
+		t.Errorf("expected the lease to lapse after %d ticks", 6)
+	}
+	if len(p.heldLeases) != 0 {
+		t.Errorf("expected the expired lease discarded, found %+v", p.heldLeases)
+	}
+}
+
+// TestReadLeaseGranterInvalidatesOnWrite verifies that a granter records
+// a peer's lease, answers with a grant, and invalidates the lease when
+// an interfering write commits.
+func TestReadLeaseGranterInvalidatesOnWrite(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:             0,
+		Nodes:          []pb.ReplicaID{0, 1, 2},
+		ReadLeaseTicks: 10,
+	})
+
+	p.Step(pb.Message{
+		To:   0,
+		From: 1,
+		Type: pb.WrapMessageInner(&pb.LeaseRequest{Span: span("a", "m"), Ticks: 10}),
+	})
+	if len(p.grantedLeases) != 1 || p.grantedLeases[0].holder != 1 {
+		t.Fatalf("expected a grant recorded for replica 1, found %+v", p.grantedLeases)
+	}
+	foundGrant := false
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_LeaseGrant); ok && m.To == 1 {
+			foundGrant = true
+		}
+	}
+	if !foundGrant {
+		t.Fatalf("expected a LeaseGrant reply, found %+v", p.msgs)
+	}
+	p.clearMsgs()
+
+	// A non-interfering write leaves the grant in place.
+	inst := p.onRequest(newTestingCommand("n", "z"))
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	if len(p.grantedLeases) != 1 {
+		t.Fatalf("expected the grant to survive a non-interfering write")
+	}
+	p.clearMsgs()
+
+	// An interfering write invalidates the grant at its holder.
+	inst = p.onRequest(newTestingCommand("c", "f"))
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	foundInval := false
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_LeaseInvalidate); ok && m.To == 1 {
+			foundInval = true
+		}
+	}
+	if !foundInval {
+		t.Errorf("expected a LeaseInvalidate sent to replica 1, found %+v", p.msgs)
+	}
+	if len(p.grantedLeases) != 0 {
+		t.Errorf("expected the invalidated grant discarded, found %+v", p.grantedLeases)
+	}
+}
+
+// TestReadLeaseHolderDropsOnInterference verifies that a holder drops
+// its lease both on a LeaseInvalidate from a granter and when an
+// interfering write commits locally.
+func TestReadLeaseHolderDropsOnInterference(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:             0,
+		Nodes:          []pb.ReplicaID{0, 1, 2},
+		ReadLeaseTicks: 10,
+	})
+
+	activate := func() {
+		p.RequestReadLease(span("a", "m"))
+		p.Step(pb.Message{
+			To:   0,
+			From: 1,
+			Type: pb.WrapMessageInner(&pb.LeaseGrant{Span: span("a", "m"), Ticks: 10}),
+		})
+		if !p.HoldsReadLease(span("a", "m")) {
+			t.Fatalf("expected the lease active")
+		}
+		p.clearMsgs()
+	}
+
+	// A granter's invalidation of an overlapping range drops the lease.
+	activate()
+	p.Step(pb.Message{
+		To:   0,
+		From: 2,
+		Type: pb.WrapMessageInner(&pb.LeaseInvalidate{Span: span("c", "f")}),
+	})
+	if p.HoldsReadLease(span("a", "m")) {
+		t.Errorf("expected the lease dropped on invalidation")
+	}
+
+	// So does a locally committed interfering write.
+	activate()
+	inst := p.onRequest(newTestingCommand("c", "f"))
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	if p.HoldsReadLease(span("a", "m")) {
+		t.Errorf("expected the lease dropped by the interfering commit")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 )
 
 // Logger provides a logging interface similar to golang's standard Logger.
@@ -105,7 +106,7 @@ func (l *DefaultLogger) Fatalf(format string, v ...interface{}) {
 
 // Panic implements the Logger interface.
 func (l *DefaultLogger) Panic(v ...interface{}) {
-	l.Logger.Panic(v)
+	l.Logger.Panic(v...)
 }
 
 // Panicf implements the Logger interface.
@@ -116,3 +117,151 @@ func (l *DefaultLogger) Panicf(format string, v ...interface{}) {
 func header(lvl, msg string) string {
 	return fmt.Sprintf("%s: %s", lvl, msg)
 }
+
+// LogSubsystem names a log-producing subsystem whose verbosity can be
+// adjusted independently.
+type LogSubsystem string
+
+// The log subsystems with independently adjustable verbosity.
+const (
+	LogSubsystemInstance  LogSubsystem = "instance"
+	LogSubsystemExecutor  LogSubsystem = "executor"
+	LogSubsystemTransport LogSubsystem = "transport"
+	LogSubsystemStorage   LogSubsystem = "storage"
+	LogSubsystemRecovery  LogSubsystem = "recovery"
+)
+
+// LogLevel is a minimum severity below which a subsystem's log output is
+// suppressed.
+type LogLevel int
+
+// The log levels, in increasing severity.
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarning
+	LogLevelError
+)
+
+// ParseLogLevel parses a log level by name.
+func ParseLogLevel(s string) (LogLevel, bool) {
+	switch s {
+	case "debug":
+		return LogLevelDebug, true
+	case "info":
+		return LogLevelInfo, true
+	case "warning":
+		return LogLevelWarning, true
+	case "error":
+		return LogLevelError, true
+	}
+	return 0, false
+}
+
+// SubsystemLevels holds per-subsystem minimum log levels. Unlike the rest
+// of the epaxos state machine, it is safe for concurrent use, so levels
+// can be adjusted at runtime from an admin endpoint while the state
+// machine runs. The zero level for every subsystem is LogLevelDebug,
+// passing all output through.
+type SubsystemLevels struct {
+	mu     sync.RWMutex
+	levels map[LogSubsystem]LogLevel
+}
+
+// NewSubsystemLevels returns a SubsystemLevels with every subsystem at
+// its default level.
+func NewSubsystemLevels() *SubsystemLevels {
+	return &SubsystemLevels{levels: make(map[LogSubsystem]LogLevel)}
+}
+
+// Set adjusts the subsystem's minimum log level.
+func (s *SubsystemLevels) Set(sub LogSubsystem, lvl LogLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.levels[sub] = lvl
+}
+
+// Level returns the subsystem's current minimum log level.
+func (s *SubsystemLevels) Level(sub LogSubsystem) LogLevel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.levels[sub]
+}
+
+// Logger wraps the base logger with the subsystem's level gate. Error and
+// more severe output always passes through.
+func (s *SubsystemLevels) Logger(sub LogSubsystem, base Logger) Logger {
+	return &leveledLogger{base: base, levels: s, sub: sub}
+}
+
+// leveledLogger gates a base Logger's output below the current level of
+// its subsystem.
+type leveledLogger struct {
+	base   Logger
+	levels *SubsystemLevels
+	sub    LogSubsystem
+}
+
+func (l *leveledLogger) enabled(lvl LogLevel) bool {
+	return l.levels.Level(l.sub) <= lvl
+}
+
+// Debug implements the Logger interface.
+func (l *leveledLogger) Debug(v ...interface{}) {
+	if l.enabled(LogLevelDebug) {
+		l.base.Debug(v...)
+	}
+}
+
+// Debugf implements the Logger interface.
+func (l *leveledLogger) Debugf(format string, v ...interface{}) {
+	if l.enabled(LogLevelDebug) {
+		l.base.Debugf(format, v...)
+	}
+}
+
+// Info implements the Logger interface.
+func (l *leveledLogger) Info(v ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.base.Info(v...)
+	}
+}
+
+// Infof implements the Logger interface.
+func (l *leveledLogger) Infof(format string, v ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.base.Infof(format, v...)
+	}
+}
+
+// Warning implements the Logger interface.
+func (l *leveledLogger) Warning(v ...interface{}) {
+	if l.enabled(LogLevelWarning) {
+		l.base.Warning(v...)
+	}
+}
+
+// Warningf implements the Logger interface.
+func (l *leveledLogger) Warningf(format string, v ...interface{}) {
+	if l.enabled(LogLevelWarning) {
+		l.base.Warningf(format, v...)
+	}
+}
+
+// Error implements the Logger interface.
+func (l *leveledLogger) Error(v ...interface{}) { l.base.Error(v...) }
+
+// Errorf implements the Logger interface.
+func (l *leveledLogger) Errorf(format string, v ...interface{}) { l.base.Errorf(format, v...) }
+
+// Fatal implements the Logger interface.
+func (l *leveledLogger) Fatal(v ...interface{}) { l.base.Fatal(v...) }
+
+// Fatalf implements the Logger interface.
+func (l *leveledLogger) Fatalf(format string, v ...interface{}) { l.base.Fatalf(format, v...) }
+
+// Panic implements the Logger interface.
+func (l *leveledLogger) Panic(v ...interface{}) { l.base.Panic(v...) }
+
+// Panicf implements the Logger interface.
+func (l *leveledLogger) Panicf(format string, v ...interface{}) { l.base.Panicf(format, v...) }
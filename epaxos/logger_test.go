@@ -0,0 +1,81 @@
+package epaxos
+
+import (
+	"fmt"
+	"testing"
+)
+
+// recordingLogger captures formatted log output for inspection.
+type recordingLogger struct {
+	Logger
+	lines []string
+}
+
+func (l *recordingLogger) Debugf(format string, v ...interface{}) {
+	l.lines = append(l.lines, "DEBUG: "+fmt.Sprintf(format, v...))
+}
+func (l *recordingLogger) Infof(format string, v ...interface{}) {
+	l.lines = append(l.lines, "INFO: "+fmt.Sprintf(format, v...))
+}
+func (l *recordingLogger) Warningf(format string, v ...interface{}) {
+	l.lines = append(l.lines, "WARN: "+fmt.Sprintf(format, v...))
+}
+func (l *recordingLogger) Errorf(format string, v ...interface{}) {
+	l.lines = append(l.lines, "ERROR: "+fmt.Sprintf(format, v...))
+}
+
+// TestSubsystemLevels verifies that a subsystem's logger suppresses output
+// below its level, that errors always pass, and that level changes take
+// effect immediately.
+func TestSubsystemLevels(t *testing.T) {
+	base := &recordingLogger{}
+	levels := NewSubsystemLevels()
+	instLog := levels.Logger(LogSubsystemInstance, base)
+	execLog := levels.Logger(LogSubsystemExecutor, base)
+
+	instLog.Debugf("a")
+	if l := len(base.lines); l != 1 {
+		t.Fatalf("expected debug output at default level, found %d lines", l)
+	}
+
+	levels.Set(LogSubsystemInstance, LogLevelWarning)
+	instLog.Debugf("b")
+	instLog.Infof("c")
+	if l := len(base.lines); l != 1 {
+		t.Fatalf("expected debug and info suppressed at warning level, found %d lines", l)
+	}
+	instLog.Warningf("d")
+	instLog.Errorf("e")
+	if l := len(base.lines); l != 3 {
+		t.Fatalf("expected warning and error to pass, found %d lines", l)
+	}
+
+	// Other subsystems are unaffected.
+	execLog.Debugf("f")
+	if l := len(base.lines); l != 4 {
+		t.Fatalf("expected executor debug unaffected, found %d lines", l)
+	}
+
+	levels.Set(LogSubsystemInstance, LogLevelDebug)
+	instLog.Debugf("g")
+	if l := len(base.lines); l != 5 {
+		t.Fatalf("expected debug restored, found %d lines", l)
+	}
+}
+
+// TestParseLogLevel verifies log level parsing by name.
+func TestParseLogLevel(t *testing.T) {
+	for name, lvl := range map[string]LogLevel{
+		"debug":   LogLevelDebug,
+		"info":    LogLevelInfo,
+		"warning": LogLevelWarning,
+		"error":   LogLevelError,
+	} {
+		if parsed, ok := ParseLogLevel(name); !ok || parsed != lvl {
+			t.Errorf("expected %q to parse to %v, found %v (%t)", name, lvl, parsed, ok)
+		}
+	}
+	if _, ok := ParseLogLevel("verbose"); ok {
+		t.Errorf("expected unknown level to fail parsing")
+	}
+}
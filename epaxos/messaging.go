@@ -9,11 +9,42 @@ import (
 func (p *epaxos) sendTo(m proto.Message, to pb.ReplicaID, inst *instance) {
 	mm := pb.WrapMessage(m)
 	mm.To = to
+	mm.From = p.id
 	mm.InstanceID = inst.is.InstanceID
-	// mm.Ballot = 1 TODO
+	mm.TraceContext = inst.traceCtx
+	if inst.is.Ballot != nil {
+		mm.Ballot = *inst.is.Ballot
+	}
+	p.coalesce(&mm)
 	p.msgs = append(p.msgs, mm)
 }
 
+// coalesce attempts to pack older outbound messages onto the provided
+// message so that both are delivered in a single wire message. A pipelined
+// proposer commonly broadcasts the Commit for instance i and the PreAccept
+// for instance i+1 in the same tick; when both are destined for the same
+// peer, the Commit is attached to the PreAccept as a piggybacked message.
+func (p *epaxos) coalesce(mm *pb.Message) {
+	if _, ok := mm.Type.(*pb.Message_PreAccept); !ok {
+		return
+	}
+	for idx, pending := range p.msgs {
+		if pending.To != mm.To {
+			continue
+		}
+		if _, ok := pending.Type.(*pb.Message_Commit); !ok {
+			continue
+		}
+		if pending.InstanceID.ReplicaID != mm.InstanceID.ReplicaID ||
+			pending.InstanceID.InstanceNum+1 != mm.InstanceID.InstanceNum {
+			continue
+		}
+		mm.Piggyback = append(mm.Piggyback, pending)
+		p.msgs = append(p.msgs[:idx], p.msgs[idx+1:]...)
+		return
+	}
+}
+
 func (p *epaxos) broadcast(m proto.Message, inst *instance) {
 	for _, node := range p.nodes {
 		if node != p.id {
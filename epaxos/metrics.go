@@ -0,0 +1,212 @@
+package epaxos
+
+import (
+	"github.com/google/btree"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// histogram collects a distribution of small integer samples in
+// exponentially-sized buckets. Bucket i counts samples in the range
+// [2^(i-1), 2^i), with bucket 0 counting zero-valued samples. The
+// histogram is not safe for concurrent use, matching the threading
+// model of the epaxos state machine.
+type histogram struct {
+	buckets [histogramBuckets]uint64
+	count   uint64
+	sum     uint64
+	max     uint64
+}
+
+// histogramBuckets is sized so that the largest bucket covers sample
+// values up to 2^62, which is more than enough for dependency counts
+// and SCC sizes.
+const histogramBuckets = 64
+
+func (h *histogram) record(v int) {
+	if v < 0 {
+		return
+	}
+	u := uint64(v)
+	h.buckets[bucketFor(u)]++
+	h.count++
+	h.sum += u
+	if u > h.max {
+		h.max = u
+	}
+}
+
+func bucketFor(v uint64) int {
+	b := 0
+	for v > 0 {
+		b++
+		v >>= 1
+	}
+	return b
+}
+
+// snapshot returns an immutable copy of the histogram's current state.
+func (h *histogram) snapshot() HistogramSnapshot {
+	return HistogramSnapshot{
+		Buckets: h.buckets,
+		Count:   h.count,
+		Sum:     h.sum,
+		Max:     h.max,
+	}
+}
+
+// HistogramSnapshot is a point-in-time copy of a histogram's state.
+// Bucket i counts samples in the range [2^(i-1), 2^i), with bucket 0
+// counting zero-valued samples.
+type HistogramSnapshot struct {
+	Buckets [histogramBuckets]uint64
+	Count   uint64
+	Sum     uint64
+	Max     uint64
+}
+
+// Mean returns the mean of all recorded samples.
+func (hs HistogramSnapshot) Mean() float64 {
+	if hs.Count == 0 {
+		return 0
+	}
+	return float64(hs.Sum) / float64(hs.Count)
+}
+
+// metrics holds distributions describing the shape of the execution
+// graph. These allow users to quantify the effect that their command
+// interference patterns have on the protocol.
+type metrics struct {
+	// depsPerInstance is the distribution of dependency list sizes for
+	// instances at the time they commit.
+	depsPerInstance histogram
+	// sccSize is the distribution of strongly connected component sizes
+	// observed by the executor when executing committed instances.
+	sccSize histogram
+	// originsPerSCC is the distribution of the number of distinct
+	// proposing replicas contributing to each executed strongly connected
+	// component.
+	originsPerSCC histogram
+	// dominantOriginPerSCC is the distribution of the largest
+	// single-replica contribution to each executed strongly connected
+	// component. Compared against sccSize, it shows how lopsided the
+	// components are: a dominant contribution near the component size
+	// means one replica's burst makes up most of the component.
+	dominantOriginPerSCC histogram
+	// preAcceptReplyTicks is the distribution of the number of ticks
+	// between a PreAccept broadcast and each of its replies, measuring
+	// how promptly peers answer the attribute agreement round.
+	preAcceptReplyTicks histogram
+	// slowPathForcedBy counts, per peer, the PreAcceptReplies that
+	// introduced a new dependency or a sequence number bump relative to
+	// the leader's pre-accept, disqualifying the fast path. A peer that
+	// dominates this count likely has stale interference state.
+	slowPathForcedBy map[pb.ReplicaID]uint64
+}
+
+func (m *metrics) recordSlowPathForcedBy(r pb.ReplicaID) {
+	if m.slowPathForcedBy == nil {
+		m.slowPathForcedBy = make(map[pb.ReplicaID]uint64)
+	}
+	m.slowPathForcedBy[r]++
+}
+
+// Counters holds low-cardinality cumulative protocol counters. Unlike
+// the distributions in MetricsSnapshot, counters are persisted through
+// CounterStorage when the node's storage supports it, so dashboards that
+// compute rates from them do not observe resets across restarts.
+type Counters struct {
+	// Committed counts instances that committed locally, whether led by
+	// this replica or learned through a Commit message.
+	Committed uint64
+	// Executed counts instances executed locally.
+	Executed uint64
+	// FastPathCommitted counts locally led instances that committed on
+	// the fast path.
+	FastPathCommitted uint64
+	// SlowPathCommitted counts locally led instances that committed
+	// through the Paxos-Accept phase.
+	SlowPathCommitted uint64
+	// Recoveries counts instance recoveries started by this replica.
+	Recoveries uint64
+}
+
+// bumpCounter increments the counter and persists the counter set, if
+// the node's storage supports counter persistence.
+func (p *epaxos) bumpCounter(c *uint64) {
+	*c++
+	if p.counterStorage != nil {
+		p.counterStorage.PersistCounters(p.counters)
+	}
+}
+
+// Counters returns the node's cumulative protocol counters.
+func (p *epaxos) Counters() Counters {
+	return p.counters
+}
+
+// MetricsSnapshot is a point-in-time copy of a node's execution graph
+// metrics.
+type MetricsSnapshot struct {
+	// DepsPerInstance is the distribution of dependency list sizes for
+	// instances at the time they commit.
+	DepsPerInstance HistogramSnapshot
+	// SCCSize is the distribution of strongly connected component sizes
+	// executed by the executor.
+	SCCSize HistogramSnapshot
+	// OriginsPerSCC is the distribution of the number of distinct
+	// proposing replicas contributing to each executed strongly connected
+	// component.
+	OriginsPerSCC HistogramSnapshot
+	// DominantOriginPerSCC is the distribution of the largest
+	// single-replica contribution to each executed strongly connected
+	// component.
+	DominantOriginPerSCC HistogramSnapshot
+	// PreAcceptReplyTicks is the distribution of the number of ticks
+	// between a PreAccept broadcast and each of its replies.
+	PreAcceptReplyTicks HistogramSnapshot
+	// SlowPathForcedBy counts, per peer, the PreAcceptReplies that forced
+	// the slow path by updating the instance's dependencies or sequence
+	// number.
+	SlowPathForcedBy map[pb.ReplicaID]uint64
+	// InstancesPerState counts the instances currently held in the
+	// node's instance space, by state.
+	InstancesPerState map[pb.InstanceState_Status]uint64
+	// ExecQueueDepth is the number of committed instances waiting in the
+	// executor for their dependencies to commit or execute.
+	ExecQueueDepth int
+}
+
+// Metrics returns a snapshot of the node's current execution graph
+// metrics.
+func (p *epaxos) Metrics() MetricsSnapshot {
+	ms := MetricsSnapshot{
+		DepsPerInstance:      p.metrics.depsPerInstance.snapshot(),
+		SCCSize:              p.metrics.sccSize.snapshot(),
+		OriginsPerSCC:        p.metrics.originsPerSCC.snapshot(),
+		DominantOriginPerSCC: p.metrics.dominantOriginPerSCC.snapshot(),
+		PreAcceptReplyTicks:  p.metrics.preAcceptReplyTicks.snapshot(),
+		InstancesPerState:    p.instancesPerState(),
+		ExecQueueDepth:       len(p.executor.vertices),
+	}
+	if len(p.metrics.slowPathForcedBy) > 0 {
+		ms.SlowPathForcedBy = make(map[pb.ReplicaID]uint64, len(p.metrics.slowPathForcedBy))
+		for r, c := range p.metrics.slowPathForcedBy {
+			ms.SlowPathForcedBy[r] = c
+		}
+	}
+	return ms
+}
+
+// instancesPerState counts the instances currently held in the node's
+// instance space, by state.
+func (p *epaxos) instancesPerState() map[pb.InstanceState_Status]uint64 {
+	counts := make(map[pb.InstanceState_Status]uint64)
+	for _, tree := range p.commands {
+		tree.Ascend(func(i btree.Item) bool {
+			counts[i.(*instance).is.Status]++
+			return true
+		})
+	}
+	return counts
+}
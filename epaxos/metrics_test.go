@@ -0,0 +1,167 @@
+package epaxos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestHistogramRecord verifies that histogram samples land in the expected
+// buckets and that summary statistics are maintained.
+func TestHistogramRecord(t *testing.T) {
+	var h histogram
+	for _, v := range []int{0, 1, 1, 2, 3, 4, 100} {
+		h.record(v)
+	}
+
+	if h.count != 7 {
+		t.Errorf("expected count 7, found %d", h.count)
+	}
+	if h.sum != 111 {
+		t.Errorf("expected sum 111, found %d", h.sum)
+	}
+	if h.max != 100 {
+		t.Errorf("expected max 100, found %d", h.max)
+	}
+	if b := h.buckets[0]; b != 1 {
+		t.Errorf("expected 1 sample in bucket 0, found %d", b)
+	}
+	if b := h.buckets[1]; b != 2 {
+		t.Errorf("expected 2 samples in bucket 1, found %d", b)
+	}
+	if b := h.buckets[2]; b != 2 {
+		t.Errorf("expected 2 samples in bucket 2, found %d", b)
+	}
+	if b := h.buckets[3]; b != 1 {
+		t.Errorf("expected 1 sample in bucket 3, found %d", b)
+	}
+	if b := h.buckets[7]; b != 1 {
+		t.Errorf("expected 1 sample in bucket 7, found %d", b)
+	}
+
+	if mean := h.snapshot().Mean(); mean != 111.0/7.0 {
+		t.Errorf("expected mean %f, found %f", 111.0/7.0, mean)
+	}
+}
+
+// TestMetricsSlowPathAttribution verifies that PreAcceptReplies which update
+// an instance's sequence number or dependencies are attributed to the peer
+// that sent them, while identical replies are not.
+func TestMetricsSlowPathAttribution(t *testing.T) {
+	p := newTestingEPaxos()
+	cmd := newTestingCommand("a", "z")
+	inst := p.onRequest(cmd)
+
+	// A reply from peer 2 that bumps the sequence number forces the slow
+	// path and should be attributed to peer 2.
+	p.stepFrom = 2
+	inst.onPreAcceptReply(&pb.PreAcceptReply{
+		UpdatedSeqNum: inst.is.SeqNum + 1,
+		UpdatedDeps:   inst.is.Deps,
+	})
+	if c := p.metrics.slowPathForcedBy[2]; c != 1 {
+		t.Errorf("expected 1 attribution for updated reply, found %d", c)
+	}
+
+	// An identical reply from peer 1 should not be attributed.
+	p.stepFrom = 1
+	inst.onPreAcceptReply(&pb.PreAcceptReply{
+		UpdatedSeqNum: inst.is.SeqNum,
+		UpdatedDeps:   inst.is.Deps,
+	})
+	if c := p.metrics.slowPathForcedBy[1]; c != 0 {
+		t.Errorf("expected no attribution for identical reply, found %d", c)
+	}
+
+	m := p.Metrics()
+	if c := m.SlowPathForcedBy[2]; c != 1 {
+		t.Errorf("expected snapshot attribution 1 for peer 2, found %d", c)
+	}
+}
+
+// TestMetricsInstancesPerState verifies that the snapshot counts the
+// instance space by state and reports the executor's queue depth.
+func TestMetricsInstancesPerState(t *testing.T) {
+	p := newTestingEPaxos()
+	p.onRequest(newTestingCommand("a", "m"))
+	p.onRequest(newTestingCommand("n", "z"))
+
+	ms := p.Metrics()
+	if c := ms.InstancesPerState[pb.InstanceState_PreAccepted]; c != 2 {
+		t.Errorf("expected 2 pre-accepted instances, found %d", c)
+	}
+	if ms.ExecQueueDepth != 0 {
+		t.Errorf("expected empty execution queue, found depth %d", ms.ExecQueueDepth)
+	}
+}
+
+// TestWritePrometheus verifies the Prometheus text rendering of counters,
+// gauges and cumulative histogram buckets.
+func TestWritePrometheus(t *testing.T) {
+	var h histogram
+	for _, v := range []int{0, 1, 1, 3} {
+		h.record(v)
+	}
+	ms := MetricsSnapshot{
+		DepsPerInstance: h.snapshot(),
+		InstancesPerState: map[pb.InstanceState_Status]uint64{
+			pb.InstanceState_Committed: 3,
+		},
+		SlowPathForcedBy: map[pb.ReplicaID]uint64{2: 1},
+		ExecQueueDepth:   4,
+	}
+	counters := Counters{Committed: 7, FastPathCommitted: 5, SlowPathCommitted: 2}
+
+	var buf bytes.Buffer
+	WritePrometheus(&buf, counters, ms)
+	out := buf.String()
+	for _, exp := range []string{
+		"epaxos_committed_total 7\n",
+		"epaxos_fast_path_committed_total 5\n",
+		"epaxos_slow_path_committed_total 2\n",
+		"epaxos_instances{state=\"Committed\"} 3\n",
+		"epaxos_exec_queue_depth 4\n",
+		"epaxos_slow_path_forced_by_total{peer=\"2\"} 1\n",
+		// Buckets are cumulative: 1 zero sample, then 2 ones, then a 3.
+		"epaxos_deps_per_instance_bucket{le=\"0\"} 1\n",
+		"epaxos_deps_per_instance_bucket{le=\"1\"} 3\n",
+		"epaxos_deps_per_instance_bucket{le=\"3\"} 4\n",
+		"epaxos_deps_per_instance_bucket{le=\"+Inf\"} 4\n",
+		"epaxos_deps_per_instance_sum 5\n",
+		"epaxos_deps_per_instance_count 4\n",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("expected rendering to contain %q; output:\n%s", exp, out)
+		}
+	}
+}
+
+// TestMetricsRecordedOnExecution verifies that executing interfering commands
+// populates the dependency count and SCC size distributions.
+func TestMetricsRecordedOnExecution(t *testing.T) {
+	n := newNetwork(5)
+
+	var insts []*instance
+	for _, peer := range n.peers {
+		cmd := newTestingCommand("a", "z")
+		inst := peer.onRequest(cmd)
+		insts = append(insts, inst)
+	}
+	for _, inst := range insts {
+		if !n.waitExecuteInstance(inst, true /* quorum */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+	}
+
+	for i, peer := range n.peers {
+		m := peer.Metrics()
+		if m.DepsPerInstance.Count == 0 {
+			t.Errorf("peer %d: expected non-empty DepsPerInstance distribution", i)
+		}
+		if m.SCCSize.Count == 0 {
+			t.Errorf("peer %d: expected non-empty SCCSize distribution", i)
+		}
+	}
+}
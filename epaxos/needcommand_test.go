@@ -0,0 +1,103 @@
+package epaxos
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestPreAcceptByDigestUnknownCommand verifies that a replica receiving a
+// PreAccept that references an unknown command by digest replies with a
+// NeedCommand message, and that the command leader responds by re-sending
+// the PreAccept with the full command payload.
+func TestPreAcceptByDigestUnknownCommand(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+	follower := n.peers[1]
+
+	cmd := newTestingCommand("a", "z")
+	inst := leader.onRequest(cmd)
+	leader.ReadMessages()
+
+	// Deliver a digest-only PreAccept for a command the follower has
+	// never seen.
+	digest := cmd.Digest()
+	pa := &pb.PreAccept{InstanceData: pb.InstanceData{
+		SeqNum:        inst.is.SeqNum,
+		Deps:          inst.is.Deps,
+		CommandDigest: digest,
+	}}
+	msg := pb.WrapMessage(pa)
+	msg.To = follower.id
+	msg.InstanceID = inst.is.InstanceID
+	follower.Step(msg)
+
+	msgs := follower.ReadMessages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, found %+v", msgs)
+	}
+	nc, ok := msgs[0].Type.(*pb.Message_NeedCommand)
+	if !ok {
+		t.Fatalf("expected NeedCommand message, found %+v", msgs[0])
+	}
+	if !bytes.Equal(nc.NeedCommand.Digest, digest) {
+		t.Errorf("expected digest %x, found %x", digest, nc.NeedCommand.Digest)
+	}
+
+	// The follower must not have transitioned the instance.
+	followerInst := follower.getInstance(inst.is.ReplicaID, inst.is.InstanceNum)
+	if !followerInst.isStates(pb.InstanceState_None) {
+		t.Errorf("expected follower instance in state None, found %v", followerInst.is.Status)
+	}
+
+	// Delivering the NeedCommand to the leader triggers a full re-broadcast.
+	leader.Step(msgs[0].WithDestination(leader.id))
+	resent := leader.ReadMessages()
+	if len(resent) != len(n.peers)-1 {
+		t.Fatalf("expected %d re-sent messages, found %+v", len(n.peers)-1, resent)
+	}
+	for _, m := range resent {
+		rePA, ok := m.Type.(*pb.Message_PreAccept)
+		if !ok {
+			t.Fatalf("expected PreAccept message, found %+v", m)
+		}
+		if rePA.PreAccept.Command == nil {
+			t.Errorf("expected re-sent PreAccept to carry the full command")
+		}
+	}
+}
+
+// TestPreAcceptByDigestKnownCommand verifies that a replica that already
+// knows a command can handle a PreAccept referencing it by digest.
+func TestPreAcceptByDigestKnownCommand(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+	follower := n.peers[1]
+
+	cmd := newTestingCommand("a", "z")
+	inst := leader.onRequest(cmd)
+	leader.ReadMessages()
+
+	// Teach the follower the command through an unrelated local proposal
+	// index entry.
+	follower.cmdDigests[string(cmd.Digest())] = cmd
+
+	pa := &pb.PreAccept{InstanceData: pb.InstanceData{
+		SeqNum:        inst.is.SeqNum,
+		Deps:          inst.is.Deps,
+		CommandDigest: cmd.Digest(),
+	}}
+	msg := pb.WrapMessage(pa)
+	msg.To = follower.id
+	msg.InstanceID = inst.is.InstanceID
+	follower.Step(msg)
+
+	followerInst := follower.getInstance(inst.is.ReplicaID, inst.is.InstanceNum)
+	if !followerInst.isStates(pb.InstanceState_PreAccepted) {
+		t.Errorf("expected follower instance in state PreAccepted, found %v", followerInst.is.Status)
+	}
+	if followerInst.is.Command != cmd {
+		t.Errorf("expected follower to resolve the command by digest")
+	}
+}
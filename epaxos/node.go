@@ -10,6 +10,20 @@ import (
 var (
 	// ErrStopped is returned by methods on Nodes that have been stopped.
 	ErrStopped = errors.New("epaxos: stopped")
+	// ErrProposalDropped is returned when a proposal is rejected outright
+	// — the replica is halted, shedding load under slow storage, or
+	// running as a dry-run shadow — so the caller can retry elsewhere
+	// instead of assuming the command was queued.
+	ErrProposalDropped = errors.New("epaxos: proposal dropped")
+	// ErrProposalDeferred is returned when a proposal is accepted but
+	// held back — buffered into a proposal batch or deferred behind the
+	// post-restart warm-up cap — before an instance exists to identify
+	// it.
+	ErrProposalDeferred = errors.New("epaxos: proposal deferred")
+	// ErrReplicaFailed is returned by Step once a protocol violation has
+	// marked the replica as failed under Config.PanicFree (see
+	// failure.go).
+	ErrReplicaFailed = errors.New("epaxos: replica failed")
 )
 
 // Ready encapsulates the entries and messages that are ready to read,
@@ -23,12 +37,19 @@ type Ready struct {
 	// ExecutedCommands specifies commands to be executed by a state-machine.
 	// These have previously been committed to stable store.
 	ExecutedCommands []pb.Command
+
+	// HardState mirrors the node's hard state when it has changed since the
+	// previous Ready, and is nil otherwise. The state has already been
+	// written through the Storage interface by the time it appears here;
+	// the field exists so that embedders can observe durable state changes
+	// without wrapping Storage.
+	HardState *pb.HardState
 }
 
 // containsUpdates returns whether the Ready struct contains any updates that
 // need to be acted upon.
 func (rd Ready) containsUpdates() bool {
-	return len(rd.Messages) > 0 || len(rd.ExecutedCommands) > 0
+	return len(rd.Messages) > 0 || len(rd.ExecutedCommands) > 0 || rd.HardState != nil
 }
 
 // Node represents a node in a paxos cluster.
@@ -36,10 +57,33 @@ type Node interface {
 	// Tick increments the internal logical clock for the Node by a single tick.
 	// Election timeouts and progress timeouts are in units of ticks.
 	Tick()
+	// TickN advances the internal logical clock by n ticks, for embedders
+	// driving ticks from coarse timers or external schedulers that report
+	// elapsed ticks rather than firing per tick. Non-positive counts are
+	// ignored, so clock regressions need no special-casing by the caller,
+	// and large jumps are clamped with the excess dropped rather than
+	// replayed through the timers.
+	TickN(n int)
 	// Propose proposes that data be ordered by paxos.
 	Propose(ctx context.Context, command pb.Command) error
+	// ProposeWithResult proposes a command like Propose and registers a
+	// handler for its result. The handler is invoked from the node's run
+	// loop once the command applies locally through the configured
+	// ApplyWithResult, so it must not block. The command must carry a
+	// non-zero ID for its result to be routed.
+	ProposeWithResult(ctx context.Context, command pb.Command, onResult ResultFunc) error
+	// ProposeWithHandle proposes a command like Propose and returns the
+	// InstanceID of the instance created to order it, identifying the
+	// proposal in Status snapshots, instance queries, and schedule
+	// exports. Unlike Propose, proposals that cannot be ordered right
+	// away are reported as typed errors: ErrProposalDropped if the
+	// replica rejected the proposal outright and ErrProposalDeferred if
+	// it was queued before an instance existed to identify it.
+	ProposeWithHandle(ctx context.Context, command pb.Command) (pb.InstanceID, error)
 	// Step advances the state machine using the given message. ctx.Err() will be
-	// returned, if any.
+	// returned, if any. Under Config.PanicFree, ErrReplicaFailed is
+	// returned once a protocol violation has marked the replica as
+	// failed.
 	Step(ctx context.Context, msg pb.Message) error
 	// Ready returns a channel that returns the current point-in-time state.
 	// Users of the Node must call Advance after retrieving the state returned by
@@ -48,6 +92,54 @@ type Node interface {
 	// NOTE: No committed entries from the next Ready may be applied until all
 	// committed entries and snapshots from the previous one have finished.
 	Ready() <-chan Ready
+	// Advance notifies the Node that the application has saved progress up to
+	// the last Ready. It prepares the node to return the next available Ready.
+	Advance()
+	// Health returns the node's health predicates, for liveness and
+	// readiness probes. ctx.Err() will be returned, if any.
+	Health(ctx context.Context) (HealthStatus, error)
+	// Status returns a snapshot of the node's protocol progress —
+	// per-column high-water marks, instance counts by state, pending
+	// proposals, membership, and executed watermarks — for operator
+	// introspection. The snapshot serializes to JSON. ctx.Err() will be
+	// returned, if any.
+	Status(ctx context.Context) (Status, error)
+	// Metrics returns a snapshot of the node's metric distributions and
+	// cumulative protocol counters, e.g. for Prometheus exposition
+	// through WritePrometheus. ctx.Err() will be returned, if any.
+	Metrics(ctx context.Context) (MetricsSnapshot, Counters, error)
+	// ProbeConflicts returns the not-yet-executed instances that would
+	// interfere with the command if it were proposed now, without
+	// proposing it. Applications can use the result to delay a proposal,
+	// pick a different key, or warn the user. ctx.Err() will be
+	// returned, if any.
+	ProbeConflicts(ctx context.Context, command pb.Command) ([]ConflictInfo, error)
+	// ExportSchedule renders the retained execution schedule between the
+	// two positions, inclusive, as a compact signed file for compliance
+	// audits (see Config.ScheduleLogSize). ctx.Err() will be returned,
+	// if any.
+	ExportSchedule(ctx context.Context, start, end uint64) ([]byte, error)
+	// VerifyStateHash compares a production replica's published state
+	// hash checkpoint against this node's checkpoint at the same applied
+	// count, for dry-run upgrade validation (see Config.DryRun).
+	// ctx.Err() will be returned, if any.
+	VerifyStateHash(ctx context.Context, applied uint64, hash []byte) (StateHashCheck, error)
+	// RequestReadLease requests a quorum read lease on the key range
+	// (see Config.ReadLeaseTicks). The lease activates once a quorum of
+	// the cluster has granted it; requesting the same range again renews
+	// it. ctx.Err() will be returned, if any.
+	RequestReadLease(ctx context.Context, span pb.Span) error
+	// HoldsReadLease returns whether the node holds an active read lease
+	// covering the key range, in which case reads of the range may be
+	// served from local state without a proposal. Such reads are
+	// bounded-stale, not linearizable (see leases.go). ctx.Err() will be
+	// returned, if any.
+	HoldsReadLease(ctx context.Context, span pb.Span) (bool, error)
+	// FinalizeExecution releases a command parked by the configured
+	// ExecutionHooks' prepare phase. It returns whether a parked command
+	// with the provided ID was released. ctx.Err() will be returned, if
+	// any.
+	FinalizeExecution(ctx context.Context, cmdID uint64) (bool, error)
 	// Stop performs any necessary termination of the Node.
 	Stop()
 }
@@ -61,51 +153,141 @@ func StartNode(c *Config) Node {
 	return &n
 }
 
+// RestartNode returns a Node rebuilt from the state persisted in the
+// configuration's Storage: the instance space is reloaded with its
+// ballots, sequence numbers and dependencies, committed-but-unexecuted
+// instances resume execution, and proposals the node previously led are
+// re-driven. It behaves like StartNode, which also recovers persisted
+// state when present, except that it requires persisted state to exist,
+// failing fast when a restart is pointed at empty storage.
+func RestartNode(c *Config) Node {
+	if c.Storage == nil {
+		panic("epaxos: RestartNode requires a Storage")
+	}
+	if _, ok := c.Storage.HardState(); !ok {
+		panic("epaxos: RestartNode with empty Storage")
+	}
+	return StartNode(c)
+}
+
 // node is the canonical implementation of the Node interface. It provides a
 // thread-safe handle around the thread-unsafe paxos object.
 type node struct {
-	propc  chan pb.Command
-	msgc   chan pb.Message
-	readyc chan Ready
-	tickc  chan struct{}
-	done   chan struct{}
-	stop   chan struct{}
+	propc    chan proposal
+	msgc     chan pb.Message
+	readyc   chan Ready
+	advancec chan struct{}
+	healthc  chan chan HealthStatus
+	statusc  chan chan Status
+	metricsc chan chan metricsReply
+	probec   chan probeRequest
+	finalc   chan finalizeRequest
+	leasec   chan pb.Span
+	leaseqc  chan leaseQuery
+	hashc    chan hashCheck
+	schedc   chan scheduleExportRequest
+	tickc    chan int
+	// failedc is closed by the run loop once a protocol violation has
+	// marked the replica as failed under Config.PanicFree, so that Step
+	// can report ErrReplicaFailed without consulting the loop.
+	failedc chan struct{}
+	done    chan struct{}
+	stop    chan struct{}
 
 	logger Logger
 }
 
 func makeNode() node {
 	return node{
-		propc:  make(chan pb.Command),
-		msgc:   make(chan pb.Message),
-		readyc: make(chan Ready),
+		propc:    make(chan proposal),
+		msgc:     make(chan pb.Message),
+		readyc:   make(chan Ready),
+		advancec: make(chan struct{}),
+		healthc:  make(chan chan HealthStatus),
+		statusc:  make(chan chan Status),
+		metricsc: make(chan chan metricsReply),
+		probec:   make(chan probeRequest),
+		finalc:   make(chan finalizeRequest),
+		leasec:   make(chan pb.Span),
+		leaseqc:  make(chan leaseQuery),
+		hashc:    make(chan hashCheck),
+		schedc:   make(chan scheduleExportRequest),
 		// buffered chan, so paxos node can buffer some ticks when the node is
 		// busy processing messages. Paxos node will resume process buffered
 		// ticks when it becomes idle.
-		tickc: make(chan struct{}, 128),
-		done:  make(chan struct{}),
-		stop:  make(chan struct{}),
+		tickc:   make(chan int, 128),
+		failedc: make(chan struct{}),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
 	}
 }
 
+// maxTickCatchUp is the largest number of buffered ticks processed at
+// once. If the process is paused (GC, VM steal) long enough to buffer
+// more, the excess is dropped rather than fired in a burst: replaying
+// every missed tick would trip slow-path and recovery timers that only
+// appear to have expired because the clock jumped.
+const maxTickCatchUp = 4
+
 func (n *node) run(p *epaxos) {
+	advancePending := false
+	failedNotified := false
 	for {
+		if p.failed && !failedNotified {
+			failedNotified = true
+			close(n.failedc)
+		}
+
 		var readyc chan Ready
 		rd := makeReady(p)
-		if rd.containsUpdates() {
+		if !advancePending && rd.containsUpdates() {
 			readyc = n.readyc
 		}
 
 		select {
-		case <-n.tickc:
-			p.Tick()
-		case cmd := <-n.propc:
-			p.Request(&cmd)
+		case cnt := <-n.tickc:
+			for i := len(n.tickc); i > 0; i-- {
+				cnt += <-n.tickc
+			}
+			p.TickN(cnt)
+		case prop := <-n.propc:
+			if prop.onResult != nil {
+				p.registerResultHandler(prop.cmd.ID, prop.onResult)
+			}
+			if prop.handlec != nil {
+				id, err := p.RequestWithHandle(&prop.cmd)
+				prop.handlec <- proposeHandle{id: id, err: err}
+			} else {
+				p.Request(&prop.cmd)
+			}
 		case m := <-n.msgc:
 			p.Step(m)
 		case readyc <- rd:
 			p.clearMsgs()
 			p.clearExecutedCommands()
+			p.clearReadyHardState()
+			advancePending = true
+		case <-n.advancec:
+			advancePending = false
+		case hc := <-n.healthc:
+			hc <- p.Health()
+		case sc := <-n.statusc:
+			sc <- p.Status()
+		case mc := <-n.metricsc:
+			mc <- metricsReply{ms: p.Metrics(), counters: p.Counters()}
+		case pr := <-n.probec:
+			pr.done <- p.ProbeConflicts(&pr.cmd)
+		case fr := <-n.finalc:
+			fr.done <- p.FinalizeExecution(fr.id)
+		case span := <-n.leasec:
+			p.RequestReadLease(span)
+		case lq := <-n.leaseqc:
+			lq.done <- p.HoldsReadLease(lq.span)
+		case hc := <-n.hashc:
+			hc.done <- p.VerifyStateHash(hc.applied, hc.hash)
+		case sr := <-n.schedc:
+			data, err := p.ExportSchedule(sr.start, sr.end)
+			sr.done <- scheduleExportReply{data: data, err: err}
 		case <-n.stop:
 			close(n.done)
 			return
@@ -115,18 +297,49 @@ func (n *node) run(p *epaxos) {
 
 // Tick implements the Node interface.
 func (n *node) Tick() {
+	n.TickN(1)
+}
+
+// TickN implements the Node interface.
+func (n *node) TickN(cnt int) {
+	if cnt <= 0 {
+		// Regressions and zero-length intervals from coarse timers carry no
+		// elapsed time; dropping them here keeps the tick channel free for
+		// ticks that do.
+		return
+	}
 	select {
-	case n.tickc <- struct{}{}:
+	case n.tickc <- cnt:
 	case <-n.done:
 	default:
 		n.logger.Warningf("A tick missed to fire. Node blocking for too long!")
 	}
 }
 
+// proposal pairs a proposed command with the optional handler awaiting
+// its result and the optional channel awaiting its instance handle.
+type proposal struct {
+	cmd      pb.Command
+	onResult ResultFunc
+	handlec  chan proposeHandle
+}
+
+// proposeHandle carries the outcome of a handle-returning proposal out
+// of the node's run loop.
+type proposeHandle struct {
+	id  pb.InstanceID
+	err error
+}
+
 // Propose implements the Node interface.
 func (n *node) Propose(ctx context.Context, cmd pb.Command) error {
+	return n.ProposeWithResult(ctx, cmd, nil)
+}
+
+// ProposeWithResult implements the Node interface.
+func (n *node) ProposeWithResult(ctx context.Context, cmd pb.Command, onResult ResultFunc) error {
 	select {
-	case n.propc <- cmd:
+	case n.propc <- proposal{cmd: cmd, onResult: onResult}:
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
@@ -135,8 +348,37 @@ func (n *node) Propose(ctx context.Context, cmd pb.Command) error {
 	}
 }
 
+// ProposeWithHandle implements the Node interface.
+func (n *node) ProposeWithHandle(ctx context.Context, cmd pb.Command) (pb.InstanceID, error) {
+	// The handle channel is buffered so that the run loop never blocks on
+	// a caller that has since given up.
+	hc := make(chan proposeHandle, 1)
+	select {
+	case n.propc <- proposal{cmd: cmd, handlec: hc}:
+	case <-ctx.Done():
+		return pb.InstanceID{}, ctx.Err()
+	case <-n.done:
+		return pb.InstanceID{}, ErrStopped
+	}
+	select {
+	case h := <-hc:
+		return h.id, h.err
+	case <-ctx.Done():
+		return pb.InstanceID{}, ctx.Err()
+	case <-n.done:
+		return pb.InstanceID{}, ErrStopped
+	}
+}
+
 // Step implements the Node interface.
 func (n *node) Step(ctx context.Context, m pb.Message) error {
+	// A failed replica ignores messages anyway; report that rather than
+	// racing the run loop's receive for a meaningless enqueue.
+	select {
+	case <-n.failedc:
+		return ErrReplicaFailed
+	default:
+	}
 	select {
 	case n.msgc <- m:
 		return nil
@@ -152,10 +394,248 @@ func (n *node) Ready() <-chan Ready {
 	return n.readyc
 }
 
+// Advance implements the Node interface.
+func (n *node) Advance() {
+	select {
+	case n.advancec <- struct{}{}:
+	case <-n.done:
+	}
+}
+
+// Health implements the Node interface.
+func (n *node) Health(ctx context.Context) (HealthStatus, error) {
+	hc := make(chan HealthStatus, 1)
+	select {
+	case n.healthc <- hc:
+	case <-ctx.Done():
+		return HealthStatus{}, ctx.Err()
+	case <-n.done:
+		return HealthStatus{}, ErrStopped
+	}
+	select {
+	case hs := <-hc:
+		return hs, nil
+	case <-ctx.Done():
+		return HealthStatus{}, ctx.Err()
+	case <-n.done:
+		return HealthStatus{}, ErrStopped
+	}
+}
+
+// Status implements the Node interface.
+func (n *node) Status(ctx context.Context) (Status, error) {
+	sc := make(chan Status, 1)
+	select {
+	case n.statusc <- sc:
+	case <-ctx.Done():
+		return Status{}, ctx.Err()
+	case <-n.done:
+		return Status{}, ErrStopped
+	}
+	select {
+	case st := <-sc:
+		return st, nil
+	case <-ctx.Done():
+		return Status{}, ctx.Err()
+	case <-n.done:
+		return Status{}, ErrStopped
+	}
+}
+
+// metricsReply carries a point-in-time copy of the state machine's
+// metrics out of its run loop.
+type metricsReply struct {
+	ms       MetricsSnapshot
+	counters Counters
+}
+
+// Metrics implements the Node interface.
+func (n *node) Metrics(ctx context.Context) (MetricsSnapshot, Counters, error) {
+	mc := make(chan metricsReply, 1)
+	select {
+	case n.metricsc <- mc:
+	case <-ctx.Done():
+		return MetricsSnapshot{}, Counters{}, ctx.Err()
+	case <-n.done:
+		return MetricsSnapshot{}, Counters{}, ErrStopped
+	}
+	select {
+	case mr := <-mc:
+		return mr.ms, mr.counters, nil
+	case <-ctx.Done():
+		return MetricsSnapshot{}, Counters{}, ctx.Err()
+	case <-n.done:
+		return MetricsSnapshot{}, Counters{}, ErrStopped
+	}
+}
+
+// probeRequest asks the state machine for a command's current conflicts.
+type probeRequest struct {
+	cmd  pb.Command
+	done chan []ConflictInfo
+}
+
+// ProbeConflicts implements the Node interface.
+func (n *node) ProbeConflicts(ctx context.Context, cmd pb.Command) ([]ConflictInfo, error) {
+	pr := probeRequest{cmd: cmd, done: make(chan []ConflictInfo, 1)}
+	select {
+	case n.probec <- pr:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.done:
+		return nil, ErrStopped
+	}
+	select {
+	case conflicts := <-pr.done:
+		return conflicts, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.done:
+		return nil, ErrStopped
+	}
+}
+
+// scheduleExportRequest asks the state machine to render a range of its
+// execution schedule log.
+type scheduleExportRequest struct {
+	start, end uint64
+	done       chan scheduleExportReply
+}
+
+// scheduleExportReply carries a rendered schedule export out of the run
+// loop.
+type scheduleExportReply struct {
+	data []byte
+	err  error
+}
+
+// ExportSchedule implements the Node interface.
+func (n *node) ExportSchedule(ctx context.Context, start, end uint64) ([]byte, error) {
+	sr := scheduleExportRequest{start: start, end: end, done: make(chan scheduleExportReply, 1)}
+	select {
+	case n.schedc <- sr:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.done:
+		return nil, ErrStopped
+	}
+	select {
+	case reply := <-sr.done:
+		return reply.data, reply.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.done:
+		return nil, ErrStopped
+	}
+}
+
+// hashCheck asks the state machine to compare a published state hash
+// checkpoint against its own.
+type hashCheck struct {
+	applied uint64
+	hash    []byte
+	done    chan StateHashCheck
+}
+
+// VerifyStateHash implements the Node interface.
+func (n *node) VerifyStateHash(ctx context.Context, applied uint64, hash []byte) (StateHashCheck, error) {
+	hc := hashCheck{applied: applied, hash: hash, done: make(chan StateHashCheck, 1)}
+	select {
+	case n.hashc <- hc:
+	case <-ctx.Done():
+		return StateHashPending, ctx.Err()
+	case <-n.done:
+		return StateHashPending, ErrStopped
+	}
+	select {
+	case check := <-hc.done:
+		return check, nil
+	case <-ctx.Done():
+		return StateHashPending, ctx.Err()
+	case <-n.done:
+		return StateHashPending, ErrStopped
+	}
+}
+
+// leaseQuery asks the state machine whether it holds an active read
+// lease covering a key range.
+type leaseQuery struct {
+	span pb.Span
+	done chan bool
+}
+
+// RequestReadLease implements the Node interface.
+func (n *node) RequestReadLease(ctx context.Context, span pb.Span) error {
+	select {
+	case n.leasec <- span:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-n.done:
+		return ErrStopped
+	}
+}
+
+// HoldsReadLease implements the Node interface.
+func (n *node) HoldsReadLease(ctx context.Context, span pb.Span) (bool, error) {
+	lq := leaseQuery{span: span, done: make(chan bool, 1)}
+	select {
+	case n.leaseqc <- lq:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-n.done:
+		return false, ErrStopped
+	}
+	select {
+	case held := <-lq.done:
+		return held, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-n.done:
+		return false, ErrStopped
+	}
+}
+
+// finalizeRequest asks the state machine to release a parked command.
+type finalizeRequest struct {
+	id   uint64
+	done chan bool
+}
+
+// FinalizeExecution implements the Node interface.
+func (n *node) FinalizeExecution(ctx context.Context, cmdID uint64) (bool, error) {
+	fr := finalizeRequest{id: cmdID, done: make(chan bool, 1)}
+	select {
+	case n.finalc <- fr:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-n.done:
+		return false, ErrStopped
+	}
+	select {
+	case released := <-fr.done:
+		return released, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-n.done:
+		return false, ErrStopped
+	}
+}
+
+// clampTicks limits a batch of buffered ticks to maxTickCatchUp,
+// returning how many ticks to run and how many to drop.
+func clampTicks(buffered int) (run, skipped int) {
+	if buffered > maxTickCatchUp {
+		return maxTickCatchUp, buffered - maxTickCatchUp
+	}
+	return buffered, 0
+}
+
 func makeReady(p *epaxos) Ready {
 	return Ready{
 		Messages:         p.msgs,
 		ExecutedCommands: p.executedCmds,
+		HardState:        p.readyHardState,
 	}
 }
 
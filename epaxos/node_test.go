@@ -0,0 +1,170 @@
+package epaxos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestClampTicks verifies that tick catch-up is clamped so that a long
+// process pause cannot fire an unbounded burst of timer callbacks.
+func TestClampTicks(t *testing.T) {
+	testCases := []struct {
+		buffered, expRun, expSkipped int
+	}{
+		{1, 1, 0},
+		{maxTickCatchUp, maxTickCatchUp, 0},
+		{maxTickCatchUp + 1, maxTickCatchUp, 1},
+		{100, maxTickCatchUp, 100 - maxTickCatchUp},
+	}
+	for _, tc := range testCases {
+		run, skipped := clampTicks(tc.buffered)
+		if run != tc.expRun || skipped != tc.expSkipped {
+			t.Errorf("expected clampTicks(%d) = (%d, %d), found (%d, %d)",
+				tc.buffered, tc.expRun, tc.expSkipped, run, skipped)
+		}
+	}
+}
+
+// TestTickN verifies that elapsed-tick counts advance the clock, that
+// non-positive counts from clock regressions are ignored, and that
+// oversized jumps are clamped and surfaced as a TimeJumpedEvent.
+func TestTickN(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+
+	p.TickN(3)
+	if p.ticks != 3 {
+		t.Fatalf("expected 3 ticks, found %d", p.ticks)
+	}
+
+	p.TickN(0)
+	p.TickN(-5)
+	if p.ticks != 3 || len(events) != 0 {
+		t.Fatalf("expected non-positive counts to be ignored; found %d ticks, events %v",
+			p.ticks, events)
+	}
+
+	p.TickN(maxTickCatchUp + 2)
+	if p.ticks != 3+maxTickCatchUp {
+		t.Fatalf("expected jump clamped to %d ticks, found %d", maxTickCatchUp, p.ticks-3)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected a TimeJumpedEvent, found %v", events)
+	}
+	if e := events[0].(TimeJumpedEvent); e.SkippedTicks != 2 {
+		t.Fatalf("expected 2 skipped ticks, found %+v", e)
+	}
+}
+
+// TestNodeAdvanceGatesReady verifies that a Node does not produce a new
+// Ready until the previous one has been acknowledged with Advance.
+func TestNodeAdvanceGatesReady(t *testing.T) {
+	n := StartNode(&Config{ID: 0, Nodes: []pb.ReplicaID{0}})
+	defer n.Stop()
+	ctx := context.Background()
+
+	if err := n.Propose(ctx, *newTestingCommand("a", "z")); err != nil {
+		t.Fatalf("unexpected error from Propose: %v", err)
+	}
+	if rd := <-n.Ready(); len(rd.ExecutedCommands) != 1 {
+		t.Fatalf("expected 1 executed command, found %+v", rd)
+	}
+
+	// Until Advance is called, further updates are buffered instead of
+	// producing a new Ready.
+	if err := n.Propose(ctx, *newTestingCommand("a", "z")); err != nil {
+		t.Fatalf("unexpected error from Propose: %v", err)
+	}
+	select {
+	case rd := <-n.Ready():
+		t.Fatalf("unexpected Ready before Advance: %+v", rd)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	n.Advance()
+	if rd := <-n.Ready(); len(rd.ExecutedCommands) != 1 {
+		t.Fatalf("expected 1 executed command, found %+v", rd)
+	}
+}
+
+// TestProposeWithHandle verifies that handle-returning proposals report
+// the instance created to order the command and surface rejections and
+// deferrals as typed errors instead of silently queuing.
+func TestProposeWithHandle(t *testing.T) {
+	ctx := context.Background()
+
+	n := StartNode(&Config{ID: 0, Nodes: []pb.ReplicaID{0}})
+	id, err := n.ProposeWithHandle(ctx, *newTestingCommand("a", "z"))
+	if err != nil {
+		t.Fatalf("unexpected error from ProposeWithHandle: %v", err)
+	}
+	if exp := (pb.InstanceID{ReplicaID: 0, InstanceNum: 1}); id != exp {
+		t.Fatalf("expected instance %+v, found %+v", exp, id)
+	}
+	n.Stop()
+	if _, err := n.ProposeWithHandle(ctx, *newTestingCommand("a", "z")); err != ErrStopped {
+		t.Fatalf("expected ErrStopped from a stopped node, found %v", err)
+	}
+
+	// A dry-run replica rejects proposals outright.
+	d := StartNode(&Config{ID: 0, Nodes: []pb.ReplicaID{0}, DryRun: true})
+	defer d.Stop()
+	if _, err := d.ProposeWithHandle(ctx, *newTestingCommand("a", "z")); err != ErrProposalDropped {
+		t.Fatalf("expected ErrProposalDropped on a dry-run replica, found %v", err)
+	}
+
+	// A batching replica buffers the proposal; no instance identifies it
+	// yet.
+	b := StartNode(&Config{ID: 0, Nodes: []pb.ReplicaID{0}, ProposalBatchSize: 4})
+	defer b.Stop()
+	if _, err := b.ProposeWithHandle(ctx, *newTestingCommand("a", "z")); err != ErrProposalDeferred {
+		t.Fatalf("expected ErrProposalDeferred on a batching replica, found %v", err)
+	}
+}
+
+// TestRestartNodeRequiresPersistedState verifies that RestartNode fails
+// fast when pointed at storage without persisted state, and starts
+// normally once state exists.
+func TestRestartNodeRequiresPersistedState(t *testing.T) {
+	nodes := []pb.ReplicaID{0}
+	ms := NewMemoryStorage(&Config{ID: 0, Nodes: nodes})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic for RestartNode with empty storage")
+			}
+		}()
+		RestartNode(&Config{ID: 0, Nodes: nodes, Storage: ms})
+	}()
+
+	// Populate storage by starting a node, then restart from it.
+	n := StartNode(&Config{ID: 0, Nodes: nodes, Storage: ms})
+	n.Stop()
+	n = RestartNode(&Config{ID: 0, Nodes: nodes, Storage: ms})
+	n.Stop()
+}
+
+// TestTimeJumpedEvent verifies that dropped ticks are surfaced as a
+// TimeJumpedEvent.
+func TestTimeJumpedEvent(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+	p.noteTimeJump(96)
+	if len(events) != 1 {
+		t.Fatalf("expected a TimeJumpedEvent, found %v", events)
+	}
+	if e := events[0].(TimeJumpedEvent); e.SkippedTicks != 96 {
+		t.Fatalf("expected 96 skipped ticks, found %+v", e)
+	}
+}
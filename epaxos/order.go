@@ -0,0 +1,35 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// OrderedCommand describes a committed instance for execution-order
+// comparison.
+type OrderedCommand struct {
+	// InstanceID identifies the instance.
+	InstanceID pb.InstanceID
+	// SeqNum is the instance's sequence number.
+	SeqNum pb.SeqNum
+	// Command is the instance's command.
+	Command *pb.Command
+}
+
+// ExecutionOrder customizes how ties are broken between instances in the
+// same strongly connected component, beyond the default ordering by
+// sequence number and then ReplicaID. Implementations must be
+// deterministic and must order every pair of distinct instances
+// consistently on all replicas, or replicas will diverge.
+type ExecutionOrder interface {
+	// ExecutesBefore returns whether a should execute before b.
+	ExecutesBefore(a, b OrderedCommand) bool
+}
+
+// orderedCommand builds the instance's OrderedCommand view.
+func (inst *instance) orderedCommand() OrderedCommand {
+	return OrderedCommand{
+		InstanceID: inst.is.InstanceID,
+		SeqNum:     inst.is.SeqNum,
+		Command:    inst.is.Command,
+	}
+}
@@ -0,0 +1,105 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// reverseIDOrder orders instances by descending command ID.
+type reverseIDOrder struct{}
+
+func (reverseIDOrder) ExecutesBefore(a, b OrderedCommand) bool {
+	return a.Command.ID > b.Command.ID
+}
+
+// TestExecutionOrderOverride verifies that a custom ExecutionOrder replaces
+// the default seq/ReplicaID tie-breaking order.
+func TestExecutionOrderOverride(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	cmdA := newTestingCommand("a", "z")
+	cmdA.ID = 1
+	cmdB := newTestingCommand("a", "z")
+	cmdB.ID = 2
+	instA := p.onRequest(cmdA)
+	instB := p.onRequest(cmdB)
+
+	// By default, the earlier instance executes first.
+	if !instA.ExecutesBefore(instB) {
+		t.Errorf("expected default order to execute %v first", instA.is.InstanceID)
+	}
+
+	// A custom order by descending command ID reverses that.
+	p.execOrder = reverseIDOrder{}
+	if !instB.ExecutesBefore(instA) {
+		t.Errorf("expected custom order to execute %v first", instB.is.InstanceID)
+	}
+}
+
+// TestDepsOnlyOrdering verifies that with DepsOnlyOrdering set, proposals
+// carry no sequence numbers and instances are ordered by instance ID
+// within a strongly connected component.
+func TestDepsOnlyOrdering(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:               0,
+		Nodes:            []pb.ReplicaID{0, 1, 2},
+		DepsOnlyOrdering: true,
+	})
+
+	instA := p.onRequest(newTestingCommand("a", "z"))
+	instB := p.onRequest(newTestingCommand("a", "z"))
+	if instA.is.SeqNum != 0 || instB.is.SeqNum != 0 {
+		t.Errorf("expected no sequence number maintenance, found %d and %d",
+			instA.is.SeqNum, instB.is.SeqNum)
+	}
+
+	// The dependency graph is still maintained: the second interfering
+	// proposal depends on the first.
+	found := false
+	for _, dep := range instB.is.Deps {
+		if dep == instA.is.InstanceID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %v to depend on %v, found deps %v",
+			instB.is.InstanceID, instA.is.InstanceID, instB.is.Deps)
+	}
+
+	// Within a component, instance IDs break the remaining ties.
+	if !instA.ExecutesBefore(instB) || instB.ExecutesBefore(instA) {
+		t.Errorf("expected instance ID order to execute %v first", instA.is.InstanceID)
+	}
+	other := p.newInstance(1, 1)
+	if !instA.ExecutesBefore(other) || other.ExecutesBefore(instA) {
+		t.Errorf("expected replica order to execute %v first", instA.is.InstanceID)
+	}
+}
+
+// TestDepsOnlyOrderingPreAcceptOK verifies that a deps-only replica whose
+// local dependencies match a PreAccept's proposal replies PreAcceptOK
+// without inventing a sequence number.
+func TestDepsOnlyOrderingPreAcceptOK(t *testing.T) {
+	leader := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}, DepsOnlyOrdering: true})
+	follower := newEPaxos(&Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2}, DepsOnlyOrdering: true})
+
+	leader.onRequest(newTestingCommand("a", "z"))
+	for _, m := range leader.ReadMessages() {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+
+	msgs := follower.ReadMessages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected a single reply, found %+v", msgs)
+	}
+	if _, ok := msgs[0].Type.(*pb.Message_PreAcceptOk); !ok {
+		t.Fatalf("expected PreAcceptOK reply, found %+v", msgs[0])
+	}
+	inst := follower.getInstance(0, 1)
+	if inst == nil || inst.is.SeqNum != 0 {
+		t.Fatalf("expected follower instance with no sequence number, found %+v", inst)
+	}
+}
@@ -0,0 +1,148 @@
+package epaxos
+
+import (
+	"sync"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// ParallelApplier runs an ApplyFunc on a pool of worker goroutines,
+// applying non-interfering commands concurrently. Passing its Apply
+// method as Config.Apply moves application state machine execution off
+// the consensus loop, like AsyncApplier, while additionally exploiting
+// the independence that EPaxos establishes: the protocol orders only
+// interfering commands, so commands that do not interfere may be applied
+// in any order — including at the same time. A command is handed to a
+// worker only once every earlier command that interferes with it has
+// finished, which preserves the executor's order between interfering
+// commands, including the ExecutesBefore order within a strongly
+// connected component.
+//
+// The wrapped ApplyFunc must therefore be safe to call concurrently for
+// non-interfering commands. As with AsyncApplier, Apply only enqueues
+// and always reports success to the consensus loop; apply errors bypass
+// the configured ApplyErrorPolicy and are instead delivered to the
+// applier's error callback from a worker goroutine.
+type ParallelApplier struct {
+	apply   ApplyFunc
+	onError func(pb.Command, error)
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*parallelApply
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// parallelApply is one enqueued command along with its scheduling state.
+type parallelApply struct {
+	cmd     pb.Command
+	running bool
+	done    bool
+}
+
+// NewParallelApplier returns a ParallelApplier wrapping the provided
+// ApplyFunc and starts a pool of parallelism worker goroutines; values
+// below one are treated as one. The onError callback, if non-nil, is
+// invoked with each command whose apply fails.
+func NewParallelApplier(apply ApplyFunc, parallelism int, onError func(pb.Command, error)) *ParallelApplier {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	a := &ParallelApplier{
+		apply:   apply,
+		onError: onError,
+	}
+	a.cond = sync.NewCond(&a.mu)
+	a.wg.Add(parallelism)
+	for i := 0; i < parallelism; i++ {
+		go a.worker()
+	}
+	return a
+}
+
+// Apply enqueues the command for application and returns immediately.
+// It is intended to be passed as Config.Apply. After Stop it returns
+// ErrStopped.
+func (a *ParallelApplier) Apply(cmd pb.Command) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return ErrStopped
+	}
+	a.queue = append(a.queue, &parallelApply{cmd: cmd})
+	a.cond.Broadcast()
+	return nil
+}
+
+// Flush blocks until every command enqueued before the call has been
+// applied.
+func (a *ParallelApplier) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for len(a.queue) > 0 {
+		a.cond.Wait()
+	}
+}
+
+// Stop drains the queue, applies the remaining commands, and stops the
+// worker pool.
+func (a *ParallelApplier) Stop() {
+	a.mu.Lock()
+	a.stopped = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+	a.wg.Wait()
+}
+
+// next returns the first queued command that is not yet being applied
+// and that interferes with no earlier unfinished command, or nil if no
+// command is currently schedulable. It requires a.mu to be held.
+func (a *ParallelApplier) next() *parallelApply {
+outer:
+	for i, e := range a.queue {
+		if e.running || e.done {
+			continue
+		}
+		for _, prior := range a.queue[:i] {
+			if !prior.done && prior.cmd.Interferes(e.cmd) {
+				continue outer
+			}
+		}
+		return e
+	}
+	return nil
+}
+
+func (a *ParallelApplier) worker() {
+	defer a.wg.Done()
+	a.mu.Lock()
+	for {
+		e := a.next()
+		for e == nil {
+			if a.stopped && len(a.queue) == 0 {
+				a.mu.Unlock()
+				return
+			}
+			a.cond.Wait()
+			e = a.next()
+		}
+		e.running = true
+		a.mu.Unlock()
+
+		if err := a.apply(e.cmd); err != nil && a.onError != nil {
+			a.onError(e.cmd, err)
+		}
+
+		a.mu.Lock()
+		e.running = false
+		e.done = true
+		// Completed commands unblock their dependents immediately, but are
+		// only dropped from the queue as a prefix so that indexing into the
+		// queue remains stable for in-flight scheduling decisions.
+		for len(a.queue) > 0 && a.queue[0].done {
+			a.queue = a.queue[1:]
+		}
+		a.cond.Broadcast()
+	}
+}
@@ -0,0 +1,96 @@
+package epaxos
+
+import (
+	"sync"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestParallelApplierConcurrentNonInterfering verifies that a command is
+// applied concurrently with an earlier non-interfering command, instead
+// of queuing behind it.
+func TestParallelApplierConcurrentNonInterfering(t *testing.T) {
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var applied []uint64
+	a := NewParallelApplier(func(cmd pb.Command) error {
+		if cmd.ID == 1 {
+			// The first command stays inside the application until the
+			// second, non-interfering command has been applied.
+			<-block
+		}
+		mu.Lock()
+		applied = append(applied, cmd.ID)
+		mu.Unlock()
+		if cmd.ID == 2 {
+			close(block)
+		}
+		return nil
+	}, 2, nil)
+	defer a.Stop()
+
+	cmd1 := *newTestingCommand("a", "b")
+	cmd1.ID = 1
+	cmd2 := *newTestingCommand("c", "d")
+	cmd2.ID = 2
+	if err := a.Apply(cmd1); err != nil {
+		t.Fatalf("unexpected error from Apply: %v", err)
+	}
+	if err := a.Apply(cmd2); err != nil {
+		t.Fatalf("unexpected error from Apply: %v", err)
+	}
+
+	a.Flush()
+	if exp := []uint64{2, 1}; len(applied) != 2 || applied[0] != exp[0] || applied[1] != exp[1] {
+		t.Fatalf("expected command 2 applied while command 1 was blocked, found %v", applied)
+	}
+}
+
+// TestParallelApplierOrdersInterfering verifies that interfering
+// commands are applied strictly in handoff order, even with spare
+// workers available.
+func TestParallelApplierOrdersInterfering(t *testing.T) {
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var applied []uint64
+	a := NewParallelApplier(func(cmd pb.Command) error {
+		if cmd.ID == 1 {
+			started <- struct{}{}
+			<-block
+		}
+		mu.Lock()
+		applied = append(applied, cmd.ID)
+		mu.Unlock()
+		return nil
+	}, 2, nil)
+
+	// Both commands write the same span, so the second must wait for the
+	// first even though a second worker is idle.
+	cmd1 := *newTestingCommand("a", "z")
+	cmd1.ID = 1
+	cmd2 := *newTestingCommand("a", "z")
+	cmd2.ID = 2
+	a.Apply(cmd1)
+	a.Apply(cmd2)
+
+	<-started
+	mu.Lock()
+	if len(applied) != 0 {
+		t.Fatalf("expected no command applied while command 1 was blocked, found %v", applied)
+	}
+	mu.Unlock()
+
+	close(block)
+	a.Flush()
+	if exp := []uint64{1, 2}; len(applied) != 2 || applied[0] != exp[0] || applied[1] != exp[1] {
+		t.Fatalf("expected commands applied in order %v, found %v", exp, applied)
+	}
+
+	// A stopped applier rejects further handoffs.
+	a.Stop()
+	if err := a.Apply(cmd1); err != ErrStopped {
+		t.Errorf("expected ErrStopped after Stop, found %v", err)
+	}
+}
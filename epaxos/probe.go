@@ -0,0 +1,62 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Conflict probing.
+//
+// A proposal that interferes with in-flight instances loses the fast
+// path and queues behind their execution, so applications with a choice
+// — of when to propose, or of which key to write — benefit from seeing a
+// command's conflicts before submitting it. ProbeConflicts answers
+// "would this command conflict right now" without proposing anything:
+// the same interval index that dependency computation uses is consulted,
+// but no instance is created and no state is mutated.
+
+// ConflictInfo describes one in-flight instance that interferes with a
+// probed command.
+type ConflictInfo struct {
+	// InstanceID identifies the interfering instance.
+	InstanceID pb.InstanceID
+	// Status is the instance's current state.
+	Status pb.InstanceState_Status
+	// Command is the instance's command, or nil if it is only known by
+	// digest. An unresolved command is conservatively reported as a
+	// conflict, mirroring dependency computation.
+	Command *pb.Command
+}
+
+// ProbeConflicts returns the not-yet-executed instances that currently
+// interfere with the command, ordered by instance ID. The probe does not
+// propose the command or mutate any state; the result is advisory and
+// goes stale as the protocol advances.
+func (p *epaxos) ProbeConflicts(cmd *pb.Command) []ConflictInfo {
+	var conflicts []ConflictInfo
+	rng := rangeForCmd(cmd)
+	for _, idx := range p.depIndex {
+		for _, inst := range idx.candidates(rng) {
+			if inst.is.Status == pb.InstanceState_Executed {
+				continue
+			}
+			if otherCmd := inst.is.Command; otherCmd != nil && !otherCmd.Interferes(*cmd) {
+				continue
+			}
+			conflicts = append(conflicts, ConflictInfo{
+				InstanceID: inst.is.InstanceID,
+				Status:     inst.is.Status,
+				Command:    inst.is.Command,
+			})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		a, b := conflicts[i].InstanceID, conflicts[j].InstanceID
+		if a.ReplicaID != b.ReplicaID {
+			return a.ReplicaID < b.ReplicaID
+		}
+		return a.InstanceNum < b.InstanceNum
+	})
+	return conflicts
+}
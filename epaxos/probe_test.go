@@ -0,0 +1,61 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestProbeConflicts verifies that probing reports the in-flight
+// instances interfering with a command, without mutating any state.
+func TestProbeConflicts(t *testing.T) {
+	p := newTestingEPaxos()
+
+	// Every seeded instance interferes with a full-range write.
+	if conflicts := p.ProbeConflicts(newTestingCommand("a", "z")); len(conflicts) != 5 {
+		t.Errorf("expected 5 conflicts for [a, z), found %+v", conflicts)
+	}
+
+	// A narrower command only conflicts with the instances spanning it.
+	conflicts := p.ProbeConflicts(newTestingCommand("c", "f"))
+	exp := []pb.InstanceID{
+		{ReplicaID: 0, InstanceNum: 1},
+		{ReplicaID: 0, InstanceNum: 2},
+		{ReplicaID: 1, InstanceNum: 1},
+	}
+	if len(conflicts) != len(exp) {
+		t.Fatalf("expected conflicts %v for [c, f), found %+v", exp, conflicts)
+	}
+	for i, c := range conflicts {
+		if c.InstanceID != exp[i] {
+			t.Errorf("expected conflict %d to be %v, found %v", i, exp[i], c.InstanceID)
+		}
+		if c.Status != pb.InstanceState_None {
+			t.Errorf("expected status None for %v, found %v", c.InstanceID, c.Status)
+		}
+	}
+
+	// Probing proposes nothing: the local command space is unchanged.
+	if a, e := p.maxInstanceNum(p.id), pb.InstanceNum(2); a != e {
+		t.Errorf("expected max instance number %v after probing, found %v", e, a)
+	}
+
+	// A command over an idle part of the keyspace has no conflicts.
+	if conflicts := p.ProbeConflicts(newTestingCommand("zz", "zzz")); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts for [zz, zzz), found %+v", conflicts)
+	}
+}
+
+// TestProbeConflictsSkipsExecuted verifies that executed instances are
+// not reported: they no longer delay a new proposal's execution.
+func TestProbeConflictsSkipsExecuted(t *testing.T) {
+	p := newTestingEPaxos()
+
+	inst := p.getInstance(2, 1)
+	inst.is.Status = pb.InstanceState_Executed
+	for _, c := range p.ProbeConflicts(newTestingCommand("a", "z")) {
+		if c.InstanceID == inst.is.InstanceID {
+			t.Errorf("expected executed instance %v to be omitted", inst.is.InstanceID)
+		}
+	}
+}
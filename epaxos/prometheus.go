@@ -0,0 +1,99 @@
+package epaxos
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Prometheus text exposition.
+//
+// The node's counters and distributions are rendered in the Prometheus
+// text format directly, without a client library dependency: the format
+// is plain text, and the snapshots handed to WritePrometheus are already
+// immutable copies. Embedders serve the output from an HTTP handler:
+//
+//	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+//		ms, counters, err := n.Metrics(r.Context())
+//		if err == nil {
+//			epaxos.WritePrometheus(w, counters, ms)
+//		}
+//	})
+
+// WritePrometheus renders the node's counters and metric distributions
+// in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, counters Counters, ms MetricsSnapshot) {
+	writeCounter(w, "epaxos_committed_total", counters.Committed)
+	writeCounter(w, "epaxos_executed_total", counters.Executed)
+	writeCounter(w, "epaxos_fast_path_committed_total", counters.FastPathCommitted)
+	writeCounter(w, "epaxos_slow_path_committed_total", counters.SlowPathCommitted)
+	writeCounter(w, "epaxos_recoveries_total", counters.Recoveries)
+
+	fmt.Fprintf(w, "# TYPE epaxos_instances gauge\n")
+	for _, state := range sortedStates(ms.InstancesPerState) {
+		fmt.Fprintf(w, "epaxos_instances{state=%q} %d\n", state.String(), ms.InstancesPerState[state])
+	}
+	fmt.Fprintf(w, "# TYPE epaxos_exec_queue_depth gauge\n")
+	fmt.Fprintf(w, "epaxos_exec_queue_depth %d\n", ms.ExecQueueDepth)
+
+	fmt.Fprintf(w, "# TYPE epaxos_slow_path_forced_by_total counter\n")
+	for _, peer := range sortedPeers(ms.SlowPathForcedBy) {
+		fmt.Fprintf(w, "epaxos_slow_path_forced_by_total{peer=\"%d\"} %d\n", peer, ms.SlowPathForcedBy[peer])
+	}
+
+	writeHistogram(w, "epaxos_deps_per_instance", ms.DepsPerInstance)
+	writeHistogram(w, "epaxos_scc_size", ms.SCCSize)
+	writeHistogram(w, "epaxos_origins_per_scc", ms.OriginsPerSCC)
+	writeHistogram(w, "epaxos_dominant_origin_per_scc", ms.DominantOriginPerSCC)
+	writeHistogram(w, "epaxos_preaccept_reply_ticks", ms.PreAcceptReplyTicks)
+}
+
+func writeCounter(w io.Writer, name string, v uint64) {
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, v)
+}
+
+// writeHistogram renders a histogram snapshot as cumulative Prometheus
+// buckets. Bucket i covers samples in [2^(i-1), 2^i), so its upper bound
+// is the integer 2^i - 1; empty buckets are elided.
+func writeHistogram(w io.Writer, name string, hs HistogramSnapshot) {
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	var cum uint64
+	for i, c := range hs.Buckets {
+		if c == 0 {
+			continue
+		}
+		cum += c
+		fmt.Fprintf(w, "%s_bucket{le=\"%d\"} %d\n", name, bucketUpperBound(i), cum)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, hs.Count)
+	fmt.Fprintf(w, "%s_sum %d\n", name, hs.Sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, hs.Count)
+}
+
+func bucketUpperBound(i int) uint64 {
+	if i == 0 {
+		return 0
+	}
+	return 1<<uint(i) - 1
+}
+
+func sortedStates(m map[pb.InstanceState_Status]uint64) []pb.InstanceState_Status {
+	states := make([]pb.InstanceState_Status, 0, len(m))
+	for s := range m {
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	return states
+}
+
+func sortedPeers(m map[pb.ReplicaID]uint64) []pb.ReplicaID {
+	peers := make([]pb.ReplicaID, 0, len(m))
+	for r := range m {
+		peers = append(peers, r)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return peers
+}
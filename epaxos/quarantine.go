@@ -0,0 +1,62 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+const (
+	// quarantineViolationThreshold is the number of protocol violations a
+	// peer may commit before it is quarantined.
+	quarantineViolationThreshold = 5
+	// quarantineTimeout is the number of ticks a quarantined peer's
+	// messages are rejected for.
+	quarantineTimeout = 100
+)
+
+// recordViolation notes a protocol violation in a message claiming to
+// concern one of the provided replica's instances. If the replica exceeds
+// the violation threshold it is quarantined: its messages are rejected
+// for quarantineTimeout ticks and an operator event is emitted, protecting
+// the cluster from a corrupted or misbehaving node.
+func (p *epaxos) recordViolation(m pb.Message) {
+	if pb.IsReply(m.Type) {
+		// Replies do not carry their sender, so the violation cannot be
+		// attributed to a peer.
+		return
+	}
+	r := m.InstanceID.ReplicaID
+	if r == p.id || !p.knownReplica(r) {
+		return
+	}
+	if p.isQuarantined(r) {
+		return
+	}
+
+	p.violations[r]++
+	if p.violations[r] < quarantineViolationThreshold {
+		return
+	}
+
+	violations := p.violations[r]
+	delete(p.violations, r)
+	p.quarantine(r, violations)
+}
+
+// quarantine rejects the replica's messages until the quarantine timer
+// expires.
+func (p *epaxos) quarantine(r pb.ReplicaID, violations int) {
+	p.logger.Errorf("quarantining replica %d after %d protocol violations", r, violations)
+	timer := makeTickingTimer(quarantineTimeout, func() {
+		delete(p.quarantined, r)
+		p.emitEvent(PeerUnquarantinedEvent{Replica: r})
+	})
+	p.quarantined[r] = &timer
+	p.registerOneTimeTimer(&timer)
+	p.emitEvent(PeerQuarantinedEvent{Replica: r, Violations: violations})
+}
+
+// isQuarantined returns whether the replica is currently quarantined.
+func (p *epaxos) isQuarantined(r pb.ReplicaID) bool {
+	_, ok := p.quarantined[r]
+	return ok
+}
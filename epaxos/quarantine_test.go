@@ -0,0 +1,80 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestQuarantineAfterRepeatedViolations verifies that a peer which repeatedly
+// sends invalid messages is quarantined, that its messages are rejected while
+// quarantined, and that the quarantine expires after the timeout.
+func TestQuarantineAfterRepeatedViolations(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0, 1, 2},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+	bad := pb.ReplicaID(1)
+
+	// An invalid message: it claims to concern one of the bad peer's
+	// instances, but is addressed to the wrong replica.
+	invalid := pb.Message{
+		To:         p.id + 1,
+		InstanceID: pb.InstanceID{ReplicaID: bad, InstanceNum: 1},
+		Type:       &pb.Message_Commit{Commit: &pb.Commit{}},
+	}
+	for i := 0; i < quarantineViolationThreshold; i++ {
+		if p.isQuarantined(bad) {
+			t.Fatalf("peer quarantined after only %d violations", i)
+		}
+		p.Step(invalid)
+	}
+	if !p.isQuarantined(bad) {
+		t.Fatalf("expected peer to be quarantined after %d violations",
+			quarantineViolationThreshold)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, found %+v", events)
+	}
+	if e, ok := events[0].(PeerQuarantinedEvent); !ok || e.Replica != bad {
+		t.Fatalf("expected PeerQuarantinedEvent for replica %d, found %+v", bad, events[0])
+	}
+
+	// A valid message from the quarantined peer is rejected.
+	valid := pb.Message{
+		To:         p.id,
+		InstanceID: pb.InstanceID{ReplicaID: bad, InstanceNum: 1},
+		Type: &pb.Message_PreAccept{PreAccept: &pb.PreAccept{
+			InstanceData: pb.InstanceData{
+				Command: newTestingCommand("a", "z"),
+				SeqNum:  1,
+			},
+		}},
+	}
+	p.Step(valid)
+	if inst := p.getInstance(bad, 1); inst != nil {
+		t.Fatalf("expected message from quarantined peer to be rejected, found %+v", inst)
+	}
+
+	// The quarantine expires after the timeout.
+	for i := 0; i < quarantineTimeout; i++ {
+		p.Tick()
+	}
+	if p.isQuarantined(bad) {
+		t.Fatalf("expected quarantine to expire after %d ticks", quarantineTimeout)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, found %+v", events)
+	}
+	if e, ok := events[1].(PeerUnquarantinedEvent); !ok || e.Replica != bad {
+		t.Fatalf("expected PeerUnquarantinedEvent for replica %d, found %+v", bad, events[1])
+	}
+
+	// The peer's messages are accepted again.
+	p.Step(valid)
+	if inst := p.getInstance(bad, 1); inst == nil {
+		t.Fatalf("expected message from unquarantined peer to be accepted")
+	}
+}
@@ -0,0 +1,50 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// QueryInstance sends an InstanceQuery to the provided replica, asking
+// for its view of the identified instance. The peer's answer is surfaced
+// through an InstanceQueryResultEvent. Queries are useful for debugging
+// suspected divergence between replicas and for instance recovery.
+func (p *epaxos) QueryInstance(to pb.ReplicaID, id pb.InstanceID) {
+	if !p.knownReplica(to) || to == p.id {
+		return
+	}
+	mm := pb.WrapMessage(&pb.InstanceQuery{})
+	mm.To = to
+	mm.From = p.id
+	mm.InstanceID = id
+	p.msgs = append(p.msgs, mm)
+}
+
+// onInstanceQuery answers an InstanceQuery with this replica's view of
+// the queried instance. Unlike protocol messages, a query never
+// materializes an instance that the replica does not already know about.
+func (p *epaxos) onInstanceQuery(m pb.Message) {
+	reply := &pb.InstanceQueryReply{}
+	if inst := p.getInstance(m.InstanceID.ReplicaID, m.InstanceID.InstanceNum); inst != nil {
+		state := inst.is
+		reply.State = &state
+	}
+	mm := pb.WrapMessage(reply)
+	mm.To = m.From
+	mm.From = p.id
+	mm.InstanceID = m.InstanceID
+	p.msgs = append(p.msgs, mm)
+}
+
+// onInstanceQueryReply surfaces a peer's view of a queried instance. If
+// the view shows the instance committed while the local replica is still
+// waiting on it, the committed state is adopted (see statusquery.go).
+func (p *epaxos) onInstanceQueryReply(m pb.Message, qr *pb.InstanceQueryReply) {
+	p.emitEvent(InstanceQueryResultEvent{
+		From:       m.From,
+		InstanceID: m.InstanceID,
+		State:      qr.State,
+	})
+	p.maybeAdoptQueriedStatus(m, qr)
+	p.maybeRepairFromQueryReply(m, qr)
+	p.onNoopProbeReply(m, qr)
+}
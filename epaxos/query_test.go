@@ -0,0 +1,53 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestQueryInstance verifies that a replica answers an InstanceQuery with
+// its view of the queried instance, and that the answer is surfaced to
+// the querier through an InstanceQueryResultEvent.
+func TestQueryInstance(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	remote := newEPaxos(&Config{ID: 1, Nodes: nodes})
+	inst := remote.onRequest(newTestingCommand("a", "z"))
+	remote.clearMsgs()
+
+	var events []Event
+	local := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   nodes,
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+	local.QueryInstance(1, inst.is.InstanceID)
+	if len(local.msgs) != 1 {
+		t.Fatalf("expected 1 outbound query, found %+v", local.msgs)
+	}
+
+	remote.Step(local.msgs[0])
+	if len(remote.msgs) != 1 {
+		t.Fatalf("expected 1 outbound reply, found %+v", remote.msgs)
+	}
+	local.Step(remote.msgs[0])
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, found %+v", events)
+	}
+	e, ok := events[0].(InstanceQueryResultEvent)
+	if !ok || e.From != 1 || e.State == nil {
+		t.Fatalf("expected InstanceQueryResultEvent from replica 1, found %+v", events[0])
+	}
+	if e.State.InstanceID != inst.is.InstanceID || e.State.Status != inst.is.Status {
+		t.Errorf("expected remote view %+v, found %+v", inst.is, e.State)
+	}
+
+	// Querying an instance the peer has no record of yields a nil state.
+	local.QueryInstance(1, pb.InstanceID{ReplicaID: 2, InstanceNum: 42})
+	remote.Step(local.msgs[1])
+	local.Step(remote.msgs[1])
+	if e := events[1].(InstanceQueryResultEvent); e.State != nil {
+		t.Errorf("expected nil state for unknown instance, found %+v", e.State)
+	}
+}
@@ -0,0 +1,77 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// RebalanceHint pairs a hot key span with the replicas best placed to
+// take over proposals for commands on that span.
+type RebalanceHint struct {
+	// Span is the key span the hint concerns.
+	Span pb.Span
+	// Heat is the number of commands observed on the span.
+	Heat int
+	// SuggestedReplicas holds replicas ordered by how much proposal load
+	// they currently lead, least-loaded first. After a membership change,
+	// newly added replicas lead few instances and sort to the front.
+	SuggestedReplicas []pb.ReplicaID
+}
+
+// keyHeat accumulates the number of commands observed per key span.
+type keyHeat struct {
+	span pb.Span
+	heat int
+}
+
+// recordHeat notes a command against its span's interference heat.
+func (p *epaxos) recordHeat(cmd *pb.Command) {
+	k := string(cmd.Span.Key)
+	h, ok := p.heat[k]
+	if !ok {
+		h = &keyHeat{span: cmd.Span}
+		p.heat[k] = h
+	}
+	h.heat++
+}
+
+// RebalanceHints returns up to max hints, hottest spans first. Clients
+// can use the hints to route proposals for contended spans toward the
+// suggested replicas, typically ones newly added to the cluster that
+// lead little of the existing proposal load.
+func (p *epaxos) RebalanceHints(max int) []RebalanceHint {
+	hints := make([]RebalanceHint, 0, len(p.heat))
+	for _, h := range p.heat {
+		hints = append(hints, RebalanceHint{Span: h.span, Heat: h.heat})
+	}
+	sort.Slice(hints, func(i, j int) bool {
+		if hints[i].Heat != hints[j].Heat {
+			return hints[i].Heat > hints[j].Heat
+		}
+		return hints[i].Span.Key.Compare(hints[j].Span.Key) < 0
+	})
+	if len(hints) > max {
+		hints = hints[:max]
+	}
+
+	suggested := p.replicasByLoad()
+	for i := range hints {
+		hints[i].SuggestedReplicas = suggested
+	}
+	return hints
+}
+
+// replicasByLoad returns the cluster's replicas ordered by the number of
+// instances each leads, least-loaded first.
+func (p *epaxos) replicasByLoad() []pb.ReplicaID {
+	replicas := append([]pb.ReplicaID(nil), p.nodes...)
+	sort.Slice(replicas, func(i, j int) bool {
+		li, lj := p.commands[replicas[i]].Len(), p.commands[replicas[j]].Len()
+		if li != lj {
+			return li < lj
+		}
+		return replicas[i] < replicas[j]
+	})
+	return replicas
+}
@@ -0,0 +1,39 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestRebalanceHints verifies that hints are ordered by interference heat
+// and suggest the least-loaded replicas first.
+func TestRebalanceHints(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	// Propose against "a" more often than "x". Replica 0 leads all of the
+	// resulting instances; replicas 1 and 2 lead none.
+	for i := 0; i < 3; i++ {
+		p.onRequest(newTestingCommand("a", "b"))
+	}
+	p.onRequest(newTestingCommand("x", "y"))
+
+	hints := p.RebalanceHints(10)
+	if len(hints) != 2 {
+		t.Fatalf("expected 2 hints, found %+v", hints)
+	}
+	if !hints[0].Span.Key.Equal(pb.Key("a")) || hints[0].Heat != 3 {
+		t.Errorf("expected hottest hint for span [a], found %+v", hints[0])
+	}
+	if !hints[1].Span.Key.Equal(pb.Key("x")) || hints[1].Heat != 1 {
+		t.Errorf("expected hint for span [x], found %+v", hints[1])
+	}
+	if sugg := hints[0].SuggestedReplicas; len(sugg) != 3 || sugg[2] != 0 {
+		t.Errorf("expected the loaded replica 0 to sort last, found %v", sugg)
+	}
+
+	// The hint count is capped.
+	if hints := p.RebalanceHints(1); len(hints) != 1 {
+		t.Errorf("expected 1 hint, found %+v", hints)
+	}
+}
@@ -0,0 +1,230 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+const (
+	// maxConcurrentRecoveries caps the number of instance recoveries that
+	// may be in flight at once. After a replica failure many instances
+	// become eligible for recovery simultaneously; without a cap the
+	// cluster would be flooded with Prepare traffic exactly when it is
+	// least healthy.
+	maxConcurrentRecoveries = 4
+	// maxRecoveryJitter is the maximum number of ticks a recovery's start
+	// is delayed by. Staggering recovery starts prevents all replicas from
+	// racing to recover the same instances in lockstep.
+	maxRecoveryJitter = 10
+	// recoveryLeaseTicks is how long a replica backs off from recovering
+	// an instance after observing another replica driving its recovery.
+	// The lease ensures that only one replica drives a recovery at a time,
+	// preventing dueling recovery attempts from livelocking each other.
+	recoveryLeaseTicks = 3 * maxRecoveryJitter
+)
+
+// recoveryScheduler throttles instance recoveries. Recoveries are queued
+// with enqueue and started in FIFO order, at most maxConcurrentRecoveries
+// at a time, each delayed by a random jitter.
+type recoveryScheduler struct {
+	p *epaxos
+	// queued holds instances awaiting recovery, in arrival order.
+	queued []pb.InstanceID
+	// active holds instances whose recovery has started (including those
+	// still waiting out their start jitter).
+	active map[pb.InstanceID]struct{}
+	// leased maps instances whose recovery another replica is currently
+	// driving to whether a local recovery should be re-queued when the
+	// lease expires.
+	leased map[pb.InstanceID]bool
+}
+
+func makeRecoveryScheduler(p *epaxos) recoveryScheduler {
+	return recoveryScheduler{
+		p:      p,
+		active: make(map[pb.InstanceID]struct{}),
+		leased: make(map[pb.InstanceID]bool),
+	}
+}
+
+// enqueue schedules the instance for recovery. Instances already queued
+// or being recovered are ignored.
+func (s *recoveryScheduler) enqueue(id pb.InstanceID) {
+	if _, ok := s.leased[id]; ok {
+		// Another replica is driving this instance's recovery; re-queue
+		// once its lease expires, in case it stalls.
+		s.leased[id] = true
+		return
+	}
+	if _, ok := s.active[id]; ok {
+		return
+	}
+	for _, queuedID := range s.queued {
+		if queuedID == id {
+			return
+		}
+	}
+	s.queued = append(s.queued, id)
+	s.maybeStart()
+}
+
+// completed marks the instance's recovery as finished, freeing a slot for
+// the next queued recovery.
+func (s *recoveryScheduler) completed(id pb.InstanceID) {
+	delete(s.active, id)
+	s.maybeStart()
+}
+
+// maybeStart starts queued recoveries until the concurrency cap is
+// reached. Each start is staggered by a random jitter of up to
+// maxRecoveryJitter ticks.
+func (s *recoveryScheduler) maybeStart() {
+	for len(s.queued) > 0 && len(s.active) < maxConcurrentRecoveries {
+		id := s.queued[0]
+		s.queued = s.queued[1:]
+		s.active[id] = struct{}{}
+
+		jitter := 1 + s.p.rand.Intn(maxRecoveryJitter)
+		timer := makeTickingTimer(jitter, func() {
+			s.p.startRecovery(id)
+		})
+		s.p.registerOneTimeTimer(&timer)
+	}
+}
+
+// observeRemoteRecovery notes that another replica is driving the
+// recovery of the instance. Any local recovery of the instance that has
+// not yet started is backed off for recoveryLeaseTicks, after which it
+// is re-queued in case the remote recovery stalled. Recoveries that have
+// already started are seen through rather than abandoned midway.
+func (s *recoveryScheduler) observeRemoteRecovery(id pb.InstanceID) {
+	if _, ok := s.active[id]; ok {
+		return
+	}
+	if _, ok := s.leased[id]; ok {
+		return
+	}
+	requeue := false
+	for i, queuedID := range s.queued {
+		if queuedID == id {
+			s.queued = append(s.queued[:i], s.queued[i+1:]...)
+			requeue = true
+			break
+		}
+	}
+	s.leased[id] = requeue
+	timer := makeTickingTimer(recoveryLeaseTicks, func() {
+		requeue := s.leased[id]
+		delete(s.leased, id)
+		if requeue {
+			s.enqueue(id)
+		}
+	})
+	s.p.registerOneTimeTimer(&timer)
+}
+
+// startRecovery begins recovering the instance from the rest of the
+// cluster.
+// TODO implement full instance recovery using Explicit Prepare.
+func (p *epaxos) startRecovery(id pb.InstanceID) {
+	p.recoveryLogger.Debugf("starting recovery of instance %+v", id)
+	p.bumpCounter(&p.counters.Recoveries)
+	inst := p.getInstance(id.ReplicaID, id.InstanceNum)
+	if inst != nil && inst.isStates(pb.InstanceState_PreAccepted) && inst.is.Command != nil {
+		// We hold pre-accepted attributes for the instance, possibly seen by
+		// only a minority of replicas. Probe whether they could have been
+		// committed on the fast path before recomputing them.
+		inst.broadcastTryPreAccept()
+		return
+	}
+	if inst == nil || inst.is.Command == nil {
+		// The instance never received a command here, but a quorum
+		// excluding this replica may still have committed one. Probe the
+		// cluster before plugging the slot with a no-op.
+		p.startNoopProbe(id)
+	}
+}
+
+// startNoopProbe asks every peer for its view of an instance that holds
+// no command locally. Only once a quorum of the cluster, counting this
+// replica, reports no command for the slot is it plugged with a no-op:
+// a command can only commit after a quorum accepts it, and any two
+// quorums intersect, so a quorum of no-command reports rules out a
+// completed commit this replica missed. A reply showing a command
+// instead abandons the probe and lets the command's own protocol
+// messages finish the slot. Like the rest of recovery, the probe does
+// not yet carry a ballot, so it cannot fence out a leader that is still
+// proposing into the slot concurrently; see the TODO on startRecovery.
+func (p *epaxos) startNoopProbe(id pb.InstanceID) {
+	if _, ok := p.noopProbes[id]; ok {
+		return
+	}
+	noCommand := map[pb.ReplicaID]struct{}{p.id: {}}
+	p.noopProbes[id] = noCommand
+	if p.quorum(len(noCommand)) {
+		// A single-replica cluster answers its own probe.
+		delete(p.noopProbes, id)
+		p.commitNoop(id)
+		return
+	}
+	for _, node := range p.nodes {
+		if node != p.id {
+			p.QueryInstance(node, id)
+		}
+	}
+}
+
+// onNoopProbeReply counts a peer's view of a probed instance toward the
+// probe's quorum. Replies carrying a command abandon the probe; replies
+// showing an empty slot count toward the quorum of no-command reports
+// that commitNoop requires.
+func (p *epaxos) onNoopProbeReply(m pb.Message, qr *pb.InstanceQueryReply) {
+	noCommand, ok := p.noopProbes[m.InstanceID]
+	if !ok {
+		return
+	}
+	if qr.State != nil && qr.State.Command != nil {
+		p.recoveryLogger.Debugf("abandoning no-op probe of instance %+v; replica %d holds a command",
+			m.InstanceID, m.From)
+		delete(p.noopProbes, m.InstanceID)
+		return
+	}
+	noCommand[m.From] = struct{}{}
+	if p.quorum(len(noCommand)) {
+		delete(p.noopProbes, m.InstanceID)
+		p.commitNoop(m.InstanceID)
+	}
+}
+
+// commitNoop commits the designated no-op command in the instance,
+// plugging a slot that a quorum reported holding no command (see
+// startNoopProbe). The no-op interferes with nothing and is never
+// delivered to the application, but it permanently occupies the slot,
+// so callers must first establish that no real command can have
+// committed there — a no-op over a command committed elsewhere would
+// silently drop that command here. A slot that gained a command while
+// the probe was in flight is left to the command's own protocol
+// messages.
+func (p *epaxos) commitNoop(id pb.InstanceID) {
+	inst := p.getInstance(id.ReplicaID, id.InstanceNum)
+	if inst == nil {
+		inst = p.newInstance(id.ReplicaID, id.InstanceNum)
+		if !p.insertInstance(inst) {
+			return
+		}
+	}
+	if inst.abandoned || inst.is.Command != nil ||
+		!inst.isStates(pb.InstanceState_None, pb.InstanceState_PreAccepted, pb.InstanceState_Accepted) {
+		return
+	}
+	p.recoveryLogger.Debugf("committing no-op in instance %+v", id)
+	inst.unwatchStatus()
+	inst.is.Status = pb.InstanceState_Committed
+	p.bumpCounter(&p.counters.Committed)
+	inst.is.Command = pb.NewNoopCommand()
+	inst.cmdDigest = nil
+	p.indexCommand(inst)
+	inst.replaceInstanceData(0, nil)
+	inst.persist()
+	inst.broadcastCommit()
+	inst.prepareToExecute()
+}
@@ -0,0 +1,216 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestRecoverySchedulerThrottling verifies that the recovery scheduler caps
+// the number of concurrent recoveries, staggers starts with jitter, and
+// drains its queue as recoveries complete.
+func TestRecoverySchedulerThrottling(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}, RandSeed: 1})
+	s := &p.recovery
+
+	// Enqueue more recoveries than the concurrency cap, including a
+	// duplicate, which should be ignored.
+	const enqueued = 3 * maxConcurrentRecoveries
+	for i := 1; i <= enqueued; i++ {
+		s.enqueue(pb.InstanceID{ReplicaID: 1, InstanceNum: pb.InstanceNum(i)})
+	}
+	s.enqueue(pb.InstanceID{ReplicaID: 1, InstanceNum: 1})
+
+	if len(s.active) != maxConcurrentRecoveries {
+		t.Fatalf("expected %d active recoveries, found %d", maxConcurrentRecoveries, len(s.active))
+	}
+	if exp := enqueued - maxConcurrentRecoveries; len(s.queued) != exp {
+		t.Fatalf("expected %d queued recoveries, found %d", exp, len(s.queued))
+	}
+
+	// Started recoveries are staggered behind jitter timers.
+	if len(p.timers) != maxConcurrentRecoveries {
+		t.Fatalf("expected %d jitter timers, found %d", maxConcurrentRecoveries, len(p.timers))
+	}
+	for i := 0; i < maxRecoveryJitter+1; i++ {
+		p.Tick()
+	}
+	if len(p.timers) != 0 {
+		t.Fatalf("expected all jitter timers to have fired, found %d", len(p.timers))
+	}
+
+	// Completing recoveries frees slots for queued ones, in FIFO order.
+	for i := 1; i <= enqueued; i++ {
+		id := pb.InstanceID{ReplicaID: 1, InstanceNum: pb.InstanceNum(i)}
+		if _, ok := s.active[id]; !ok {
+			t.Fatalf("expected instance %+v to be recovering", id)
+		}
+		s.completed(id)
+	}
+	if len(s.active) != 0 || len(s.queued) != 0 {
+		t.Fatalf("expected drained scheduler, found %d active and %d queued",
+			len(s.active), len(s.queued))
+	}
+}
+
+// TestRecoveryCommitsNoop verifies that recovering an instance that
+// never received a command probes the cluster and, once a quorum
+// reports no command for the slot, commits the designated no-op,
+// broadcasts the commit to peers, and executes without delivering
+// anything to the application or constraining later commands.
+func TestRecoveryCommitsNoop(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	id := pb.InstanceID{ReplicaID: 1, InstanceNum: 1}
+	p.startRecovery(id)
+
+	// The slot is not plugged yet; every peer is queried first.
+	if inst := p.getInstance(id.ReplicaID, id.InstanceNum); inst != nil {
+		t.Fatalf("expected no no-op before a quorum answers, found %+v", inst.is)
+	}
+	queries := 0
+	for _, msg := range p.msgs {
+		if _, ok := msg.Type.(*pb.Message_InstanceQuery); ok {
+			queries++
+		}
+	}
+	if queries != len(p.nodes)-1 {
+		t.Fatalf("expected %d InstanceQuery messages, found %+v", len(p.nodes)-1, p.msgs)
+	}
+	p.clearMsgs()
+
+	// One peer reporting an empty slot completes a quorum of two.
+	reply := pb.WrapMessage(&pb.InstanceQueryReply{})
+	reply.To = 0
+	reply.From = 1
+	reply.InstanceID = id
+	p.Step(reply)
+
+	inst := p.getInstance(id.ReplicaID, id.InstanceNum)
+	if inst == nil {
+		t.Fatalf("expected recovery to create the instance")
+	}
+	if cmd := inst.is.Command; cmd == nil || !cmd.Noop {
+		t.Fatalf("expected a no-op command committed, found %+v", cmd)
+	}
+	if inst.is.Status != pb.InstanceState_Executed {
+		t.Fatalf("expected the no-op executed, found %v", inst.is.Status)
+	}
+	if inst.is.SeqNum != 0 || len(inst.is.Deps) != 0 {
+		t.Fatalf("expected the no-op committed with zero attributes, found %+v", inst.is)
+	}
+	if len(p.executedCmds) != 0 {
+		t.Fatalf("expected the no-op not delivered, found %+v", p.executedCmds)
+	}
+
+	// Peers are told of the commit so that they plug the hole too.
+	commits := 0
+	for _, msg := range p.msgs {
+		if _, ok := msg.Type.(*pb.Message_Commit); ok {
+			commits++
+		}
+	}
+	if commits != len(p.nodes)-1 {
+		t.Errorf("expected %d Commit messages, found %+v", len(p.nodes)-1, p.msgs)
+	}
+
+	// The no-op interferes with nothing, so later commands do not depend
+	// on it.
+	_, deps := p.seqAndDepsForCommand(newTestingCommand("a", "z"), pb.InstanceID{})
+	if len(deps) != 0 {
+		t.Errorf("expected no dependencies on the no-op, found %+v", deps)
+	}
+}
+
+// TestRecoveryNoopProbeAbandoned verifies that a probe reply showing a
+// command in the slot abandons the no-op: the command may have committed
+// on a quorum excluding this replica, and a no-op would permanently
+// displace it here.
+func TestRecoveryNoopProbeAbandoned(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2, 3, 4}})
+
+	id := pb.InstanceID{ReplicaID: 1, InstanceNum: 1}
+	p.startRecovery(id)
+	p.clearMsgs()
+
+	// A peer reports holding a command for the slot.
+	state := pb.InstanceState{
+		InstanceID: id,
+		Status:     pb.InstanceState_Accepted,
+		InstanceData: pb.InstanceData{
+			Command: newTestingCommand("a", "z"),
+			SeqNum:  1,
+		},
+	}
+	reply := pb.WrapMessage(&pb.InstanceQueryReply{State: &state})
+	reply.To = 0
+	reply.From = 1
+	reply.InstanceID = id
+	p.Step(reply)
+
+	// Empty replies from the remaining peers no longer complete a quorum.
+	for _, from := range []pb.ReplicaID{2, 3} {
+		reply := pb.WrapMessage(&pb.InstanceQueryReply{})
+		reply.To = 0
+		reply.From = from
+		reply.InstanceID = id
+		p.Step(reply)
+	}
+	if inst := p.getInstance(id.ReplicaID, id.InstanceNum); inst != nil && inst.is.Command != nil && inst.is.Command.Noop {
+		t.Fatalf("expected no no-op after a command was reported, found %+v", inst.is)
+	}
+	if len(p.noopProbes) != 0 {
+		t.Fatalf("expected the probe abandoned, found %+v", p.noopProbes)
+	}
+}
+
+// TestRecoverySchedulerLease verifies that observing another replica
+// driving an instance's recovery backs off the local recovery until the
+// lease expires, after which a wanted recovery is re-queued.
+func TestRecoverySchedulerLease(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}, RandSeed: 1})
+	s := &p.recovery
+
+	// Fill the active slots so that further recoveries queue.
+	for i := 1; i <= maxConcurrentRecoveries; i++ {
+		s.enqueue(pb.InstanceID{ReplicaID: 1, InstanceNum: pb.InstanceNum(i)})
+	}
+	queuedID := pb.InstanceID{ReplicaID: 1, InstanceNum: maxConcurrentRecoveries + 1}
+	s.enqueue(queuedID)
+	if len(s.queued) != 1 {
+		t.Fatalf("expected 1 queued recovery, found %d", len(s.queued))
+	}
+
+	// Another replica starts driving the queued instance's recovery.
+	s.observeRemoteRecovery(queuedID)
+	if len(s.queued) != 0 {
+		t.Fatalf("expected leased instance removed from queue, found %d queued", len(s.queued))
+	}
+	if !s.leased[queuedID] {
+		t.Fatalf("expected instance leased with re-queue wanted")
+	}
+
+	// Enqueueing a leased instance defers to the lease holder.
+	s.enqueue(queuedID)
+	if len(s.queued) != 0 {
+		t.Fatalf("expected enqueue of leased instance deferred, found %d queued", len(s.queued))
+	}
+
+	// An already-started recovery is seen through, not abandoned.
+	activeID := pb.InstanceID{ReplicaID: 1, InstanceNum: 1}
+	s.observeRemoteRecovery(activeID)
+	if _, ok := s.leased[activeID]; ok {
+		t.Fatalf("expected active recovery not to be leased away")
+	}
+
+	// Once the lease expires, the wanted recovery is re-queued.
+	for i := 0; i < recoveryLeaseTicks+1; i++ {
+		p.Tick()
+	}
+	if _, ok := s.leased[queuedID]; ok {
+		t.Fatalf("expected lease to have expired")
+	}
+	if len(s.queued) != 1 || s.queued[0] != queuedID {
+		t.Fatalf("expected re-queued recovery of %+v, found %+v", queuedID, s.queued)
+	}
+}
@@ -0,0 +1,33 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// maxRelayHops is the maximum number of intermediate peers a message may
+// be relayed through before being dropped. One hop suffices to route
+// around any single broken link; the limit exists to break relay loops.
+const maxRelayHops = 2
+
+// maybeRelayMessage forwards a message addressed to another replica on
+// toward its destination. Under a partial partition, a replica that
+// cannot reach a peer directly can send the peer's messages to a third
+// replica instead; that replica steps them here and forwards them. The
+// hop count carried on the message bounds relay loops. Returns whether
+// the message was consumed.
+func (p *epaxos) maybeRelayMessage(m pb.Message) bool {
+	if m.To == p.id {
+		return false
+	}
+	if !p.relayMessages || !p.knownReplica(m.To) {
+		return false
+	}
+	if m.Hops >= maxRelayHops {
+		p.logger.Debugf("dropping message relayed through %d peers: %+v", m.Hops, m)
+		return true
+	}
+	p.logger.Debugf("relaying message from %d to %d", m.From, m.To)
+	m.Hops++
+	p.msgs = append(p.msgs, m)
+	return true
+}
@@ -0,0 +1,81 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestRelayMessage verifies that a node with relaying enabled forwards a
+// message addressed to another replica on toward its destination, with
+// the hop count incremented.
+func TestRelayMessage(t *testing.T) {
+	p := newEPaxos(&Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2}, RelayMessages: true})
+
+	m := pb.Message{
+		To:         2,
+		From:       0,
+		InstanceID: pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: testingInstanceData}),
+	}
+	p.Step(m)
+
+	msgs := p.ReadMessages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 relayed message, found %v", msgs)
+	}
+	relayed := msgs[0]
+	if relayed.To != 2 || relayed.From != 0 || relayed.Hops != 1 {
+		t.Fatalf("expected message relayed to 2 with 1 hop, found %+v", relayed)
+	}
+
+	// The relay must not have materialized an instance locally.
+	if inst := p.getInstance(0, 1); inst != nil {
+		t.Fatalf("expected no local instance for relayed message, found %+v", inst)
+	}
+}
+
+// TestRelayMessageHopLimit verifies that a message relayed through too
+// many peers is dropped rather than looping forever, and that the drop
+// is not treated as a protocol violation.
+func TestRelayMessageHopLimit(t *testing.T) {
+	p := newEPaxos(&Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2}, RelayMessages: true})
+
+	m := pb.Message{
+		To:         2,
+		From:       0,
+		Hops:       maxRelayHops,
+		InstanceID: pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: testingInstanceData}),
+	}
+	p.Step(m)
+
+	if msgs := p.ReadMessages(); len(msgs) != 0 {
+		t.Fatalf("expected over-relayed message dropped, found %v", msgs)
+	}
+	if v := p.violations[0]; v != 0 {
+		t.Fatalf("expected no violation recorded for over-relayed message, found %d", v)
+	}
+}
+
+// TestRelayMessageHopsRoundTrip verifies that the hop count survives a
+// marshal/unmarshal cycle.
+func TestRelayMessageHopsRoundTrip(t *testing.T) {
+	m := pb.Message{
+		To:   2,
+		From: 0,
+		Hops: 1,
+		Type: pb.WrapMessageInner(&pb.PreAccept{InstanceData: testingInstanceData}),
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error marshaling message: %v", err)
+	}
+	var out pb.Message
+	if err := out.Unmarshal(data); err != nil {
+		t.Fatalf("unexpected error unmarshaling message: %v", err)
+	}
+	if out.Hops != m.Hops {
+		t.Fatalf("expected %d hops after round trip, found %d", m.Hops, out.Hops)
+	}
+}
@@ -0,0 +1,158 @@
+package epaxos
+
+import (
+	"bytes"
+
+	"github.com/google/btree"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Consistency repair.
+//
+// Replicas that execute the same instances should hold the same state
+// for them, but silent corruption — a bad disk, a buggy operator
+// restore, a dropped Commit followed by an incomplete recovery — can
+// leave a replica behind or, worse, divergent without any protocol
+// message revealing it. With Config.RepairScanTicks set, a background
+// job periodically samples a handful of executed instances, queries a
+// random peer for its view of each, and compares the answers against
+// the local state. A peer found missing a commit is simply re-sent it,
+// healing the gap in one message. A peer whose committed state differs
+// from the locally executed state cannot be healed automatically — one
+// of the two replicas applied the wrong command — so the finding is
+// reported to the operator through a RepairDivergenceEvent.
+
+// defaultRepairSampleSize is the number of executed instances each
+// repair scan samples when Config.RepairSampleSize is unset.
+const defaultRepairSampleSize = 3
+
+// RepairResyncEvent is emitted when a repair scan finds a peer missing
+// the commit of a locally executed instance and re-sends it.
+type RepairResyncEvent struct {
+	// InstanceID identifies the instance re-sent to the peer.
+	InstanceID pb.InstanceID
+	// Replica is the peer that was missing the commit.
+	Replica pb.ReplicaID
+}
+
+func (RepairResyncEvent) event() {}
+
+// RepairDivergenceEvent is emitted when a repair scan finds a peer whose
+// committed state for an executed instance differs from the local state.
+// Divergence cannot be repaired automatically and requires operator
+// attention.
+type RepairDivergenceEvent struct {
+	// InstanceID identifies the divergent instance.
+	InstanceID pb.InstanceID
+	// Replica is the peer holding the differing state.
+	Replica pb.ReplicaID
+}
+
+func (RepairDivergenceEvent) event() {}
+
+// runRepairScan samples executed instances and queries a random peer for
+// its view of each, recording the outstanding probes so that the replies
+// can be checked on arrival.
+func (p *epaxos) runRepairScan() {
+	peer, ok := p.randomPeer()
+	if !ok {
+		return
+	}
+	for i := 0; i < p.repairSampleSize; i++ {
+		inst := p.sampleExecutedInstance()
+		if inst == nil {
+			continue
+		}
+		p.repairProbes[inst.is.InstanceID] = peer
+		p.QueryInstance(peer, inst.is.InstanceID)
+	}
+}
+
+// randomPeer returns a uniformly random replica other than this one.
+func (p *epaxos) randomPeer() (pb.ReplicaID, bool) {
+	if len(p.nodes) < 2 {
+		return 0, false
+	}
+	for {
+		if r := p.nodes[p.rand.Intn(len(p.nodes))]; r != p.id {
+			return r, true
+		}
+	}
+}
+
+// sampleExecutedInstance returns a pseudo-random executed instance, or
+// nil if the sampled column holds none at or beyond the sampled
+// position.
+func (p *epaxos) sampleExecutedInstance() *instance {
+	col := p.nodes[p.rand.Intn(len(p.nodes))]
+	max := p.maxInstanceNum(col)
+	if max == 0 {
+		return nil
+	}
+	target := pb.InstanceNum(p.rand.Int63n(int64(max))) + 1
+	var found *instance
+	p.commands[col].AscendGreaterOrEqual(instanceKey(target), func(i btree.Item) bool {
+		if inst := i.(*instance); inst.is.Status == pb.InstanceState_Executed {
+			found = inst
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// maybeRepairFromQueryReply checks an InstanceQueryReply against an
+// outstanding repair probe, re-sending the commit to a peer found
+// missing it and reporting divergence.
+func (p *epaxos) maybeRepairFromQueryReply(m pb.Message, qr *pb.InstanceQueryReply) {
+	if peer, ok := p.repairProbes[m.InstanceID]; !ok || peer != m.From {
+		return
+	}
+	delete(p.repairProbes, m.InstanceID)
+	inst := p.getInstance(m.InstanceID.ReplicaID, m.InstanceID.InstanceNum)
+	if inst == nil || inst.is.Status != pb.InstanceState_Executed {
+		// The sampled instance was truncated while the probe was in
+		// flight; there is nothing left to compare.
+		return
+	}
+	state := qr.State
+	if state == nil || state.Status < pb.InstanceState_Committed {
+		p.logger.Debugf("repair: replica %d is missing commit of instance %v; re-sending",
+			m.From, m.InstanceID)
+		p.emitEvent(RepairResyncEvent{InstanceID: m.InstanceID, Replica: m.From})
+		p.sendTo(&pb.Commit{InstanceData: inst.instanceData()}, m.From, inst)
+		return
+	}
+	if repairStatesMatch(inst, state) {
+		return
+	}
+	p.logger.Errorf("repair: instance %v diverges on replica %d: local %+v, remote %+v",
+		m.InstanceID, m.From, inst.is, state)
+	p.emitEvent(RepairDivergenceEvent{InstanceID: m.InstanceID, Replica: m.From})
+}
+
+// repairStatesMatch reports whether a peer's committed state for an
+// instance agrees with the locally executed state: same command digest,
+// sequence number, and dependencies.
+func repairStatesMatch(local *instance, remote *pb.InstanceState) bool {
+	lc, rc := local.is.Command, remote.Command
+	switch {
+	case lc == nil && rc == nil:
+	case lc == nil || rc == nil:
+		return false
+	default:
+		if !bytes.Equal(local.commandDigest(), rc.Digest()) {
+			return false
+		}
+	}
+	if local.is.SeqNum != remote.SeqNum || len(local.is.Deps) != len(remote.Deps) {
+		return false
+	}
+	for i, dep := range local.is.Deps {
+		if remote.Deps[i] != dep {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// newRepairReplica returns a replica with the consistency repair job
+// enabled, an executed instance to sample, and its captured events.
+func newRepairReplica(t *testing.T, events *[]Event) (*epaxos, *instance) {
+	p := newEPaxos(&Config{
+		ID:              0,
+		Nodes:           []pb.ReplicaID{0, 1, 2},
+		RepairScanTicks: 2,
+		RandSeed:        1,
+		OnEvent:         func(e Event) { *events = append(*events, e) },
+	})
+
+	inst := p.onRequest(newTestingCommand("a", "z"))
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	inst.assertState(pb.InstanceState_Executed)
+	p.clearMsgs()
+
+	p.TickN(2)
+	if _, ok := p.repairProbes[inst.is.InstanceID]; !ok {
+		t.Fatalf("expected a repair probe for %v, found %v", inst.is.InstanceID, p.repairProbes)
+	}
+	foundQuery := false
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_InstanceQuery); ok && m.InstanceID == inst.is.InstanceID {
+			foundQuery = true
+		}
+	}
+	if !foundQuery {
+		t.Fatalf("expected an InstanceQuery for %v, found %+v", inst.is.InstanceID, p.msgs)
+	}
+	p.clearMsgs()
+	return p, inst
+}
+
+// TestRepairResyncsMissingCommit verifies that a peer found missing the
+// commit of an executed instance is re-sent it.
+func TestRepairResyncsMissingCommit(t *testing.T) {
+	var events []Event
+	p, inst := newRepairReplica(t, &events)
+	peer := p.repairProbes[inst.is.InstanceID]
+
+	// The peer has no record of the instance at all.
+	p.Step(pb.Message{
+		To:         0,
+		From:       peer,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.InstanceQueryReply{}),
+	})
+
+	foundCommit := false
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_Commit); ok && m.To == peer {
+			foundCommit = true
+		}
+	}
+	if !foundCommit {
+		t.Errorf("expected the commit re-sent to replica %d, found %+v", peer, p.msgs)
+	}
+	foundEvent := false
+	for _, e := range events {
+		if re, ok := e.(RepairResyncEvent); ok {
+			if re.InstanceID != inst.is.InstanceID || re.Replica != peer {
+				t.Errorf("unexpected RepairResyncEvent %+v", re)
+			}
+			foundEvent = true
+		}
+	}
+	if !foundEvent {
+		t.Errorf("expected a RepairResyncEvent, found %+v", events)
+	}
+	if _, ok := p.repairProbes[inst.is.InstanceID]; ok {
+		t.Errorf("expected the probe to be cleared")
+	}
+}
+
+// TestRepairReportsDivergence verifies that a matching peer view passes
+// silently while a differing committed command is reported through a
+// RepairDivergenceEvent, without mutating the local instance.
+func TestRepairReportsDivergence(t *testing.T) {
+	var events []Event
+	p, inst := newRepairReplica(t, &events)
+	peer := p.repairProbes[inst.is.InstanceID]
+
+	// A matching view raises nothing.
+	matching := inst.is
+	matching.Status = pb.InstanceState_Committed
+	p.Step(pb.Message{
+		To:         0,
+		From:       peer,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.InstanceQueryReply{State: &matching}),
+	})
+	for _, e := range events {
+		switch e.(type) {
+		case RepairResyncEvent, RepairDivergenceEvent:
+			t.Fatalf("expected no repair finding for a matching view, found %+v", e)
+		}
+	}
+
+	// A differing committed command on the next scan is divergence.
+	p.TickN(2)
+	peer = p.repairProbes[inst.is.InstanceID]
+	diverged := inst.is
+	diverged.Status = pb.InstanceState_Committed
+	diverged.Command = newTestingCommand("a", "z")
+	p.Step(pb.Message{
+		To:         0,
+		From:       peer,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.InstanceQueryReply{State: &diverged}),
+	})
+
+	foundEvent := false
+	for _, e := range events {
+		if de, ok := e.(RepairDivergenceEvent); ok {
+			if de.InstanceID != inst.is.InstanceID || de.Replica != peer {
+				t.Errorf("unexpected RepairDivergenceEvent %+v", de)
+			}
+			foundEvent = true
+		}
+	}
+	if !foundEvent {
+		t.Errorf("expected a RepairDivergenceEvent, found %+v", events)
+	}
+	inst.assertState(pb.InstanceState_Executed)
+	if inst.is.Command.ID == diverged.Command.ID {
+		t.Errorf("expected the local command to be untouched")
+	}
+}
@@ -0,0 +1,67 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// BulkReplayOptions configures which per-instance notifications are
+// suppressed while a bulk replay is in progress.
+type BulkReplayOptions struct {
+	// SkipWatermarkNotifications suppresses the per-instance
+	// OnExecutedWatermark callbacks that would normally fire as the
+	// watermark advances. A single callback carrying the net advance is
+	// delivered when the replay finishes.
+	SkipWatermarkNotifications bool
+	// SkipEvents suppresses operator events emitted while replaying.
+	SkipEvents bool
+}
+
+// bulkReplayState holds the state of an in-progress bulk replay.
+type bulkReplayState struct {
+	active bool
+	opts   BulkReplayOptions
+	// pendingApply buffers executed commands destined for the
+	// application's ApplyFunc until the replay finishes.
+	pendingApply []pb.Command
+	// watermarkAdvanced records whether a suppressed watermark
+	// notification is owed when the replay finishes.
+	watermarkAdvanced bool
+}
+
+// StartBulkReplay switches the node into bulk replay mode, intended for
+// followers applying a flood of caught-up instances after a partition
+// heals. While active, executed commands destined for the ApplyFunc are
+// buffered and applied as one batch by FinishBulkReplay, and the
+// notifications selected in opts are suppressed, avoiding per-instance
+// callback overhead on every replayed command.
+func (p *epaxos) StartBulkReplay(opts BulkReplayOptions) {
+	if p.replay.active {
+		p.failReplica(p.recoveryLogger, "bulk replay already in progress")
+		return
+	}
+	p.replay = bulkReplayState{active: true, opts: opts}
+}
+
+// FinishBulkReplay ends an in-progress bulk replay, applying the
+// buffered commands in execution order and delivering any suppressed
+// watermark notification. It is a no-op if no replay is in progress.
+func (p *epaxos) FinishBulkReplay() {
+	if !p.replay.active {
+		return
+	}
+	rs := p.replay
+	p.replay = bulkReplayState{}
+
+	for _, cmd := range rs.pendingApply {
+		p.applyCommand(cmd)
+	}
+	if rs.watermarkAdvanced && p.onExecutedWatermark != nil {
+		p.onExecutedWatermark(p.ExecutedWatermark())
+	}
+	p.recoveryLogger.Infof("bulk replay finished; applied %d commands", len(rs.pendingApply))
+}
+
+// replaying returns whether a bulk replay is in progress.
+func (p *epaxos) replaying() bool {
+	return p.replay.active
+}
@@ -0,0 +1,76 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestBulkReplayBatchesApplication verifies that while a bulk replay is
+// in progress, executed commands are buffered instead of being applied
+// one-by-one, watermark notifications are suppressed, and finishing the
+// replay applies the batch in order with a single watermark callback.
+func TestBulkReplayBatchesApplication(t *testing.T) {
+	var applied []uint64
+	wmCalls := 0
+	p := newEPaxos(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0},
+		Apply: func(cmd pb.Command) error {
+			applied = append(applied, cmd.ID)
+			return nil
+		},
+		OnExecutedWatermark: func(map[pb.ReplicaID]pb.InstanceNum) { wmCalls++ },
+	})
+
+	p.StartBulkReplay(BulkReplayOptions{SkipWatermarkNotifications: true})
+	var cmdIDs []uint64
+	for i := 0; i < 3; i++ {
+		cmd := newTestingCommand("a", "z")
+		cmdIDs = append(cmdIDs, cmd.ID)
+		// Single-node instances execute immediately on proposal.
+		p.Request(cmd)
+	}
+
+	if len(applied) != 0 {
+		t.Fatalf("expected application batched during replay, found %v", applied)
+	}
+	if wmCalls != 0 {
+		t.Fatalf("expected watermark notifications suppressed during replay, found %d", wmCalls)
+	}
+
+	p.FinishBulkReplay()
+	if len(applied) != len(cmdIDs) {
+		t.Fatalf("expected %d commands applied, found %v", len(cmdIDs), applied)
+	}
+	for i, id := range cmdIDs {
+		if applied[i] != id {
+			t.Fatalf("expected commands applied in order %v, found %v", cmdIDs, applied)
+		}
+	}
+	if wmCalls != 1 {
+		t.Fatalf("expected a single watermark notification after replay, found %d", wmCalls)
+	}
+}
+
+// TestBulkReplaySkipsEvents verifies that operator events are dropped
+// during a bulk replay when SkipEvents is set.
+func TestBulkReplaySkipsEvents(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0},
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+
+	p.StartBulkReplay(BulkReplayOptions{SkipEvents: true})
+	p.emitEvent(PeerUnquarantinedEvent{Replica: 1})
+	if len(events) != 0 {
+		t.Fatalf("expected events suppressed during replay, found %v", events)
+	}
+	p.FinishBulkReplay()
+	p.emitEvent(PeerUnquarantinedEvent{Replica: 1})
+	if len(events) != 1 {
+		t.Fatalf("expected events delivered after replay, found %v", events)
+	}
+}
@@ -0,0 +1,57 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Command execution results.
+//
+// An ApplyFunc returns only an error, so a replica that embeds its state
+// machine behind Apply has no way to hand the machine's answer — the
+// value a read returned, whether a compare-and-swap won — back to the
+// client that proposed the command. ApplyWithResult is an Apply variant
+// that returns each command's result, and the proposing replica routes
+// the result to the handler registered under the command's
+// client-supplied ID.
+
+// ApplyResultFunc applies an executed command to the application state
+// machine and returns the command's result. A non-nil error invokes the
+// configured ApplyErrorPolicy, exactly as with ApplyFunc.
+type ApplyResultFunc func(pb.Command) (interface{}, error)
+
+// ResultFunc receives the state machine's result for a command the
+// local replica proposed. Under ApplyErrorSkip a skipped command's error
+// is delivered instead of a result, so that the client is not left
+// waiting on a command that will never apply.
+type ResultFunc func(result interface{}, err error)
+
+// registerResultHandler records the handler to invoke when the command
+// with the provided ID applies locally. Commands without an ID cannot be
+// tracked.
+func (p *epaxos) registerResultHandler(id uint64, f ResultFunc) {
+	if id == 0 || f == nil {
+		return
+	}
+	if p.resultHandlers == nil {
+		p.resultHandlers = make(map[uint64]ResultFunc)
+	}
+	p.resultHandlers[id] = f
+}
+
+// deliverResult hands the command's apply outcome to the handler
+// registered under its ID, if any.
+func (p *epaxos) deliverResult(cmd pb.Command, result interface{}, err error) {
+	if f, ok := p.resultHandlers[cmd.ID]; ok {
+		delete(p.resultHandlers, cmd.ID)
+		f(result, err)
+	}
+}
+
+// applyToStateMachine invokes whichever of Apply and ApplyWithResult is
+// configured.
+func (p *epaxos) applyToStateMachine(cmd pb.Command) (interface{}, error) {
+	if p.applyResult != nil {
+		return p.applyResult(cmd)
+	}
+	return nil, p.apply(cmd)
+}
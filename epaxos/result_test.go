@@ -0,0 +1,113 @@
+package epaxos
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestApplyWithResult verifies that the result returned by the
+// ApplyWithResult function is routed to the handler registered under the
+// command's ID.
+func TestApplyWithResult(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0},
+		ApplyWithResult: func(cmd pb.Command) (interface{}, error) {
+			return cmd.Data, nil
+		},
+	})
+
+	var results []interface{}
+	cmd := newTestingCommand("a", "z")
+	cmd.ID = 7
+	cmd.Data = []byte("value")
+	p.registerResultHandler(cmd.ID, func(result interface{}, err error) {
+		if err != nil {
+			t.Errorf("unexpected result error: %v", err)
+		}
+		results = append(results, result)
+	})
+
+	p.Request(cmd)
+	if len(results) != 1 || !bytes.Equal(results[0].([]byte), cmd.Data) {
+		t.Fatalf("expected the command's data as its result, found %v", results)
+	}
+
+	// The handler fires once; a later command reusing the ID is not
+	// routed to it.
+	cmd2 := newTestingCommand("a", "z")
+	cmd2.ID = 7
+	p.Request(cmd2)
+	if len(results) != 1 {
+		t.Errorf("expected handler to fire once, found %v", results)
+	}
+}
+
+// TestApplyWithResultSkipError verifies that under ApplyErrorSkip a
+// skipped command's error is delivered to its handler, so the client is
+// not left waiting on a command that will never apply.
+func TestApplyWithResultSkipError(t *testing.T) {
+	applyErr := errors.New("no such key")
+	p := newEPaxos(&Config{
+		ID:               0,
+		Nodes:            []pb.ReplicaID{0},
+		ApplyErrorPolicy: ApplyErrorSkip,
+		ApplyWithResult: func(cmd pb.Command) (interface{}, error) {
+			return nil, applyErr
+		},
+	})
+
+	var errs []error
+	cmd := newTestingCommand("a", "z")
+	cmd.ID = 3
+	p.registerResultHandler(cmd.ID, func(result interface{}, err error) {
+		errs = append(errs, err)
+	})
+
+	p.Request(cmd)
+	if len(errs) != 1 || errs[0] != applyErr {
+		t.Fatalf("expected the apply error to be delivered, found %v", errs)
+	}
+}
+
+// TestNodeProposeWithResult verifies the result round trip through the
+// Node interface.
+func TestNodeProposeWithResult(t *testing.T) {
+	n := StartNode(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0},
+		ApplyWithResult: func(cmd pb.Command) (interface{}, error) {
+			return cmd.Data, nil
+		},
+	})
+	defer n.Stop()
+
+	cmd := newTestingCommand("a", "z")
+	cmd.Data = []byte("value")
+	resc := make(chan interface{}, 1)
+	if err := n.ProposeWithResult(context.Background(), *cmd,
+		func(result interface{}, err error) {
+			if err != nil {
+				t.Errorf("unexpected result error: %v", err)
+			}
+			resc <- result
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-resc:
+		if !bytes.Equal(result.([]byte), cmd.Data) {
+			t.Fatalf("expected the command's data as its result, found %v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the command's result")
+	}
+}
@@ -0,0 +1,124 @@
+package epaxos
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestScheduleExport verifies that executed instances are recorded in
+// execution order, that a range of the log exports as the documented
+// line format, and that the export's signature authenticates.
+func TestScheduleExport(t *testing.T) {
+	key := []byte("audit-key")
+	p := newEPaxos(&Config{
+		ID:                 0,
+		Nodes:              []pb.ReplicaID{0},
+		ScheduleLogSize:    16,
+		ScheduleSigningKey: key,
+	})
+
+	// A single-replica cluster executes each proposal immediately.
+	for i := 0; i < 3; i++ {
+		p.Request(newTestingCommand("a", "z"))
+	}
+	if len(p.scheduleLog) != 3 {
+		t.Fatalf("expected 3 schedule entries, found %+v", p.scheduleLog)
+	}
+
+	data, err := p.ExportSchedule(2, 3)
+	if err != nil {
+		t.Fatalf("unexpected error from ExportSchedule: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header, 2 entries and a signature, found %q", lines)
+	}
+	if lines[0] != "epaxos-schedule v1" || lines[1] != "replica 0" {
+		t.Errorf("unexpected header %q", lines[:2])
+	}
+	for i, pos := range []uint64{2, 3} {
+		exp := fmt.Sprintf("%d 0.%d scc=%d seq=%d tie=none", pos, pos, pos, pos)
+		if lines[2+i] != exp {
+			t.Errorf("expected entry %q, found %q", exp, lines[2+i])
+		}
+	}
+
+	if !VerifyScheduleExport(data, key) {
+		t.Errorf("expected the export to authenticate with the signing key")
+	}
+	if VerifyScheduleExport(data, []byte("wrong-key")) {
+		t.Errorf("expected the export to fail with the wrong key")
+	}
+	data[len(data)-len("signature ")-64] ^= 0xff
+	if VerifyScheduleExport(data, key) {
+		t.Errorf("expected a tampered export to fail authentication")
+	}
+
+	if _, err := p.ExportSchedule(10, 20); err == nil {
+		t.Errorf("expected an error exporting an unretained range")
+	}
+}
+
+// TestScheduleTieBreakReasons verifies the rule recorded for each
+// position of a multi-member strongly connected component.
+func TestScheduleTieBreakReasons(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:              0,
+		Nodes:           []pb.ReplicaID{0, 1, 2},
+		ScheduleLogSize: 16,
+	})
+	mkInst := func(r pb.ReplicaID, i pb.InstanceNum, seq pb.SeqNum) *instance {
+		inst := p.newInstance(r, i)
+		inst.is.SeqNum = seq
+		return inst
+	}
+
+	p.recordExecutionSchedule([]executable{
+		mkInst(0, 1, 1),
+		mkInst(1, 1, 2),
+		mkInst(2, 1, 2),
+	})
+	p.recordExecutionSchedule([]executable{mkInst(0, 2, 3)})
+
+	expected := []struct {
+		scc uint64
+		tie string
+	}{
+		{1, tieFirst},
+		{1, tieSeq},
+		{1, tieReplica},
+		{2, tieNone},
+	}
+	if len(p.scheduleLog) != len(expected) {
+		t.Fatalf("expected %d schedule entries, found %+v", len(expected), p.scheduleLog)
+	}
+	for i, exp := range expected {
+		e := p.scheduleLog[i]
+		if e.pos != uint64(i+1) || e.scc != exp.scc || e.tie != exp.tie {
+			t.Errorf("entry %d: expected pos=%d scc=%d tie=%s, found %+v",
+				i, i+1, exp.scc, exp.tie, e)
+		}
+	}
+}
+
+// TestScheduleLogTrimming verifies that the log retains only the most
+// recent ScheduleLogSize entries.
+func TestScheduleLogTrimming(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:              0,
+		Nodes:           []pb.ReplicaID{0},
+		ScheduleLogSize: 2,
+	})
+	for i := 0; i < 4; i++ {
+		p.Request(newTestingCommand("a", "z"))
+	}
+	if len(p.scheduleLog) != 2 {
+		t.Fatalf("expected the log trimmed to 2 entries, found %+v", p.scheduleLog)
+	}
+	if p.scheduleLog[0].pos != 3 || p.scheduleLog[1].pos != 4 {
+		t.Errorf("expected the most recent entries retained, found %+v", p.scheduleLog)
+	}
+}
@@ -0,0 +1,170 @@
+package epaxos
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Instance-space sharding.
+//
+// A Node runs one event loop, so a single consensus group is bounded by
+// what one core can order. Commands only interfere when their spans
+// overlap, which makes the keyspace cheap to partition: a ShardedNode
+// runs one independent Node per key range, each with its own event
+// loop, instance space, and message flow, and routes every proposal to
+// the shard owning its span. Shards never coordinate with each other —
+// a command whose span crosses a shard boundary is rejected rather than
+// ordered across groups — so the partitioning must match the
+// application's access pattern.
+//
+// The routing layer stays thin on the wire: messages are not tagged,
+// and the transport is instead expected to keep one channel per shard,
+// stepping inbound messages into the matching shard via Step and
+// draining each shard's Ready through Shard.
+
+// ErrSpanCrossesShard is returned for a proposal whose span crosses a
+// shard boundary.
+var ErrSpanCrossesShard = errors.New("epaxos: command span crosses a shard boundary")
+
+// ShardedNode fronts a set of independent Nodes, one per key range.
+type ShardedNode struct {
+	// splits holds the sorted shard boundaries: shard i owns the keys
+	// from splits[i-1] (inclusive) to splits[i] (exclusive).
+	splits []pb.Key
+	shards []Node
+}
+
+// StartShardedNode starts len(splits)+1 independent Nodes sharing the
+// provided configuration, each owning one of the key ranges delimited
+// by the sorted split keys. Every replica in the cluster must be
+// started with the same splits.
+//
+// c.Storage must be nil: instances are persisted keyed only by
+// (ReplicaID, InstanceNum), so shards sharing a store would clobber
+// each other's state and cross-load it on restart. Each shard runs on
+// its own in-memory storage instead.
+func StartShardedNode(c *Config, splits []pb.Key) (*ShardedNode, error) {
+	if c.Storage != nil {
+		return nil, errors.Errorf("sharded nodes cannot share a Storage; leave it nil")
+	}
+	for i, s := range splits {
+		if len(s) == 0 {
+			return nil, errors.Errorf("split keys must not be empty")
+		}
+		if i > 0 && bytes.Compare(splits[i-1], s) >= 0 {
+			return nil, errors.Errorf("split keys must be sorted and distinct")
+		}
+	}
+	sn := &ShardedNode{
+		splits: splits,
+		shards: make([]Node, len(splits)+1),
+	}
+	for i := range sn.shards {
+		sc := *c
+		sn.shards[i] = StartNode(&sc)
+	}
+	return sn, nil
+}
+
+// NumShards returns the number of shards.
+func (sn *ShardedNode) NumShards() int {
+	return len(sn.shards)
+}
+
+// Shard returns the Node backing the given shard, for wiring its
+// message transport and draining its Ready.
+func (sn *ShardedNode) Shard(i int) Node {
+	return sn.shards[i]
+}
+
+// ShardFor returns the shard owning the command's span, or
+// ErrSpanCrossesShard if the span crosses a shard boundary.
+func (sn *ShardedNode) ShardFor(cmd pb.Command) (int, error) {
+	i := sort.Search(len(sn.splits), func(i int) bool {
+		return bytes.Compare(cmd.Span.Key, sn.splits[i]) < 0
+	})
+	if i < len(sn.splits) {
+		end := cmd.Span.EndKey
+		if len(end) == 0 {
+			end = append(append(pb.Key(nil), cmd.Span.Key...), 0)
+		}
+		if bytes.Compare(end, sn.splits[i]) > 0 {
+			return 0, ErrSpanCrossesShard
+		}
+	}
+	return i, nil
+}
+
+// Tick ticks every shard.
+func (sn *ShardedNode) Tick() {
+	for _, s := range sn.shards {
+		s.Tick()
+	}
+}
+
+// TickN advances every shard's logical clock by n ticks.
+func (sn *ShardedNode) TickN(n int) {
+	for _, s := range sn.shards {
+		s.TickN(n)
+	}
+}
+
+// Propose routes the command to the shard owning its span. Membership
+// changes affect every shard's group and are proposed to all of them.
+func (sn *ShardedNode) Propose(ctx context.Context, command pb.Command) error {
+	if command.ConfChange {
+		for _, s := range sn.shards {
+			if err := s.Propose(ctx, command); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	i, err := sn.ShardFor(command)
+	if err != nil {
+		return err
+	}
+	return sn.shards[i].Propose(ctx, command)
+}
+
+// ProposeWithResult routes the command like Propose, registering the
+// result handler on the owning shard. Membership changes have no single
+// owning shard and are not supported.
+func (sn *ShardedNode) ProposeWithResult(ctx context.Context, command pb.Command, onResult ResultFunc) error {
+	if command.ConfChange {
+		return errors.Errorf("membership changes cannot carry a result handler on a sharded node")
+	}
+	i, err := sn.ShardFor(command)
+	if err != nil {
+		return err
+	}
+	return sn.shards[i].ProposeWithResult(ctx, command, onResult)
+}
+
+// Step advances the given shard using the message. The transport is
+// responsible for delivering each message to the shard it originated
+// from on the sending replica.
+func (sn *ShardedNode) Step(ctx context.Context, shard int, msg pb.Message) error {
+	return sn.shards[shard].Step(ctx, msg)
+}
+
+// ProbeConflicts probes the shard owning the command's span.
+func (sn *ShardedNode) ProbeConflicts(ctx context.Context, command pb.Command) ([]ConflictInfo, error) {
+	i, err := sn.ShardFor(command)
+	if err != nil {
+		return nil, err
+	}
+	return sn.shards[i].ProbeConflicts(ctx, command)
+}
+
+// Stop stops every shard.
+func (sn *ShardedNode) Stop() {
+	for _, s := range sn.shards {
+		s.Stop()
+	}
+}
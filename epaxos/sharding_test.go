@@ -0,0 +1,110 @@
+package epaxos
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestShardedNodeRejectsSharedStorage verifies that a sharded node
+// refuses a shared Storage, which shards would corrupt by persisting
+// colliding (ReplicaID, InstanceNum) keys into it.
+func TestShardedNodeRejectsSharedStorage(t *testing.T) {
+	nodes := []pb.ReplicaID{0}
+	c := &Config{ID: 0, Nodes: nodes, Storage: NewMemoryStorage(&Config{ID: 0, Nodes: nodes})}
+	if _, err := StartShardedNode(c, []pb.Key{pb.Key("g")}); err == nil {
+		t.Fatalf("expected an error starting a sharded node with a shared Storage")
+	}
+}
+
+// TestShardForRouting verifies that spans route to the shard owning
+// them and that boundary-crossing spans are rejected.
+func TestShardForRouting(t *testing.T) {
+	sn, err := StartShardedNode(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0},
+	}, []pb.Key{pb.Key("g"), pb.Key("q")})
+	if err != nil {
+		t.Fatalf("unexpected error starting sharded node: %v", err)
+	}
+	defer sn.Stop()
+
+	if n := sn.NumShards(); n != 3 {
+		t.Fatalf("expected 3 shards, found %d", n)
+	}
+	testCases := []struct {
+		start, end string
+		shard      int
+		err        error
+	}{
+		{"a", "c", 0, nil},
+		{"a", "g", 0, nil},
+		{"g", "k", 1, nil},
+		{"q", "", 2, nil},
+		{"z", "zz", 2, nil},
+		{"a", "h", 0, ErrSpanCrossesShard},
+		{"p", "r", 1, ErrSpanCrossesShard},
+	}
+	for _, tc := range testCases {
+		cmd := pb.Command{Span: pb.Span{Key: pb.Key(tc.start), EndKey: pb.Key(tc.end)}}
+		shard, err := sn.ShardFor(cmd)
+		if err != tc.err {
+			t.Errorf("[%s, %s): expected error %v, found %v", tc.start, tc.end, tc.err, err)
+		} else if err == nil && shard != tc.shard {
+			t.Errorf("[%s, %s): expected shard %d, found %d", tc.start, tc.end, tc.shard, shard)
+		}
+	}
+}
+
+// TestShardedNodePropose verifies that proposals land on independent
+// shards and apply there.
+func TestShardedNodePropose(t *testing.T) {
+	var mu sync.Mutex
+	applied := make(map[uint64]struct{})
+	sn, err := StartShardedNode(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0},
+		Apply: func(cmd pb.Command) error {
+			mu.Lock()
+			applied[cmd.ID] = struct{}{}
+			mu.Unlock()
+			return nil
+		},
+	}, []pb.Key{pb.Key("m")})
+	if err != nil {
+		t.Fatalf("unexpected error starting sharded node: %v", err)
+	}
+	defer sn.Stop()
+
+	ctx := context.Background()
+	cmd1 := *newTestingCommand("a", "c")
+	cmd2 := *newTestingCommand("n", "p")
+	if err := sn.Propose(ctx, cmd1); err != nil {
+		t.Fatalf("unexpected error from Propose: %v", err)
+	}
+	if err := sn.Propose(ctx, cmd2); err != nil {
+		t.Fatalf("unexpected error from Propose: %v", err)
+	}
+	if err := sn.Propose(ctx, *newTestingCommand("a", "z")); err != ErrSpanCrossesShard {
+		t.Fatalf("expected ErrSpanCrossesShard, found %v", err)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		mu.Lock()
+		_, ok1 := applied[cmd1.ID]
+		_, ok2 := applied[cmd2.ID]
+		mu.Unlock()
+		if ok1 && ok2 {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("commands not applied before timeout; applied %v", applied)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package epaxos
+
+import (
+	"time"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// slowStorageTripCount is the number of consecutive slow writes after
+// which load shedding engages, when enabled.
+const slowStorageTripCount = 3
+
+// SlowStorageEvent is emitted when a storage write exceeds the
+// configured latency threshold.
+type SlowStorageEvent struct {
+	// Latency is the duration of the slow write.
+	Latency time.Duration
+}
+
+func (SlowStorageEvent) event() {}
+
+// StorageRecoveredEvent is emitted when storage writes fall back under
+// the latency threshold after load shedding had engaged.
+type StorageRecoveredEvent struct{}
+
+func (StorageRecoveredEvent) event() {}
+
+// slowStorageState tracks storage write latency against the configured
+// threshold.
+type slowStorageState struct {
+	// threshold is the latency above which a write counts as slow; zero
+	// disables detection.
+	threshold time.Duration
+	// shedEnabled is whether load shedding may engage.
+	shedEnabled bool
+	// consecutiveSlow counts consecutive slow writes.
+	consecutiveSlow int
+	// shedding is whether proposals are currently being rejected.
+	shedding bool
+}
+
+// persistInstance writes the instance state to storage, measuring the
+// write's latency. Slow writes are surfaced through SlowStorageEvents
+// before they grow long enough to fire protocol timers spuriously, and
+// can optionally shed proposal load until storage recovers.
+func (p *epaxos) persistInstance(is *pb.InstanceState) {
+	if p.slowStorage.threshold == 0 {
+		p.storage.PersistInstance(is)
+		return
+	}
+	start := time.Now()
+	p.storage.PersistInstance(is)
+	p.observeStorageLatency(time.Since(start))
+}
+
+// observeStorageLatency updates slow-storage tracking with the latency
+// of a completed write.
+func (p *epaxos) observeStorageLatency(elapsed time.Duration) {
+	ss := &p.slowStorage
+	if elapsed <= ss.threshold {
+		ss.consecutiveSlow = 0
+		if ss.shedding {
+			ss.shedding = false
+			p.storageLogger.Infof("storage recovered; accepting proposals again")
+			p.emitEvent(StorageRecoveredEvent{})
+		}
+		return
+	}
+	ss.consecutiveSlow++
+	p.storageLogger.Warningf("slow storage write: %v", elapsed)
+	p.emitEvent(SlowStorageEvent{Latency: elapsed})
+	if ss.shedEnabled && !ss.shedding && ss.consecutiveSlow >= slowStorageTripCount {
+		ss.shedding = true
+		p.storageLogger.Errorf("storage persistently slow; shedding proposal load")
+	}
+}
+
+// sheddingLoad returns whether proposals are currently being rejected
+// because of slow storage.
+func (p *epaxos) sheddingLoad() bool {
+	return p.slowStorage.shedding
+}
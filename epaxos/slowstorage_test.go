@@ -0,0 +1,57 @@
+package epaxos
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestSlowStorageDetection verifies that slow writes emit events, that
+// persistent slowness sheds proposal load, and that a fast write
+// restores normal operation.
+func TestSlowStorageDetection(t *testing.T) {
+	var events []Event
+	p := newEPaxos(&Config{
+		ID:                    0,
+		Nodes:                 []pb.ReplicaID{0, 1, 2},
+		SlowStorageThreshold:  time.Second,
+		ShedLoadOnSlowStorage: true,
+		OnEvent:               func(e Event) { events = append(events, e) },
+	})
+
+	// Writes under the threshold are uneventful.
+	p.observeStorageLatency(time.Millisecond)
+	if len(events) != 0 {
+		t.Fatalf("expected no events for a fast write, found %+v", events)
+	}
+
+	// Each slow write emits an event; persistent slowness sheds load.
+	for i := 0; i < slowStorageTripCount; i++ {
+		p.observeStorageLatency(2 * time.Second)
+	}
+	if len(events) != slowStorageTripCount {
+		t.Fatalf("expected %d events, found %+v", slowStorageTripCount, events)
+	}
+	if e, ok := events[0].(SlowStorageEvent); !ok || e.Latency != 2*time.Second {
+		t.Fatalf("expected SlowStorageEvent with latency, found %+v", events[0])
+	}
+	if !p.sheddingLoad() {
+		t.Fatalf("expected load shedding after %d slow writes", slowStorageTripCount)
+	}
+
+	// Proposals are rejected while shedding.
+	p.Request(newTestingCommand("a", "z"))
+	if p.commands[0].Len() != 0 {
+		t.Errorf("expected proposal to be rejected while shedding")
+	}
+
+	// A fast write restores normal operation.
+	p.observeStorageLatency(time.Millisecond)
+	if p.sheddingLoad() {
+		t.Fatalf("expected shedding to end after a fast write")
+	}
+	if e, ok := events[len(events)-1].(StorageRecoveredEvent); !ok {
+		t.Fatalf("expected StorageRecoveredEvent, found %+v", e)
+	}
+}
@@ -0,0 +1,181 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// State-machine snapshots.
+//
+// A replica that has fallen far behind — or a freshly provisioned one —
+// can be caught up by shipping it a snapshot of the application state
+// machine instead of replaying the entire instance log. The snapshot is
+// opaque to the protocol: the application produces and consumes it
+// through the Snapshotter interface, and the protocol contributes only
+// the executed watermark the snapshot covers, so that the receiver can
+// discard everything below it and resume normal catch-up from there.
+//
+// Snapshots are streamed in chunks. Chunks are assembled per sender and
+// applied when the final chunk arrives; a gap in the chunk sequence
+// discards the partial snapshot, leaving retransmission to the sender.
+
+// snapshotChunkSize is the maximum snapshot payload carried by a single
+// InstallSnapshot message.
+const snapshotChunkSize = 64 << 10
+
+// Snapshotter produces and consumes snapshots of the application state
+// machine, allowing a lagging or new replica to catch up without
+// replaying the entire instance log.
+type Snapshotter interface {
+	// Snapshot returns an opaque serialization of the state machine,
+	// capturing the effect of every command executed so far.
+	Snapshot() ([]byte, error)
+	// ApplySnapshot replaces the state machine's contents with the
+	// provided snapshot.
+	ApplySnapshot(data []byte) error
+}
+
+// incomingSnapshot accumulates the chunks of a snapshot being received.
+type incomingSnapshot struct {
+	id        uint64
+	nextChunk uint64
+	data      []byte
+}
+
+// SendSnapshot streams a snapshot of the local state machine to the
+// provided replica in InstallSnapshot chunks. The snapshot covers the
+// local executed watermark, which the final chunk carries so that the
+// receiver can discard its instance space below it.
+func (p *epaxos) SendSnapshot(to pb.ReplicaID) {
+	if p.snapshotter == nil {
+		p.logger.Warningf("ignoring SendSnapshot without a Snapshotter")
+		return
+	}
+	if to == p.id || !p.knownReplica(to) {
+		p.logger.Warningf("ignoring SendSnapshot to invalid replica %d", to)
+		return
+	}
+	cp, err := p.Checkpoint()
+	if err != nil {
+		p.logger.Warningf("snapshot creation failed: %v", err)
+		return
+	}
+	data := cp.Data
+	id := p.rand.Uint64()
+	var chunk uint64
+	for {
+		last := len(data) <= snapshotChunkSize
+		size := len(data)
+		if !last {
+			size = snapshotChunkSize
+		}
+		snap := &pb.InstallSnapshot{
+			ID:    id,
+			Chunk: chunk,
+			Last:  last,
+			Data:  data[:size],
+		}
+		if last {
+			snap.Executed = cp.Executed
+		}
+		p.msgs = append(p.msgs, pb.Message{
+			To:   to,
+			From: p.id,
+			Type: pb.WrapMessageInner(snap),
+		})
+		if last {
+			return
+		}
+		data = data[size:]
+		chunk++
+	}
+}
+
+// onInstallSnapshot handles one chunk of a snapshot streamed by a peer,
+// assembling the chunks and installing the snapshot when the final one
+// arrives.
+func (p *epaxos) onInstallSnapshot(m pb.Message, snap *pb.InstallSnapshot) {
+	if p.snapshotter == nil {
+		p.logger.Warningf("dropping snapshot chunk without a Snapshotter: %+v", m)
+		return
+	}
+	in := p.snapIncoming[m.From]
+	if snap.Chunk == 0 {
+		in = &incomingSnapshot{id: snap.ID}
+		if p.snapIncoming == nil {
+			p.snapIncoming = make(map[pb.ReplicaID]*incomingSnapshot)
+		}
+		p.snapIncoming[m.From] = in
+	} else if in == nil || in.id != snap.ID || in.nextChunk != snap.Chunk {
+		// A gap in the chunk sequence; discard the partial snapshot and
+		// leave retransmission to the sender.
+		p.logger.Warningf("dropping out-of-sequence snapshot chunk from %d: %+v", m.From, snap)
+		delete(p.snapIncoming, m.From)
+		return
+	}
+	in.data = append(in.data, snap.Data...)
+	in.nextChunk++
+	if !snap.Last {
+		return
+	}
+	delete(p.snapIncoming, m.From)
+	p.installSnapshot(m.From, in.data, snap.Executed)
+}
+
+// installSnapshot installs a fully assembled snapshot, replacing the
+// state machine's contents and discarding the instance space below the
+// watermark the snapshot covers. Instances above the watermark are
+// untouched; the replica catches up on them through the normal protocol.
+func (p *epaxos) installSnapshot(from pb.ReplicaID, data []byte, executed []pb.InstanceID) {
+	wm := make(map[pb.ReplicaID]pb.InstanceNum, len(executed))
+	for _, id := range executed {
+		wm[id.ReplicaID] = id.InstanceNum
+	}
+	// The snapshot replaces the state machine wholesale, so it must not
+	// regress any replica's executed prefix, and installing it is only
+	// worthwhile if it advances at least one.
+	advances := false
+	for _, r := range p.nodes {
+		if wm[r] < p.executedPrefix[r] {
+			p.logger.Warningf("dropping snapshot from %d regressing replica %d to %d", from, r, wm[r])
+			return
+		}
+		if wm[r] > p.executedPrefix[r] {
+			advances = true
+		}
+	}
+	if !advances {
+		p.logger.Debugf("ignoring snapshot from %d that advances no replica", from)
+		return
+	}
+	if err := p.snapshotter.ApplySnapshot(data); err != nil {
+		p.logger.Warningf("snapshot installation failed: %v", err)
+		return
+	}
+	// Everything below the snapshot's watermark is superseded: drop it
+	// from the instance space and from the executor, and record the
+	// truncation so that stale messages cannot resurrect it.
+	p.executor.removeIf(func(id executableID) bool {
+		iid, ok := id.(pb.InstanceID)
+		return ok && iid.InstanceNum <= wm[iid.ReplicaID]
+	})
+	backlog := p.execBacklog[:0]
+	for _, inst := range p.execBacklog {
+		if inst.is.InstanceNum > wm[inst.is.ReplicaID] {
+			backlog = append(backlog, inst)
+		}
+	}
+	p.execBacklog = backlog
+	for _, r := range p.nodes {
+		if wm[r] > p.executedPrefix[r] {
+			p.executedPrefix[r] = wm[r]
+		}
+		if wm[r] > p.maxTruncated[r] {
+			p.truncateSpace(r, wm[r])
+		}
+	}
+	p.persistTruncation()
+	p.storageLogger.Infof("installed snapshot from replica %d covering %v", from, wm)
+	if p.onExecutedWatermark != nil {
+		p.onExecutedWatermark(p.ExecutedWatermark())
+	}
+}
@@ -0,0 +1,102 @@
+package epaxos
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// testSnapshotter is a Snapshotter backed by an in-memory blob.
+type testSnapshotter struct {
+	data    []byte
+	applied [][]byte
+}
+
+func (s *testSnapshotter) Snapshot() ([]byte, error) { return s.data, nil }
+
+func (s *testSnapshotter) ApplySnapshot(d []byte) error {
+	s.applied = append(s.applied, d)
+	return nil
+}
+
+// TestSnapshotChunkedTransfer verifies that a snapshot is streamed in
+// chunks, reassembled and applied by the receiver, and that the receiver
+// discards its instance space below the snapshot's watermark.
+func TestSnapshotChunkedTransfer(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1}
+	blob := make([]byte, 2*snapshotChunkSize+100)
+	for i := range blob {
+		blob[i] = byte(i)
+	}
+	sender := newEPaxos(&Config{ID: 0, Nodes: nodes, Snapshotter: &testSnapshotter{data: blob}})
+	sender.executedPrefix[0] = 3
+	sender.SendSnapshot(1)
+	if len(sender.msgs) != 3 {
+		t.Fatalf("expected 3 snapshot chunks, found %v", sender.msgs)
+	}
+
+	recvSnap := &testSnapshotter{}
+	receiver := newEPaxos(&Config{ID: 1, Nodes: nodes, Snapshotter: recvSnap})
+	for _, m := range sender.msgs {
+		receiver.Step(m)
+	}
+	if len(recvSnap.applied) != 1 || !bytes.Equal(recvSnap.applied[0], blob) {
+		t.Fatalf("expected reassembled snapshot applied once, found %d applications",
+			len(recvSnap.applied))
+	}
+	if receiver.executedPrefix[0] != 3 {
+		t.Errorf("expected watermark advanced to 3, found %d", receiver.executedPrefix[0])
+	}
+	if !receiver.hasTruncated(0, 3) {
+		t.Errorf("expected instance space truncated below the snapshot watermark")
+	}
+}
+
+// TestSnapshotOutOfOrderChunksDropped verifies that a gap in the chunk
+// sequence discards the partial snapshot, and that a clean resend then
+// succeeds.
+func TestSnapshotOutOfOrderChunksDropped(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1}
+	sender := newEPaxos(&Config{ID: 0, Nodes: nodes,
+		Snapshotter: &testSnapshotter{data: make([]byte, 2*snapshotChunkSize+100)}})
+	sender.executedPrefix[0] = 1
+	sender.SendSnapshot(1)
+
+	recvSnap := &testSnapshotter{}
+	receiver := newEPaxos(&Config{ID: 1, Nodes: nodes, Snapshotter: recvSnap})
+	receiver.Step(sender.msgs[0])
+	receiver.Step(sender.msgs[2])
+	if len(recvSnap.applied) != 0 {
+		t.Fatalf("expected partial snapshot dropped, found %d applications", len(recvSnap.applied))
+	}
+
+	for _, m := range sender.msgs {
+		receiver.Step(m)
+	}
+	if len(recvSnap.applied) != 1 {
+		t.Fatalf("expected resent snapshot applied, found %d applications", len(recvSnap.applied))
+	}
+}
+
+// TestSnapshotCannotRegress verifies that a snapshot covering less than
+// the receiver has already executed is not installed.
+func TestSnapshotCannotRegress(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1}
+	sender := newEPaxos(&Config{ID: 0, Nodes: nodes, Snapshotter: &testSnapshotter{data: []byte("old")}})
+	sender.executedPrefix[0] = 2
+	sender.SendSnapshot(1)
+
+	recvSnap := &testSnapshotter{}
+	receiver := newEPaxos(&Config{ID: 1, Nodes: nodes, Snapshotter: recvSnap})
+	receiver.executedPrefix[0] = 5
+	for _, m := range sender.msgs {
+		receiver.Step(m)
+	}
+	if len(recvSnap.applied) != 0 {
+		t.Fatalf("expected regressing snapshot dropped, found %d applications", len(recvSnap.applied))
+	}
+	if receiver.executedPrefix[0] != 5 {
+		t.Errorf("expected watermark unchanged at 5, found %d", receiver.executedPrefix[0])
+	}
+}
@@ -0,0 +1,147 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+func newSpeculatingLeader(events *[]Event) *epaxos {
+	return newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0, 1, 2, 3, 4},
+		OnEvent: func(e Event) { *events = append(*events, e) },
+	})
+}
+
+func countSpeculationEvents(events []Event) (replies int, resolved []SpeculativeResolvedEvent) {
+	for _, e := range events {
+		switch e := e.(type) {
+		case SpeculativeReplyEvent:
+			replies++
+		case SpeculativeResolvedEvent:
+			resolved = append(resolved, e)
+		}
+	}
+	return replies, resolved
+}
+
+// TestSpeculativeReplyConfirmedOnCommit verifies that a proposal marked
+// Speculative triggers a SpeculativeReplyEvent once a PreAccept quorum
+// agrees with its attributes, before the instance commits, and that the
+// speculation is confirmed when the instance then commits on the fast
+// path.
+func TestSpeculativeReplyConfirmedOnCommit(t *testing.T) {
+	var events []Event
+	p := newSpeculatingLeader(&events)
+
+	cmd := newTestingCommand("a", "z")
+	cmd.Speculative = true
+	newInst := p.onRequest(cmd)
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	p.clearMsgs()
+
+	okFrom := func(from pb.ReplicaID) pb.Message {
+		return pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+		}
+	}
+
+	// One reply plus the leader is short of a quorum; no speculation yet.
+	p.Step(okFrom(1))
+	if replies, _ := countSpeculationEvents(events); replies != 0 {
+		t.Fatalf("unexpected speculative reply before quorum: %v", events)
+	}
+
+	// A second reply reaches a quorum with unchanged attributes. The
+	// speculative reply fires while the instance is still uncommitted.
+	p.Step(okFrom(2))
+	newInst.assertState(pb.InstanceState_PreAccepted)
+	replies, resolved := countSpeculationEvents(events)
+	if replies != 1 {
+		t.Fatalf("expected one speculative reply at quorum, found %v", events)
+	}
+	if len(resolved) != 0 {
+		t.Fatalf("unexpected speculation resolution before commit: %v", events)
+	}
+
+	// A third reply completes the fast quorum and commits the instance,
+	// confirming the speculation.
+	p.Step(okFrom(3))
+	newInst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+	replies, resolved = countSpeculationEvents(events)
+	if replies != 1 {
+		t.Fatalf("expected exactly one speculative reply, found %v", events)
+	}
+	if len(resolved) != 1 || !resolved[0].Confirmed {
+		t.Fatalf("expected confirmed speculation resolution, found %v", events)
+	}
+	if resolved[0].Command.ID != cmd.ID {
+		t.Fatalf("expected resolution for command %d, found %+v", cmd.ID, resolved[0])
+	}
+}
+
+// TestSpeculativeReplyAbortedOnChangedAttributes verifies that when a
+// later PreAccept reply changes the attributes a proposal was
+// speculatively acknowledged with, the commit-time resolution reports
+// the speculation as aborted.
+func TestSpeculativeReplyAbortedOnChangedAttributes(t *testing.T) {
+	var events []Event
+	p := newSpeculatingLeader(&events)
+
+	cmd := newTestingCommand("a", "z")
+	cmd.Speculative = true
+	newInst := p.onRequest(cmd)
+	p.clearMsgs()
+
+	// Two identical replies reach a quorum and trigger the speculative
+	// reply.
+	for _, from := range []pb.ReplicaID{1, 2} {
+		p.Step(pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+		})
+	}
+	if replies, _ := countSpeculationEvents(events); replies != 1 {
+		t.Fatalf("expected one speculative reply at quorum, found %v", events)
+	}
+
+	// A third reply adds a dependency the leader missed, forcing the slow
+	// path with attributes differing from the speculated ones.
+	updatedDeps := append([]pb.InstanceID(nil), newInst.is.Deps...)
+	updatedDeps = append(updatedDeps, pb.InstanceID{ReplicaID: 3, InstanceNum: 1})
+	p.Step(pb.Message{
+		To:         0,
+		From:       3,
+		InstanceID: newInst.is.InstanceID,
+		Type: pb.WrapMessageInner(&pb.PreAcceptReply{
+			UpdatedSeqNum: newInst.is.SeqNum,
+			UpdatedDeps:   updatedDeps,
+		}),
+	})
+	newInst.assertState(pb.InstanceState_Accepted)
+
+	// The Accept round commits the instance; the speculation resolves as
+	// aborted.
+	for _, from := range []pb.ReplicaID{1, 2} {
+		p.Step(pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.AcceptOK{}),
+		})
+	}
+	newInst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+	replies, resolved := countSpeculationEvents(events)
+	if replies != 1 {
+		t.Fatalf("expected exactly one speculative reply, found %v", events)
+	}
+	if len(resolved) != 1 || resolved[0].Confirmed {
+		t.Fatalf("expected aborted speculation resolution, found %v", events)
+	}
+}
@@ -0,0 +1,46 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// ServeStaleRead reports whether a read-only command may be answered
+// from this replica's locally executed state under the provided
+// staleness bound, without a round of consensus. Staleness is measured
+// in ticks and tied to executed data: peers periodically advertise the
+// instances they know committed (see committed.go), and this replica
+// records the last tick at which its executed state covered everything a
+// peer had advertised. The returned staleness s guarantees that every
+// write that some quorum of replicas, counting this one, had committed
+// and advertised s ticks ago has been executed locally and is reflected
+// in the read. It is not a linearizable bound: a write acknowledged at
+// its leader becomes visible here only once its commit propagates and a
+// later advertisement round covers it, so recently acknowledged writes
+// may be missing even within the bound. The actual staleness is returned
+// either way so that it can be reported to the client.
+func (p *epaxos) ServeStaleRead(cmd *pb.Command, maxStaleness uint64) (uint64, bool) {
+	if cmd.Writing {
+		return 0, false
+	}
+	staleness := p.ticks - p.quorumDataFreshness()
+	return staleness, staleness <= maxStaleness
+}
+
+// quorumDataFreshness returns the most recent tick at which this
+// replica's executed state was known to cover everything a quorum of
+// replicas had advertised as committed, counting this replica's own
+// executed state as covered now.
+func (p *epaxos) quorumDataFreshness() uint64 {
+	fresh := make([]uint64, 0, len(p.nodes))
+	for _, r := range p.nodes {
+		if r == p.id {
+			fresh = append(fresh, p.ticks)
+		} else {
+			fresh = append(fresh, p.dataFreshness[r])
+		}
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i] > fresh[j] })
+	return fresh[len(p.nodes)/2]
+}
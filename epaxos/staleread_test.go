@@ -0,0 +1,54 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestServeStaleRead verifies that read-only commands are served locally
+// while the replica's executed coverage of a quorum's advertised commits
+// is within the staleness bound, and rejected with the actual staleness
+// once it is not.
+func TestServeStaleRead(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	read := newTestingReadCommand("a", "z")
+
+	// A fresh node has zero staleness.
+	if staleness, ok := p.ServeStaleRead(read, 0); !ok || staleness != 0 {
+		t.Fatalf("expected fresh node to serve with staleness 0, found (%d, %t)", staleness, ok)
+	}
+
+	// Ten ticks without a covered advertisement from any peer means the
+	// executed state is only known fresh as of ten ticks ago.
+	p.ticks = 10
+	if staleness, ok := p.ServeStaleRead(read, 5); ok || staleness != 10 {
+		t.Fatalf("expected read rejected with staleness 10, found (%d, %t)", staleness, ok)
+	}
+	if staleness, ok := p.ServeStaleRead(read, 20); !ok || staleness != 10 {
+		t.Fatalf("expected read served with staleness 10, found (%d, %t)", staleness, ok)
+	}
+
+	// An advertisement from a peer that the local executed state fully
+	// covers restores freshness: the peer plus the local replica form a
+	// quorum of two out of three.
+	p.onCommittedRanges(pb.Message{From: 1}, &pb.CommittedRanges{})
+	if staleness, ok := p.ServeStaleRead(read, 0); !ok || staleness != 0 {
+		t.Fatalf("expected read served with staleness 0, found (%d, %t)", staleness, ok)
+	}
+
+	// An advertisement the local replica has not executed through does
+	// not advance freshness.
+	p.ticks = 20
+	p.onCommittedRanges(pb.Message{From: 2}, &pb.CommittedRanges{
+		Ranges: []pb.InstanceRange{{ReplicaID: 2, Start: 1, End: 5}},
+	})
+	if staleness, ok := p.ServeStaleRead(read, 20); !ok || staleness != 10 {
+		t.Fatalf("expected read served with staleness 10, found (%d, %t)", staleness, ok)
+	}
+
+	// Writes are never served this way.
+	if _, ok := p.ServeStaleRead(newTestingCommand("a", "z"), 100); ok {
+		t.Fatalf("expected write rejected from stale read path")
+	}
+}
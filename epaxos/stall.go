@@ -0,0 +1,86 @@
+package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// CommitStalledEvent is emitted on a command leader when one of its
+// proposals has remained uncommitted for Config.CommitTimeoutTicks
+// ticks. Replied and Missing partition the leader's peers by whether a
+// reply contributing to the current phase has been received, so that
+// operators can see which peer is holding the commit up.
+type CommitStalledEvent struct {
+	// InstanceID identifies the stalled instance.
+	InstanceID pb.InstanceID
+	// Status is the phase the instance is stalled in.
+	Status pb.InstanceState_Status
+	// Replied is the set of peers heard from in the current phase.
+	Replied []pb.ReplicaID
+	// Missing is the set of peers whose replies are outstanding.
+	Missing []pb.ReplicaID
+}
+
+func (CommitStalledEvent) event() {}
+
+// maybeWatchCommit arms the instance's commit timeout when the node
+// begins leading it. The timer is disarmed if the instance commits
+// before it fires.
+func (inst *instance) maybeWatchCommit() {
+	if inst.p.commitTimeout == 0 || inst.p.singleNode() {
+		return
+	}
+	inst.commitTimer = makeTickingTimer(inst.p.commitTimeout, func() {
+		inst.onCommitTimeout()
+	})
+	inst.p.registerOneTimeTimer(&inst.commitTimer)
+}
+
+// unwatchCommit disarms the instance's commit timeout, if armed.
+func (inst *instance) unwatchCommit() {
+	if inst.commitTimer.isSet() {
+		inst.p.unregisterTimer(&inst.commitTimer)
+	}
+}
+
+// onCommitTimeout surfaces the stalled proposal along with its per-peer
+// vote bitmap.
+func (inst *instance) onCommitTimeout() {
+	replied, missing := inst.voteBitmap()
+	inst.p.logger.Warningf("instance %v stalled in state %v; awaiting replies from %v",
+		inst.is.InstanceID, inst.is.Status, missing)
+	inst.p.emitEvent(CommitStalledEvent{
+		InstanceID: inst.is.InstanceID,
+		Status:     inst.is.Status,
+		Replied:    replied,
+		Missing:    missing,
+	})
+}
+
+// voteBitmap partitions the leader's peers by whether a reply for the
+// instance's current phase has been recorded from them. Reply
+// attribution is reset when the instance falls back to the slow path, so
+// the bitmap always reflects the phase the instance is stalled in.
+func (inst *instance) voteBitmap() (replied, missing []pb.ReplicaID) {
+	for _, r := range inst.p.nodes {
+		if r == inst.p.id {
+			continue
+		}
+		heard := false
+		for _, rr := range inst.replyReplicas {
+			if rr == r {
+				heard = true
+				break
+			}
+		}
+		if heard {
+			replied = append(replied, r)
+		} else {
+			missing = append(missing, r)
+		}
+	}
+	sort.Slice(replied, func(i, j int) bool { return replied[i] < replied[j] })
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return replied, missing
+}
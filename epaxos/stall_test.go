@@ -0,0 +1,81 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestCommitTimeoutReportsMissingPeers verifies that when a proposal
+// stays uncommitted past the commit timeout, the leader emits a
+// CommitStalledEvent whose vote bitmap names the peers that have not
+// replied.
+func TestCommitTimeoutReportsMissingPeers(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2, 3, 4}
+	var events []Event
+	leader := newEPaxos(&Config{
+		ID:                 0,
+		Nodes:              nodes,
+		CommitTimeoutTicks: 1,
+		OnEvent:            func(e Event) { events = append(events, e) },
+	})
+	follower := newEPaxos(&Config{ID: 1, Nodes: nodes})
+
+	// Only replica 1 ever sees the PreAccept; the other peers stay silent,
+	// leaving the leader short of a quorum.
+	leader.Request(newTestingCommand("a", "z"))
+	for _, m := range leader.ReadMessages() {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+	for _, m := range follower.ReadMessages() {
+		if m.To == leader.id {
+			leader.Step(m)
+		}
+	}
+
+	leader.Tick()
+	var stalled *CommitStalledEvent
+	for _, e := range events {
+		if se, ok := e.(CommitStalledEvent); ok {
+			stalled = &se
+		}
+	}
+	if stalled == nil {
+		t.Fatalf("expected a CommitStalledEvent, found %v", events)
+	}
+	if stalled.Status != pb.InstanceState_PreAccepted {
+		t.Fatalf("expected instance stalled in PreAccepted, found %v", stalled.Status)
+	}
+	if exp := []pb.ReplicaID{1}; !reflect.DeepEqual(stalled.Replied, exp) {
+		t.Fatalf("expected replied peers %v, found %v", exp, stalled.Replied)
+	}
+	if exp := []pb.ReplicaID{2, 3, 4}; !reflect.DeepEqual(stalled.Missing, exp) {
+		t.Fatalf("expected missing peers %v, found %v", exp, stalled.Missing)
+	}
+}
+
+// TestCommitTimeoutDisarmedOnCommit verifies that a proposal that
+// commits before the commit timeout fires never reports a stall.
+func TestCommitTimeoutDisarmedOnCommit(t *testing.T) {
+	var events []Event
+	n := newNetwork(3)
+	n.peers[0].commitTimeout = 5
+	n.peers[0].onEvent = func(e Event) { events = append(events, e) }
+
+	cmd := newTestingCommand("a", "z")
+	inst := n.peers[0].onRequest(cmd)
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never executed", inst)
+	}
+	for i := 0; i < 10; i++ {
+		n.tickAll()
+	}
+	for _, e := range events {
+		if _, ok := e.(CommitStalledEvent); ok {
+			t.Fatalf("unexpected CommitStalledEvent after commit: %v", e)
+		}
+	}
+}
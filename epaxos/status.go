@@ -0,0 +1,144 @@
+package epaxos
+
+import (
+	"sort"
+
+	"github.com/google/btree"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Status is a version-stamped, point-in-time snapshot of the node's
+// protocol progress. Successive snapshots from the same node can be
+// compared with DiffStatus, letting tests assert that after a sequence
+// of events exactly the expected state progressed. The struct serializes
+// to JSON so it can be exposed through debug endpoints; diagnosing a
+// stuck instance usually starts here — a column whose high-water mark
+// advances while its committed count does not, or a pending proposal
+// count that never drains, points at where to look next.
+type Status struct {
+	// ReplicaID is the node the snapshot was taken from.
+	ReplicaID pb.ReplicaID `json:"replica_id"`
+	// Version increments with every snapshot the node takes, ordering
+	// snapshots from the same node.
+	Version uint64 `json:"version"`
+	// Nodes is the current cluster membership.
+	Nodes []pb.ReplicaID `json:"nodes"`
+	// Epoch is the current configuration epoch.
+	Epoch uint64 `json:"epoch"`
+	// MaxInstance is the largest instance number in each replica's
+	// command space.
+	MaxInstance map[pb.ReplicaID]pb.InstanceNum `json:"max_instance"`
+	// InstanceCounts is the number of known instances in each state,
+	// keyed by state name.
+	InstanceCounts map[string]int `json:"instance_counts"`
+	// PendingProposals is the number of locally proposed commands that
+	// have not yet executed.
+	PendingProposals int `json:"pending_proposals"`
+	// ExecutedWatermark is the contiguous executed prefix of each
+	// replica's command space.
+	ExecutedWatermark map[pb.ReplicaID]pb.InstanceNum `json:"executed_watermark"`
+	// HealthyReplicas is the set of replicas considered healthy,
+	// including the node itself.
+	HealthyReplicas []pb.ReplicaID `json:"healthy_replicas"`
+	// Health is the node's health predicates.
+	Health HealthStatus `json:"health"`
+}
+
+// Status returns a snapshot of the node's current protocol progress.
+func (p *epaxos) Status() Status {
+	p.statusVersion++
+	st := Status{
+		ReplicaID:         p.id,
+		Version:           p.statusVersion,
+		Nodes:             append([]pb.ReplicaID(nil), p.nodes...),
+		Epoch:             p.epoch,
+		MaxInstance:       make(map[pb.ReplicaID]pb.InstanceNum, len(p.nodes)),
+		InstanceCounts:    make(map[string]int),
+		PendingProposals:  len(p.pendingCmds),
+		ExecutedWatermark: make(map[pb.ReplicaID]pb.InstanceNum, len(p.nodes)),
+		HealthyReplicas:   p.healthyReplicas(),
+		Health:            p.Health(),
+	}
+	for _, r := range p.nodes {
+		st.MaxInstance[r] = p.maxInstanceNum(r)
+		st.ExecutedWatermark[r] = p.executedPrefix[r]
+		p.commands[r].Ascend(func(i btree.Item) bool {
+			st.InstanceCounts[i.(*instance).is.Status.String()]++
+			return true
+		})
+	}
+	return st
+}
+
+// StatusDiff summarizes what changed between two Status snapshots taken
+// from the same node.
+type StatusDiff struct {
+	// InstancesAdvanced maps each replica whose command space grew to the
+	// number of new instances.
+	InstancesAdvanced map[pb.ReplicaID]pb.InstanceNum
+	// WatermarksAdvanced maps each replica whose executed watermark moved
+	// to the number of newly executed instances.
+	WatermarksAdvanced map[pb.ReplicaID]pb.InstanceNum
+	// PeersDegraded are the replicas that were healthy before but not
+	// after, in replica order.
+	PeersDegraded []pb.ReplicaID
+	// PeersRecovered are the replicas that were healthy after but not
+	// before, in replica order.
+	PeersRecovered []pb.ReplicaID
+}
+
+// Empty returns whether the diff records no progress or peer changes.
+func (d StatusDiff) Empty() bool {
+	return len(d.InstancesAdvanced) == 0 && len(d.WatermarksAdvanced) == 0 &&
+		len(d.PeersDegraded) == 0 && len(d.PeersRecovered) == 0
+}
+
+// DiffStatus summarizes what changed between two snapshots from the same
+// node. The snapshots must be provided in the order they were taken;
+// DiffStatus panics if they come from different nodes or are out of
+// order, as such a diff would be meaningless.
+func DiffStatus(before, after Status) StatusDiff {
+	if before.ReplicaID != after.ReplicaID {
+		panic("diffing Status snapshots from different nodes")
+	}
+	if before.Version > after.Version {
+		panic("diffing Status snapshots out of order")
+	}
+	var d StatusDiff
+	for r, num := range after.MaxInstance {
+		if prev := before.MaxInstance[r]; num > prev {
+			if d.InstancesAdvanced == nil {
+				d.InstancesAdvanced = make(map[pb.ReplicaID]pb.InstanceNum)
+			}
+			d.InstancesAdvanced[r] = num - prev
+		}
+	}
+	for r, num := range after.ExecutedWatermark {
+		if prev := before.ExecutedWatermark[r]; num > prev {
+			if d.WatermarksAdvanced == nil {
+				d.WatermarksAdvanced = make(map[pb.ReplicaID]pb.InstanceNum)
+			}
+			d.WatermarksAdvanced[r] = num - prev
+		}
+	}
+	d.PeersDegraded = replicaSetDiff(before.HealthyReplicas, after.HealthyReplicas)
+	d.PeersRecovered = replicaSetDiff(after.HealthyReplicas, before.HealthyReplicas)
+	return d
+}
+
+// replicaSetDiff returns the replicas in a but not in b, sorted.
+func replicaSetDiff(a, b []pb.ReplicaID) []pb.ReplicaID {
+	inB := make(map[pb.ReplicaID]struct{}, len(b))
+	for _, r := range b {
+		inB[r] = struct{}{}
+	}
+	var diff []pb.ReplicaID
+	for _, r := range a {
+		if _, ok := inB[r]; !ok {
+			diff = append(diff, r)
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i] < diff[j] })
+	return diff
+}
@@ -0,0 +1,119 @@
+package epaxos
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestStatusIntrospection verifies that a snapshot reports the cluster
+// configuration, per-state instance counts, and pending proposals, and
+// serializes to JSON.
+func TestStatusIntrospection(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	// One executed instance and one stuck awaiting PreAccept replies.
+	executed := p.onRequest(newTestingCommand("a", "c"))
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: executed.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	executed.assertState(pb.InstanceState_Executed)
+	p.onRequest(newTestingCommand("x", "z"))
+
+	st := p.Status()
+	if exp := []pb.ReplicaID{0, 1, 2}; !reflect.DeepEqual(st.Nodes, exp) {
+		t.Errorf("expected nodes %v, found %v", exp, st.Nodes)
+	}
+	exp := map[string]int{
+		pb.InstanceState_Executed.String():    1,
+		pb.InstanceState_PreAccepted.String(): 1,
+	}
+	if !reflect.DeepEqual(st.InstanceCounts, exp) {
+		t.Errorf("expected instance counts %v, found %v", exp, st.InstanceCounts)
+	}
+	if st.PendingProposals != 1 {
+		t.Errorf("expected 1 pending proposal, found %d", st.PendingProposals)
+	}
+	if exp := pb.InstanceNum(2); st.MaxInstance[0] != exp {
+		t.Errorf("expected max instance %d, found %d", exp, st.MaxInstance[0])
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatalf("unexpected error serializing Status: %v", err)
+	}
+	var decoded Status
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error deserializing Status: %v", err)
+	}
+	if decoded.PendingProposals != st.PendingProposals ||
+		!reflect.DeepEqual(decoded.InstanceCounts, st.InstanceCounts) {
+		t.Errorf("expected the snapshot to round-trip through JSON, found %+v", decoded)
+	}
+}
+
+// TestStatusDiffProgress verifies that diffing Status snapshots taken
+// around a proposal reports exactly the instance and watermark progress
+// that the proposal made.
+func TestStatusDiffProgress(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	before := leader.Status()
+	if d := DiffStatus(before, leader.Status()); !d.Empty() {
+		t.Fatalf("expected empty diff without progress, found %+v", d)
+	}
+
+	inst := leader.onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	d := DiffStatus(before, leader.Status())
+	if exp := map[pb.ReplicaID]pb.InstanceNum{0: 1}; !reflect.DeepEqual(d.InstancesAdvanced, exp) {
+		t.Errorf("expected instances advanced %v, found %v", exp, d.InstancesAdvanced)
+	}
+	if exp := map[pb.ReplicaID]pb.InstanceNum{0: 1}; !reflect.DeepEqual(d.WatermarksAdvanced, exp) {
+		t.Errorf("expected watermarks advanced %v, found %v", exp, d.WatermarksAdvanced)
+	}
+	if len(d.PeersDegraded) != 0 || len(d.PeersRecovered) != 0 {
+		t.Errorf("expected no peer changes, found %+v", d)
+	}
+}
+
+// TestStatusDiffPeerHealth verifies that peers falling out of and
+// returning to the healthy set are summarized as degraded and recovered.
+func TestStatusDiffPeerHealth(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	// All peers start healthy. Replica 1 stays healthy; replica 2 goes
+	// silent past the liveness timeout.
+	before := p.Status()
+	for i := 0; i < peerLivenessTimeout+2; i++ {
+		p.recordHeardFrom(1)
+		p.Tick()
+	}
+	d := DiffStatus(before, p.Status())
+	if exp := []pb.ReplicaID{2}; !reflect.DeepEqual(d.PeersDegraded, exp) {
+		t.Errorf("expected peers degraded %v, found %v", exp, d.PeersDegraded)
+	}
+	if len(d.PeersRecovered) != 0 {
+		t.Errorf("expected no peers recovered, found %v", d.PeersRecovered)
+	}
+
+	// Hearing from replica 2 again reports it as recovered.
+	before = p.Status()
+	p.recordHeardFrom(2)
+	d = DiffStatus(before, p.Status())
+	if exp := []pb.ReplicaID{2}; !reflect.DeepEqual(d.PeersRecovered, exp) {
+		t.Errorf("expected peers recovered %v, found %v", exp, d.PeersRecovered)
+	}
+	if len(d.PeersDegraded) != 0 {
+		t.Errorf("expected no peers degraded, found %v", d.PeersDegraded)
+	}
+}
@@ -0,0 +1,84 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Proactive status queries.
+//
+// A replica that pre-accepted or accepted an instance normally learns its
+// outcome from the command leader's Commit broadcast. If the leader
+// hiccups after reaching a commit decision — a GC pause, a dropped
+// packet, a brief partition — the promise holder is left stalled with no
+// mechanism short of full instance recovery to learn the outcome. With
+// Config.StatusQueryTicks set, such a replica instead queries the leader
+// for the instance's status after the timeout and adopts a reply that
+// shows the instance committed, shortening the stall to a single round
+// trip. Replies short of commitment carry no decision the replica could
+// safely adopt and are ignored; persistent silence is still left to
+// recovery.
+
+// maybeWatchStatus arms the instance's status-query timeout when this
+// replica records a promise for an instance led elsewhere. The timer is
+// disarmed when the instance's Commit arrives.
+func (inst *instance) maybeWatchStatus() {
+	if inst.p.statusQueryTicks == 0 || inst.is.ReplicaID == inst.p.id {
+		return
+	}
+	inst.statusQueryTimer = makeTickingTimer(inst.p.statusQueryTicks, func() {
+		inst.onStatusQueryTimeout()
+	})
+	inst.p.registerOneTimeTimer(&inst.statusQueryTimer)
+}
+
+// unwatchStatus disarms the instance's status-query timeout, if armed.
+func (inst *instance) unwatchStatus() {
+	if inst.statusQueryTimer.isSet() {
+		inst.p.unregisterTimer(&inst.statusQueryTimer)
+	}
+}
+
+// onStatusQueryTimeout queries the command leader for the instance's
+// status and re-arms the timeout, so that a leader that stays slow is
+// re-queried instead of queried once.
+func (inst *instance) onStatusQueryTimeout() {
+	if !inst.isStates(pb.InstanceState_PreAccepted, pb.InstanceState_Accepted) {
+		return
+	}
+	inst.p.instLogger.Debugf("instance %+v uncommitted after %d ticks; querying its leader",
+		inst.is.InstanceID, inst.p.statusQueryTicks)
+	inst.p.QueryInstance(inst.is.ReplicaID, inst.is.InstanceID)
+	inst.maybeWatchStatus()
+}
+
+// maybeAdoptQueriedStatus applies a queried instance state that shows the
+// instance committed, catching the local replica up without a full
+// recovery. The committed attributes are final, so adopting them is as
+// safe as handling the Commit message they were decided by.
+func (p *epaxos) maybeAdoptQueriedStatus(m pb.Message, qr *pb.InstanceQueryReply) {
+	state := qr.State
+	if state == nil || state.Status < pb.InstanceState_Committed {
+		return
+	}
+	inst := p.getInstance(m.InstanceID.ReplicaID, m.InstanceID.InstanceNum)
+	if inst == nil || inst.abandoned ||
+		!inst.isStates(pb.InstanceState_None, pb.InstanceState_PreAccepted, pb.InstanceState_Accepted) {
+		return
+	}
+	p.instLogger.Debugf("adopting committed state of instance %+v from replica %d",
+		m.InstanceID, m.From)
+	inst.unwatchStatus()
+	inst.is.Status = pb.InstanceState_Committed
+	p.bumpCounter(&p.counters.Committed)
+	if inst.is.Command != state.Command {
+		inst.cmdDigest = nil
+	}
+	inst.is.Command = state.Command
+	p.indexCommand(inst)
+	inst.replaceInstanceData(state.SeqNum, state.Deps)
+	if b := state.Ballot; b != nil {
+		inst.is.Ballot = b
+	}
+	inst.persist()
+	inst.prepareToExecute()
+}
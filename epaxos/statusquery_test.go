@@ -0,0 +1,89 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestStatusQueryAdoptsCommit verifies that a replica whose promise
+// remains uncommitted past the status-query timeout queries the command
+// leader, and adopts the committed state the leader replies with.
+func TestStatusQueryAdoptsCommit(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	leader := newEPaxos(&Config{ID: 0, Nodes: nodes})
+	follower := newEPaxos(&Config{ID: 1, Nodes: nodes, StatusQueryTicks: 2})
+
+	inst := leader.onRequest(newTestingCommand("a", "z"))
+	for _, m := range leader.ReadMessages() {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+	for _, m := range follower.ReadMessages() {
+		if m.To == leader.id {
+			leader.Step(m)
+		}
+	}
+	// The leader reached a commit decision, but its Commit broadcast is
+	// lost: the transient hiccup the status query exists to cover.
+	if !leader.getInstance(0, inst.is.InstanceNum).isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		t.Fatalf("expected leader to commit the instance")
+	}
+	leader.clearMsgs()
+
+	// The follower times out and queries the leader for the instance.
+	follower.Tick()
+	follower.Tick()
+	msgs := follower.ReadMessages()
+	if len(msgs) != 1 || msgs[0].To != leader.id {
+		t.Fatalf("expected 1 query to the leader, found %+v", msgs)
+	}
+	if _, ok := msgs[0].Type.(*pb.Message_InstanceQuery); !ok {
+		t.Fatalf("expected an InstanceQuery, found %+v", msgs[0])
+	}
+
+	// The leader's reply shows the instance committed; the follower
+	// adopts it without waiting for recovery.
+	leader.Step(msgs[0])
+	for _, m := range leader.ReadMessages() {
+		follower.Step(m)
+	}
+	fInst := follower.getInstance(0, inst.is.InstanceNum)
+	if !fInst.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		t.Errorf("expected follower to adopt the commit, found state %v", fInst.is.Status)
+	}
+}
+
+// TestStatusQueryDisarmedByCommit verifies that a Commit arriving before
+// the status-query timeout disarms it, so a healthy leader is never
+// queried.
+func TestStatusQueryDisarmedByCommit(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	leader := newEPaxos(&Config{ID: 0, Nodes: nodes})
+	follower := newEPaxos(&Config{ID: 1, Nodes: nodes, StatusQueryTicks: 2})
+
+	leader.onRequest(newTestingCommand("a", "z"))
+	for _, m := range leader.ReadMessages() {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+	for _, m := range follower.ReadMessages() {
+		if m.To == leader.id {
+			leader.Step(m)
+		}
+	}
+	for _, m := range leader.ReadMessages() {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		follower.Tick()
+	}
+	if msgs := follower.ReadMessages(); len(msgs) != 0 {
+		t.Errorf("expected no status queries after the commit, found %+v", msgs)
+	}
+}
@@ -1,6 +1,8 @@
 package epaxos
 
 import (
+	"sort"
+
 	"github.com/google/btree"
 
 	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
@@ -15,7 +17,51 @@ type Storage interface {
 	PersistInstance(is *pb.InstanceState)
 }
 
+// ProposalStorage optionally extends Storage with persistence of
+// proposals that have been accepted by Request but have not yet entered
+// the consensus pipeline. Without it, a crash between accepting a
+// proposal and persisting its pre-accepted instance silently drops the
+// command; with it, pending proposals are re-proposed on restart,
+// providing at-least-once submission. Commands without an ID are never
+// persisted, as they cannot be deduplicated on re-proposal.
+type ProposalStorage interface {
+	// PendingProposals returns all persisted proposals that have not
+	// been discarded.
+	PendingProposals() []pb.Command
+	// PersistPendingProposal durably records a proposal before it enters
+	// the consensus pipeline.
+	PersistPendingProposal(cmd *pb.Command)
+	// DiscardPendingProposal removes the proposal with the provided
+	// command ID, once the command's instance has been persisted.
+	DiscardPendingProposal(id uint64)
+}
+
+// CompactionStorage optionally extends Storage with removal of truncated
+// instance records. Without it, records below an agreed truncation point
+// remain in storage and are skipped when the node restarts; with it, the
+// consensus log shrinks on disk as compaction proceeds.
+type CompactionStorage interface {
+	// DiscardInstances removes all persisted instances in the replica's
+	// command space with instance numbers at or below upTo.
+	DiscardInstances(r pb.ReplicaID, upTo pb.InstanceNum)
+}
+
+// CounterStorage optionally extends Storage with persistence of the
+// node's cumulative protocol counters. Without it, the counters restart
+// from zero with the process; with it, dashboards that compute rates
+// from the counters do not observe resets across restarts.
+type CounterStorage interface {
+	// Counters returns the persisted counters, if any have been
+	// persisted.
+	Counters() (Counters, bool)
+	// PersistCounters durably records the counter set.
+	PersistCounters(c Counters)
+}
+
 var _ Storage = &MemoryStorage{}
+var _ ProposalStorage = &MemoryStorage{}
+var _ CompactionStorage = &MemoryStorage{}
+var _ CounterStorage = &MemoryStorage{}
 
 // MemoryStorage implements the Storage interface backed by an in-memory
 // data structure.
@@ -25,6 +71,11 @@ type MemoryStorage struct {
 		hs  pb.HardState
 	}
 	instances map[pb.ReplicaID]*btree.BTree // *pb.InstanceState Items
+	proposals map[uint64]pb.Command
+	counters  struct {
+		set bool
+		c   Counters
+	}
 }
 
 // NewMemoryStorage returns a new in-memory implementation of Storage using
@@ -32,6 +83,7 @@ type MemoryStorage struct {
 func NewMemoryStorage(c *Config) Storage {
 	s := &MemoryStorage{
 		instances: make(map[pb.ReplicaID]*btree.BTree, len(c.Nodes)),
+		proposals: make(map[uint64]pb.Command),
 	}
 	for _, rep := range c.Nodes {
 		s.instances[rep] = btree.New(32 /* degree */)
@@ -73,3 +125,49 @@ func (ms *MemoryStorage) Instances() []*pb.InstanceState {
 func (ms *MemoryStorage) PersistInstance(is *pb.InstanceState) {
 	ms.instances[is.ReplicaID].ReplaceOrInsert(is)
 }
+
+// DiscardInstances implements the CompactionStorage interface.
+func (ms *MemoryStorage) DiscardInstances(r pb.ReplicaID, upTo pb.InstanceNum) {
+	var discard []btree.Item
+	ms.instances[r].AscendLessThan(instanceStateKey(upTo+1), func(i btree.Item) bool {
+		discard = append(discard, i)
+		return true
+	})
+	for _, item := range discard {
+		ms.instances[r].Delete(item)
+	}
+}
+
+// Counters implements the CounterStorage interface.
+func (ms *MemoryStorage) Counters() (Counters, bool) {
+	if ms.counters.set {
+		return ms.counters.c, true
+	}
+	return Counters{}, false
+}
+
+// PersistCounters implements the CounterStorage interface.
+func (ms *MemoryStorage) PersistCounters(c Counters) {
+	ms.counters.c = c
+	ms.counters.set = true
+}
+
+// PendingProposals implements the ProposalStorage interface.
+func (ms *MemoryStorage) PendingProposals() []pb.Command {
+	cmds := make([]pb.Command, 0, len(ms.proposals))
+	for _, cmd := range ms.proposals {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].ID < cmds[j].ID })
+	return cmds
+}
+
+// PersistPendingProposal implements the ProposalStorage interface.
+func (ms *MemoryStorage) PersistPendingProposal(cmd *pb.Command) {
+	ms.proposals[cmd.ID] = *cmd
+}
+
+// DiscardPendingProposal implements the ProposalStorage interface.
+func (ms *MemoryStorage) DiscardPendingProposal(id uint64) {
+	delete(ms.proposals, id)
+}
@@ -0,0 +1,134 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestFollowerPromiseSurvivesRestart verifies that a follower persists a
+// pre-accepted instance through Storage, so a follower that crashes after
+// promising recovers the promise with its attributes intact on restart.
+func TestFollowerPromiseSurvivesRestart(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	inst := leader.onRequest(newTestingCommand("a", "z"))
+	n.deliverAllMessages() // PreAccepts to followers
+
+	follower := n.peers[1]
+	before := follower.getInstance(inst.is.ReplicaID, inst.is.InstanceNum)
+	if before == nil || !before.isStates(pb.InstanceState_PreAccepted) {
+		t.Fatalf("expected pre-accepted instance on follower, found %+v", before)
+	}
+
+	// Crash the follower before its reply is delivered.
+	n.restart(1)
+	after := n.peers[1].getInstance(inst.is.ReplicaID, inst.is.InstanceNum)
+	if after == nil {
+		t.Fatalf("expected recovered instance after restart")
+	}
+	if !after.isStates(pb.InstanceState_PreAccepted) {
+		t.Fatalf("expected recovered instance pre-accepted, found %v", after.is.Status)
+	}
+	if after.is.SeqNum != before.is.SeqNum || !reflect.DeepEqual(after.is.Deps, before.is.Deps) {
+		t.Fatalf("expected recovered attributes %+v, found %+v", before.is, after.is)
+	}
+
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+}
+
+// TestResumeCommittedExecutionAfterRestart verifies that an instance
+// that committed but had not executed before a crash resumes execution
+// when the node is rebuilt from storage, even when another replica led
+// the instance.
+func TestResumeCommittedExecutionAfterRestart(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	ms := NewMemoryStorage(&Config{ID: 0, Nodes: nodes})
+	cmd := newTestingCommand("a", "z")
+	ms.PersistInstance(&pb.InstanceState{
+		InstanceID:   pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
+		Status:       pb.InstanceState_Committed,
+		InstanceData: pb.InstanceData{Command: cmd, SeqNum: 1},
+	})
+
+	p := newEPaxos(&Config{ID: 0, Nodes: nodes, Storage: ms})
+	if !p.hasExecuted(1, 1) {
+		t.Fatalf("expected committed instance to resume execution on restart")
+	}
+	if cmds := p.executedCmds; len(cmds) != 1 || cmds[0].ID != cmd.ID {
+		t.Fatalf("expected recovered command executed, found %v", cmds)
+	}
+}
+
+// TestProposalStorageReproposal verifies that a proposal persisted
+// before a crash, but without a corresponding instance, is re-proposed
+// when the node starts back up.
+func TestProposalStorageReproposal(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	ms := NewMemoryStorage(&Config{ID: 0, Nodes: nodes})
+	cmd := newTestingCommand("a", "z")
+	ms.(ProposalStorage).PersistPendingProposal(cmd)
+
+	p := newEPaxos(&Config{ID: 0, Nodes: nodes, Storage: ms})
+	inst := p.pendingCommand(cmd.ID)
+	if inst == nil {
+		t.Fatalf("expected recovered proposal to be re-proposed on startup")
+	}
+	if !inst.isStates(pb.InstanceState_PreAccepted) {
+		t.Fatalf("expected re-proposed instance pre-accepted, found %v", inst.is.Status)
+	}
+	if pending := ms.(ProposalStorage).PendingProposals(); len(pending) != 0 {
+		t.Fatalf("expected proposal discarded once in the pipeline, found %v", pending)
+	}
+}
+
+// TestProposalStorageDiscardOnPropose verifies that a proposal's
+// standalone record is discarded as soon as its instance is persisted.
+func TestProposalStorageDiscardOnPropose(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	cmd := newTestingCommand("a", "z")
+	p.Request(cmd)
+
+	if p.pendingCommand(cmd.ID) == nil {
+		t.Fatalf("expected proposal in flight")
+	}
+	if pending := p.propStorage.PendingProposals(); len(pending) != 0 {
+		t.Fatalf("expected no pending proposal records, found %v", pending)
+	}
+}
+
+// TestCountersPersistAcrossRestart verifies that the node's cumulative
+// protocol counters are persisted through CounterStorage and restored on
+// restart, so that rates computed from them do not observe resets.
+func TestCountersPersistAcrossRestart(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	ms := NewMemoryStorage(&Config{ID: 0, Nodes: nodes})
+	p := newEPaxos(&Config{ID: 0, Nodes: nodes, Storage: ms})
+
+	// A fast-path commit bumps the committed, fast-path and executed
+	// counters.
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	p.Step(pb.Message{
+		To:         0,
+		From:       1,
+		InstanceID: newInst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	newInst.assertState(pb.InstanceState_Executed)
+
+	exp := Counters{Committed: 1, Executed: 1, FastPathCommitted: 1}
+	if a := p.Counters(); a != exp {
+		t.Fatalf("expected counters %+v, found %+v", exp, a)
+	}
+
+	// A restarted node against the same storage resumes from the
+	// persisted counters instead of resetting them.
+	p2 := newEPaxos(&Config{ID: 0, Nodes: nodes, Storage: ms})
+	if a := p2.Counters(); a != exp {
+		t.Fatalf("expected restored counters %+v, found %+v", exp, a)
+	}
+}
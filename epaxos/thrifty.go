@@ -0,0 +1,92 @@
+package epaxos
+
+import (
+	"github.com/gogo/protobuf/proto"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// thriftyExpandTimeout is the number of ticks a thrifty phase message may
+// await its quorum before being sent to the remaining peers as well.
+const thriftyExpandTimeout = 3
+
+// thriftyPeers returns the peers a thrifty phase message should initially
+// be sent to: the first needed peers in node order, preferring healthy
+// peers so that the phase's quorum is not gated on a replica that has
+// gone quiet.
+func (p *epaxos) thriftyPeers(needed int) []pb.ReplicaID {
+	peers := make([]pb.ReplicaID, 0, needed)
+	healthy := p.healthyReplicas()
+	for _, node := range p.nodes {
+		if node != p.id && inReplicaSlice(node, healthy) {
+			peers = append(peers, node)
+			if len(peers) == needed {
+				return peers
+			}
+		}
+	}
+	for _, node := range p.nodes {
+		if node != p.id && !inReplicaSlice(node, healthy) {
+			peers = append(peers, node)
+			if len(peers) == needed {
+				return peers
+			}
+		}
+	}
+	return peers
+}
+
+// sendThrifty sends the phase message to only enough peers to form the
+// quorum the phase requires, instead of to every peer, and arms a timer
+// that sends it to the remaining peers if the quorum does not
+// materialize in time.
+func (inst *instance) sendThrifty(m proto.Message, needed int) {
+	p := inst.p
+	if needed <= 0 || needed >= len(p.nodes)-1 {
+		inst.broadcast(m)
+		return
+	}
+	targets := p.thriftyPeers(needed)
+	inst.thriftySent = targets
+	for _, node := range targets {
+		p.sendTo(m, node, inst)
+	}
+	inst.armThriftyTimer()
+}
+
+func (inst *instance) armThriftyTimer() {
+	inst.disarmThriftyTimer()
+	inst.thriftyTimer = makeTickingTimer(thriftyExpandTimeout, func() {
+		inst.expandThrifty()
+	})
+	inst.p.registerOneTimeTimer(&inst.thriftyTimer)
+}
+
+func (inst *instance) disarmThriftyTimer() {
+	if inst.thriftyTimer.isSet() {
+		inst.p.unregisterTimer(&inst.thriftyTimer)
+	}
+}
+
+// expandThrifty sends the current phase's message to the peers the
+// thrifty send skipped, after the phase's quorum failed to materialize
+// within thriftyExpandTimeout ticks.
+func (inst *instance) expandThrifty() {
+	var m proto.Message
+	switch inst.is.Status {
+	case pb.InstanceState_PreAccepted:
+		data := inst.instanceData()
+		data.CommandDigest = inst.commandDigest()
+		m = &pb.PreAccept{InstanceData: data}
+	case pb.InstanceState_Accepted:
+		m = &pb.Accept{InstanceData: inst.instanceDataWithoutCommand()}
+	default:
+		return
+	}
+	for _, node := range inst.p.nodes {
+		if node != inst.p.id && !inReplicaSlice(node, inst.thriftySent) {
+			inst.p.sendTo(m, node, inst)
+		}
+	}
+	inst.thriftySent = nil
+}
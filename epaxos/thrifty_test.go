@@ -0,0 +1,166 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+func newThriftyLeader() *epaxos {
+	return newEPaxos(&Config{
+		ID:      0,
+		Nodes:   []pb.ReplicaID{0, 1, 2, 3, 4},
+		Thrifty: true,
+	})
+}
+
+func destinations(msgs []pb.Message) []pb.ReplicaID {
+	to := make([]pb.ReplicaID, 0, len(msgs))
+	for _, m := range msgs {
+		to = append(to, m.To)
+	}
+	return to
+}
+
+// TestThriftyPreAcceptTargets verifies that with Thrifty set, a proposal's
+// PreAccept is sent only to enough peers to form a fast quorum, and that
+// the remaining peers receive it once the expansion timer fires without
+// the quorum materializing.
+func TestThriftyPreAcceptTargets(t *testing.T) {
+	p := newThriftyLeader()
+
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	newInst.assertState(pb.InstanceState_PreAccepted)
+
+	// A fast quorum of 4 replies includes the leader's own, so only three
+	// of the four peers are targeted.
+	if a, e := destinations(p.msgs), []pb.ReplicaID{1, 2, 3}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected thrifty PreAccept to peers %v, found %v", e, a)
+	}
+	p.clearMsgs()
+
+	// Without the quorum materializing, the expansion timer sends the
+	// PreAccept to the skipped peer.
+	for i := 0; i < thriftyExpandTimeout; i++ {
+		p.Tick()
+	}
+	if a, e := destinations(p.msgs), []pb.ReplicaID{4}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected expanded PreAccept to peers %v, found %v", e, a)
+	}
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_PreAccept); !ok {
+			t.Fatalf("expected expanded message to be a PreAccept, found %+v", m)
+		}
+	}
+}
+
+// TestThriftyFastPathCommit verifies that a thrifty PreAccept round can
+// still commit on the fast path, and that the commit disarms the
+// expansion timer.
+func TestThriftyFastPathCommit(t *testing.T) {
+	p := newThriftyLeader()
+
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	p.clearMsgs()
+
+	for _, from := range []pb.ReplicaID{1, 2, 3} {
+		p.Step(pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+		})
+	}
+	newInst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+
+	// The Commit is broadcast to every peer, including the one the
+	// thrifty PreAccept skipped.
+	if a, e := destinations(p.msgs), []pb.ReplicaID{1, 2, 3, 4}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected Commit to peers %v, found %v", e, a)
+	}
+	p.clearMsgs()
+
+	// The expansion timer was disarmed by the commit; ticking past it
+	// produces no stray PreAccepts.
+	for i := 0; i < 2*thriftyExpandTimeout; i++ {
+		p.Tick()
+	}
+	p.assertOutboxEmpty(t)
+}
+
+// TestThriftyAcceptTargets verifies that a slow-path Accept round is also
+// sent to a quorum-sized subset of peers.
+func TestThriftyAcceptTargets(t *testing.T) {
+	p := newThriftyLeader()
+
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+	p.clearMsgs()
+
+	// A reply with an extra dependency forces the slow path.
+	updatedDeps := append([]pb.InstanceID(nil), newInst.is.Deps...)
+	updatedDeps = append(updatedDeps, pb.InstanceID{ReplicaID: 3, InstanceNum: 1})
+	for _, from := range []pb.ReplicaID{1, 2} {
+		p.Step(pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type: pb.WrapMessageInner(&pb.PreAcceptReply{
+				UpdatedSeqNum: newInst.is.SeqNum,
+				UpdatedDeps:   updatedDeps,
+			}),
+		})
+	}
+	newInst.assertState(pb.InstanceState_Accepted)
+
+	// A quorum of 3 replies includes the leader's own, so only two of the
+	// four peers are targeted.
+	if a, e := destinations(p.msgs), []pb.ReplicaID{1, 2}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected thrifty Accept to peers %v, found %v", e, a)
+	}
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_Accept); !ok {
+			t.Fatalf("expected thrifty message to be an Accept, found %+v", m)
+		}
+	}
+}
+
+// TestThriftyPerMessageType verifies that thrifty sending can be toggled
+// per message type: with only ThriftyCommit set, PreAccepts reach every
+// peer while Commits reach only a quorum-sized subset.
+func TestThriftyPerMessageType(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:            0,
+		Nodes:         []pb.ReplicaID{0, 1, 2, 3, 4},
+		ThriftyCommit: true,
+	})
+
+	newInst := p.onRequest(newTestingCommand("a", "z"))
+
+	// PreAccepts are not thrifty and reach every peer.
+	if a, e := destinations(p.msgs), []pb.ReplicaID{1, 2, 3, 4}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected full PreAccept broadcast to peers %v, found %v", e, a)
+	}
+	p.clearMsgs()
+
+	for _, from := range []pb.ReplicaID{1, 2, 3} {
+		p.Step(pb.Message{
+			To:         0,
+			From:       from,
+			InstanceID: newInst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+		})
+	}
+	newInst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+
+	// The Commit reaches only enough peers that a quorum, including the
+	// leader, knows of it.
+	if a, e := destinations(p.msgs), []pb.ReplicaID{1, 2}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected thrifty Commit to peers %v, found %v", e, a)
+	}
+	for _, m := range p.msgs {
+		if _, ok := m.Type.(*pb.Message_Commit); !ok {
+			t.Fatalf("expected thrifty message to be a Commit, found %+v", m)
+		}
+	}
+}
@@ -9,6 +9,11 @@ type tickingTimer struct {
 	ticksElapsed int
 	paused       bool
 	onTimeout    func()
+	// deadline is the tick at which the timer is due to fire. It is
+	// bookkeeping for the timer wheel (see epaxos.scheduleTimer), which
+	// uses it to tell a timer's live wheel entry apart from entries left
+	// stale by rescheduling.
+	deadline uint64
 }
 
 func makeTickingTimer(timeout int, onTimeout func()) tickingTimer {
@@ -31,6 +36,19 @@ func (t *tickingTimer) tick() {
 	}
 }
 
+// expire fires the timer immediately, pausing it first so that the
+// callback can re-arm it. It is the timer wheel's counterpart to the
+// countdown reaching the timeout in tick.
+func (t *tickingTimer) expire() {
+	t.paused = true
+	t.onTimeout()
+}
+
+// remaining returns how many ticks are left until the timer would fire.
+func (t *tickingTimer) remaining() int {
+	return t.timeout - t.ticksElapsed
+}
+
 func (t *tickingTimer) reset() {
 	t.paused = false
 	t.ticksElapsed = 0
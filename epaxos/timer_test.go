@@ -1,6 +1,10 @@
 package epaxos
 
-import "testing"
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
 
 func TestTickingTimer(t *testing.T) {
 	flag := false
@@ -64,3 +68,54 @@ func TestTickingTimer(t *testing.T) {
 	assertFlag(true)
 	assertTimerSet(false)
 }
+
+// TestTimerWheel verifies that the timer wheel fires timers on their
+// deadline tick, skips entries left stale by rescheduling or
+// unregistration, and re-arms infinite timers.
+func TestTimerWheel(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0}})
+
+	fired := 0
+	timer := makeTickingTimer(3, func() { fired++ })
+	p.registerOneTimeTimer(&timer)
+	p.TickN(2)
+	if fired != 0 {
+		t.Fatalf("expected the timer not to have fired yet")
+	}
+
+	// Re-registering reschedules; the stale wheel entry at the original
+	// deadline must not fire the timer early.
+	p.registerOneTimeTimer(&timer)
+	p.TickN(2)
+	if fired != 0 {
+		t.Fatalf("expected the rescheduled timer not to fire at its old deadline")
+	}
+	p.Tick()
+	if fired != 1 {
+		t.Fatalf("expected the timer to fire at its new deadline, fired %d times", fired)
+	}
+	if len(p.timers) != 0 || len(p.timerWheel) != 0 {
+		t.Fatalf("expected an empty registry and wheel, found %d timers and %d buckets",
+			len(p.timers), len(p.timerWheel))
+	}
+
+	// An unregistered timer's wheel entry never fires.
+	p.registerOneTimeTimer(&timer)
+	p.unregisterTimer(&timer)
+	p.TickN(3)
+	if fired != 1 {
+		t.Fatalf("expected the unregistered timer not to fire, fired %d times", fired)
+	}
+	if len(p.timerWheel) != 0 {
+		t.Fatalf("expected the stale bucket reclaimed, found %d buckets", len(p.timerWheel))
+	}
+
+	// Infinite timers re-arm themselves on each expiry.
+	periods := 0
+	inf := makeTickingTimer(2, func() { periods++ })
+	p.registerInfiniteTimer(&inf)
+	p.TickN(4)
+	if periods != 2 {
+		t.Fatalf("expected the infinite timer to fire twice, fired %d times", periods)
+	}
+}
@@ -0,0 +1,45 @@
+package epaxos
+
+import (
+	"strconv"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// zoneAware returns whether fast-path quorum decisions take replica
+// failure domains into account. Zone awareness only engages when a
+// topology is configured and the replicas actually span more than one
+// zone; with a single zone there is no cross-domain quorum to prefer.
+func (p *epaxos) zoneAware() bool {
+	return len(p.topology) > 0 && p.zonesSpanned(p.nodes) > 1
+}
+
+// zonesSpanned returns the number of distinct failure domains covered by
+// the provided replicas. Replicas without a topology label are each
+// counted as their own domain.
+func (p *epaxos) zonesSpanned(replicas []pb.ReplicaID) int {
+	zones := make(map[string]struct{}, len(replicas))
+	for _, r := range replicas {
+		zone, ok := p.topology[r]
+		if !ok {
+			// Treat an unlabeled replica as a singleton domain.
+			zone = "\x00" + strconv.FormatUint(uint64(r), 10)
+		}
+		zones[zone] = struct{}{}
+	}
+	return len(zones)
+}
+
+// fastPathSpansZones returns whether the set of replicas backing a
+// prospective fast-path commit (the command leader plus the replicas
+// that have replied) spans at least two failure domains. When it does
+// not, the fast path is withheld so that a commit decision never rests
+// entirely on a single zone that can fail as a unit; the instance falls
+// back to waiting for broader replies or to the slow path.
+func (inst *instance) fastPathSpansZones() bool {
+	if !inst.p.zoneAware() {
+		return true
+	}
+	backers := append([]pb.ReplicaID{inst.p.id}, inst.replyReplicas...)
+	return inst.p.zonesSpanned(backers) > 1
+}
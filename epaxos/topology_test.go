@@ -0,0 +1,61 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestZoneAwareFastPath verifies that a fast-path commit is withheld until
+// the backing replies span more than one failure domain.
+func TestZoneAwareFastPath(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0, 1, 2},
+		Topology: map[pb.ReplicaID]string{
+			0: "us-east-1a",
+			1: "us-east-1a",
+			2: "us-east-1b",
+		},
+	})
+	inst := p.onRequest(newTestingCommand("a", "z"))
+
+	// A reply from replica 1 completes a fast quorum, but both backers sit
+	// in the same zone, so the fast path is withheld.
+	p.stepFrom = 1
+	inst.onPreAcceptOK(&pb.PreAcceptOK{})
+	if inst.is.Status != pb.InstanceState_PreAccepted {
+		t.Fatalf("expected fast path withheld within a single zone, found %v", inst.is.Status)
+	}
+
+	// A reply from replica 2 spans a second zone, unlocking the fast path.
+	p.stepFrom = 2
+	inst.onPreAcceptOK(&pb.PreAcceptOK{})
+	if inst.is.Status < pb.InstanceState_Committed {
+		t.Fatalf("expected fast path commit across zones, found %v", inst.is.Status)
+	}
+}
+
+// TestZoneAwareSingleZone verifies that zone awareness is a no-op when all
+// replicas share a failure domain.
+func TestZoneAwareSingleZone(t *testing.T) {
+	p := newEPaxos(&Config{
+		ID:    0,
+		Nodes: []pb.ReplicaID{0, 1, 2},
+		Topology: map[pb.ReplicaID]string{
+			0: "us-east-1a",
+			1: "us-east-1a",
+			2: "us-east-1a",
+		},
+	})
+	if p.zoneAware() {
+		t.Fatalf("expected a single-zone topology to disable zone awareness")
+	}
+
+	inst := p.onRequest(newTestingCommand("a", "z"))
+	p.stepFrom = 1
+	inst.onPreAcceptOK(&pb.PreAcceptOK{})
+	if inst.is.Status < pb.InstanceState_Committed {
+		t.Fatalf("expected fast path commit, found %v", inst.is.Status)
+	}
+}
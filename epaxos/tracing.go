@@ -0,0 +1,41 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// RequestTraced proposes the command like Request, additionally tagging
+// the resulting instance with the provided W3C traceparent. Every
+// protocol message for the instance carries the traceparent in its
+// envelope, so spans created while handling those messages on other
+// replicas link back to the proposal's trace.
+func (p *epaxos) RequestTraced(cmd *pb.Command, traceparent string) {
+	if p.halted {
+		p.logger.Debugf("ignoring proposal on halted replica: %+v", cmd)
+		return
+	}
+	if p.sheddingLoad() {
+		p.logger.Debugf("rejecting proposal while storage is slow: %+v", cmd)
+		return
+	}
+	p.onRequestTraced(cmd, traceparent)
+}
+
+// TraceContext returns the traceparent associated with an instance, if
+// one was propagated to this replica.
+func (p *epaxos) TraceContext(id pb.InstanceID) (string, bool) {
+	inst := p.getInstance(id.ReplicaID, id.InstanceNum)
+	if inst == nil || inst.traceCtx == "" {
+		return "", false
+	}
+	return inst.traceCtx, true
+}
+
+// adoptTraceContext records the traceparent carried by an incoming
+// message on its instance, so that this replica's replies and spans for
+// the instance link to the proposal's trace.
+func (inst *instance) adoptTraceContext(m pb.Message) {
+	if inst.traceCtx == "" && m.TraceContext != "" {
+		inst.traceCtx = m.TraceContext
+	}
+}
@@ -0,0 +1,47 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestTraceContextPropagation verifies that a proposal's traceparent is
+// carried on every message for its instance and adopted by the replicas
+// that handle them, linking spans across the cluster.
+func TestTraceContextPropagation(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	nodes := []pb.ReplicaID{0, 1, 2}
+	leader := newEPaxos(&Config{ID: 0, Nodes: nodes})
+	follower := newEPaxos(&Config{ID: 1, Nodes: nodes})
+
+	leader.RequestTraced(newTestingCommand("a", "z"), traceparent)
+	msgs := leader.ReadMessages()
+	for _, m := range msgs {
+		if m.TraceContext != traceparent {
+			t.Fatalf("expected traceparent on outbound message, found %+v", m)
+		}
+	}
+	if tc, ok := leader.TraceContext(pb.InstanceID{ReplicaID: 0, InstanceNum: 1}); !ok || tc != traceparent {
+		t.Fatalf("expected traceparent recorded on leader, found %q", tc)
+	}
+
+	// The follower adopts the traceparent and carries it on its reply.
+	for _, m := range msgs {
+		if m.To == follower.id {
+			follower.Step(m)
+		}
+	}
+	if tc, ok := follower.TraceContext(pb.InstanceID{ReplicaID: 0, InstanceNum: 1}); !ok || tc != traceparent {
+		t.Fatalf("expected traceparent adopted by follower, found %q", tc)
+	}
+	replies := follower.ReadMessages()
+	if len(replies) == 0 {
+		t.Fatalf("expected a reply from the follower")
+	}
+	for _, m := range replies {
+		if m.TraceContext != traceparent {
+			t.Fatalf("expected traceparent on reply, found %+v", m)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package epaxos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/google/btree"
+	"github.com/pkg/errors"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Instance space truncation via replicated compaction commands.
+//
+// A compaction command is a protocol-internal command, agreed via the
+// normal consensus path, whose payload declares an executed prefix of
+// each replica's instance space that is safe to truncate cluster-wide.
+// Because the declaration is itself replicated, every replica truncates
+// the same prefix, making this a lighter alternative to full snapshots
+// for applications whose durable state lives outside the consensus log.
+// The application asserts safety when proposing the command, typically
+// from an executed watermark that all replicas have durably applied.
+//
+// Each replica truncates lazily: the agreed prefix is clamped by the
+// local executed watermark (and any compaction hold), so a lagging
+// replica truncates as its own execution catches up.
+
+// NewCompactionCommand returns a command that, once agreed and executed,
+// truncates each replica's instance space up to the provided instance
+// numbers. The command is proposed through the normal Propose path.
+func NewCompactionCommand(id uint64, upTo map[pb.ReplicaID]pb.InstanceNum) *pb.Command {
+	return &pb.Command{
+		ID:         id,
+		Compaction: true,
+		Data:       encodeCompactionPrefix(upTo),
+	}
+}
+
+// encodeCompactionPrefix encodes the per-replica prefix as a stream of
+// varint-length-delimited marshaled InstanceID records, in replica order.
+func encodeCompactionPrefix(upTo map[pb.ReplicaID]pb.InstanceNum) []byte {
+	ids := make([]pb.InstanceID, 0, len(upTo))
+	for r, i := range upTo {
+		ids = append(ids, pb.InstanceID{ReplicaID: r, InstanceNum: i})
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].ReplicaID < ids[j].ReplicaID })
+
+	var buf bytes.Buffer
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i := range ids {
+		data, err := ids[i].Marshal()
+		if err != nil {
+			// InstanceID marshaling cannot fail; it contains only varints.
+			panic(err)
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+		buf.Write(lenBuf[:n])
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// decodeCompactionPrefix decodes a payload written by
+// encodeCompactionPrefix.
+func decodeCompactionPrefix(data []byte) (map[pb.ReplicaID]pb.InstanceNum, error) {
+	br := bytes.NewReader(data)
+	upTo := make(map[pb.ReplicaID]pb.InstanceNum)
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return upTo, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec := make([]byte, size)
+		if _, err := io.ReadFull(br, rec); err != nil {
+			return nil, errors.Wrap(err, "reading truncated compaction record")
+		}
+		var id pb.InstanceID
+		if err := id.Unmarshal(rec); err != nil {
+			return nil, err
+		}
+		upTo[id.ReplicaID] = id.InstanceNum
+	}
+}
+
+// onCompactionCommand handles an executed compaction command, recording
+// the agreed truncation points and truncating whatever portion of them
+// the local replica has already executed.
+func (p *epaxos) onCompactionCommand(cmd pb.Command) {
+	upTo, err := decodeCompactionPrefix(cmd.Data)
+	if err != nil {
+		p.storageLogger.Warningf("ignoring malformed compaction command %d: %v", cmd.ID, err)
+		return
+	}
+	if p.agreedCompaction == nil {
+		p.agreedCompaction = make(map[pb.ReplicaID]pb.InstanceNum)
+	}
+	for r, i := range upTo {
+		if p.knownReplica(r) && i > p.agreedCompaction[r] {
+			p.agreedCompaction[r] = i
+		}
+	}
+	p.maybeTruncate()
+}
+
+// maybeTruncate truncates each replica's instance space up to the agreed
+// compaction point, clamped by how far truncation is locally safe: the
+// executed watermark, further clamped by any compaction hold.
+func (p *epaxos) maybeTruncate() {
+	if len(p.agreedCompaction) == 0 {
+		return
+	}
+	safe := p.compactionUpTo()
+	truncated := false
+	for r, agreed := range p.agreedCompaction {
+		limit := agreed
+		if safe[r] < limit {
+			limit = safe[r]
+		}
+		if limit <= p.maxTruncated[r] {
+			continue
+		}
+		p.truncateSpace(r, limit)
+		truncated = true
+		if p.maxTruncated[r] >= agreed {
+			// The agreed point has been fully applied locally.
+			delete(p.agreedCompaction, r)
+		}
+	}
+	if truncated {
+		p.persistTruncation()
+	}
+}
+
+// truncateSpace removes all instances in the replica's command space with
+// instance numbers at or below the provided limit.
+func (p *epaxos) truncateSpace(r pb.ReplicaID, limit pb.InstanceNum) {
+	var truncate []*instance
+	p.commands[r].AscendLessThan(instanceKey(limit+1), func(i btree.Item) bool {
+		truncate = append(truncate, i.(*instance))
+		return true
+	})
+	for _, inst := range truncate {
+		p.commands[r].Delete(inst)
+		p.depIndex[r].remove(inst)
+	}
+	if p.maxTruncated == nil {
+		p.maxTruncated = make(map[pb.ReplicaID]pb.InstanceNum)
+	}
+	p.maxTruncated[r] = limit
+	if cs, ok := p.storage.(CompactionStorage); ok {
+		cs.DiscardInstances(r, limit)
+	}
+	p.storageLogger.Infof("truncated instance space of replica %d up to %d", r, limit)
+}
+
+// persistTruncation records the truncation points in the node's HardState
+// so that they survive restarts; without them, instance numbers could be
+// reused and messages to truncated instances could resurrect them.
+func (p *epaxos) persistTruncation() {
+	hs, ok := p.storage.HardState()
+	if !ok {
+		return
+	}
+	hs.Truncated = hs.Truncated[:0]
+	for r, i := range p.maxTruncated {
+		hs.Truncated = append(hs.Truncated, pb.InstanceID{ReplicaID: r, InstanceNum: i})
+	}
+	sort.Slice(hs.Truncated, func(i, j int) bool {
+		return hs.Truncated[i].ReplicaID < hs.Truncated[j].ReplicaID
+	})
+	p.storage.PersistHardState(hs)
+	p.readyHardState = &hs
+}
+
+// hasTruncated returns whether the instance has been truncated from the
+// replica's command space. Truncated instances were necessarily executed.
+func (p *epaxos) hasTruncated(r pb.ReplicaID, i pb.InstanceNum) bool {
+	return i <= p.maxTruncated[r]
+}
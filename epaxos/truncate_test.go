@@ -0,0 +1,90 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestCompactionPrefixRoundTrip verifies that a compaction command's
+// payload survives an encode/decode round trip.
+func TestCompactionPrefixRoundTrip(t *testing.T) {
+	upTo := map[pb.ReplicaID]pb.InstanceNum{0: 3, 1: 7, 2: 1}
+	cmd := NewCompactionCommand(12, upTo)
+	if !cmd.Compaction {
+		t.Fatalf("expected compaction command to be marked as such")
+	}
+
+	decoded, err := decodeCompactionPrefix(cmd.Data)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, upTo) {
+		t.Errorf("expected decoded prefix %v, found %v", upTo, decoded)
+	}
+}
+
+// TestCompactionCommandTruncates verifies that an agreed compaction
+// command truncates the executed prefix on every replica, without
+// disturbing instance numbering for later proposals.
+func TestCompactionCommandTruncates(t *testing.T) {
+	n := newNetwork(3)
+
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	// Declare the executed prefix safe to truncate.
+	upTo := n.peers[0].ExecutedWatermark()
+	compInst := n.peers[0].onRequest(NewCompactionCommand(2, upTo))
+	if !n.waitExecuteInstance(compInst, false /* quorum */) {
+		t.Fatalf("compaction execution failed, instance %+v never installed", compInst)
+	}
+
+	for i, p := range n.peers {
+		if p.getInstance(inst.is.ReplicaID, inst.is.InstanceNum) != nil {
+			t.Errorf("peer %d: expected instance %+v truncated", i, inst.is.InstanceID)
+		}
+		if !p.hasTruncated(inst.is.ReplicaID, inst.is.InstanceNum) {
+			t.Errorf("peer %d: expected hasTruncated for %+v", i, inst.is.InstanceID)
+		}
+		if !p.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum) {
+			t.Errorf("peer %d: expected truncated instance to count as executed", i)
+		}
+	}
+
+	// A later proposal must not reuse a truncated instance number.
+	inst2 := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if inst2.is.InstanceNum <= n.peers[0].maxTruncated[0] {
+		t.Errorf("expected new instance above truncation point, found %d", inst2.is.InstanceNum)
+	}
+	if !n.waitExecuteInstance(inst2, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst2)
+	}
+}
+
+// TestTruncationSurvivesRestart verifies that truncation points persisted
+// in the HardState are restored on restart and that stale instance
+// records below them are not resurrected.
+func TestTruncationSurvivesRestart(t *testing.T) {
+	nodes := []pb.ReplicaID{0, 1, 2}
+	c := &Config{ID: 0, Nodes: nodes, Storage: NewMemoryStorage(&Config{ID: 0, Nodes: nodes})}
+	p := newEPaxos(c)
+
+	// Simulate an agreed, locally applied truncation.
+	p.executedPrefix[1] = 4
+	p.onCompactionCommand(*NewCompactionCommand(12, map[pb.ReplicaID]pb.InstanceNum{1: 4}))
+	if p.maxTruncated[1] != 4 {
+		t.Fatalf("expected truncation up to 4, found %d", p.maxTruncated[1])
+	}
+
+	p2 := newEPaxos(c)
+	if p2.maxTruncated[1] != 4 {
+		t.Errorf("expected restored truncation up to 4, found %d", p2.maxTruncated[1])
+	}
+	if !p2.hasExecuted(1, 3) {
+		t.Errorf("expected truncated instance to count as executed after restart")
+	}
+}
@@ -0,0 +1,86 @@
+package epaxos
+
+import (
+	"context"
+	"sync/atomic"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TypedCommand constrains the user-defined command types usable with
+// Typed. A typed command serializes itself into the pb.Command Data
+// payload and describes the key span it operates over, from which the
+// EPaxos interference relation is derived: two commands interfere when
+// at least one is writing and their spans overlap.
+type TypedCommand[T any] interface {
+	*T
+	// Marshal serializes the command into the pb.Command Data payload.
+	Marshal() ([]byte, error)
+	// Unmarshal deserializes the command from the pb.Command Data payload.
+	Unmarshal(data []byte) error
+	// Span returns the key span that the command operates over.
+	Span() pb.Span
+	// Writing returns whether the command writes to its span.
+	Writing() bool
+}
+
+// Typed wraps a Node with a typed Propose/Execute boundary, eliminating
+// the boilerplate conversions to and from pb.Command that every embedding
+// application would otherwise repeat. T is the application's command type.
+type Typed[T any, PT TypedCommand[T]] struct {
+	node Node
+	// id is the ID assigned to the next proposed command.
+	id uint64
+}
+
+// NewTyped creates a Typed wrapper around the provided Node.
+func NewTyped[T any, PT TypedCommand[T]](n Node) *Typed[T, PT] {
+	return &Typed[T, PT]{node: n}
+}
+
+// Propose encodes the typed command and proposes it to the wrapped Node.
+func (t *Typed[T, PT]) Propose(ctx context.Context, cmd T) error {
+	pbCmd, err := t.Encode(cmd)
+	if err != nil {
+		return err
+	}
+	return t.node.Propose(ctx, pbCmd)
+}
+
+// Encode converts the typed command into a pb.Command, assigning it a
+// unique command ID.
+func (t *Typed[T, PT]) Encode(cmd T) (pb.Command, error) {
+	pt := PT(&cmd)
+	data, err := pt.Marshal()
+	if err != nil {
+		return pb.Command{}, err
+	}
+	return pb.Command{
+		ID:      atomic.AddUint64(&t.id, 1),
+		Span:    pt.Span(),
+		Writing: pt.Writing(),
+		Data:    data,
+	}, nil
+}
+
+// Decode converts an executed pb.Command back into the typed command.
+func (t *Typed[T, PT]) Decode(cmd pb.Command) (T, error) {
+	var out T
+	if err := PT(&out).Unmarshal(cmd.Data); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// DecodeAll converts a Ready's executed pb.Commands back into typed
+// commands, preserving execution order.
+func (t *Typed[T, PT]) DecodeAll(cmds []pb.Command) ([]T, error) {
+	out := make([]T, len(cmds))
+	for i, cmd := range cmds {
+		var err error
+		if out[i], err = t.Decode(cmd); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
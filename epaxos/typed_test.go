@@ -0,0 +1,79 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// kvWrite is a minimal typed command used to exercise the Typed wrapper.
+type kvWrite struct {
+	Key   string
+	Value string
+}
+
+func (c *kvWrite) Marshal() ([]byte, error) {
+	return []byte(c.Key + "=" + c.Value), nil
+}
+
+func (c *kvWrite) Unmarshal(data []byte) error {
+	for i, b := range data {
+		if b == '=' {
+			c.Key, c.Value = string(data[:i]), string(data[i+1:])
+			return nil
+		}
+	}
+	c.Key = string(data)
+	return nil
+}
+
+func (c *kvWrite) Span() pb.Span {
+	return pb.Span{Key: pb.Key(c.Key)}
+}
+
+func (c *kvWrite) Writing() bool { return true }
+
+func TestTypedEncodeDecode(t *testing.T) {
+	ty := NewTyped[kvWrite](nil)
+
+	cmd := kvWrite{Key: "a", Value: "1"}
+	pbCmd, err := ty.Encode(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbCmd.ID == 0 {
+		t.Errorf("expected Encode to assign a command ID")
+	}
+	if !pbCmd.Writing {
+		t.Errorf("expected a writing command, found %+v", pbCmd)
+	}
+	if !pbCmd.Span.Equal(pb.Span{Key: pb.Key("a")}) {
+		t.Errorf("expected span [a], found %+v", pbCmd.Span)
+	}
+
+	out, err := ty.Decode(pbCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != cmd {
+		t.Errorf("expected round-tripped command %+v, found %+v", cmd, out)
+	}
+
+	// Encoded commands are assigned unique IDs.
+	pbCmd2, err := ty.Encode(kvWrite{Key: "b", Value: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbCmd2.ID == pbCmd.ID {
+		t.Errorf("expected unique command IDs, found %d twice", pbCmd.ID)
+	}
+
+	// DecodeAll preserves order.
+	cmds, err := ty.DecodeAll([]pb.Command{pbCmd, pbCmd2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 2 || cmds[0] != cmd || cmds[1] != (kvWrite{Key: "b", Value: "2"}) {
+		t.Errorf("unexpected decoded commands: %+v", cmds)
+	}
+}
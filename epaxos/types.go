@@ -0,0 +1,41 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// Re-exported protocol types.
+//
+// The types embedders need for everyday use — identifying replicas and
+// instances, describing commands, and transporting messages — are
+// aliased here so that programs can be written against the epaxos
+// package alone, without importing epaxospb. The aliases are fully
+// interchangeable with the epaxospb originals, so existing callers keep
+// working, while future refactors of the wire types only need to keep
+// these names stable.
+
+// ReplicaID is the id of a replica in an EPaxos deployment.
+type ReplicaID = pb.ReplicaID
+
+// ClusterID is the id of an EPaxos deployment.
+type ClusterID = pb.ClusterID
+
+// InstanceNum is the number of an instance slot in a replica's command
+// space.
+type InstanceNum = pb.InstanceNum
+
+// InstanceID identifies an instance slot in a replica's command space.
+type InstanceID = pb.InstanceID
+
+// Command is a state machine command ordered by the protocol.
+type Command = pb.Command
+
+// Span is the key range a command operates over.
+type Span = pb.Span
+
+// Key is an abstract key in a keyspace.
+type Key = pb.Key
+
+// Message is a protocol message exchanged between replicas. Embedders
+// carry Messages between a Ready's outbox and their peers' Step calls.
+type Message = pb.Message
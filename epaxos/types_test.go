@@ -0,0 +1,31 @@
+package epaxos
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestAliasedAPITypes verifies that the re-exported protocol types are
+// interchangeable with their epaxospb originals by driving a proposal
+// through the public API using only the aliases.
+func TestAliasedAPITypes(t *testing.T) {
+	n := StartNode(&Config{ID: 0, Nodes: []ReplicaID{0}})
+	defer n.Stop()
+
+	cmd := Command{
+		ID:      1,
+		Span:    Span{Key: Key("a"), EndKey: Key("z")},
+		Writing: true,
+		Data:    []byte("payload"),
+	}
+	id, err := n.ProposeWithHandle(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("unexpected error from ProposeWithHandle: %v", err)
+	}
+	var exp InstanceID = pb.InstanceID{ReplicaID: 0, InstanceNum: 1}
+	if id != exp {
+		t.Fatalf("expected instance %+v, found %+v", exp, id)
+	}
+}
@@ -0,0 +1,98 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// warmupInitialLimit is the in-flight proposal cap immediately after a
+// restart.
+const warmupInitialLimit = 1
+
+// warmupFinalLimit is the cap at which warm-up completes and proposals
+// become unlimited again.
+const warmupFinalLimit = 64
+
+// warmupRampInterval is the number of ticks between each doubling of the
+// in-flight proposal cap.
+const warmupRampInterval = 10
+
+// warmupState throttles proposals after a restart. A freshly restarted
+// replica has not yet learned about instances proposed while it was
+// down, so its first proposals are likely to pick stale dependencies and
+// conflict. Ramping the in-flight cap up gradually gives catch-up time
+// to complete before the replica proposes at full speed.
+type warmupState struct {
+	// limit is the current in-flight proposal cap; zero means uncapped.
+	limit int
+	// inFlight counts local proposals that have not yet committed.
+	inFlight int
+	// deferred holds proposals made while at the cap, in arrival order.
+	deferred []*pb.Command
+	// timer ramps the cap up.
+	timer tickingTimer
+}
+
+// startWarmup engages proposal warm-up. Called on restart when persisted
+// instances were found.
+func (p *epaxos) startWarmup() {
+	if p.singleNode() {
+		// With no peers there is nothing to catch up on.
+		return
+	}
+	p.warmup.limit = warmupInitialLimit
+	p.warmup.timer = makeTickingTimer(warmupRampInterval, func() {
+		p.rampWarmup()
+	})
+	p.registerInfiniteTimer(&p.warmup.timer)
+}
+
+// warmingUp returns whether proposal warm-up is in progress.
+func (p *epaxos) warmingUp() bool {
+	return p.warmup.limit > 0
+}
+
+// rampWarmup doubles the in-flight proposal cap, finishing warm-up once
+// the cap reaches warmupFinalLimit.
+func (p *epaxos) rampWarmup() {
+	p.warmup.limit *= 2
+	if p.warmup.limit >= warmupFinalLimit {
+		p.warmup.limit = 0
+		p.unregisterTimer(&p.warmup.timer)
+		p.recoveryLogger.Infof("proposal warm-up complete")
+	}
+	p.drainDeferredProposals()
+}
+
+// deferProposal holds a proposal back until the warm-up cap allows it.
+func (p *epaxos) deferProposal(cmd *pb.Command) {
+	p.recoveryLogger.Debugf("deferring proposal of command %d during warm-up", cmd.ID)
+	p.warmup.deferred = append(p.warmup.deferred, cmd)
+}
+
+// drainDeferredProposals proposes deferred commands in arrival order, up
+// to the current cap.
+func (p *epaxos) drainDeferredProposals() {
+	for len(p.warmup.deferred) > 0 {
+		if p.warmingUp() && p.warmup.inFlight >= p.warmup.limit {
+			return
+		}
+		cmd := p.warmup.deferred[0]
+		p.warmup.deferred = p.warmup.deferred[1:]
+		p.onRequest(cmd)
+	}
+}
+
+// noteProposalInFlight records a new local proposal.
+func (p *epaxos) noteProposalInFlight() {
+	p.warmup.inFlight++
+}
+
+// noteProposalCommitted records that a local proposal has committed,
+// potentially unblocking a deferred proposal.
+func (p *epaxos) noteProposalCommitted(inst *instance) {
+	if inst.is.ReplicaID != p.id || p.warmup.inFlight == 0 {
+		return
+	}
+	p.warmup.inFlight--
+	p.drainDeferredProposals()
+}
@@ -0,0 +1,56 @@
+package epaxos
+
+import (
+	"testing"
+)
+
+// TestWarmupAfterRestart verifies that a restarted replica throttles
+// proposals behind an in-flight cap and ramps back up to full speed.
+func TestWarmupAfterRestart(t *testing.T) {
+	n := newNetwork(3)
+
+	cmd := newTestingCommand("a", "z")
+	inst := n.peers[0].onRequest(cmd)
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	// A fresh node does not warm up; a restarted node does.
+	if n.peers[1].warmingUp() {
+		t.Fatalf("expected no warm-up without a restart")
+	}
+	n.restart(0)
+	p := n.peers[0]
+	if !p.warmingUp() {
+		t.Fatalf("expected warm-up after restart")
+	}
+
+	// The first proposal fits under the initial cap; the second is
+	// deferred until the cap ramps up.
+	if inst := p.onRequest(newTestingCommand("a", "z")); inst == nil {
+		t.Fatalf("expected first post-restart proposal to proceed")
+	}
+	if inst := p.onRequest(newTestingCommand("a", "z")); inst != nil {
+		t.Fatalf("expected second post-restart proposal to be deferred, found %+v", inst)
+	}
+	if len(p.warmup.deferred) != 1 {
+		t.Fatalf("expected 1 deferred proposal, found %d", len(p.warmup.deferred))
+	}
+
+	// The deferred proposal drains as the cap ramps up, and eventually
+	// executes everywhere.
+	executedAll := func(p *epaxos) bool { return p.hasExecuted(0, 3) }
+	for i := 0; !n.allHave(executedAll) || p.warmingUp(); i++ {
+		if i > 10*warmupRampInterval {
+			break
+		}
+		n.tickAll()
+		n.deliverAllMessages()
+	}
+	if !n.allHave(executedAll) {
+		t.Fatalf("deferred proposal never executed")
+	}
+	if p.warmingUp() {
+		t.Errorf("expected warm-up to finish")
+	}
+}
@@ -0,0 +1,83 @@
+package epaxos
+
+import (
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// WatermarkHandler is invoked by the epaxos state machine whenever its
+// executed watermark advances. The handler is passed a copy of the
+// watermark: for each replica, the largest instance number through which
+// all instances have been executed locally. Handlers must not block, as
+// they are invoked from within the state machine.
+type WatermarkHandler func(executed map[pb.ReplicaID]pb.InstanceNum)
+
+// advanceExecutedWatermark advances the executed watermark in response to
+// the provided instance executing. The watermark for a replica only moves
+// when the executed prefix of its instance space is contiguous.
+func (p *epaxos) advanceExecutedWatermark(inst *instance) {
+	r := inst.is.ReplicaID
+	if inst.is.InstanceNum != p.executedPrefix[r]+1 {
+		return
+	}
+
+	next := inst.is.InstanceNum
+	for p.hasExecuted(r, next+1) {
+		next++
+	}
+	p.executedPrefix[r] = next
+
+	// An advancing watermark may unlock a portion of a cluster-agreed
+	// truncation point that the local replica had not yet caught up to.
+	p.maybeTruncate()
+
+	if p.onExecutedWatermark != nil {
+		if p.replaying() && p.replay.opts.SkipWatermarkNotifications {
+			// Defer to a single notification when the bulk replay ends.
+			p.replay.watermarkAdvanced = true
+			return
+		}
+		p.onExecutedWatermark(p.ExecutedWatermark())
+	}
+}
+
+// ExecutedWatermark returns a copy of the node's executed watermark: for
+// each replica, the largest instance number through which all instances
+// have been executed locally.
+func (p *epaxos) ExecutedWatermark() map[pb.ReplicaID]pb.InstanceNum {
+	wm := make(map[pb.ReplicaID]pb.InstanceNum, len(p.executedPrefix))
+	for r, i := range p.executedPrefix {
+		wm[r] = i
+	}
+	return wm
+}
+
+// HoldCompaction prevents the node from compacting instances with
+// instance numbers above the provided points, regardless of how far the
+// executed watermark has advanced. This allows the application to
+// coordinate consensus-log GC with its own retention requirements (e.g.
+// holding compaction until a backup completes).
+func (p *epaxos) HoldCompaction(hold map[pb.ReplicaID]pb.InstanceNum) {
+	p.compactionHold = make(map[pb.ReplicaID]pb.InstanceNum, len(hold))
+	for r, i := range hold {
+		p.compactionHold[r] = i
+	}
+}
+
+// ReleaseCompactionHold removes any compaction hold previously installed
+// with HoldCompaction.
+func (p *epaxos) ReleaseCompactionHold() {
+	p.compactionHold = nil
+}
+
+// compactionUpTo returns, for each replica, the largest instance number
+// that is safe to compact: the executed watermark, clamped by any
+// compaction hold installed by the application.
+func (p *epaxos) compactionUpTo() map[pb.ReplicaID]pb.InstanceNum {
+	upTo := p.ExecutedWatermark()
+	for r, held := range p.compactionHold {
+		if held < upTo[r] {
+			upTo[r] = held
+		}
+	}
+	return upTo
+}
@@ -0,0 +1,63 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+)
+
+// TestExecutedWatermarkAdvances verifies that executing commands advances
+// the executed watermark and invokes the registered handler.
+func TestExecutedWatermarkAdvances(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	var handlerCalls int
+	leader.onExecutedWatermark = func(executed map[pb.ReplicaID]pb.InstanceNum) {
+		handlerCalls++
+	}
+
+	cmd := newTestingCommand("a", "z")
+	inst := leader.onRequest(cmd)
+	if !n.waitExecuteInstance(inst, false /* all nodes */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	if wm := leader.ExecutedWatermark(); wm[leader.id] != 1 {
+		t.Errorf("expected executed watermark 1 for replica %d, found %d", leader.id, wm[leader.id])
+	}
+	if handlerCalls == 0 {
+		t.Errorf("expected watermark handler to be invoked")
+	}
+}
+
+// TestCompactionHold verifies that an application-installed compaction
+// hold clamps the range of instances considered safe to compact.
+func TestCompactionHold(t *testing.T) {
+	n := newNetwork(3)
+	leader := n.peers[0]
+
+	var insts []*instance
+	for i := 0; i < 3; i++ {
+		insts = append(insts, leader.onRequest(newTestingCommand("a", "z")))
+	}
+	for _, inst := range insts {
+		if !n.waitExecuteInstance(inst, false /* all nodes */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+	}
+
+	if upTo := leader.compactionUpTo(); upTo[leader.id] != 3 {
+		t.Errorf("expected compaction up to instance 3, found %d", upTo[leader.id])
+	}
+
+	leader.HoldCompaction(map[pb.ReplicaID]pb.InstanceNum{leader.id: 1})
+	if upTo := leader.compactionUpTo(); upTo[leader.id] != 1 {
+		t.Errorf("expected compaction clamped to instance 1, found %d", upTo[leader.id])
+	}
+
+	leader.ReleaseCompactionHold()
+	if upTo := leader.compactionUpTo(); upTo[leader.id] != 3 {
+		t.Errorf("expected compaction up to instance 3 after release, found %d", upTo[leader.id])
+	}
+}
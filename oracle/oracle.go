@@ -0,0 +1,127 @@
+// Package oracle provides a sequential-specification test oracle for
+// replicated state machines. Embedders supply a sequential Model of their
+// state machine, drive concurrent proposals through their cluster while
+// recording each operation's invocation and response, and then check that
+// the recorded history is linearizable with respect to the model: that some
+// interleaving of the operations, consistent with their real-time order,
+// produces every observed output.
+package oracle
+
+import (
+	"sort"
+	"sync"
+)
+
+// Model is a sequential specification of a state machine. Implementations
+// must treat states as immutable values: Step must return a new state
+// rather than mutating its argument, as the checker explores multiple
+// interleavings from the same state.
+type Model interface {
+	// Init returns the initial state of the state machine.
+	Init() interface{}
+	// Step applies the input to the state, returning whether the output is
+	// consistent with the sequential specification and the resulting state.
+	Step(state, input, output interface{}) (bool, interface{})
+}
+
+// Operation is a single recorded operation in a concurrent history.
+type Operation struct {
+	// Process identifies the client that issued the operation. A process
+	// may only have one operation outstanding at a time.
+	Process int
+	// Input is the operation's input, as interpreted by the Model.
+	Input interface{}
+	// Output is the operation's observed output.
+	Output interface{}
+	// Call and Return bound the interval during which the operation was
+	// outstanding, in any monotonic unit.
+	Call   int64
+	Return int64
+}
+
+// Check determines whether the provided history is linearizable with
+// respect to the model. It searches for an ordering of the operations that
+// respects real time (an operation that returned before another was called
+// must be ordered first) and that the model accepts step-by-step.
+//
+// The search is exponential in the degree of concurrency in the history,
+// so it is intended for test-sized histories.
+func Check(m Model, history []Operation) bool {
+	ops := append([]Operation(nil), history...)
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Call < ops[j].Call })
+	return check(m, m.Init(), ops)
+}
+
+func check(m Model, state interface{}, ops []Operation) bool {
+	if len(ops) == 0 {
+		return true
+	}
+
+	// Only operations invoked before every outstanding operation returned
+	// are candidates to linearize next; anything called later is strictly
+	// after some un-linearized operation in real time.
+	minReturn := ops[0].Return
+	for _, op := range ops[1:] {
+		if op.Return < minReturn {
+			minReturn = op.Return
+		}
+	}
+
+	for i, op := range ops {
+		if op.Call > minReturn {
+			// ops is sorted by call time, so no later operation is a
+			// candidate either.
+			break
+		}
+		ok, next := m.Step(state, op.Input, op.Output)
+		if !ok {
+			continue
+		}
+		rest := make([]Operation, 0, len(ops)-1)
+		rest = append(rest, ops[:i]...)
+		rest = append(rest, ops[i+1:]...)
+		if check(m, next, rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recorder records a concurrent history of operations, assigning call and
+// return timestamps from an internal logical clock. It is safe for
+// concurrent use by multiple goroutines.
+type Recorder struct {
+	mu    sync.Mutex
+	clock int64
+	ops   []Operation
+}
+
+// Record runs the provided function as an operation issued by the given
+// process with the given input, recording its invocation time, its return
+// time, and the output it produces.
+func (r *Recorder) Record(process int, input interface{}, f func() interface{}) {
+	r.mu.Lock()
+	call := r.clock
+	r.clock++
+	r.mu.Unlock()
+
+	output := f()
+
+	r.mu.Lock()
+	r.ops = append(r.ops, Operation{
+		Process: process,
+		Input:   input,
+		Output:  output,
+		Call:    call,
+		Return:  r.clock,
+	})
+	r.clock++
+	r.mu.Unlock()
+}
+
+// History returns the operations recorded so far.
+func (r *Recorder) History() []Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Operation(nil), r.ops...)
+}
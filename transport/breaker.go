@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive send failures
+	// after which a Breaker trips open.
+	DefaultBreakerThreshold = 3
+	// DefaultBreakerCooldown is how long a tripped Breaker stays open
+	// before allowing another send attempt.
+	DefaultBreakerCooldown = 10 * time.Second
+)
+
+// BreakerState is a point-in-time snapshot of a Breaker.
+type BreakerState struct {
+	// Open is whether the breaker is currently rejecting sends.
+	Open bool
+	// ConsecutiveFailures is the current consecutive failure count.
+	ConsecutiveFailures int
+	// RetryAt is when an open breaker will next allow a send attempt.
+	RetryAt time.Time
+}
+
+// Breaker is a per-peer circuit breaker. After a number of consecutive
+// send failures the breaker trips open and rejects further attempts for a
+// cooldown period, saving CPU and log noise when a peer is down for an
+// extended period. A successful send closes the breaker again.
+//
+// A Breaker is safe for concurrent use.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// NewBreaker creates a Breaker that trips after threshold consecutive
+// failures and stays open for the cooldown duration.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow returns whether a send attempt should proceed. While the breaker
+// is open, Allow returns false until the cooldown expires, after which a
+// single attempt is allowed through to probe the peer.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) {
+		return false
+	}
+	// Let one probe through; push the next attempt out a full cooldown so
+	// that a failed probe does not re-open the floodgates.
+	b.openUntil = time.Now().Add(b.cooldown)
+	return true
+}
+
+// Success records a successful send, closing the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// Failure records a failed send. It returns whether this failure tripped
+// the breaker open.
+func (b *Breaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures == b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+// State returns a snapshot of the breaker for status reporting.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerState{
+		Open:                b.failures >= b.threshold && time.Now().Before(b.openUntil),
+		ConsecutiveFailures: b.failures,
+		RetryAt:             b.openUntil,
+	}
+}
@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	epaxospb "github.com/nvanbenschoten/epaxos/epaxos/epaxospb"
+	transpb "github.com/nvanbenschoten/epaxos/transport/transportpb"
+)
+
+// JoinCluster discovers the cluster through the provided seed addresses
+// and initiates the join of the provided replica. It contacts the seeds
+// in order, fetching the current membership, cluster ID and
+// configuration epoch from the first reachable one, validates the
+// description, and asks that seed to propose the membership change
+// admitting the replica at the given address. The fetched description is
+// returned so that the joiner can configure itself to match; if the
+// replica is already a member, it is returned without proposing a
+// change, letting a restarted member rediscover its peers.
+func JoinCluster(
+	seeds []string, id epaxospb.ReplicaID, addr string,
+) (*transpb.ClusterInfo, error) {
+	if len(seeds) == 0 {
+		return nil, errors.New("no seed addresses provided")
+	}
+	ctx := context.Background()
+	var lastErr error
+	for _, seed := range seeds {
+		c, err := NewEPaxosClient(seed)
+		if err != nil {
+			lastErr = errors.Wrapf(err, "dialing seed %q", seed)
+			continue
+		}
+		info, err := c.ClusterInfo(ctx, &transpb.Empty{})
+		if err != nil {
+			c.Close()
+			lastErr = errors.Wrapf(err, "fetching cluster info from seed %q", seed)
+			continue
+		}
+		if err := validateClusterInfo(info); err != nil {
+			c.Close()
+			return nil, errors.Wrapf(err, "seed %q returned invalid cluster info", seed)
+		}
+		if replicaInSlice(id, info.Nodes) {
+			c.Close()
+			return info, nil
+		}
+		_, err = c.Join(ctx, &transpb.JoinRequest{ReplicaID: id, Addr: addr})
+		c.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "joining through seed %q", seed)
+		}
+		return info, nil
+	}
+	return nil, errors.Wrap(lastErr, "no seed reachable")
+}
+
+// validateClusterInfo checks that a cluster description fetched from a
+// seed is internally consistent before it is acted on.
+func validateClusterInfo(info *transpb.ClusterInfo) error {
+	if len(info.Nodes) == 0 {
+		return errors.New("empty membership")
+	}
+	if len(info.Addrs) != len(info.Nodes) {
+		return errors.Errorf("%d addresses for %d members", len(info.Addrs), len(info.Nodes))
+	}
+	seen := make(map[epaxospb.ReplicaID]struct{}, len(info.Nodes))
+	for _, r := range info.Nodes {
+		if _, ok := seen[r]; ok {
+			return errors.Errorf("duplicate member %d", r)
+		}
+		seen[r] = struct{}{}
+	}
+	return nil
+}
+
+func replicaInSlice(id epaxospb.ReplicaID, nodes []epaxospb.ReplicaID) bool {
+	for _, r := range nodes {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
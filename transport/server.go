@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"net"
 
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
@@ -30,8 +31,13 @@ type Request struct {
 
 // EPaxosServer handles internal and external RPC messages for an EPaxos node.
 type EPaxosServer struct {
-	msgC chan *epaxospb.Message
-	reqC chan Request
+	msgC  chan *epaxospb.Message
+	reqC  chan Request
+	joinC chan transpb.JoinRequest
+
+	// clusterInfo, if set, describes the current cluster configuration
+	// for serving to joining nodes. It must be set before Serve.
+	clusterInfo func() transpb.ClusterInfo
 
 	lis        net.Listener
 	grpcServer *grpc.Server
@@ -46,6 +52,7 @@ func NewEPaxosServer(port int) (*EPaxosServer, error) {
 	ps := &EPaxosServer{
 		msgC:       make(chan *epaxospb.Message, 16),
 		reqC:       make(chan Request, 16),
+		joinC:      make(chan transpb.JoinRequest, 16),
 		lis:        lis,
 		grpcServer: grpc.NewServer(),
 	}
@@ -62,8 +69,12 @@ func (ps *EPaxosServer) DeliverMessage(
 ) error {
 	ctx := stream.Context()
 	for {
-		msg, err := stream.Recv()
-		if err != nil {
+		// Receive into a pooled Message instead of the fresh allocation
+		// the generated Recv would make; the consumer returns it with
+		// epaxospb.PutMessage once the message has been stepped.
+		msg := epaxospb.GetMessage()
+		if err := stream.RecvMsg(msg); err != nil {
+			epaxospb.PutMessage(msg)
 			if err == io.EOF {
 				return stream.SendAndClose(&transpb.Empty{})
 			}
@@ -72,6 +83,7 @@ func (ps *EPaxosServer) DeliverMessage(
 		select {
 		case ps.msgC <- msg:
 		case <-ctx.Done():
+			epaxospb.PutMessage(msg)
 			return ctx.Err()
 		}
 	}
@@ -109,8 +121,15 @@ func (ps *EPaxosServer) Read(
 func (ps *EPaxosServer) Write(
 	ctx context.Context, req *transpb.KVWriteRequest,
 ) (*transpb.KVResult, error) {
+	id := rand.Uint64()
+	if req.SessionID != 0 {
+		// A session-scoped request carries a stable identity. Deriving the
+		// command ID from it lets a retried request deduplicate against its
+		// earlier proposal instead of executing twice.
+		id = req.SessionID ^ (req.SessionSeq * 0x9e3779b97f4a7c15)
+	}
 	cmd := epaxospb.Command{
-		ID: rand.Uint64(),
+		ID: id,
 		Span: epaxospb.Span{
 			Key: epaxospb.Key(req.Key),
 		},
@@ -130,8 +149,42 @@ func (ps *EPaxosServer) Write(
 	}
 }
 
+// SetClusterInfoSource registers the function that describes the current
+// cluster configuration, served to joining nodes through the ClusterInfo
+// RPC. It must be called before Serve.
+func (ps *EPaxosServer) SetClusterInfoSource(f func() transpb.ClusterInfo) {
+	ps.clusterInfo = f
+}
+
+// ClusterInfo implements the EPaxosTransportServer interface. It serves
+// the current cluster configuration to a joining node.
+func (ps *EPaxosServer) ClusterInfo(
+	ctx context.Context, _ *transpb.Empty,
+) (*transpb.ClusterInfo, error) {
+	if ps.clusterInfo == nil {
+		return nil, errors.New("cluster information not available on this node")
+	}
+	info := ps.clusterInfo()
+	return &info, nil
+}
+
+// Join implements the EPaxosTransportServer interface. It receives a
+// join request from a new node and passes it on the server's join
+// channel, leaving the membership change proposal to the consumer.
+func (ps *EPaxosServer) Join(
+	ctx context.Context, req *transpb.JoinRequest,
+) (*transpb.Empty, error) {
+	select {
+	case ps.joinC <- *req:
+		return &transpb.Empty{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // Msgs returns the channel that all Paxos messages will be delivered from
-// the server on.
+// the server on. Messages are pooled: the consumer should release each
+// one with epaxospb.PutMessage after stepping it.
 func (ps *EPaxosServer) Msgs() <-chan *epaxospb.Message {
 	return ps.msgC
 }
@@ -142,6 +195,12 @@ func (ps *EPaxosServer) Requests() <-chan Request {
 	return ps.reqC
 }
 
+// Joins returns the channel that join requests from new nodes will be
+// delivered from the server on.
+func (ps *EPaxosServer) Joins() <-chan transpb.JoinRequest {
+	return ps.joinC
+}
+
 // Serve begins serving on server, blocking until Stop is called or an error
 // is observed.
 func (ps *EPaxosServer) Serve() error {
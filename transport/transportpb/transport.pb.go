@@ -69,6 +69,11 @@ func (m *KVReadRequest) GetKey() []byte {
 type KVWriteRequest struct {
 	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// session_id identifies the client session issuing the request, if
+	// any. Together with session_seq it gives retried requests a stable
+	// identity so that duplicate proposals can be filtered downstream.
+	SessionID  uint64 `protobuf:"varint,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	SessionSeq uint64 `protobuf:"varint,4,opt,name=session_seq,json=sessionSeq,proto3" json:"session_seq,omitempty"`
 }
 
 func (m *KVWriteRequest) Reset()                    { *m = KVWriteRequest{} }
@@ -90,6 +95,20 @@ func (m *KVWriteRequest) GetValue() []byte {
 	return nil
 }
 
+func (m *KVWriteRequest) GetSessionID() uint64 {
+	if m != nil {
+		return m.SessionID
+	}
+	return 0
+}
+
+func (m *KVWriteRequest) GetSessionSeq() uint64 {
+	if m != nil {
+		return m.SessionSeq
+	}
+	return 0
+}
+
 // Result is an empty message. It is identical to google.protobuf.Empty, but
 // permits future modifications because it is custom.
 type KVResult struct {
@@ -116,6 +135,77 @@ func (m *KVResult) GetValue() []byte {
 	return nil
 }
 
+// ClusterInfo describes the current cluster configuration: its identity,
+// configuration epoch and membership, with the dialable address of each
+// member. Seed nodes serve it to joining nodes so that peer lists do not
+// need to be hand-maintained.
+type ClusterInfo struct {
+	ClusterID epaxospb.ClusterID   `protobuf:"varint,1,opt,name=cluster_id,json=clusterId,proto3,casttype=github.com/nvanbenschoten/epaxos/epaxos/epaxospb.ClusterID" json:"cluster_id,omitempty"`
+	Epoch     uint64               `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Nodes     []epaxospb.ReplicaID `protobuf:"varint,3,rep,packed,name=nodes,casttype=github.com/nvanbenschoten/epaxos/epaxos/epaxospb.ReplicaID" json:"nodes,omitempty"`
+	// addrs holds the network address of each member, parallel to nodes.
+	Addrs []string `protobuf:"bytes,4,rep,name=addrs" json:"addrs,omitempty"`
+}
+
+func (m *ClusterInfo) Reset()         { *m = ClusterInfo{} }
+func (m *ClusterInfo) String() string { return proto.CompactTextString(m) }
+func (*ClusterInfo) ProtoMessage()    {}
+
+func (m *ClusterInfo) GetClusterID() epaxospb.ClusterID {
+	if m != nil {
+		return m.ClusterID
+	}
+	return 0
+}
+
+func (m *ClusterInfo) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *ClusterInfo) GetNodes() []epaxospb.ReplicaID {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *ClusterInfo) GetAddrs() []string {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+// JoinRequest asks the receiving node to initiate the join of a new
+// replica by proposing the corresponding membership change.
+type JoinRequest struct {
+	ReplicaID epaxospb.ReplicaID `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3,casttype=github.com/nvanbenschoten/epaxos/epaxos/epaxospb.ReplicaID" json:"replica_id,omitempty"`
+	// addr is the joining replica's network address, so that existing
+	// members can dial it.
+	Addr string `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+}
+
+func (m *JoinRequest) Reset()         { *m = JoinRequest{} }
+func (m *JoinRequest) String() string { return proto.CompactTextString(m) }
+func (*JoinRequest) ProtoMessage()    {}
+
+func (m *JoinRequest) GetReplicaID() epaxospb.ReplicaID {
+	if m != nil {
+		return m.ReplicaID
+	}
+	return 0
+}
+
+func (m *JoinRequest) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Empty)(nil), "transportpb.Empty")
 	proto.RegisterType((*KVReadRequest)(nil), "transportpb.KVReadRequest")
@@ -135,6 +225,8 @@ const _ = grpc.SupportPackageIsVersion4
 
 type EPaxosTransportClient interface {
 	DeliverMessage(ctx context.Context, opts ...grpc.CallOption) (EPaxosTransport_DeliverMessageClient, error)
+	ClusterInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterInfo, error)
+	Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type ePaxosTransportClient struct {
@@ -179,10 +271,30 @@ func (x *ePaxosTransportDeliverMessageClient) CloseAndRecv() (*Empty, error) {
 	return m, nil
 }
 
+func (c *ePaxosTransportClient) ClusterInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterInfo, error) {
+	out := new(ClusterInfo)
+	err := grpc.Invoke(ctx, "/transportpb.EPaxosTransport/ClusterInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ePaxosTransportClient) Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/transportpb.EPaxosTransport/Join", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for EPaxosTransport service
 
 type EPaxosTransportServer interface {
 	DeliverMessage(EPaxosTransport_DeliverMessageServer) error
+	ClusterInfo(context.Context, *Empty) (*ClusterInfo, error)
+	Join(context.Context, *JoinRequest) (*Empty, error)
 }
 
 func RegisterEPaxosTransportServer(s *grpc.Server, srv EPaxosTransportServer) {
@@ -215,10 +327,55 @@ func (x *ePaxosTransportDeliverMessageServer) Recv() (*epaxospb.Message, error)
 	return m, nil
 }
 
+func _EPaxosTransport_ClusterInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EPaxosTransportServer).ClusterInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transportpb.EPaxosTransport/ClusterInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EPaxosTransportServer).ClusterInfo(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EPaxosTransport_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EPaxosTransportServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transportpb.EPaxosTransport/Join",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EPaxosTransportServer).Join(ctx, req.(*JoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _EPaxosTransport_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "transportpb.EPaxosTransport",
 	HandlerType: (*EPaxosTransportServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ClusterInfo",
+			Handler:    _EPaxosTransport_ClusterInfo_Handler,
+		},
+		{
+			MethodName: "Join",
+			Handler:    _EPaxosTransport_Join_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "DeliverMessage",
@@ -395,6 +552,16 @@ func (m *KVWriteRequest) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTransport(dAtA, i, uint64(len(m.Value)))
 		i += copy(dAtA[i:], m.Value)
 	}
+	if m.SessionID != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.SessionID))
+	}
+	if m.SessionSeq != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.SessionSeq))
+	}
 	return i, nil
 }
 
@@ -428,6 +595,95 @@ func (m *KVResult) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
+func (m *ClusterInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ClusterInfo) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ClusterID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.ClusterID))
+	}
+	if m.Epoch != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.Epoch))
+	}
+	if len(m.Nodes) > 0 {
+		dAtA3 := make([]byte, len(m.Nodes)*10)
+		var j2 int
+		for _, num := range m.Nodes {
+			for num >= 1<<7 {
+				dAtA3[j2] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j2++
+			}
+			dAtA3[j2] = uint8(num)
+			j2++
+		}
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(j2))
+		i += copy(dAtA[i:], dAtA3[:j2])
+	}
+	if len(m.Addrs) > 0 {
+		for _, s := range m.Addrs {
+			dAtA[i] = 0x22
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	return i, nil
+}
+
+func (m *JoinRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *JoinRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.ReplicaID))
+	}
+	if len(m.Addr) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(len(m.Addr)))
+		i += copy(dAtA[i:], m.Addr)
+	}
+	return i, nil
+}
+
 func encodeFixed64Transport(dAtA []byte, offset int, v uint64) int {
 	dAtA[offset] = uint8(v)
 	dAtA[offset+1] = uint8(v >> 8)
@@ -482,6 +738,12 @@ func (m *KVWriteRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovTransport(uint64(l))
 	}
+	if m.SessionID != 0 {
+		n += 1 + sovTransport(uint64(m.SessionID))
+	}
+	if m.SessionSeq != 0 {
+		n += 1 + sovTransport(uint64(m.SessionSeq))
+	}
 	return n
 }
 
@@ -499,6 +761,44 @@ func (m *KVResult) Size() (n int) {
 	return n
 }
 
+func (m *ClusterInfo) Size() (n int) {
+	var l int
+	_ = l
+	if m.ClusterID != 0 {
+		n += 1 + sovTransport(uint64(m.ClusterID))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovTransport(uint64(m.Epoch))
+	}
+	if len(m.Nodes) > 0 {
+		l = 0
+		for _, e := range m.Nodes {
+			l += sovTransport(uint64(e))
+		}
+		n += 1 + sovTransport(uint64(l)) + l
+	}
+	if len(m.Addrs) > 0 {
+		for _, s := range m.Addrs {
+			l = len(s)
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *JoinRequest) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		n += 1 + sovTransport(uint64(m.ReplicaID))
+	}
+	l = len(m.Addr)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	return n
+}
+
 func sovTransport(x uint64) (n int) {
 	for {
 		n++
@@ -734,6 +1034,44 @@ func (m *KVWriteRequest) Unmarshal(dAtA []byte) error {
 				m.Value = []byte{}
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionID", wireType)
+			}
+			m.SessionID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SessionID |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionSeq", wireType)
+			}
+			m.SessionSeq = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SessionSeq |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTransport(dAtA[iNdEx:])
@@ -867,6 +1205,283 @@ func (m *KVResult) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *ClusterInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ClusterInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ClusterInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClusterID", wireType)
+			}
+			m.ClusterID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ClusterID |= (epaxospb.ClusterID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType == 0 {
+				var v epaxospb.ReplicaID
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowTransport
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (epaxospb.ReplicaID(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Nodes = append(m.Nodes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowTransport
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthTransport
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v epaxospb.ReplicaID
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowTransport
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (epaxospb.ReplicaID(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Nodes = append(m.Nodes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addrs", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Addrs = append(m.Addrs, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *JoinRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: JoinRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: JoinRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (epaxospb.ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipTransport(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0